@@ -0,0 +1,96 @@
+package main
+
+// api.go – wires the optional HTTP control API (pkg/api) to the app.
+// Enabled by the api section of config.yml; it shares ipc.go's SetKey.
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/api"
+	"github.com/merith-tk/nomad/pkg/scripting"
+)
+
+// startAPI starts the HTTP control API if it is enabled.
+func (a *App) startAPI() {
+	cfg := a.config.API
+	if !cfg.Enabled {
+		return
+	}
+	if host, _, err := net.SplitHostPort(cfg.Listen); err == nil {
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Printf("[!] HTTP API listening on non-loopback address %s; anyone on the network can control the deck\n", cfg.Listen)
+		}
+	}
+
+	a.api = api.New(a)
+	a.api.SetAllowedOrigins(cfg.AllowedOrigins)
+	go func() {
+		if err := a.api.ListenAndServe(cfg.Listen); err != nil {
+			log.Printf("HTTP API stopped: %v", err)
+		}
+	}()
+	fmt.Printf("[*] HTTP API listening on http://%s\n", cfg.Listen)
+}
+
+// State implements api.Controller.
+func (a *App) State() api.State {
+	st := api.State{
+		Model:      a.device.Model.Name,
+		Cols:       a.device.Cols(),
+		Rows:       a.device.Rows(),
+		Keys:       a.device.Model.Keys,
+		InSettings: a.inSettings,
+	}
+	a.sleepMu.Lock()
+	st.Sleeping = a.sleeping
+	a.sleepMu.Unlock()
+
+	if rel, err := filepath.Rel(a.configPath, a.nav.CurrentPath()); err == nil {
+		st.Path = filepath.ToSlash(rel)
+	}
+	if page, err := a.nav.LoadPage(); err == nil {
+		st.Page = page.PageIndex
+		st.Pages = page.TotalPages
+	}
+	return st
+}
+
+// Metrics implements api.Controller.
+func (a *App) Metrics() api.Metrics {
+	sm := a.scriptMgr.Metrics()
+	hid := a.device.WriteStats()
+	out := api.Metrics{
+		Render:      apiTiming(sm.Render),
+		PassiveTick: apiTiming(sm.PassiveTick),
+		Passive:     make(map[string]api.Timing, len(sm.Passive)),
+		HIDReports:  hid.Reports,
+		HIDErrors:   hid.Errors,
+		KeyImages:   hid.KeyImages,
+	}
+	for path, t := range sm.Passive {
+		if rel, err := filepath.Rel(a.configPath, path); err == nil {
+			path = filepath.ToSlash(rel)
+		}
+		out.Passive[path] = apiTiming(t)
+	}
+	return out
+}
+
+// apiTiming converts a scripting.Timing to milliseconds.
+func apiTiming(t scripting.Timing) api.Timing {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	return api.Timing{Count: t.Count, AvgMS: ms(t.Avg()), MaxMS: ms(t.Max), LastMS: ms(t.Last)}
+}
+
+// Describe implements api.Controller.
+func (a *App) Describe() string {
+	desc := a.nav.Describe()
+	if a.inSettings {
+		desc = "settings menu open over:\n" + desc
+	}
+	return desc
+}
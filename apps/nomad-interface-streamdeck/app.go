@@ -17,9 +17,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"image"
 	"image/color"
+	"image/draw"
+	"io"
 	"log"
 	"os"
 	"os/signal"
@@ -28,6 +32,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/merith-tk/nomad/pkg/api"
+	"github.com/merith-tk/nomad/pkg/ipc"
 	"github.com/merith-tk/nomad/pkg/scripting"
 	"github.com/merith-tk/nomad/pkg/streamdeck"
 )
@@ -51,17 +57,63 @@ type App struct {
 	sleeping     bool
 	sleepTimer   *time.Timer
 	lastActivity time.Time
+
+	// Local IPC socket for external programs (nil when disabled)
+	ipc *ipc.Server
+
+	// HTTP control API (nil when disabled)
+	api *api.Server
+
+	// Script log file (nil when logging.file is unset)
+	logFile *os.File
+
+	// HID report trace file (nil unless --trace-reports names a file)
+	traceFile *os.File
+
+	// Command-line overrides
+	opts Options
+
+	// Keys currently held, for the debug combo (see debug.go)
+	keysDown map[int]bool
+
+	// Script each held key's press was sent to, for "long" and
+	// trigger_release, and the last tap per key, for "double"
+	held    map[int]heldKey
+	lastTap map[int]keyTap
+
+	// Last queued trigger work per key (see queueKey)
+	keyTail map[int]chan struct{}
 }
 
-// NewApp creates a new application instance.
-func NewApp() *App {
-	return &App{}
+// Press timing for the trigger event kinds (see triggerScript).
+const (
+	longPressDelay  = 500 * time.Millisecond
+	doubleTapWindow = 300 * time.Millisecond
+)
+
+// heldKey is the script a key press triggered, so the release reaches the
+// same script even if the page changed while the key was down.
+type heldKey struct {
+	script   string
+	released chan struct{} // closed by handleKeyRelease
+}
+
+// keyTap is a press that fired a "tap", for double-tap detection.
+type keyTap struct {
+	script string
+	at     time.Time
+}
+
+// NewApp creates a new application instance using the command-line options.
+func NewApp(opts Options) *App {
+	return &App{opts: opts}
 }
 
 // Init initializes the application, including device discovery and setup.
 // It performs the following steps:
 // 1. Initializes the Stream Deck library
-// 2. Enumerates available devices and selects the first one
+// 2. Enumerates available devices and selects the first one (or the one
+// matching device.serial), or creates a virtual device in headless mode
 // 3. Opens the device and sets initial brightness
 // 4. Creates the config directory structure
 // 5. Initializes the script manager and navigator
@@ -70,7 +122,7 @@ func NewApp() *App {
 // Returns an error if initialization fails at any step.
 func (a *App) Init() error {
 	// Determine config path first
-	configPath := getConfigPath()
+	configPath := a.opts.configDir(os.Getenv)
 
 	// Ensure config directory exists
 	absConfigPath, err := ensureConfigDir(configPath)
@@ -84,15 +136,120 @@ func (a *App) Init() error {
 	if err != nil {
 		log.Printf("Warning: Failed to load config, using defaults: %v", err)
 		config = DefaultConfig()
+		applyEnvOverrides(config)
 	}
+	a.opts.apply(config)
 	a.config = config
 
 	fmt.Printf("\n[*] Config directory: %s\n", absConfigPath)
 	fmt.Printf("[*] Configuration loaded\n")
 
+	if a.config.Scripting.InstallExamples {
+		if n, err := installDefaultScripts(absConfigPath); err != nil {
+			fmt.Printf("[!] Failed to install example scripts: %v\n", err)
+		} else if n > 0 {
+			fmt.Printf("[*] Installed %d example files into the empty config directory\n", n)
+		}
+	}
+
+	dev, err := a.openDevice()
+	if err != nil {
+		return err
+	}
+	a.device = dev
+	if a.opts.TraceReports != "" {
+		a.startReportTrace(a.opts.TraceReports)
+	}
+	if err := dev.SetRotation(a.config.UI.Rotation); err != nil {
+		fmt.Printf("[!] Ignoring ui.rotation: %v\n", err)
+	}
+	dev.SetKeyPolling(time.Duration(a.config.Performance.PollIntervalMs)*time.Millisecond,
+		time.Duration(a.config.Performance.ReadTimeoutMs)*time.Millisecond)
+	dev.SetBlockingReads(a.config.Performance.BlockingReads)
+
+	// Set brightness from config
+	if err := dev.SetBrightness(a.config.Application.Brightness); err != nil {
+		log.Printf("SetBrightness failed: %v", err)
+	}
+
+	fmt.Printf("\n[*] Config directory: %s\n", a.configPath)
+
+	// Create script manager and boot (loads scripts, starts background workers)
+	fmt.Println("[*] Booting script manager...")
+	a.scriptMgr = scripting.NewScriptManager(dev, absConfigPath, a.config.Application.PassiveFPS)
+	images := scripting.NewImageCacheWithLimits(
+		a.config.Performance.ImageCacheSize, a.config.Performance.ImageCacheEntries)
+	images.SetSVGSize(dev.Model.PixelSize)
+	a.scriptMgr.SetImageCache(images)
+	a.scriptMgr.SetBackgroundEnabled(a.config.Scripting.EnableBackground)
+	a.scriptMgr.SetNetworkBlocked(a.config.Security.BlockNetwork)
+	a.scriptMgr.SetSandboxed(a.config.Security.SandboxStdlib)
+	a.scriptMgr.SetVersion(version)
+	a.scriptMgr.SetLimits(scripting.ScriptLimits{
+		CallDepth:  a.config.Scripting.MaxCallDepth,
+		StackSlots: a.config.Scripting.MaxStackSlots,
+		MemoryMB:   a.config.Scripting.MaxMemoryMB,
+	})
+	a.scriptMgr.SetLogger(a.newScriptLogger())
+	a.scriptMgr.SetMaxBackgroundWorkers(a.config.Scripting.MaxConcurrentScripts)
+	a.scriptMgr.SetMaxConcurrentLoads(a.config.Scripting.MaxConcurrentScripts)
+	a.scriptMgr.SetLazyLoad(a.config.Scripting.LazyLoad)
+	a.scriptMgr.SetUnloadPolicy(time.Duration(a.config.Scripting.UnloadAfter)*time.Second,
+		a.config.Scripting.MaxLoadedScripts)
+
+	// Create navigator (before Boot so eagerly loaded scripts can claim
+	// content keys)
+	a.nav = streamdeck.NewNavigator(dev, absConfigPath)
+	a.nav.SetScriptValidator(a.scriptMgr.IsUsableScript)
+	a.nav.SetScriptError(a.scriptMgr.LoadError)
+	a.nav.SetShowHidden(a.config.UI.ShowHiddenFiles)
+	a.nav.SetSortMode(streamdeck.ParseSortMode(a.config.UI.Sort))
+	a.nav.SetItemLoading(func(item *streamdeck.PageItem) bool {
+		return item.Script != "" && a.scriptMgr.ImagePending(item.Script)
+	})
+	if err := a.nav.SetStartPath(a.config.UI.StartPath); err != nil {
+		fmt.Printf("[!] Ignoring ui.start_path: %v\n", err)
+	}
+	a.scriptMgr.SetCurrentPath(a.nav.CurrentPath())
+	a.setupKeyClaims()
+
+	// Create a context for the entire application
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+
+	// Boot scripts (shows loading indicator, loads all scripts)
+	if err := a.scriptMgr.Boot(a.ctx); err != nil {
+		log.Printf("Warning: Script boot error: %v", err)
+	}
+
+	// Set up passive key updates from scripts
+	a.setupKeyUpdateCallback()
+
+	// Start the passive update loop (15fps)
+	a.scriptMgr.StartPassiveLoop()
+
+	// Let external programs drive keys
+	a.startIPC()
+	a.startAPI()
+
+	return nil
+}
+
+// openDevice opens the configured Stream Deck, or a virtual one in headless
+// mode.
+func (a *App) openDevice() (*streamdeck.Device, error) {
+	if err := checkDeviceModel(a.config.Device.Model); err != nil {
+		fmt.Printf("[!] Ignoring device.model: %v\n", err)
+	}
+
+	if a.config.Device.Headless {
+		model := virtualModel(a.config.Device.Model)
+		fmt.Printf("[*] Headless mode: emulating a %s\n", model.Name)
+		return streamdeck.OpenVirtual(model), nil
+	}
+
 	// Initialize the streamdeck library
 	if err := streamdeck.Init(); err != nil {
-		return fmt.Errorf("failed to init streamdeck: %w", err)
+		return nil, fmt.Errorf("failed to init streamdeck: %w", err)
 	}
 
 	// Probe for all Stream Deck devices
@@ -100,12 +257,17 @@ func (a *App) Init() error {
 
 	devices, err := streamdeck.Enumerate()
 	if err != nil {
-		return fmt.Errorf("failed to enumerate devices: %w", err)
+		return nil, fmt.Errorf("failed to enumerate devices: %w", err)
 	}
 
 	if len(devices) == 0 {
 		fmt.Println("No Stream Deck devices found.")
-		return fmt.Errorf("no devices found")
+		return nil, fmt.Errorf("no devices found")
+	}
+
+	forced, force := streamdeck.FindModel(a.config.Device.Model)
+	if force {
+		forceModel(devices, forced)
 	}
 
 	fmt.Printf("Found %d Stream Deck device(s):\n\n", len(devices))
@@ -116,51 +278,61 @@ func (a *App) Init() error {
 		fmt.Println()
 	}
 
-	// Use the first device
-	info := devices[0]
-	if info.Model.PixelSize == 0 {
-		fmt.Println("First device has no display (e.g., Pedal). Skipping.")
-		return fmt.Errorf("device has no display")
+	info, err := selectDevice(devices, a.config.Device.Serial)
+	if err != nil {
+		return nil, err
 	}
 
 	fmt.Printf("Opening %s...\n", info.Model.Name)
 
 	dev, err := streamdeck.OpenWithConfig(info.Path, a.config.Performance.JPEGQuality)
 	if err != nil {
-		return fmt.Errorf("failed to open device: %w", err)
+		return nil, fmt.Errorf("failed to open device: %w", err)
 	}
-	a.device = dev
-
-	// Set brightness from config
-	if err := dev.SetBrightness(a.config.Application.Brightness); err != nil {
-		log.Printf("SetBrightness failed: %v", err)
+	if force && dev.Model != forced {
+		fmt.Printf("[*] device.model: driving %s as a %s\n", dev.Model.Name, forced.Name)
+		dev.UseModel(forced)
 	}
+	return dev, nil
+}
 
-	fmt.Printf("\n[*] Config directory: %s\n", a.configPath)
-
-	// Create script manager and boot (loads scripts, starts background workers)
-	fmt.Println("[*] Booting script manager...")
-	a.scriptMgr = scripting.NewScriptManager(dev, absConfigPath, a.config.Application.PassiveFPS)
-
-	// Create a context for the entire application
-	a.ctx, a.cancel = context.WithCancel(context.Background())
-
-	// Boot scripts (shows loading indicator, loads all scripts)
-	if err := a.scriptMgr.Boot(a.ctx); err != nil {
-		log.Printf("Warning: Script boot error: %v", err)
+// forceModel gives every device the parameters of model m (device.model) in
+// place of the detected ones, so clones and new variants, including those
+// reporting another model's product ID, can be driven as the model they
+// match. Devices already detected as m are left alone.
+func forceModel(devices []streamdeck.DeviceInfo, m streamdeck.Model) {
+	for i, info := range devices {
+		if info.Model != m {
+			devices[i].Model = m.Forced(info.Model.ProductID)
+		}
 	}
+}
 
-	// Create navigator
-	a.nav = streamdeck.NewNavigator(dev, absConfigPath)
-	a.nav.SetScriptValidator(a.scriptMgr.IsUsableScript)
-
-	// Set up passive key updates from scripts
-	a.setupKeyUpdateCallback()
-
-	// Start the passive update loop (15fps)
-	a.scriptMgr.StartPassiveLoop()
+// selectDevice picks the device with the given serial, or the first device
+// when serial is "". The chosen device must have a display.
+func selectDevice(devices []streamdeck.DeviceInfo, serial string) (streamdeck.DeviceInfo, error) {
+	for _, info := range devices {
+		if serial != "" && info.Serial != serial {
+			continue
+		}
+		if info.Model.PixelSize == 0 {
+			return info, fmt.Errorf("%s has no display", info.Model.Name)
+		}
+		return info, nil
+	}
+	if serial != "" {
+		return streamdeck.DeviceInfo{}, fmt.Errorf("no device with serial %q", serial)
+	}
+	return streamdeck.DeviceInfo{}, fmt.Errorf("no devices found")
+}
 
-	return nil
+// virtualModel finds the model named by device.model (case-insensitive) for
+// headless mode, defaulting to the Stream Deck MK.2.
+func virtualModel(name string) streamdeck.Model {
+	if m, ok := streamdeck.FindModel(name); ok && m.HasDisplay() {
+		return m
+	}
+	return streamdeck.Models[0x0080]
 }
 
 // setupKeyUpdateCallback sets up the callback for script-driven key updates.
@@ -173,55 +345,106 @@ func (a *App) setupKeyUpdateCallback() {
 
 		// Don't let passive/background scripts paint over the settings overlay
 		// or a sleeping (blank) display.
-		if a.inSettings {
-			return
-		}
-		a.sleepMu.Lock()
-		isSleeping := a.sleeping
-		a.sleepMu.Unlock()
-		if isSleeping {
+		if a.displayBusy() {
 			return
 		}
 
-		// Check for custom image first
-		if appearance.Image != "" {
-			img, err := scripting.LoadImage(appearance.Image)
-			if err == nil {
-				// Resize to fit key and display
-				resized := a.device.ResizeImage(img)
-				a.device.SetImage(keyIndex, resized)
-				return
-			}
-			// Fall through to color/text if image load fails
-			log.Printf("Image load failed: %v", err)
+		if err := a.renderAppearance(keyIndex, appearance); err != nil {
+			log.Printf("Key %d update failed: %v", keyIndex, err)
 		}
+	})
+}
 
-		// Apply appearance to key
-		c := color.RGBA{
-			R: uint8(appearance.Color[0]),
-			G: uint8(appearance.Color[1]),
-			B: uint8(appearance.Color[2]),
-			A: 255,
+// setupKeyClaims lets scripts claim content keys (streamdeck.claim_key).
+// Claimed keys are skipped by page rendering and redrawn when released.
+func (a *App) setupKeyClaims() {
+	a.scriptMgr.SetClaimableKeys(a.nav.GetContentKeys())
+	a.nav.SetKeyClaimed(func(keyIndex int) bool {
+		return a.scriptMgr.KeyClaimant(keyIndex) != ""
+	})
+	a.scriptMgr.SetKeyReleasedCallback(func(keyIndex int) {
+		if a.displayBusy() {
+			return
 		}
-		if appearance.Text != "" {
-			// Create text image with appearance colors
-			img := a.nav.CreateTextImageWithColors(
-				appearance.Text,
-				c,
-				color.RGBA{
-					R: uint8(appearance.TextColor[0]),
-					G: uint8(appearance.TextColor[1]),
-					B: uint8(appearance.TextColor[2]),
-					A: 255,
-				},
-			)
-			a.device.SetImage(keyIndex, img)
-		} else {
-			a.device.SetKeyColor(keyIndex, c)
+		if err := a.nav.RenderKey(keyIndex); err != nil {
+			log.Printf("Key %d redraw failed: %v", keyIndex, err)
 		}
 	})
 }
 
+// displayBusy reports whether the settings overlay is open or the display is
+// asleep, in which case key updates from scripts and clients are dropped.
+func (a *App) displayBusy() bool {
+	if a.inSettings {
+		return true
+	}
+	a.sleepMu.Lock()
+	defer a.sleepMu.Unlock()
+	return a.sleeping
+}
+
+// renderAppearance draws a KeyAppearance on a key: its image if one is set
+// and loads, otherwise text on the background colour, otherwise the colour.
+// Remote images are fetched in the background; the key shows a loading
+// placeholder until a later update finds the image cached.
+func (a *App) renderAppearance(keyIndex int, appearance *scripting.KeyAppearance) error {
+	filter := appearance.Filter()
+
+	// Check for custom image first
+	if appearance.Image != "" {
+		img, pending, err := a.scriptMgr.LoadImageNonBlocking(appearance.Image)
+		if pending {
+			return a.nav.RenderLoadingKey(keyIndex)
+		}
+		if err == nil {
+			// Resize to fit key and display
+			resized := a.device.ResizeImage(img)
+			if appearance.Text != "" {
+				// Label the icon; filters apply to the composite
+				var outline color.Color
+				if o := appearance.Outline; o != nil {
+					outline = rgb(*o)
+				}
+				resized = streamdeck.LabelImage(resized, appearance.Text, rgb(appearance.TextColor), outline)
+			}
+			return a.setKeyImage(keyIndex, streamdeck.ApplyFilter(resized, filter))
+		}
+		// Fall through to color/text if image load fails
+		log.Printf("Image load failed: %v", err)
+	}
+
+	// Apply appearance to key
+	c := rgb(appearance.Color)
+	if appearance.Text != "" {
+		// Create text image with appearance colors
+		img := a.nav.CreateTextImageWithColors(appearance.Text, c, rgb(appearance.TextColor))
+		return a.setKeyImage(keyIndex, streamdeck.ApplyFilter(img, filter))
+	}
+	if !filter.IsZero() || a.config.UI.TransitionMs > 0 {
+		size := a.device.PixelSize()
+		img := image.NewRGBA(image.Rect(0, 0, size, size))
+		draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+		return a.setKeyImage(keyIndex, streamdeck.ApplyFilter(img, filter))
+	}
+	return a.device.SetKeyColor(keyIndex, c)
+}
+
+// setKeyImage draws a script-supplied image, crossfading from the key's
+// previous image when ui.transition_ms is set.
+func (a *App) setKeyImage(keyIndex int, img image.Image) error {
+	d := time.Duration(a.config.UI.TransitionMs) * time.Millisecond
+	if d <= 0 {
+		return a.device.SetImage(keyIndex, img)
+	}
+	_, err := a.device.FadeImage(keyIndex, img, d)
+	return err
+}
+
+// rgb converts an appearance colour to an opaque color.RGBA.
+func rgb(c [3]int) color.RGBA {
+	return color.RGBA{R: uint8(c[0]), G: uint8(c[1]), B: uint8(c[2]), A: 255}
+}
+
 // resetSleepTimer resets (or starts) the inactivity sleep timer.
 // Must be called after any key activity and after timeout config changes.
 func (a *App) resetSleepTimer() {
@@ -268,17 +491,9 @@ func (a *App) wakeDisplay() bool {
 // It renders the initial page, sets up signal handling for graceful shutdown,
 // and processes key events from the Stream Deck device.
 func (a *App) Run() error {
-	// Helper to update visible scripts
-	updateVisibleScripts := func() {
-		a.scriptMgr.SetVisibleScripts(a.nav.GetVisibleScripts())
-	}
-
 	// Render initial page
 	fmt.Println("[*] Loading page...")
-	a.scriptMgr.SetVisibleScripts(nil) // Clear before render
-	if err := a.nav.RenderPage(); err != nil {
-		log.Printf("Warning: RenderPage failed: %v", err)
-	}
+	a.showPage()
 
 	// Show current path
 	page, _ := a.nav.LoadPage()
@@ -296,9 +511,6 @@ func (a *App) Run() error {
 	a.lastActivity = time.Now()
 	a.resetSleepTimer()
 
-	// Update visible scripts for initial page
-	updateVisibleScripts()
-
 	// Handle Ctrl+C
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
@@ -308,39 +520,66 @@ func (a *App) Run() error {
 		a.cancel()
 	}()
 
+	// Dial and touch strip events (Stream Deck +); nil channels never
+	// deliver on other models
+	var dials chan streamdeck.DialEvent
+	var touches chan streamdeck.TouchEvent
+	if a.device.Model.Dials > 0 || a.device.Model.TouchStrip {
+		dials = make(chan streamdeck.DialEvent, 10)
+		touches = make(chan streamdeck.TouchEvent, 10)
+		a.device.SetInputEvents(dials, touches)
+	}
+
 	// Listen for key events
 	events := make(chan streamdeck.KeyEvent, 10)
 	a.device.ListenKeys(a.ctx, events)
 
-	for event := range events {
-		if err := a.handleKeyEvent(event); err != nil {
-			log.Printf("Error handling key event: %v", err)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				fmt.Println("Done!")
+				return nil
+			}
+			if a.ipc != nil {
+				a.ipc.PublishKey(event.Key, event.Pressed)
+			}
+			if a.api != nil {
+				a.api.PublishKey(event.Key, event.Pressed)
+			}
+			if err := a.handleKeyEvent(event); err != nil {
+				log.Printf("Error handling key event: %v", err)
+			}
+		case ev := <-dials:
+			a.handleDialEvent(ev)
+		case ev := <-touches:
+			if err := a.handleTouchEvent(ev); err != nil {
+				log.Printf("Error handling touch event: %v", err)
+			}
 		}
 	}
-
-	fmt.Println("Done!")
-	return nil
 }
 
 // handleKeyEvent processes a single key event.
 // It handles navigation, toggle states, and script triggers based on the key pressed.
 func (a *App) handleKeyEvent(event streamdeck.KeyEvent) error {
-	// Only handle key presses, not releases
+	a.trackKey(event)
+
+	// Releases only go to the script the press triggered (trigger_release)
 	if !event.Pressed {
+		a.handleKeyRelease(event.Key)
 		return nil
 	}
 
-	// Reset / restart the inactivity sleep timer on every key press.
-	a.lastActivity = time.Now()
-	a.resetSleepTimer()
+	// Every key press restarts the inactivity sleep timer; if the display
+	// is sleeping, the press only wakes it up.
+	if a.wake() {
+		return nil
+	}
 
-	// If the display is sleeping, the first key press only wakes it up.
-	if a.wakeDisplay() {
-		if a.inSettings {
-			a.renderSettingsPage()
-		} else {
-			_ = a.nav.RenderPage()
-		}
+	// Holding one toggle key and pressing the other dumps the app state.
+	if a.isDebugCombo(event.Key) {
+		a.dumpState(debugOutput)
 		return nil
 	}
 
@@ -357,7 +596,8 @@ func (a *App) handleKeyEvent(event streamdeck.KeyEvent) error {
 
 	// Intercept T1/T2 BEFORE passing to the navigator so the old toggle
 	// logic inside HandleKeyPress never fires for these keys.
-	if event.Key == streamdeck.KeyToggle1 {
+	t1, t2 := a.nav.ToggleKeys()
+	if event.Key == t1 {
 		if a.scriptMgr.HasT1Script() {
 			go func() {
 				if err := a.scriptMgr.TriggerT1(); err != nil {
@@ -368,7 +608,7 @@ func (a *App) handleKeyEvent(event streamdeck.KeyEvent) error {
 		// No script assigned: key is reserved/inert.
 		return nil
 	}
-	if event.Key == streamdeck.KeyToggle2 {
+	if event.Key == t2 {
 		if a.scriptMgr.HasT2Script() {
 			go func() {
 				if err := a.scriptMgr.TriggerT2(); err != nil {
@@ -380,23 +620,28 @@ func (a *App) handleKeyEvent(event streamdeck.KeyEvent) error {
 		return nil
 	}
 
+	// A claimed key belongs to the script that claimed it, whatever the
+	// page shows underneath.
+	if owner := a.scriptMgr.KeyClaimant(event.Key); owner != "" {
+		a.triggerScript(owner, event.Key, false)
+		return nil
+	}
+
 	// Handle the key press
 	item, navigated, err := a.nav.HandleKeyPress(event.Key)
 	if err != nil {
 		return fmt.Errorf("handling key press: %w", err)
 	}
+	a.activate(item, navigated, event.Key)
+	return nil
+}
 
+// activate acts on the result of pressing key on the page: it shows the new
+// page after navigation, logs why an error tile's script did not load, or
+// triggers the item's script.
+func (a *App) activate(item *streamdeck.PageItem, navigated bool, key int) {
 	if navigated {
-		// Clear visible scripts BEFORE render to prevent race condition
-		a.scriptMgr.SetVisibleScripts(nil)
-
-		// Page changed, re-render
-		if err := a.nav.RenderPage(); err != nil {
-			log.Printf("RenderPage failed: %v", err)
-		}
-
-		// Update visible scripts for passive updates
-		a.updateVisibleScripts()
+		a.showPage()
 
 		page, _ := a.nav.LoadPage()
 		if page != nil {
@@ -408,32 +653,226 @@ func (a *App) handleKeyEvent(event streamdeck.KeyEvent) error {
 			}
 			fmt.Printf("[*] Navigated to: %s (%d items)\n", relPath, len(page.Items))
 		}
+	} else if item != nil && item.LoadErr != nil {
+		// Pressing an error tile shows why the script did not load
+		fmt.Printf("[!] %s failed to load: %v\n", item.Name, item.LoadErr)
 	} else if item != nil {
 		// Action/script triggered
 		fmt.Printf("[*] Action triggered: %s\n", item.Name)
 		if item.Script != "" {
 			fmt.Printf("    Script: %s\n", item.Script)
-			// Run trigger asynchronously so the event loop never blocks waiting
-			// for a slow trigger function (HTTP, shell, sleep, etc.)
-			scriptPath := item.Script
-			go func() {
-				if err := a.scriptMgr.TriggerScript(scriptPath); err != nil {
-					log.Printf("Script error: %v", err)
-				}
-				// Refresh only the triggered key instead of redrawing the whole page
-				a.scriptMgr.RefreshScript(scriptPath)
-			}()
+			if a.config.UI.FlashOnTrigger {
+				go a.flashKey(key)
+			}
+			a.triggerScript(item.Script, key, true)
 		}
 	}
+}
 
+// wake restarts the inactivity timer and, if the display was sleeping,
+// wakes and redraws it. It reports whether the display was woken; the input
+// that woke it is then swallowed.
+func (a *App) wake() bool {
+	a.lastActivity = time.Now()
+	a.resetSleepTimer()
+	if !a.wakeDisplay() {
+		return false
+	}
+	if a.inSettings {
+		a.renderSettingsPage()
+	} else {
+		a.showPage()
+	}
+	return true
+}
+
+// handleDialEvent passes a dial event to the scripts hooked to that dial
+// (streamdeck.on_dial).
+func (a *App) handleDialEvent(ev streamdeck.DialEvent) {
+	if a.wake() {
+		return
+	}
+	a.scriptMgr.DispatchDial(ev)
+}
+
+// handleTouchEvent passes a touch strip event to the scripts hooked to the
+// strip (streamdeck.on_touch). Outside the settings menu a tap also acts
+// like a press of the content key for its strip region, see
+// Navigator.HandleTouch.
+func (a *App) handleTouchEvent(ev streamdeck.TouchEvent) error {
+	if a.wake() {
+		return nil
+	}
+	a.scriptMgr.DispatchTouch(ev)
+	if ev.Gesture != streamdeck.TouchTap || a.inSettings {
+		return nil
+	}
+	slot, ok := a.nav.TouchSlot(ev.X, 0)
+	if !ok {
+		return nil
+	}
+	item, navigated, err := a.nav.HandleTouch(ev.X, 0)
+	if err != nil {
+		return fmt.Errorf("handling touch: %w", err)
+	}
+	key := a.nav.GetContentKeys()[slot]
+	a.activate(item, navigated, key)
+	a.handleKeyRelease(key) // A tap is never held
 	return nil
 }
 
+// triggerScript runs a script's trigger() for a key press. The press fires
+// "tap" at once, or "double" when it follows a tap of the same script's key
+// within doubleTapWindow; holding the key for longPressDelay then fires a
+// further "long". Taps are never delayed waiting to see what follows.
+//
+// The calls run asynchronously, in order, so the event loop never blocks
+// waiting for a slow trigger function (HTTP, shell, sleep, etc.). With
+// refresh set, only the triggered key is redrawn after each call instead of
+// the whole page. The key is remembered until it is released, for
+// handleKeyRelease.
+func (a *App) triggerScript(scriptPath string, keyIndex int, refresh bool) {
+	now := time.Now()
+	if a.held == nil {
+		a.held = make(map[int]heldKey)
+		a.lastTap = make(map[int]keyTap)
+	}
+	if prev, ok := a.held[keyIndex]; ok {
+		close(prev.released) // its release was missed
+	}
+	event := scripting.EventTap
+	if last, ok := a.lastTap[keyIndex]; ok && last.script == scriptPath && now.Sub(last.at) < doubleTapWindow {
+		event = scripting.EventDouble
+		delete(a.lastTap, keyIndex) // a third press starts over
+	} else {
+		a.lastTap[keyIndex] = keyTap{script: scriptPath, at: now}
+	}
+
+	h := heldKey{script: scriptPath, released: make(chan struct{})}
+	a.held[keyIndex] = h
+
+	run := func(event scripting.TriggerEvent) {
+		if err := a.scriptMgr.TriggerScript(scriptPath, keyIndex, event); err != nil {
+			log.Printf("Script error: %v", err)
+		}
+		if refresh {
+			a.scriptMgr.RefreshScript(scriptPath)
+		}
+	}
+	a.queueKey(keyIndex, func() {
+		run(event)
+
+		hold := time.NewTimer(time.Until(now.Add(longPressDelay)))
+		defer hold.Stop()
+		select {
+		case <-h.released:
+			return
+		default:
+		}
+		select {
+		case <-hold.C:
+			run(scripting.EventLong)
+		case <-h.released:
+		}
+	})
+}
+
+// queueKey runs fn in the background once the work queued before it for the
+// same key has finished, so one key's trigger() and trigger_release() calls
+// never overtake each other. Only called from the event loop.
+func (a *App) queueKey(keyIndex int, fn func()) {
+	if a.keyTail == nil {
+		a.keyTail = make(map[int]chan struct{})
+	}
+	prev := a.keyTail[keyIndex]
+	done := make(chan struct{})
+	a.keyTail[keyIndex] = done
+	go func() {
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		fn()
+	}()
+}
+
+// handleKeyRelease passes a key release to the script its press triggered,
+// queued behind that press's trigger() calls, so trigger_release() always
+// runs last (e.g. push-to-talk). Releases of keys that did not
+// trigger a script, such as navigation and toggle keys, are ignored.
+func (a *App) handleKeyRelease(keyIndex int) {
+	h, ok := a.held[keyIndex]
+	if !ok {
+		return
+	}
+	delete(a.held, keyIndex)
+	close(h.released)
+
+	a.queueKey(keyIndex, func() {
+		handled, err := a.scriptMgr.ReleaseScript(h.script, keyIndex)
+		if err != nil {
+			log.Printf("Script error: %v", err)
+		}
+		if handled {
+			a.scriptMgr.RefreshScript(h.script)
+		}
+	})
+}
+
+// flashDuration is how long a key stays lit by flashKey.
+const flashDuration = 120 * time.Millisecond
+
+// flashKey briefly paints a key white as press feedback, then restores the
+// image it showed before. If something else redraws the key during the flash
+// (e.g. a passive update or page change) that newer image is left alone.
+func (a *App) flashKey(keyIndex int) {
+	prev := a.device.KeyData(keyIndex)
+	if err := a.device.SetKeyColor(keyIndex, color.White); err != nil {
+		return
+	}
+	flash := a.device.KeyData(keyIndex)
+
+	time.Sleep(flashDuration)
+
+	if prev == nil || !bytes.Equal(a.device.KeyData(keyIndex), flash) {
+		return
+	}
+	if err := a.device.WriteKeyData(keyIndex, prev); err != nil {
+		log.Printf("flash restore: %v", err)
+	}
+}
+
+// showPage renders the current page and points passive updates at its
+// scripts. It holds the script manager's render lock throughout, so no
+// passive update for the previous page lands in between.
+func (a *App) showPage() {
+	a.scriptMgr.LockRender()
+	defer a.scriptMgr.UnlockRender()
+
+	a.scriptMgr.SetVisibleScripts(nil)
+	a.scriptMgr.SetCurrentPath(a.nav.CurrentPath())
+	if err := a.nav.RenderPage(); err != nil {
+		log.Printf("RenderPage failed: %v", err)
+	}
+	a.updateVisibleScripts()
+}
+
 // updateVisibleScripts updates the visible scripts in the script manager and
 // wires the T1/T2 keys to .directory.lua of the current folder if it defines
 // t1_passive / t1_trigger / t2_passive / t2_trigger.
 func (a *App) updateVisibleScripts() {
-	a.scriptMgr.SetVisibleScripts(a.nav.GetVisibleScripts())
+	visible := a.nav.GetVisibleScripts()
+	a.scriptMgr.SetVisibleScripts(visible)
+
+	// Deferred scripts only fail once shown; swap their keys for the error
+	// tile.
+	for path, key := range visible {
+		if a.scriptMgr.LoadError(path) != nil {
+			if err := a.nav.RenderKey(key); err != nil {
+				log.Printf("RenderKey failed: %v", err)
+			}
+		}
+	}
 
 	// Determine T1/T2 script assignments from the current folder's .directory.lua
 	dirScript := a.nav.CurrentDirScript()
@@ -448,20 +887,52 @@ func (a *App) updateVisibleScripts() {
 			}
 		}
 	}
-	a.scriptMgr.SetToggleScripts(t1Script, streamdeck.KeyToggle1, t2Script, streamdeck.KeyToggle2)
+	t1, t2 := a.nav.ToggleKeys()
+	a.scriptMgr.SetToggleScripts(t1Script, t1, t2Script, t2)
 }
 
 // Shutdown cleans up resources.
 // It shuts down the script manager, closes the device, and exits the Stream Deck library.
 func (a *App) Shutdown() {
+	if a.ipc != nil {
+		a.ipc.Close()
+	}
+	if a.api != nil {
+		a.api.Close()
+	}
 	if a.scriptMgr != nil {
 		a.scriptMgr.Shutdown()
 	}
+	if a.logFile != nil {
+		a.logFile.Close()
+	}
 	if a.device != nil {
 		// Blank the display on exit to prevent burn-in.
 		_ = a.device.SetBrightness(0)
 		_ = a.device.Clear()
 		a.device.Close()
 	}
+	if a.traceFile != nil {
+		a.traceFile.Close()
+	}
 	streamdeck.Exit()
 }
+
+// startReportTrace dumps the device's raw HID reports to path ("-" for
+// stderr), after a header identifying the device, for --trace-reports.
+func (a *App) startReportTrace(path string) {
+	var w io.Writer = os.Stderr
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Printf("[!] Report trace disabled: %v\n", err)
+			return
+		}
+		a.traceFile = f
+		w = f
+	}
+	info := a.device.Info
+	fmt.Fprintf(w, "# %s, product ID 0x%04x, firmware %q\n", a.device.Model.Name, a.device.Model.ProductID, info.Firmware)
+	a.device.EnableReportTrace(w)
+	fmt.Printf("[*] Tracing HID reports to %s\n", path)
+}
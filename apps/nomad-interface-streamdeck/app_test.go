@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/scripting"
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+)
+
+// Navigating in and out of a folder while its scripts' passive() keeps
+// returning updates must never leave their colour on the parent page.
+func TestNavigationSuspendsPassiveUpdates(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "return { passive = function() return { color = {255, 0, 0} } end }\n"
+	for _, name := range []string{"a.lua", "b.lua", "c.lua", "d.lua", "e.lua", "f.lua", "g.lua", "h.lua"} {
+		if err := os.WriteFile(filepath.Join(sub, name), []byte(script), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a := &App{
+		device:     dev,
+		scriptMgr:  scripting.NewScriptManager(dev, dir, 200),
+		nav:        streamdeck.NewNavigator(dev, dir),
+		config:     DefaultConfig(),
+		configPath: dir,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	a.nav.SetScriptValidator(a.scriptMgr.IsUsableScript)
+	if err := a.scriptMgr.Boot(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer a.scriptMgr.Shutdown()
+	a.setupKeyUpdateCallback()
+	a.scriptMgr.StartPassiveLoop()
+	a.showPage()
+
+	keys := a.nav.GetContentKeys()
+	folderKey := keys[0]
+	press := func(key int) {
+		t.Helper()
+		if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 30; i++ {
+		press(folderKey) // into sub/, whose scripts fill the content keys
+		time.Sleep(time.Duration(i%5) * 2 * time.Millisecond)
+		press(streamdeck.KeyBack)
+	}
+	if !a.nav.IsAtRoot() {
+		t.Fatal("not back at the root")
+	}
+
+	want := make(map[int][]byte)
+	for _, key := range keys {
+		want[key] = dev.KeyData(key)
+	}
+	time.Sleep(50 * time.Millisecond) // several passive ticks
+	for _, key := range keys {
+		got := dev.KeyData(key)
+		if !bytes.Equal(got, want[key]) {
+			t.Errorf("key %d was redrawn after the page render", key)
+		}
+		img, _, err := image.Decode(bytes.NewReader(got))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r, g, b, _ := img.At(36, 36).RGBA(); r>>8 > 200 && g>>8 < 50 && b>>8 < 50 {
+			t.Errorf("key %d shows a hidden script's red", key)
+		}
+	}
+}
+
+// newScriptApp boots an App on a virtual MK.2 showing the scripts in dir,
+// with press flashing off.
+func newScriptApp(t *testing.T, dir string) *App {
+	t.Helper()
+	return newModelApp(t, dir, streamdeck.Models[0x0080])
+}
+
+// newModelApp is newScriptApp on a virtual deck of the given model.
+func newModelApp(t *testing.T, dir string, model streamdeck.Model) *App {
+	t.Helper()
+	dev := streamdeck.OpenVirtual(model)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	a := &App{
+		device:     dev,
+		scriptMgr:  scripting.NewScriptManager(dev, dir, 0),
+		nav:        streamdeck.NewNavigator(dev, dir),
+		config:     DefaultConfig(),
+		configPath: dir,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	a.config.UI.FlashOnTrigger = false
+	a.nav.SetScriptValidator(a.scriptMgr.IsUsableScript)
+	if err := a.scriptMgr.Boot(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(a.scriptMgr.Shutdown)
+	a.showPage()
+	return a
+}
+
+// waitForLog waits for the file at path to hold exactly want.
+func waitForLog(t *testing.T, path, want string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := os.ReadFile(path)
+		if string(got) == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("event log = %q, want %q", got, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// A key release reaches the trigger_release hook of the script its press
+// triggered, after trigger() has returned.
+func TestKeyReleaseReachesReleaseHook(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(t.TempDir(), "events.log")
+	script := fmt.Sprintf(`
+		local LOG = %q
+		local function record(ctx)
+			local f = io.open(LOG, "a")
+			f:write(ctx.event, " ", tostring(ctx.pressed), " ", ctx.key, "\n")
+			f:close()
+		end
+		local script = {}
+		function script.trigger(state, ctx)
+			local n = 0
+			for i = 1, 2000000 do n = n + i end -- a slow trigger
+			record(ctx)
+		end
+		function script.trigger_release(state, ctx)
+			record(ctx)
+		end
+		return script
+	`, logPath)
+	if err := os.WriteFile(filepath.Join(dir, "ptt.lua"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newScriptApp(t, dir)
+	key := a.nav.GetContentKeys()[0]
+	for _, pressed := range []bool{true, false} {
+		if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: pressed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := fmt.Sprintf("tap true %d\nrelease false %d\n", key, key)
+	waitForLog(t, logPath, want)
+
+	// A second release without a press is not passed on
+	if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: false}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got, _ := os.ReadFile(logPath); string(got) != want {
+		t.Errorf("unpaired release reached the script: %q", got)
+	}
+}
+
+// Presses reach trigger() as "tap", a quick second press as "double", and a
+// key held down fires a further "long" before the release.
+func TestTriggerEventKinds(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(t.TempDir(), "events.log")
+	script := fmt.Sprintf(`
+		local LOG = %q
+		local function record(ctx)
+			local f = io.open(LOG, "a")
+			f:write(ctx.event, "\n")
+			f:close()
+		end
+		return {
+			trigger = function(state, ctx) record(ctx) end,
+			trigger_release = function(state, ctx) record(ctx) end,
+		}
+	`, logPath)
+	if err := os.WriteFile(filepath.Join(dir, "multi.lua"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newScriptApp(t, dir)
+	key := a.nav.GetContentKeys()[0]
+	send := func(pressed bool) {
+		t.Helper()
+		if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: pressed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Tap, then a second tap within the window
+	send(true)
+	send(false)
+	send(true)
+	send(false)
+	waitForLog(t, logPath, "tap\nrelease\ndouble\nrelease\n")
+
+	// After the window a press is a tap again; holding it adds "long"
+	time.Sleep(doubleTapWindow)
+	send(true)
+	time.Sleep(longPressDelay + 100*time.Millisecond)
+	send(false)
+	waitForLog(t, logPath, "tap\nrelease\ndouble\nrelease\ntap\nlong\nrelease\n")
+}
+
+// waitForKey waits until key shows exactly want.
+func waitForKey(t *testing.T, dev *streamdeck.Device, key int, want []byte, what string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !bytes.Equal(dev.KeyData(key), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("key %d never showed %s", key, what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// With UI.flash_on_trigger a pressed script key flashes white and then
+// shows its own image again.
+func TestFlashOnTrigger(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.lua"), []byte("return { trigger = function() end }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := newScriptApp(t, dir)
+	a.config.UI.FlashOnTrigger = true
+	key := a.nav.GetContentKeys()[0]
+	tile := a.device.KeyData(key)
+	if tile == nil {
+		t.Fatal("script key was not drawn")
+	}
+
+	ref := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	if err := ref.SetKeyColor(key, color.White); err != nil {
+		t.Fatal(err)
+	}
+	white := ref.KeyData(key)
+
+	if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: true}); err != nil {
+		t.Fatal(err)
+	}
+	waitForKey(t, a.device, key, white, "the flash")
+	waitForKey(t, a.device, key, tile, "its tile again after the flash")
+	a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: false})
+
+	// A key redrawn during the flash keeps the newer image
+	if err := ref.SetKeyColor(key, color.RGBA{R: 255, A: 255}); err != nil {
+		t.Fatal(err)
+	}
+	red := ref.KeyData(key)
+	done := make(chan struct{})
+	go func() {
+		a.flashKey(key)
+		close(done)
+	}()
+	waitForKey(t, a.device, key, white, "the flash")
+	if err := a.device.WriteKeyData(key, red); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if !bytes.Equal(a.device.KeyData(key), red) {
+		t.Error("flash restore overwrote an image drawn during the flash")
+	}
+}
+
+func TestWakeRendersUnderRenderLock(t *testing.T) {
+	a := newScriptApp(t, t.TempDir())
+	a.sleeping = true
+
+	// While another render holds the lock the wake-up redraw must wait
+	a.scriptMgr.LockRender()
+	done := make(chan error, 1)
+	go func() { done <- a.handleKeyEvent(streamdeck.KeyEvent{Key: 6, Pressed: true}) }()
+	select {
+	case <-done:
+		a.scriptMgr.UnlockRender()
+		t.Fatal("wake-up redraw did not take the render lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	a.scriptMgr.UnlockRender()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if a.sleeping {
+		t.Fatal("key press did not wake the display")
+	}
+}
+
+// Dial and touch events reach the scripts' on_dial / on_touch hooks, and a
+// tap on the strip presses the content key of its region.
+func TestDialAndTouchEvents(t *testing.T) {
+	dir := t.TempDir()
+	hooksLog := filepath.Join(dir, "hooks.log")
+	targetLog := filepath.Join(dir, "target.log")
+	writeFile := func(name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("a_hooks.lua", fmt.Sprintf(`
+		local deck = require("streamdeck")
+		local file = require("file")
+		deck.on_dial(1, function(delta, pressed)
+			file.append(%[1]q, "dial " .. delta .. " " .. tostring(pressed) .. "\n")
+		end)
+		deck.on_touch(function(gesture, x)
+			file.append(%[1]q, gesture .. " " .. x .. "\n")
+		end)
+		return { trigger = function() end }
+	`, hooksLog))
+	writeFile("b_target.lua", fmt.Sprintf(`
+		local file = require("file")
+		return { trigger = function() file.append(%q, "triggered\n") end }
+	`, targetLog))
+
+	a := newModelApp(t, dir, streamdeck.Models[0x009a])
+
+	a.handleDialEvent(streamdeck.DialEvent{Dial: 1, Delta: -2})
+	a.handleDialEvent(streamdeck.DialEvent{Dial: 0, Pressed: true}) // No hook
+	waitForLog(t, hooksLog, "dial -2 false\n")
+
+	// The second of one region per content key
+	x := a.device.Model.StripWidth/len(a.nav.GetContentKeys()) + 10
+	tap := streamdeck.TouchEvent{Gesture: streamdeck.TouchTap, X: x, Y: 50, EndX: x, EndY: 50}
+	if err := a.handleTouchEvent(tap); err != nil {
+		t.Fatal(err)
+	}
+	waitForLog(t, hooksLog, fmt.Sprintf("dial -2 false\ntap %d\n", x))
+	waitForLog(t, targetLog, "triggered\n")
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/merith-tk/nomad/pkg/streamdeck"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,6 +18,7 @@ type Config struct {
 	UI          UIConfig          `yaml:"ui"`
 	Performance PerformanceConfig `yaml:"performance"`
 	Network     NetworkConfig     `yaml:"network"`
+	API         APIConfig         `yaml:"api"`
 	Logging     LoggingConfig     `yaml:"logging"`
 	Security    SecurityConfig    `yaml:"security"`
 }
@@ -30,30 +33,55 @@ type ApplicationConfig struct {
 type DeviceConfig struct {
 	AutoDetect bool   `yaml:"auto_detect"`
 	Path       string `yaml:"path"`
-	Model      string `yaml:"model"`
+	Model      string `yaml:"model"`    // Model name emulated in headless mode; "" = Stream Deck MK.2
+	Serial     string `yaml:"serial"`   // Open the deck with this serial; "" = first deck with a display
+	Headless   bool   `yaml:"headless"` // Run without a device (control API only)
 }
 
 type ScriptingConfig struct {
 	EnableBackground     bool `yaml:"enable_background"`
 	ExecutionTimeout     int  `yaml:"execution_timeout"`
 	MaxConcurrentScripts int  `yaml:"max_concurrent_scripts"`
+	LazyLoad             bool `yaml:"lazy_load"`          // Load scripts when first shown
+	UnloadAfter          int  `yaml:"unload_after"`       // Seconds off-screen before a script is closed; 0 = never
+	MaxLoadedScripts     int  `yaml:"max_loaded_scripts"` // Close least recently shown scripts above this; 0 = no limit
+	InstallExamples      bool `yaml:"install_examples"`   // Copy example scripts into an empty config dir
+	MaxCallDepth         int  `yaml:"max_call_depth"`     // Nested Lua calls per script; 0 = gopher-lua default
+	MaxStackSlots        int  `yaml:"max_stack_slots"`    // Lua data stack slots per script; 0 = gopher-lua default
+	MaxMemoryMB          int  `yaml:"max_memory_mb"`      // Abort running scripts above this heap size; 0 = no limit
 }
 
 type UIConfig struct {
 	NavigationStyle string            `yaml:"navigation_style"`
 	ShowHiddenFiles bool              `yaml:"show_hidden_files"`
+	Sort            string            `yaml:"sort"` // "name", "modified" or "manual" (_order file)
+	FlashOnTrigger  bool              `yaml:"flash_on_trigger"`
+	TransitionMs    int               `yaml:"transition_ms"` // Crossfade when a script changes a key; 0 = off
+	StartPath       string            `yaml:"start_path"`    // Folder (relative to the config dir) shown at startup; "" = root
+	Rotation        int               `yaml:"rotation"`      // Degrees the deck is mounted rotated clockwise: 0, 90, 180 or 270
 	Labels          map[string]string `yaml:"labels"`
 }
 
 type PerformanceConfig struct {
-	ImageCacheSize int  `yaml:"image_cache_size"`
-	CompressImages bool `yaml:"compress_images"`
-	JPEGQuality    int  `yaml:"jpeg_quality"`
+	ImageCacheSize    int  `yaml:"image_cache_size"`    // MB
+	ImageCacheEntries int  `yaml:"image_cache_entries"` // max cached images
+	CompressImages    bool `yaml:"compress_images"`
+	JPEGQuality       int  `yaml:"jpeg_quality"`
+	PollIntervalMs    int  `yaml:"poll_interval_ms"` // Pause between key reads; 0 = read back to back (lowest latency)
+	ReadTimeoutMs     int  `yaml:"read_timeout_ms"`  // Max wait per key read; also how long a read can delay image writes
+	BlockingReads     bool `yaml:"blocking_reads"`   // Wait for key input in a blocking read instead of polling
 }
 
 type NetworkConfig struct {
-	HTTPTimeout int  `yaml:"http_timeout"`
-	VerifySSL   bool `yaml:"verify_ssl"`
+	HTTPTimeout int    `yaml:"http_timeout"`
+	VerifySSL   bool   `yaml:"verify_ssl"`
+	IPCSocket   string `yaml:"ipc_socket"` // Unix socket for external control; "" = disabled
+}
+
+type APIConfig struct {
+	Enabled        bool     `yaml:"enabled"`         // Serve the HTTP control API
+	Listen         string   `yaml:"listen"`          // host:port; keep on localhost unless you trust the network
+	AllowedOrigins []string `yaml:"allowed_origins"` // Extra browser origins allowed to open the WebSocket feed
 }
 
 type LoggingConfig struct {
@@ -67,6 +95,7 @@ type SecurityConfig struct {
 	RestrictFileAccess bool     `yaml:"restrict_file_access"`
 	AllowedCommands    []string `yaml:"allowed_commands"`
 	BlockNetwork       bool     `yaml:"block_network"`
+	SandboxStdlib      bool     `yaml:"sandbox_stdlib"` // Remove io, os.execute, dofile, ...
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
@@ -82,28 +111,50 @@ func DefaultConfig() *Config {
 			AutoDetect: true,
 			Path:       "",
 			Model:      "",
+			Serial:     "",
+			Headless:   false,
 		},
 		Scripting: ScriptingConfig{
 			EnableBackground:     true,
 			ExecutionTimeout:     30,
 			MaxConcurrentScripts: 10,
+			LazyLoad:             false,
+			UnloadAfter:          0,
+			MaxLoadedScripts:     0,
+			InstallExamples:      true,
+			MaxCallDepth:         200,
+			MaxStackSlots:        0,
+			MaxMemoryMB:          0,
 		},
 		UI: UIConfig{
 			NavigationStyle: "folder",
 			ShowHiddenFiles: false,
+			Sort:            "name",
+			FlashOnTrigger:  false,
+			TransitionMs:    0,
 			Labels: map[string]string{
 				"back": "<-",
 				"home": "HOME",
 			},
 		},
 		Performance: PerformanceConfig{
-			ImageCacheSize: 50,
-			CompressImages: true,
-			JPEGQuality:    90,
+			ImageCacheSize:    50,
+			ImageCacheEntries: 500,
+			CompressImages:    true,
+			JPEGQuality:       90,
+			PollIntervalMs:    10,
+			ReadTimeoutMs:     100,
+			BlockingReads:     true,
 		},
 		Network: NetworkConfig{
 			HTTPTimeout: 10,
 			VerifySSL:   true,
+			IPCSocket:   "",
+		},
+		API: APIConfig{
+			Enabled:        false,
+			Listen:         "127.0.0.1:8765",
+			AllowedOrigins: []string{},
 		},
 		Logging: LoggingConfig{
 			Level:       "info",
@@ -115,11 +166,14 @@ func DefaultConfig() *Config {
 			RestrictFileAccess: true,
 			AllowedCommands:    []string{},
 			BlockNetwork:       false,
+			SandboxStdlib:      false,
 		},
 	}
 }
 
-// LoadConfig loads configuration from the config file.
+// LoadConfig loads configuration from the config file, then applies the
+// NOMAD_* environment overrides (see env.go). The overrides are never
+// written to a newly created file.
 func LoadConfig(configDir string) (*Config, error) {
 	configPath := filepath.Join(configDir, "config.yml")
 
@@ -132,6 +186,7 @@ func LoadConfig(configDir string) (*Config, error) {
 		if err := SaveConfig(config, configPath); err != nil {
 			return config, fmt.Errorf("failed to create default config: %w", err)
 		}
+		applyEnvOverrides(config)
 		return config, nil
 	}
 
@@ -146,9 +201,23 @@ func LoadConfig(configDir string) (*Config, error) {
 		return config, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	applyEnvOverrides(config)
 	return config, nil
 }
 
+// checkDeviceModel reports whether device.model names a known model
+// (case-insensitive). The empty name means auto-detect and is always valid;
+// otherwise the error lists the names that would be accepted.
+func checkDeviceModel(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, ok := streamdeck.FindModel(name); ok {
+		return nil
+	}
+	return fmt.Errorf("no model named %q; valid models: %s", name, strings.Join(streamdeck.ListModelNames(), ", "))
+}
+
 // SaveConfig saves configuration to the config file.
 func SaveConfig(config *Config, configPath string) error {
 	data, err := yaml.Marshal(config)
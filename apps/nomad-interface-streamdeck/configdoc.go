@@ -0,0 +1,151 @@
+package main
+
+// configdoc.go – config.yml with every option documented, for --print-config.
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configHeader starts the commented config.
+const configHeader = "NOMAD Stream Deck Interface Configuration\n" +
+	"Every option with its default. Save as config.yml in the config directory."
+
+// configComments documents config.yml, keyed by the dotted path of each
+// section and option.
+var configComments = map[string]string{
+	"application":             "Application settings",
+	"application.brightness":  "Brightness level (0-100)",
+	"application.passive_fps": "Passive update frequency in FPS (1-10, lower = less CPU usage)",
+	"application.timeout":     "Seconds of inactivity before the display sleeps; 0 = never",
+	"application.debug": "Enable debug logging. Also enables the debug snapshot: hold T1 and\n" +
+		"press T2 (or the reverse) to print the app state to the console.",
+
+	"device":             "Device settings",
+	"device.auto_detect": "Auto-detect device (true) or specify path",
+	"device.path":        "Specific device path (only used if auto_detect is false)",
+	"device.model": "Model to drive the deck as, in place of the detected one, e.g. \"Stream Deck XL\"\n" +
+		"for a clone or new revision (leave empty for auto-detection). In headless\n" +
+		"mode this names the model to emulate (default \"Stream Deck MK.2\"). Unknown\n" +
+		"names are ignored with a warning listing the valid ones.",
+	"device.serial":   "Open the deck with this serial number when several are connected\n(--device-serial)",
+	"device.headless": "Run without a device, driven by the HTTP API (--headless)",
+
+	"scripting":                   "Script settings",
+	"scripting.enable_background": "Enable background script execution",
+	"scripting.execution_timeout": "Script execution timeout in seconds (0 = no timeout)",
+	"scripting.max_concurrent_scripts": "Maximum number of concurrent script executions: background workers\n" +
+		"running at once, and scripts loaded at once during boot",
+	"scripting.lazy_load": "Also defer scripts with background() until they first appear on screen.\n" +
+		"Scripts that set EAGER_LOAD = true are still loaded at boot.",
+	"scripting.unload_after": "Seconds a script may stay off-screen before it is unloaded to free memory\n" +
+		"(its state is reset when it is loaded again). Scripts with a running\n" +
+		"background(), claimed keys, subscriptions, file watches, dial or touch\n" +
+		"hooks or a running macro are never unloaded. 0 = never unload.",
+	"scripting.max_loaded_scripts": "Maximum number of scripts kept loaded; the least recently shown are\n" +
+		"unloaded first. 0 = no limit.",
+	"scripting.install_examples": "Copy a few example scripts (clock, CPU, launcher) into the config\n" +
+		"directory on first run if it has no scripts yet.",
+	"scripting.max_call_depth":  "Nested Lua calls allowed per script before a stack overflow error\n(0 = gopher-lua default, 256)",
+	"scripting.max_stack_slots": "Lua data stack slots per script (0 = gopher-lua default, 5120)",
+	"scripting.max_memory_mb": "While the process heap is above this, every script running Lua at\n" +
+		"that moment is aborted (0 = no limit)",
+
+	"ui":                   "UI settings",
+	"ui.navigation_style":  "Navigation style: \"folder\" or \"flat\"",
+	"ui.show_hidden_files": "Show hidden files in navigation",
+	"ui.sort":              "Item order: \"name\", \"modified\" (newest first) or \"manual\" (per-folder _order file)",
+	"ui.flash_on_trigger":  "Briefly flash a key white when its script is triggered",
+	"ui.transition_ms": "Crossfade duration in milliseconds when a script changes a key's\n" +
+		"appearance (e.g. play -> pause). 0 = swap instantly.",
+	"ui.start_path": "Folder shown at startup, relative to the config directory (e.g. \"apps\").\n" +
+		"Empty or missing folders fall back to the root.",
+	"ui.rotation": "Degrees the deck is mounted rotated clockwise: 0, 90, 180 or 270. Keys,\n" +
+		"key images and presses follow the rotation (at 90 and 270 columns and rows\n" +
+		"swap); the dials and touch strip of the Stream Deck + are not remapped.",
+	"ui.labels": "Custom button labels",
+
+	"performance":                     "Performance settings",
+	"performance.image_cache_size":    "Image cache size in MB",
+	"performance.image_cache_entries": "Maximum number of cached images",
+	"performance.compress_images":     "Enable image compression",
+	"performance.jpeg_quality":        "JPEG quality for button images (1-100)",
+	"performance.poll_interval_ms": "Key polling. A press is noticed up to poll_interval_ms late; 0 reads\n" +
+		"back to back for the lowest latency.",
+	"performance.read_timeout_ms": "How long one key read waits for a report. Image writes wait for the\n" +
+		"read to finish, so large values can delay key updates.",
+	"performance.blocking_reads": "Wait for key input in a blocking read on its own thread instead of\n" +
+		"polling. The two polling settings above only apply when this is off.",
+
+	"network":              "Network settings",
+	"network.http_timeout": "HTTP request timeout in seconds",
+	"network.verify_ssl":   "Enable HTTPS certificate verification",
+	"network.ipc_socket": "Unix socket for external programs to set keys and receive key events.\n" +
+		"Relative paths are inside the config directory. Empty = disabled.",
+
+	"api": "HTTP control API. Off by default; keep it on localhost unless every\n" +
+		"machine that can reach the port is trusted.",
+	"api.enabled": "Serve the HTTP control API",
+	"api.listen":  "host:port to listen on",
+	"api.allowed_origins": "Browser pages on other origins allowed to open the /events WebSocket.\n" +
+		"Pages served from the API host always are.",
+
+	"logging":               "Logging settings",
+	"logging.level":         "Log level: \"debug\", \"info\", \"warn\", \"error\"",
+	"logging.file":          "Log to file (leave empty to log to console only)",
+	"logging.max_file_size": "Maximum log file size in MB",
+	"logging.max_files":     "Maximum number of log files to keep",
+
+	"security":                      "Security settings",
+	"security.restrict_file_access": "Restrict file operations to config directory",
+	"security.allowed_commands":     "Allowed shell commands (empty = allow all)",
+	"security.block_network":        "Block network access for scripts",
+	"security.sandbox_stdlib": "Remove the io library and os.execute, os.remove, os.rename, os.exit,\n" +
+		"dofile and loadfile from scripts; use the file and shell modules instead",
+}
+
+// WithComments serializes the config as YAML with every section and option
+// preceded by a comment describing it.
+func (c *Config) WithComments() ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return nil, err
+	}
+	doc.HeadComment = configHeader
+	commentMapping(&doc, "")
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// commentMapping attaches configComments to the keys of a mapping node and
+// recurses into nested mappings. prefix is the dotted path of node.
+func commentMapping(node *yaml.Node, prefix string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if comment, ok := configComments[path]; ok {
+			key.HeadComment = comment
+		}
+		// Only sections are walked; map-valued options such as ui.labels
+		// hold user keys, not documented options.
+		if prefix == "" {
+			commentMapping(value, path)
+		}
+	}
+}
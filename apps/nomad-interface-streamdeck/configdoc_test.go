@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestWithCommentsParsesBackToDefaults(t *testing.T) {
+	out, err := DefaultConfig().WithComments()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("printed config does not parse: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(&cfg, DefaultConfig()) {
+		t.Errorf("printed config parses to\n%+v\nwant the defaults\n%+v", cfg, *DefaultConfig())
+	}
+
+	text := string(out)
+	for _, want := range []string{
+		"# NOMAD Stream Deck Interface Configuration",
+		"# Application settings\napplication:",
+		"  # Brightness level (0-100)\n  brightness:",
+		"  # Remove the io library",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("printed config lacks %q", want)
+		}
+	}
+}
+
+func TestConfigCommentsCoverEveryOption(t *testing.T) {
+	var doc yaml.Node
+	if err := doc.Encode(DefaultConfig()); err != nil {
+		t.Fatal(err)
+	}
+	sections := doc.Content
+	for i := 0; i+1 < len(sections); i += 2 {
+		section := sections[i].Value
+		if _, ok := configComments[section]; !ok {
+			t.Errorf("section %s is undocumented", section)
+		}
+		fields := sections[i+1].Content
+		for j := 0; j+1 < len(fields); j += 2 {
+			path := section + "." + fields[j].Value
+			if _, ok := configComments[path]; !ok {
+				t.Errorf("option %s is undocumented", path)
+			}
+		}
+	}
+}
+
+func TestPrintConfigFlag(t *testing.T) {
+	opts, err := parseFlags([]string{"--print-config"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opts.PrintConfig {
+		t.Error("--print-config not set")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+// debug.go – the debug key combo. With application.debug set, holding one
+// toggle key (T1 or T2) and pressing the other prints a snapshot of the app
+// (page, visible scripts, script status, device, timings) to the console. The first
+// toggle key still does whatever it normally does when it is pressed.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+)
+
+// debugOutput is where the debug combo writes its snapshot.
+var debugOutput io.Writer = os.Stdout
+
+// trackKey records whether a key is held, for key combos.
+func (a *App) trackKey(event streamdeck.KeyEvent) {
+	if a.keysDown == nil {
+		a.keysDown = make(map[int]bool)
+	}
+	a.keysDown[event.Key] = event.Pressed
+}
+
+// isDebugCombo reports whether pressing key completes the debug combo: one
+// toggle key pressed while the other is held, in debug mode.
+func (a *App) isDebugCombo(key int) bool {
+	if !a.config.Application.Debug {
+		return false
+	}
+	t1, t2 := a.nav.ToggleKeys()
+	switch key {
+	case t1:
+		return a.keysDown[t2]
+	case t2:
+		return a.keysDown[t1]
+	}
+	return false
+}
+
+// dumpState writes the current page, visible scripts, script status and
+// device info to w.
+func (a *App) dumpState(w io.Writer) {
+	fmt.Fprintln(w, "[*] Debug snapshot")
+
+	info := a.device.Info
+	fmt.Fprintf(w, "[*] Device: %s (%dx%d, %d keys) serial=%q firmware=%q brightness=%d%%\n",
+		a.device.Model.Name, a.device.Cols(), a.device.Rows(), a.device.Model.Keys,
+		info.Serial, info.Firmware, a.device.Brightness())
+
+	a.sleepMu.Lock()
+	sleeping := a.sleeping
+	a.sleepMu.Unlock()
+	fmt.Fprintf(w, "[*] Settings open: %v, sleeping: %v\n", a.inSettings, sleeping)
+
+	fmt.Fprint(w, "[*] Page: ", a.nav.Describe())
+
+	visible := a.nav.GetVisibleScripts()
+	paths := make([]string, 0, len(visible))
+	for path := range visible {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool { return visible[paths[i]] < visible[paths[j]] })
+	fmt.Fprintf(w, "[*] Visible scripts (%d)\n", len(paths))
+	for _, path := range paths {
+		rel, err := filepath.Rel(a.configPath, path)
+		if err != nil {
+			rel = path
+		}
+		fmt.Fprintf(w, "    key %-3d %s\n", visible[path], rel)
+	}
+
+	a.writeScriptStatus(w)
+	a.writeMetrics(w)
+}
+
+// writeMetrics writes the render, passive and HID write timings to w.
+func (a *App) writeMetrics(w io.Writer) {
+	m := a.Metrics()
+	fmt.Fprintf(w, "[*] Metrics: render avg %.1fms max %.1fms (%d), passive tick avg %.1fms max %.1fms (%d)\n",
+		m.Render.AvgMS, m.Render.MaxMS, m.Render.Count,
+		m.PassiveTick.AvgMS, m.PassiveTick.MaxMS, m.PassiveTick.Count)
+	fmt.Fprintf(w, "[*] HID: %d reports, %d errors, %d key images\n", m.HIDReports, m.HIDErrors, m.KeyImages)
+
+	paths := make([]string, 0, len(m.Passive))
+	for path := range m.Passive {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		t := m.Passive[path]
+		fmt.Fprintf(w, "    passive %-24s avg %.2fms max %.2fms (%d)\n", path, t.AvgMS, t.MaxMS, t.Count)
+	}
+}
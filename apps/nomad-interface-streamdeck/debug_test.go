@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/merith-tk/nomad/pkg/scripting"
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+)
+
+func TestDebugComboDumpsState(t *testing.T) {
+	dir := t.TempDir()
+	script := "local s = {}\nfunction s.trigger() end\nreturn s\n"
+	if err := os.WriteFile(filepath.Join(dir, "lamp.lua"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	a := &App{
+		device:     dev,
+		scriptMgr:  scripting.NewScriptManager(dev, dir, 0),
+		nav:        streamdeck.NewNavigator(dev, dir),
+		config:     DefaultConfig(),
+		configPath: dir,
+	}
+	if err := a.scriptMgr.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer a.scriptMgr.Shutdown()
+	a.showPage()
+
+	var out bytes.Buffer
+	defer func(w io.Writer) { debugOutput = w }(debugOutput)
+	debugOutput = &out
+
+	press := func(key int, pressed bool) {
+		t.Helper()
+		if err := a.handleKeyEvent(streamdeck.KeyEvent{Key: key, Pressed: pressed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Without debug mode the combo does nothing
+	press(streamdeck.KeyToggle1, true)
+	press(streamdeck.KeyToggle2, true)
+	press(streamdeck.KeyToggle2, false)
+	press(streamdeck.KeyToggle1, false)
+	if out.Len() != 0 {
+		t.Fatalf("combo dumped state with debug off:\n%s", out.String())
+	}
+
+	a.config.Application.Debug = true
+	press(streamdeck.KeyToggle1, true)
+	if out.Len() != 0 {
+		t.Fatal("a single toggle key dumped state")
+	}
+	press(streamdeck.KeyToggle2, true)
+
+	got := out.String()
+	for _, want := range []string{
+		"[*] Debug snapshot",
+		"[*] Device: " + dev.Model.Name,
+		"[*] Page: / (page 1/1)",
+		"lamp",
+		"[*] Visible scripts (1)",
+		"[*] Script status (1 loaded)",
+		"[*] Metrics: render",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("snapshot lacks %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+// defaults.go – example scripts copied into an empty config directory on
+// first run so a new deck is not blank. Disabled by
+// scripting.install_examples: false.
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed defaults
+var defaultScripts embed.FS
+
+// installDefaultScripts copies the embedded example scripts into dir if it
+// holds no .lua files yet. It returns the number of files written.
+func installDefaultScripts(dir string) (int, error) {
+	hasScripts, err := containsScripts(dir)
+	if err != nil || hasScripts {
+		return 0, err
+	}
+
+	written := 0
+	err = fs.WalkDir(defaultScripts, "defaults", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(path, "defaults"), "/")
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := defaultScripts.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return err
+		}
+		written++
+		return nil
+	})
+	return written, err
+}
+
+// containsScripts reports whether any .lua file exists under dir.
+func containsScripts(dir string) (bool, error) {
+	found := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".lua" {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found, err
+}
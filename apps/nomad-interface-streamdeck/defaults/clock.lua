@@ -0,0 +1,21 @@
+-- clock.lua - Shows the current time; press to toggle the date
+
+local time = require("time")
+
+local script = {}
+
+function script.passive(key, state)
+    local text
+    if state.show_date then
+        text = time.format(time.now(), "Jan 2\nMon")
+    else
+        text = time.format(time.now(), "15:04")
+    end
+    return { color = {20, 20, 60}, text = text, text_color = {255, 255, 255} }
+end
+
+function script.trigger(state)
+    state.show_date = not state.show_date
+end
+
+return script
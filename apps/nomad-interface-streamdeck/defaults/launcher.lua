@@ -0,0 +1,17 @@
+-- launcher.lua - Opens the config folder in the file manager
+-- Copy this script and change the path (or use shell.open on a URL or
+-- program) to make your own launchers.
+
+local shell = require("shell")
+
+local script = {}
+
+function script.passive(key, state)
+    return { color = {120, 80, 0}, text = "OPEN\nCFG", text_color = {255, 255, 255} }
+end
+
+function script.trigger(state)
+    shell.open(CONFIG_DIR)
+end
+
+return script
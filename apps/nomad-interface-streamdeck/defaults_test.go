@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/merith-tk/nomad/pkg/scripting"
+)
+
+func TestInstallDefaultScriptsFreshDir(t *testing.T) {
+	dir := t.TempDir()
+	// LoadConfig writes config.yml before scripts are installed
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte("{}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := installDefaultScripts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no files installed into an empty config dir")
+	}
+	for _, name := range []string{"clock.lua", "launcher.lua", filepath.Join("system", "cpu.lua")} {
+		r, err := scripting.NewScriptRunner(filepath.Join(dir, name), nil, dir, nil, nil)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !r.HasPassive() {
+			t.Errorf("%s has no passive()", name)
+		}
+		r.Close()
+	}
+
+	// A second run finds the scripts and does nothing
+	if n, err := installDefaultScripts(dir); err != nil || n != 0 {
+		t.Errorf("second install = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestInstallDefaultScriptsExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	own := filepath.Join(dir, "apps", "mine.lua")
+	if err := os.MkdirAll(filepath.Dir(own), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(own, []byte("return {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := installDefaultScripts(dir)
+	if err != nil || n != 0 {
+		t.Fatalf("install = %d, %v; want 0, nil", n, err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "apps" {
+		t.Errorf("existing dir was modified: %v", entries)
+	}
+}
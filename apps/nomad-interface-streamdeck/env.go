@@ -0,0 +1,78 @@
+package main
+
+// env.go – environment-variable overrides for config.yml, for containers
+// and other setups where editing the file is awkward. The precedence is
+// command-line flags, then environment, then config.yml.
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// envOverride maps one environment variable onto a config value.
+type envOverride struct {
+	name string
+	set  func(cfg *Config, value string) error
+}
+
+// envOverrides lists every supported variable. NOMAD_CONFIG_DIR is handled
+// separately (see Options.configDir) since it picks where config.yml lives.
+var envOverrides = []envOverride{
+	{"NOMAD_BRIGHTNESS", envInt(func(c *Config) *int { return &c.Application.Brightness })},
+	{"NOMAD_PASSIVE_FPS", envInt(func(c *Config) *int { return &c.Application.PassiveFPS })},
+	{"NOMAD_TIMEOUT", envInt(func(c *Config) *int { return &c.Application.Timeout })},
+	{"NOMAD_DEBUG", envBool(func(c *Config) *bool { return &c.Application.Debug })},
+	{"NOMAD_DEVICE_SERIAL", envString(func(c *Config) *string { return &c.Device.Serial })},
+	{"NOMAD_DEVICE_MODEL", envString(func(c *Config) *string { return &c.Device.Model })},
+	{"NOMAD_HEADLESS", envBool(func(c *Config) *bool { return &c.Device.Headless })},
+	{"NOMAD_START_PATH", envString(func(c *Config) *string { return &c.UI.StartPath })},
+	{"NOMAD_IPC_SOCKET", envString(func(c *Config) *string { return &c.Network.IPCSocket })},
+	{"NOMAD_API_ENABLED", envBool(func(c *Config) *bool { return &c.API.Enabled })},
+	{"NOMAD_API_LISTEN", envString(func(c *Config) *string { return &c.API.Listen })},
+	{"NOMAD_LOG_LEVEL", envString(func(c *Config) *string { return &c.Logging.Level })},
+	{"NOMAD_LOG_FILE", envString(func(c *Config) *string { return &c.Logging.File })},
+}
+
+// applyEnvOverrides sets config values from the NOMAD_* environment
+// variables that are set. Invalid values are reported and ignored.
+func applyEnvOverrides(cfg *Config) {
+	for _, o := range envOverrides {
+		value, ok := os.LookupEnv(o.name)
+		if !ok {
+			continue
+		}
+		if err := o.set(cfg, value); err != nil {
+			fmt.Printf("[!] Ignoring %s: %v\n", o.name, err)
+		}
+	}
+}
+
+func envString(field func(*Config) *string) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		*field(cfg) = value
+		return nil
+	}
+}
+
+func envInt(field func(*Config) *int) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(cfg) = n
+		return nil
+	}
+}
+
+func envBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(cfg *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field(cfg) = b
+		return nil
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	yml := "application:\n  brightness: 40\n  passive_fps: 5\napi:\n  listen: 127.0.0.1:9000\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NOMAD_BRIGHTNESS", "20")
+	t.Setenv("NOMAD_HEADLESS", "true")
+	t.Setenv("NOMAD_API_LISTEN", "0.0.0.0:8765")
+	t.Setenv("NOMAD_PASSIVE_FPS", "fast") // invalid: keeps the file's value
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Application.Brightness != 20 {
+		t.Errorf("brightness = %d, want 20 from the environment", cfg.Application.Brightness)
+	}
+	if !cfg.Device.Headless {
+		t.Error("NOMAD_HEADLESS was not applied")
+	}
+	if cfg.API.Listen != "0.0.0.0:8765" {
+		t.Errorf("api listen = %q", cfg.API.Listen)
+	}
+	if cfg.Application.PassiveFPS != 5 {
+		t.Errorf("passive fps = %d, want 5 from config.yml", cfg.Application.PassiveFPS)
+	}
+
+	// Flags beat the environment.
+	opts, err := parseFlags([]string{"--brightness", "90"}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.apply(cfg)
+	if cfg.Application.Brightness != 90 {
+		t.Errorf("brightness = %d, want 90 from the flag", cfg.Application.Brightness)
+	}
+}
+
+func TestEnvOverridesNotSavedToNewConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("NOMAD_BRIGHTNESS", "20")
+
+	cfg, err := LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Application.Brightness != 20 {
+		t.Errorf("brightness = %d, want 20", cfg.Application.Brightness)
+	}
+
+	t.Setenv("NOMAD_BRIGHTNESS", "")
+	os.Unsetenv("NOMAD_BRIGHTNESS")
+	cfg, err = LoadConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DefaultConfig().Application.Brightness; cfg.Application.Brightness != want {
+		t.Errorf("created config.yml has brightness %d, want the default %d", cfg.Application.Brightness, want)
+	}
+}
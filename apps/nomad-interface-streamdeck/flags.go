@@ -0,0 +1,76 @@
+package main
+
+// flags.go – command-line options. Flags override config.yml, and --config
+// overrides the NOMAD_CONFIG_DIR environment variable.
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+// configDirEnv names the environment variable that sets the config
+// directory when --config is not given.
+const configDirEnv = "NOMAD_CONFIG_DIR"
+
+// Options holds the command-line overrides. Zero values mean the flag was
+// not given, except Brightness, which is -1 when unset.
+type Options struct {
+	ConfigDir    string
+	DeviceSerial string
+	Brightness   int
+	Headless     bool
+	PrintConfig  bool
+	Verify       bool
+	TraceReports string
+}
+
+// parseFlags parses the command line (without the program name). Usage and
+// errors are written to output; -h returns flag.ErrHelp.
+func parseFlags(args []string, output io.Writer) (Options, error) {
+	var opts Options
+	fs := flag.NewFlagSet("nomad-interface-streamdeck", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&opts.ConfigDir, "config", "", "config directory (default: $"+configDirEnv+", ./.nomad or ~/.nomad)")
+	fs.StringVar(&opts.DeviceSerial, "device-serial", "", "open the Stream Deck with this serial number")
+	fs.IntVar(&opts.Brightness, "brightness", -1, "display brightness 0-100")
+	fs.BoolVar(&opts.Headless, "headless", false, "run without a device, driven by the control API")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the default config.yml with every option documented and exit")
+	fs.BoolVar(&opts.Verify, "verify", false, "load every script on a virtual deck, report errors and exit (non-zero on failure)")
+	fs.StringVar(&opts.TraceReports, "trace-reports", "", "append a hex dump of every HID input and feature report to `file` (- for stderr)")
+	if err := fs.Parse(args); err != nil {
+		return Options{}, err
+	}
+	if fs.NArg() > 0 {
+		return Options{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if opts.Brightness > 100 || opts.Brightness < -1 {
+		return Options{}, fmt.Errorf("--brightness must be between 0 and 100")
+	}
+	return opts, nil
+}
+
+// configDir picks the config directory: --config, then $NOMAD_CONFIG_DIR,
+// then the usual discovery (getConfigPath).
+func (o Options) configDir(getenv func(string) string) string {
+	if o.ConfigDir != "" {
+		return o.ConfigDir
+	}
+	if dir := getenv(configDirEnv); dir != "" {
+		return dir
+	}
+	return getConfigPath()
+}
+
+// apply overrides config values with the flags that were given.
+func (o Options) apply(cfg *Config) {
+	if o.DeviceSerial != "" {
+		cfg.Device.Serial = o.DeviceSerial
+	}
+	if o.Brightness >= 0 {
+		cfg.Application.Brightness = o.Brightness
+	}
+	if o.Headless {
+		cfg.Device.Headless = true
+	}
+}
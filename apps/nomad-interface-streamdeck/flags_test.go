@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+)
+
+func TestFlagsOverrideConfig(t *testing.T) {
+	dir := t.TempDir()
+	yml := "application:\n  brightness: 40\ndevice:\n  serial: CFG123\n"
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(args ...string) *Config {
+		t.Helper()
+		opts, err := parseFlags(args, io.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadConfig(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.apply(cfg)
+		return cfg
+	}
+
+	cfg := load()
+	if cfg.Application.Brightness != 40 || cfg.Device.Serial != "CFG123" || cfg.Device.Headless {
+		t.Errorf("without flags: %+v %+v", cfg.Application, cfg.Device)
+	}
+
+	cfg = load("--brightness", "0", "--device-serial", "FLAG456", "--headless")
+	if cfg.Application.Brightness != 0 {
+		t.Errorf("brightness = %d, want the flag's 0", cfg.Application.Brightness)
+	}
+	if cfg.Device.Serial != "FLAG456" {
+		t.Errorf("serial = %q, want FLAG456", cfg.Device.Serial)
+	}
+	if !cfg.Device.Headless {
+		t.Error("--headless did not enable headless mode")
+	}
+
+	opts, err := parseFlags([]string{"--trace-reports", "-"}, io.Discard)
+	if err != nil || opts.TraceReports != "-" {
+		t.Errorf("--trace-reports: opts=%+v err=%v", opts, err)
+	}
+
+	for _, bad := range [][]string{{"--brightness", "101"}, {"extra"}, {"--bogus"}} {
+		if _, err := parseFlags(bad, io.Discard); err == nil {
+			t.Errorf("parseFlags(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestConfigDirPrecedence(t *testing.T) {
+	env := map[string]string{configDirEnv: "/from/env"}
+	getenv := func(k string) string { return env[k] }
+
+	if got := (Options{ConfigDir: "/from/flag"}).configDir(getenv); got != "/from/flag" {
+		t.Errorf("flag and env: got %s, want /from/flag", got)
+	}
+	if got := (Options{}).configDir(getenv); got != "/from/env" {
+		t.Errorf("env only: got %s, want /from/env", got)
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	devices := []streamdeck.DeviceInfo{
+		{Path: "a", Serial: "A1", Model: streamdeck.Models[0x0080]},
+		{Path: "b", Serial: "B2", Model: streamdeck.Models[0x006c]},
+		{Path: "p", Serial: "P3", Model: streamdeck.Models[0x0086]},
+	}
+
+	if info, err := selectDevice(devices, ""); err != nil || info.Path != "a" {
+		t.Errorf("default: got %q, %v", info.Path, err)
+	}
+	if info, err := selectDevice(devices, "B2"); err != nil || info.Path != "b" {
+		t.Errorf("by serial: got %q, %v", info.Path, err)
+	}
+	if _, err := selectDevice(devices, "P3"); err == nil {
+		t.Error("selected a device without a display")
+	}
+	if _, err := selectDevice(devices, "missing"); err == nil {
+		t.Error("selected a device for an unknown serial")
+	}
+}
+
+func TestForceModel(t *testing.T) {
+	devices := []streamdeck.DeviceInfo{
+		{Path: "a", Model: streamdeck.Models[0x0080]},
+		{Path: "u", Model: streamdeck.ResolveModel(0x00a6, "Mystery Controller")},
+		{Path: "x", Model: streamdeck.Models[0x006c]},
+	}
+	if _, err := selectDevice(devices[1:], ""); err == nil {
+		t.Fatal("selected an unknown device without a display")
+	}
+
+	xl, _ := streamdeck.FindModel("Stream Deck XL")
+	forceModel(devices, xl)
+	// A known PID is overridden too (a clone reporting the MK.2's PID).
+	if m := devices[0].Model; m.ProductID != 0x0080 || m.Cols != 8 || m.Keys != 32 {
+		t.Errorf("MK.2 PID forced to %+v, want XL parameters", m)
+	}
+	if devices[2].Model != streamdeck.Models[0x006c] {
+		t.Errorf("device detected as the XL renamed to %q", devices[2].Model.Name)
+	}
+	m := devices[1].Model
+	if m.ProductID != 0x00a6 || m.Keys != 32 || m.PixelSize != 96 {
+		t.Errorf("forced model = %+v, want XL parameters with PID 0x00a6", m)
+	}
+	if info, err := selectDevice(devices[1:], ""); err != nil || info.Path != "u" {
+		t.Errorf("forced device not selectable: %q, %v", info.Path, err)
+	}
+}
+
+func TestVirtualModel(t *testing.T) {
+	if m := virtualModel("stream deck xl"); m.ProductID != 0x006c {
+		t.Errorf("virtualModel by name = %s", m.Name)
+	}
+	for _, name := range []string{"", "Stream Deck Pedal", "nonsense"} {
+		if m := virtualModel(name); m.ProductID != 0x0080 {
+			t.Errorf("virtualModel(%q) = %s, want the MK.2", name, m.Name)
+		}
+	}
+}
+
+func TestCheckDeviceModel(t *testing.T) {
+	for _, name := range []string{"", "Stream Deck XL", "stream deck mini"} {
+		if err := checkDeviceModel(name); err != nil {
+			t.Errorf("checkDeviceModel(%q) = %v", name, err)
+		}
+	}
+	err := checkDeviceModel("Stream Deck Max")
+	if err == nil {
+		t.Fatal("checkDeviceModel accepted an unknown model")
+	}
+	for _, want := range []string{`"Stream Deck Max"`, "Stream Deck MK.2", "Stream Deck XL"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+// ipc.go – wires the local IPC socket (pkg/ipc) to the app so external
+// programs can set keys and receive key events. Enabled by network.ipc_socket.
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/merith-tk/nomad/pkg/ipc"
+	"github.com/merith-tk/nomad/pkg/scripting"
+)
+
+// startIPC opens the IPC socket if one is configured. A relative path is
+// resolved against the config directory.
+func (a *App) startIPC() {
+	path := a.config.Network.IPCSocket
+	if path == "" {
+		return
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.configPath, path)
+	}
+
+	l, err := ipc.Listen(path)
+	if err != nil {
+		log.Printf("IPC disabled: %v", err)
+		return
+	}
+	a.ipc = ipc.NewServer(a)
+	go func() {
+		if err := a.ipc.Serve(l); err != nil {
+			log.Printf("IPC server stopped: %v", err)
+		}
+	}()
+	fmt.Printf("[*] IPC socket listening on %s\n", path)
+}
+
+// SetKey implements ipc.Handler by rendering the update like a passive
+// appearance. Colours default to a black key with white text.
+func (a *App) SetKey(key int, u ipc.KeyUpdate) error {
+	if key < 0 || key >= a.device.Model.Keys {
+		return fmt.Errorf("key %d out of range (0-%d)", key, a.device.Model.Keys-1)
+	}
+	if a.displayBusy() {
+		return errors.New("display is asleep or showing settings")
+	}
+
+	ap := &scripting.KeyAppearance{
+		Text:      u.Text,
+		TextColor: [3]int{255, 255, 255},
+		Outline:   u.Outline,
+		Image:     u.Image,
+	}
+	if u.Color != nil {
+		ap.Color = *u.Color
+	}
+	if u.TextColor != nil {
+		ap.TextColor = *u.TextColor
+	}
+	// Clients send one update, so fetch a remote image now rather than
+	// leaving the loading placeholder up; fall back to colour/text on error.
+	if ap.Image != "" {
+		if _, err := a.scriptMgr.LoadImage(ap.Image); err != nil {
+			log.Printf("Image load failed: %v", err)
+			ap.Image = ""
+		}
+	}
+	return a.renderAppearance(key, ap)
+}
@@ -0,0 +1,43 @@
+package main
+
+// logging.go – builds the script logger from the logging section of
+// config.yml so Lua log.* output honours the configured level and file.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/merith-tk/nomad/pkg/lualib"
+)
+
+// newScriptLogger returns the logger handed to every script runner.
+// Debug mode always logs at debug level regardless of logging.level.
+func (a *App) newScriptLogger() *lualib.Logger {
+	cfg := a.config.Logging
+	level, err := lualib.ParseLevel(cfg.Level)
+	if err != nil {
+		fmt.Printf("[!] %v, using info\n", err)
+		level = lualib.LevelInfo
+	}
+	if a.config.Application.Debug {
+		level = lualib.LevelDebug
+	}
+
+	var out io.Writer = os.Stdout
+	if cfg.File != "" {
+		path := cfg.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(a.configPath, path)
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Printf("[!] Failed to open log file %s: %v\n", path, err)
+		} else {
+			a.logFile = f
+			out = io.MultiWriter(os.Stdout, f)
+		}
+	}
+	return lualib.NewLogger(out, level)
+}
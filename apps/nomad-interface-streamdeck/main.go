@@ -1,11 +1,37 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"log"
+	"os"
 )
 
+// version is reported to scripts by nomad.version(). Release builds set it
+// with -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
 func main() {
-	app := NewApp()
+	opts, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.PrintConfig {
+		out, err := DefaultConfig().WithComments()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+	if opts.Verify {
+		os.Exit(runVerify(opts, os.Stdout))
+	}
+
+	app := NewApp(opts)
 
 	if err := app.Init(); err != nil {
 		log.Fatal(err)
@@ -8,18 +8,23 @@ package main
 // Layout (5-col × 3-row MK.2 example):
 //
 //	Col 0 (reserved)  Col 1      Col 2      Col 3      Col 4
-//	Row 0:  [BACK]    [BRT-]    [B:XX%]   [BRT+]    [     ]
-//	Row 1:  [     ]   [TMO-]   [T:XXs]   [TMO+]    [     ]
+//	Row 0:  [BACK]    [BRT-]    [B:XX%]   [BRT+]    [ ID  ]
+//	Row 1:  [     ]   [TMO-]   [T:XXs]   [TMO+]    [DIAG ]
 //	Row 2:  [     ]   [EXIT]    [     ]   [     ]   [     ]
 //
 // Brightness steps: ±5, clamped to [5, 100].
 // Timeout cycles:   0 (never) → 30 → 60 → 120 → 300 → 0 …
+// ID blinks the deck so it can be told apart from other connected devices.
+// DIAG prints the health of every loaded script to the console.
 
 import (
 	"fmt"
 	"image/color"
+	"io"
 	"log"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/merith-tk/nomad/pkg/streamdeck"
 )
@@ -33,12 +38,12 @@ const (
 	sSlotBrtDown = 0 // BRT-
 	sSlotBrtVal  = 1 // B:XX%  (display only)
 	sSlotBrtUp   = 2 // BRT+
-	// slot 3 is empty
+	sSlotIdent   = 3 // ID (blink the deck)
 	sSlotTmoDown = 4 // TMO-
 	sSlotTmoVal  = 5 // timeout value display
 	sSlotTmoUp   = 6 // TMO+
-	// slot 7 is empty
-	sSlotExit = 8 // EXIT (kill connection)
+	sSlotDiag    = 7 // DIAG (print script status)
+	sSlotExit    = 8 // EXIT (kill connection)
 )
 
 // enterSettings switches the App into settings mode and renders the settings page.
@@ -54,10 +59,7 @@ func (a *App) exitSettings() {
 	fmt.Println("[*] Exiting settings menu")
 
 	// Re-render the regular navigation page
-	if err := a.nav.RenderPage(); err != nil {
-		log.Printf("RenderPage after settings exit: %v", err)
-	}
-	a.updateVisibleScripts()
+	a.showPage()
 }
 
 // renderSettingsPage draws all settings keys on the Stream Deck.
@@ -78,19 +80,20 @@ func (a *App) renderSettingsPage() {
 	// T1 / T2 are page-scroll arrows for settings.
 	// Currently there is only one settings page so they are shown dimmed.
 	const totalSettingsPages = 1
+	t1, t2 := a.nav.ToggleKeys()
 	if a.settingsPage > 0 {
 		t1Img := a.nav.CreateTextImageWithColors("PG^", color.RGBA{80, 80, 80, 255}, color.White)
-		a.device.SetImage(streamdeck.KeyToggle1, t1Img)
+		a.device.SetImage(t1, t1Img)
 	} else {
 		t1Img := a.nav.CreateTextImageWithColors("PG^", color.RGBA{30, 30, 30, 255}, color.RGBA{80, 80, 80, 255})
-		a.device.SetImage(streamdeck.KeyToggle1, t1Img)
+		a.device.SetImage(t1, t1Img)
 	}
 	if a.settingsPage < totalSettingsPages-1 {
 		t2Img := a.nav.CreateTextImageWithColors("PGv", color.RGBA{80, 80, 80, 255}, color.White)
-		a.device.SetImage(streamdeck.KeyToggle2, t2Img)
+		a.device.SetImage(t2, t2Img)
 	} else {
 		t2Img := a.nav.CreateTextImageWithColors("PG▼", color.RGBA{30, 30, 30, 255}, color.RGBA{80, 80, 80, 255})
-		a.device.SetImage(streamdeck.KeyToggle2, t2Img)
+		a.device.SetImage(t2, t2Img)
 	}
 
 	// Helper to set a content key by slot index
@@ -108,6 +111,7 @@ func (a *App) renderSettingsPage() {
 		fmt.Sprintf("B:%d%%", a.config.Application.Brightness),
 		color.RGBA{20, 20, 60, 255}, color.RGBA{200, 200, 255, 255})
 	setSlot(sSlotBrtUp, "BRT+", color.RGBA{40, 40, 120, 255}, color.RGBA{160, 160, 255, 255})
+	setSlot(sSlotIdent, "ID", color.RGBA{80, 60, 0, 255}, color.RGBA{255, 215, 0, 255})
 
 	// ── Timeout row ───────────────────────────────────────────────────────────
 	setSlot(sSlotTmoDown, "TMO-", color.RGBA{40, 80, 40, 255}, color.RGBA{160, 255, 160, 255})
@@ -115,6 +119,16 @@ func (a *App) renderSettingsPage() {
 	setSlot(sSlotTmoVal, tmoText, color.RGBA{20, 40, 20, 255}, color.RGBA{160, 255, 160, 255})
 	setSlot(sSlotTmoUp, "TMO+", color.RGBA{40, 80, 40, 255}, color.RGBA{160, 255, 160, 255})
 
+	// Diagnostics: label turns red when any script has a background error
+	diagBg := color.RGBA{40, 40, 40, 255}
+	for _, st := range a.scriptMgr.Status() {
+		if st.LastError != nil {
+			diagBg = color.RGBA{120, 20, 20, 255}
+			break
+		}
+	}
+	setSlot(sSlotDiag, "DIAG", diagBg, color.RGBA{220, 220, 220, 255})
+
 	// ── Actions row ──────────────────────────────────────────────────────────
 	setSlot(sSlotExit, "EXIT", color.RGBA{140, 20, 20, 255}, color.RGBA{255, 180, 180, 255})
 }
@@ -129,14 +143,15 @@ func (a *App) handleSettingsKeyEvent(keyIndex int) error {
 
 	// T1/T2 scroll through settings pages (future expansion; no-op on single page)
 	const totalSettingsPages = 1
-	if keyIndex == streamdeck.KeyToggle1 {
+	t1, t2 := a.nav.ToggleKeys()
+	if keyIndex == t1 {
 		if a.settingsPage > 0 {
 			a.settingsPage--
 			a.renderSettingsPage()
 		}
 		return nil
 	}
-	if keyIndex == streamdeck.KeyToggle2 {
+	if keyIndex == t2 {
 		if a.settingsPage < totalSettingsPages-1 {
 			a.settingsPage++
 			a.renderSettingsPage()
@@ -164,6 +179,13 @@ func (a *App) handleSettingsKeyEvent(keyIndex int) error {
 		a.stepTimeout(-1)
 	case sSlotTmoUp:
 		a.stepTimeout(+1)
+	case sSlotIdent:
+		fmt.Println("[*] Identifying device")
+		a.device.Identify()
+		return nil
+	case sSlotDiag:
+		a.printScriptStatus()
+		return nil
 	case sSlotExit:
 		fmt.Println("[*] EXIT pressed – shutting down")
 		a.cancel()
@@ -222,6 +244,45 @@ func (a *App) persistConfig() {
 	}
 }
 
+// printScriptStatus writes a diagnostics table of all loaded scripts.
+func (a *App) printScriptStatus() {
+	a.writeScriptStatus(os.Stdout)
+}
+
+// writeScriptStatus writes the diagnostics table printed by DIAG to w.
+func (a *App) writeScriptStatus(w io.Writer) {
+	statuses := a.scriptMgr.Status()
+	fmt.Fprintf(w, "[*] Script status (%d loaded)\n", len(statuses))
+	for _, st := range statuses {
+		var funcs []string
+		if st.HasBackground {
+			funcs = append(funcs, "background")
+		}
+		if st.HasPassive {
+			funcs = append(funcs, "passive")
+		}
+		if st.HasTrigger {
+			funcs = append(funcs, "trigger")
+		}
+		rel, err := filepath.Rel(a.configPath, st.Path)
+		if err != nil {
+			rel = st.Path
+		}
+		fmt.Fprintf(w, "    %-30s [%s]", rel, strings.Join(funcs, ","))
+		if st.HasBackground {
+			state := "stopped"
+			if st.BackgroundRunning {
+				state = "running"
+			}
+			fmt.Fprintf(w, " bg=%s restarts=%d", state, st.Restarts)
+		}
+		fmt.Fprintln(w)
+		if st.LastError != nil {
+			fmt.Fprintf(w, "      last error: %v\n", st.LastError)
+		}
+	}
+}
+
 // fmtTimeout returns a human-readable label for a timeout value in seconds.
 func fmtTimeout(seconds int) string {
 	if seconds == 0 {
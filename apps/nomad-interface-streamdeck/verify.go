@@ -0,0 +1,79 @@
+package main
+
+// verify.go – the --verify mode: load every script under the config
+// directory on a virtual deck and report what each one defines, for CI and
+// pre-commit checks. No hardware is needed and nothing is written.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merith-tk/nomad/pkg/scripting"
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+)
+
+// runVerify checks the scripts of the config directory chosen by opts and
+// writes one line per script to out. It returns the exit code: 0 if every
+// script loaded, 1 otherwise.
+func runVerify(opts Options, out io.Writer) int {
+	dir := opts.configDir(os.Getenv)
+	if st, err := os.Stat(dir); err != nil || !st.IsDir() {
+		fmt.Fprintf(out, "[!] No config directory at %s\n", dir)
+		return 1
+	}
+
+	// Unlike LoadConfig, never create config.yml
+	config := DefaultConfig()
+	if _, err := os.Stat(filepath.Join(dir, "config.yml")); err == nil {
+		if config, err = LoadConfig(dir); err != nil {
+			fmt.Fprintf(out, "[!] %v\n", err)
+			return 1
+		}
+	} else {
+		applyEnvOverrides(config)
+	}
+	opts.apply(config)
+
+	dev := streamdeck.OpenVirtual(virtualModel(config.Device.Model))
+	mgr := scripting.NewScriptManager(dev, dir, 0)
+	mgr.SetNetworkBlocked(config.Security.BlockNetwork)
+	mgr.SetSandboxed(config.Security.SandboxStdlib)
+	mgr.SetVersion(version)
+	mgr.SetLimits(scripting.ScriptLimits{
+		CallDepth:  config.Scripting.MaxCallDepth,
+		StackSlots: config.Scripting.MaxStackSlots,
+		MemoryMB:   config.Scripting.MaxMemoryMB,
+	})
+
+	results, err := mgr.Verify()
+	if err != nil {
+		fmt.Fprintf(out, "[!] %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, r := range results {
+		name, err := filepath.Rel(dir, r.Path)
+		if err != nil {
+			name = r.Path
+		}
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL %s: %v\n", name, r.Err)
+			continue
+		}
+		funcs := "no entry points"
+		if len(r.Functions) > 0 {
+			funcs = strings.Join(r.Functions, ", ")
+		}
+		fmt.Fprintf(out, "ok   %s (%s)\n", name, funcs)
+	}
+	fmt.Fprintf(out, "%d scripts, %d failed\n", len(results), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
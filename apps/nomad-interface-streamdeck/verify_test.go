@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunVerify(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, src string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("clock.lua", `
+		local script = {}
+		function script.passive(key, state) return {text = "12:00"} end
+		function script.trigger(state) end
+		return script
+	`)
+	write("apps/lib.lua", `return {}`)
+	write("apps/broken.lua", `
+		local script = {}
+		function script.trigger(
+		return script
+	`)
+
+	var out bytes.Buffer
+	if code := runVerify(Options{ConfigDir: dir}, &out); code != 1 {
+		t.Fatalf("exit code %d with a broken script, want 1\n%s", code, &out)
+	}
+	for _, want := range []string{
+		"FAIL " + filepath.Join("apps", "broken.lua") + ":",
+		"ok   " + filepath.Join("apps", "lib.lua") + " (no entry points)",
+		"ok   clock.lua (passive, trigger)",
+		"3 scripts, 1 failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, &out)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yml")); !os.IsNotExist(err) {
+		t.Error("verify created config.yml")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "apps", "broken.lua")); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if code := runVerify(Options{ConfigDir: dir}, &out); code != 0 {
+		t.Errorf("exit code %d with valid scripts, want 0\n%s", code, &out)
+	}
+	if code := runVerify(Options{ConfigDir: filepath.Join(dir, "missing")}, &out); code != 1 {
+		t.Errorf("exit code %d for a missing config directory, want 1", code)
+	}
+}
@@ -3,13 +3,24 @@ module github.com/merith-tk/nomad
 go 1.24.4
 
 require (
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/makiuchi-d/gozxing v0.1.1
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20220730225603-2ab79fcdd4ef
 	github.com/sstallion/go-hid v0.15.0
 	github.com/yuin/gopher-lua v1.1.1
 	golang.org/x/image v0.36.0
+	golang.org/x/text v0.34.0
 )
 
 require github.com/Merith-TK/utils v0.0.0-20250915201218-d2a29b353f31
 
+require (
+	golang.org/x/net v0.35.0 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+)
+
 require (
 	golang.org/x/sys v0.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1
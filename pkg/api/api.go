@@ -0,0 +1,341 @@
+// Package api provides an optional HTTP control API for the running app.
+//
+// Endpoints:
+//
+//	GET  /state             device and page information (JSON)
+//	GET  /page              text layout of the current page (see
+//	                        streamdeck.Navigator.Describe)
+//	GET  /metrics           render, passive and HID write timings (JSON)
+//	POST /keys/{i}          update a key from an ipc.KeyUpdate JSON body
+//	POST /keys/{i}/color    {"color": [r, g, b]}
+//	POST /keys/{i}/image    {"image": "path|url|data URI"}, or a raw image
+//	                        body with an image/* Content-Type
+//	GET  /events            key events as a WebSocket feed when the request
+//	                        is an upgrade, otherwise as Server-Sent Events
+//
+// Events use the same JSON shape as the IPC socket (ipc.Event). Only key
+// events exist today; the supported decks have no dials or touch strip.
+//
+// Successful updates answer 204 No Content; failures answer a JSON
+// {"error": "..."} body.
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/ipc"
+)
+
+const (
+	// maxImageBody caps uploaded image size.
+	maxImageBody = 4 << 20
+
+	// eventBuffer is how many events a slow /events client may fall
+	// behind before further events are dropped for it.
+	eventBuffer = 64
+)
+
+// State describes what the deck is currently showing.
+type State struct {
+	Model      string `json:"model"`
+	Cols       int    `json:"cols"`
+	Rows       int    `json:"rows"`
+	Keys       int    `json:"keys"`
+	Path       string `json:"path"` // current folder, relative to the config dir
+	Page       int    `json:"page"` // zero-based
+	Pages      int    `json:"pages"`
+	Sleeping   bool   `json:"sleeping"`
+	InSettings bool   `json:"in_settings"`
+}
+
+// Timing summarises the durations of one kind of operation.
+type Timing struct {
+	Count  int     `json:"count"`
+	AvgMS  float64 `json:"avg_ms"`
+	MaxMS  float64 `json:"max_ms"`
+	LastMS float64 `json:"last_ms"`
+}
+
+// Metrics reports how long rendering and scripts take, for tuning.
+type Metrics struct {
+	Render      Timing            `json:"render"`       // page renders
+	PassiveTick Timing            `json:"passive_tick"` // one passive loop tick
+	Passive     map[string]Timing `json:"passive"`      // passive() by script, relative to the config dir
+	HIDReports  uint64            `json:"hid_reports"`  // HID output reports written
+	HIDErrors   uint64            `json:"hid_errors"`   // HID writes that failed after retries
+	KeyImages   uint64            `json:"key_images"`   // key images written
+}
+
+// Controller is implemented by the app.
+type Controller interface {
+	ipc.Handler
+	State() State
+	// Describe returns a plain-text layout of what the deck shows.
+	Describe() string
+	Metrics() Metrics
+}
+
+// Server serves the control API. Use New.
+type Server struct {
+	ctrl Controller
+	mux  *http.ServeMux
+
+	events *broadcaster
+
+	// allowedOrigins lists extra browser origins that may open the
+	// WebSocket feed; same-host pages are always allowed.
+	allowedOrigins []string
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// New creates an API server backed by ctrl.
+func New(ctrl Controller) *Server {
+	s := &Server{
+		ctrl:   ctrl,
+		mux:    http.NewServeMux(),
+		events: newBroadcaster(),
+	}
+	s.mux.HandleFunc("GET /state", s.handleState)
+	s.mux.HandleFunc("GET /page", s.handlePage)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("POST /keys/{i}", s.handleKey)
+	s.mux.HandleFunc("POST /keys/{i}/color", s.handleKeyColor)
+	s.mux.HandleFunc("POST /keys/{i}/image", s.handleKeyImage)
+	s.mux.HandleFunc("GET /events", s.handleEvents)
+	return s
+}
+
+// SetAllowedOrigins sets which cross-origin pages (e.g.
+// "http://localhost:3000") may open the WebSocket feed. "*" allows any.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.allowedOrigins = origins
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe listens on addr (e.g. "127.0.0.1:8765") and serves until
+// Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("api listen: %w", err)
+	}
+	srv := &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.mu.Lock()
+	s.server = srv
+	s.mu.Unlock()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Close shuts the server down and ends all event streams.
+func (s *Server) Close() error {
+	s.events.close()
+
+	s.mu.Lock()
+	srv := s.server
+	s.mu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// PublishKey sends a key event to every /events subscriber. Slow
+// subscribers miss events rather than blocking the caller.
+func (s *Server) PublishKey(key int, pressed bool) {
+	s.events.publish(ipc.Event{Event: "key", Key: key, Pressed: pressed})
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.ctrl.State())
+}
+
+func (s *Server) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, s.ctrl.Describe())
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.ctrl.Metrics())
+}
+
+func (s *Server) handleKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := keyIndex(w, r)
+	if !ok {
+		return
+	}
+	var u ipc.KeyUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	s.apply(w, key, u)
+}
+
+func (s *Server) handleKeyColor(w http.ResponseWriter, r *http.Request) {
+	key, ok := keyIndex(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Color *[3]int `json:"color"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Color == nil {
+		writeError(w, http.StatusBadRequest, `body must be {"color": [r, g, b]}`)
+		return
+	}
+	s.apply(w, key, ipc.KeyUpdate{Color: body.Color})
+}
+
+func (s *Server) handleKeyImage(w http.ResponseWriter, r *http.Request) {
+	key, ok := keyIndex(w, r)
+	if !ok {
+		return
+	}
+
+	// Raw upload: pass it on as a data URI
+	if ct := r.Header.Get("Content-Type"); strings.HasPrefix(ct, "image/") {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImageBody))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		uri := "data:" + ct + ";base64," + base64.StdEncoding.EncodeToString(data)
+		s.apply(w, key, ipc.KeyUpdate{Image: uri})
+		return
+	}
+
+	var body struct {
+		Image string `json:"image"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Image == "" {
+		writeError(w, http.StatusBadRequest, `body must be {"image": "..."} or an image/* upload`)
+		return
+	}
+	s.apply(w, key, ipc.KeyUpdate{Image: body.Image})
+}
+
+// handleEvents streams key events until the client disconnects or the
+// server closes.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if isWebSocketUpgrade(r) {
+		s.serveWebSocket(w, r)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Event, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// serveWebSocket streams key events as JSON text messages.
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !originAllowed(r, s.allowedOrigins) {
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	ch := s.events.subscribe()
+	defer s.events.unsubscribe(ch)
+
+	done := make(chan struct{})
+	go func() {
+		ws.readLoop()
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				ws.writeFrame(wsOpClose, []byte{0x03, 0xE9}) // 1001 going away
+				return
+			}
+			if err := ws.writeJSON(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// apply forwards an update to the controller and writes the response.
+func (s *Server) apply(w http.ResponseWriter, key int, u ipc.KeyUpdate) {
+	if err := s.ctrl.SetKey(key, u); err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keyIndex parses the {i} path value, writing a 400 if it is not a number.
+func keyIndex(w http.ResponseWriter, r *http.Request) (int, bool) {
+	key, err := strconv.Atoi(r.PathValue("i"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "key must be a number")
+		return 0, false
+	}
+	return key, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/ipc"
+)
+
+// fakeController records key updates and rejects keys >= keys.
+type fakeController struct {
+	keys    int
+	updates map[int]ipc.KeyUpdate
+}
+
+func newFakeController() *fakeController {
+	return &fakeController{keys: 15, updates: make(map[int]ipc.KeyUpdate)}
+}
+
+func (f *fakeController) SetKey(key int, u ipc.KeyUpdate) error {
+	if key < 0 || key >= f.keys {
+		return errors.New("key out of range")
+	}
+	f.updates[key] = u
+	return nil
+}
+
+func (f *fakeController) State() State {
+	return State{Model: "Fake", Cols: 5, Rows: 3, Keys: f.keys, Path: "games", Pages: 2}
+}
+
+func (f *fakeController) Describe() string {
+	return "/games (page 1/2)\nrow 1: Back | snake | -\n"
+}
+
+func (f *fakeController) Metrics() Metrics {
+	return Metrics{
+		Render:    Timing{Count: 3, AvgMS: 12.5, MaxMS: 20, LastMS: 8},
+		Passive:   map[string]Timing{"clock.lua": {Count: 40, AvgMS: 0.2}},
+		KeyImages: 45,
+	}
+}
+
+func do(t *testing.T, s *Server, method, path, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// waitSubscribed waits for an /events client to register, since that
+// happens after the response headers have been sent.
+func waitSubscribed(t *testing.T, s *Server) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for s.events.len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscriber never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestState(t *testing.T) {
+	s := New(newFakeController())
+	rec := do(t, s, "GET", "/state", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"model":"Fake"`) || !strings.Contains(body, `"path":"games"`) {
+		t.Fatalf("unexpected body %s", body)
+	}
+}
+
+func TestPage(t *testing.T) {
+	s := New(newFakeController())
+	rec := do(t, s, "GET", "/page", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type %q", ct)
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(body, "/games (page 1/2)\n") {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := New(newFakeController())
+	rec := do(t, s, "GET", "/metrics", "", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`"render":{"count":3,"avg_ms":12.5`, `"clock.lua":{"count":40`, `"key_images":45`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body lacks %s: %s", want, body)
+		}
+	}
+}
+
+func TestKeyColor(t *testing.T) {
+	ctrl := newFakeController()
+	s := New(ctrl)
+
+	rec := do(t, s, "POST", "/keys/6/color", "application/json", `{"color": [255, 0, 10]}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status %d, want 204: %s", rec.Code, rec.Body)
+	}
+	if c := ctrl.updates[6].Color; c == nil || *c != [3]int{255, 0, 10} {
+		t.Fatalf("key 6 colour = %v", c)
+	}
+
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/keys/x/color", `{"color": [1, 2, 3]}`, http.StatusBadRequest},
+		{"/keys/6/color", `{}`, http.StatusBadRequest},
+		{"/keys/99/color", `{"color": [1, 2, 3]}`, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		if rec := do(t, s, "POST", tc.path, "application/json", tc.body); rec.Code != tc.want {
+			t.Errorf("POST %s %s: status %d, want %d", tc.path, tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestKeyImage(t *testing.T) {
+	ctrl := newFakeController()
+	s := New(ctrl)
+
+	if rec := do(t, s, "POST", "/keys/1/image", "application/json", `{"image": "icons/a.png"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("JSON image: status %d", rec.Code)
+	}
+	if got := ctrl.updates[1].Image; got != "icons/a.png" {
+		t.Fatalf("key 1 image = %q", got)
+	}
+
+	if rec := do(t, s, "POST", "/keys/2/image", "image/png", "PNG"); rec.Code != http.StatusNoContent {
+		t.Fatalf("raw image: status %d", rec.Code)
+	}
+	if got := ctrl.updates[2].Image; got != "data:image/png;base64,UE5H" {
+		t.Fatalf("key 2 image = %q", got)
+	}
+}
+
+func TestKeyUpdate(t *testing.T) {
+	ctrl := newFakeController()
+	s := New(ctrl)
+
+	rec := do(t, s, "POST", "/keys/3", "application/json", `{"text": "REC", "color": [255, 0, 0]}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status %d", rec.Code)
+	}
+	if u := ctrl.updates[3]; u.Text != "REC" || u.Color == nil {
+		t.Fatalf("key 3 update = %+v", u)
+	}
+	if rec := do(t, s, "GET", "/keys/3", "", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET /keys/3: status %d, want 405", rec.Code)
+	}
+}
+
+func TestEventsStream(t *testing.T) {
+	s := New(newFakeController())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type %q", ct)
+	}
+
+	waitSubscribed(t, s)
+	s.PublishKey(4, true)
+
+	r := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	if lines[0] != "event: key" || lines[1] != `data: {"event":"key","key":4,"pressed":true}` {
+		t.Fatalf("unexpected event %q", lines)
+	}
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	s := New(newFakeController())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	// Example key from RFC 6455 section 1.3.
+	io.WriteString(conn, "GET /events HTTP/1.1\r\n"+
+		"Host: "+strings.TrimPrefix(ts.URL, "http://")+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status %d, want 101", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Sec-WebSocket-Accept %q", got)
+	}
+
+	waitSubscribed(t, s)
+	s.PublishKey(9, false)
+
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if hdr[0] != 0x80|wsOpText || hdr[1]&0x80 != 0 {
+		t.Fatalf("frame header % x, want unmasked final text frame", hdr)
+	}
+	payload := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if got := string(payload); got != `{"event":"key","key":9,"pressed":false}` {
+		t.Fatalf("payload %s", got)
+	}
+
+	// A masked close frame from the client is echoed back.
+	mask := []byte{1, 2, 3, 4}
+	code := binary.BigEndian.AppendUint16(nil, 1000)
+	frame := append([]byte{0x80 | wsOpClose, 0x80 | byte(len(code))}, mask...)
+	for i, b := range code {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		t.Fatalf("read close: %v", err)
+	}
+	if hdr[0] != 0x80|wsOpClose {
+		t.Fatalf("got opcode %x, want close", hdr[0]&0x0F)
+	}
+}
+
+func TestEventsWebSocketRejectsForeignOrigin(t *testing.T) {
+	s := New(newFakeController())
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Origin", "https://evil.example")
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want 403", rec.Code)
+	}
+}
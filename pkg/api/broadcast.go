@@ -0,0 +1,74 @@
+package api
+
+import (
+	"sync"
+
+	"github.com/merith-tk/nomad/pkg/ipc"
+)
+
+// broadcaster fans key events from the app's ListenKeys loop out to every
+// connected /events client, whichever transport it uses.
+type broadcaster struct {
+	mu     sync.Mutex
+	subs   map[chan ipc.Event]struct{}
+	closed bool
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan ipc.Event]struct{})}
+}
+
+// subscribe registers a new buffered event channel. After close it returns
+// an already-closed channel.
+func (b *broadcaster) subscribe() chan ipc.Event {
+	ch := make(chan ipc.Event, eventBuffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes and closes ch unless close already did.
+func (b *broadcaster) unsubscribe(ch chan ipc.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends ev to every subscriber without blocking; subscribers that
+// have fallen behind miss it.
+func (b *broadcaster) publish(ev ipc.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// len reports the number of subscribers.
+func (b *broadcaster) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// close ends every subscription.
+func (b *broadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,262 @@
+// Package ipc provides a local socket server that lets external programs
+// drive Stream Deck keys and receive key events.
+//
+// The protocol is newline-delimited JSON over a Unix domain socket (also
+// supported on Windows 10+). Each line a client sends is a Request; the server
+// answers every request with a Response carrying the same id. After a
+// "subscribe" request the client additionally receives an Event line for every
+// key press and release.
+//
+//	→ {"id": 1, "cmd": "set", "key": 6, "color": [255, 0, 0], "text": "REC"}
+//	← {"id": 1, "ok": true}
+//	→ {"id": 2, "cmd": "subscribe"}
+//	← {"id": 2, "ok": true}
+//	← {"event": "key", "key": 6, "pressed": true}
+//
+// Commands:
+//
+//	set          update a key; any of color, text, text_color, image
+//	subscribe    start receiving key events
+//	unsubscribe  stop receiving key events
+//	ping         no-op, answers ok
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+)
+
+// KeyUpdate describes a change to a key's appearance. Nil / empty fields are
+// left to the handler's defaults.
+type KeyUpdate struct {
+	Color     *[3]int `json:"color,omitempty"`
+	Text      string  `json:"text,omitempty"`
+	TextColor *[3]int `json:"text_color,omitempty"`
+	Outline   *[3]int `json:"outline,omitempty"`
+	Image     string  `json:"image,omitempty"` // file path, URL or data URI
+}
+
+// Handler applies key updates requested by clients.
+type Handler interface {
+	SetKey(key int, u KeyUpdate) error
+}
+
+// Request is a single command sent by a client.
+type Request struct {
+	ID  json.RawMessage `json:"id,omitempty"`
+	Cmd string          `json:"cmd"`
+	Key *int            `json:"key,omitempty"`
+	KeyUpdate
+}
+
+// Response answers a Request.
+type Response struct {
+	ID    json.RawMessage `json:"id,omitempty"`
+	OK    bool            `json:"ok"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Event is pushed to subscribed clients.
+type Event struct {
+	Event   string `json:"event"` // "key"
+	Key     int    `json:"key"`
+	Pressed bool   `json:"pressed"`
+}
+
+// eventBuffer is how many events a slow client may fall behind before
+// further events are dropped for it.
+const eventBuffer = 64
+
+// Server accepts IPC clients. The zero value is not usable; use NewServer.
+type Server struct {
+	handler Handler
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	clients   map[*client]struct{}
+	closed    bool
+}
+
+// client is one connected program.
+type client struct {
+	conn       net.Conn
+	writeMu    sync.Mutex
+	enc        *json.Encoder
+	events     chan Event
+	subscribed bool // guarded by Server.mu
+}
+
+// NewServer creates a server that applies key updates through h.
+func NewServer(h Handler) *Server {
+	return &Server{
+		handler:   h,
+		listeners: make(map[net.Listener]struct{}),
+		clients:   make(map[*client]struct{}),
+	}
+}
+
+// Listen creates a Unix domain socket at path, replacing a stale socket left
+// by a previous run. The socket is only accessible by the current user.
+func Listen(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("ipc listen: %w", err)
+	}
+	os.Chmod(path, 0600)
+	return l, nil
+}
+
+// Serve accepts connections on l until Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn handles a single client until it disconnects or the server is
+// closed. The connection is closed on return.
+func (s *Server) ServeConn(conn net.Conn) {
+	c := &client{
+		conn:   conn,
+		enc:    json.NewEncoder(conn),
+		events: make(chan Event, eventBuffer),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case ev := <-c.events:
+				c.write(ev)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		close(done)
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			c.write(Response{Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+		resp := Response{ID: req.ID, OK: true}
+		if err := s.handle(c, &req); err != nil {
+			resp.OK = false
+			resp.Error = err.Error()
+		}
+		c.write(resp)
+	}
+}
+
+// handle executes one request.
+func (s *Server) handle(c *client, req *Request) error {
+	switch req.Cmd {
+	case "set":
+		if req.Key == nil {
+			return errors.New("set requires key")
+		}
+		return s.handler.SetKey(*req.Key, req.KeyUpdate)
+	case "subscribe", "unsubscribe":
+		s.mu.Lock()
+		c.subscribed = req.Cmd == "subscribe"
+		s.mu.Unlock()
+		return nil
+	case "ping":
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", req.Cmd)
+	}
+}
+
+// write sends one JSON line to the client. Errors are ignored; a broken
+// connection is noticed by the read loop.
+func (c *client) write(v any) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.enc.Encode(v)
+}
+
+// PublishKey sends a key event to every subscribed client. It never blocks;
+// clients that have fallen behind miss the event.
+func (s *Server) PublishKey(key int, pressed bool) {
+	ev := Event{Event: "key", Key: key, Pressed: pressed}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		if !c.subscribed {
+			continue
+		}
+		select {
+		case c.events <- ev:
+		default:
+		}
+	}
+}
+
+// Close stops all listeners and disconnects every client.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var errs []error
+	for l := range s.listeners {
+		if err := l.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	for c := range s.clients {
+		c.conn.Close()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,182 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingHandler struct {
+	mu      sync.Mutex
+	updates map[int]KeyUpdate
+}
+
+func (h *recordingHandler) SetKey(key int, u KeyUpdate) error {
+	if key < 0 {
+		return errors.New("no such key")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.updates[key] = u
+	return nil
+}
+
+// testClient is the program side of an in-memory connection.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func newTestClient(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	go s.ServeConn(serverConn)
+	t.Cleanup(func() { clientConn.Close() })
+	return &testClient{t: t, conn: clientConn, r: bufio.NewReader(clientConn)}
+}
+
+func (c *testClient) send(line string) {
+	c.t.Helper()
+	c.conn.SetWriteDeadline(time.Now().Add(2 * time.Second))
+	if _, err := io.WriteString(c.conn, line+"\n"); err != nil {
+		c.t.Fatalf("write %s: %v", line, err)
+	}
+}
+
+// recv decodes the next line the server sent into v.
+func (c *testClient) recv(v any) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	if err := json.Unmarshal(line, v); err != nil {
+		c.t.Fatalf("decode %s: %v", line, err)
+	}
+}
+
+func (c *testClient) call(line string) Response {
+	c.t.Helper()
+	c.send(line)
+	var resp Response
+	c.recv(&resp)
+	return resp
+}
+
+func TestSetKeyRoundTrip(t *testing.T) {
+	h := &recordingHandler{updates: make(map[int]KeyUpdate)}
+	s := NewServer(h)
+	defer s.Close()
+	c := newTestClient(t, s)
+
+	resp := c.call(`{"id": 1, "cmd": "set", "key": 6, "color": [255, 0, 0], "text": "REC", "image": "rec.png"}`)
+	if !resp.OK || string(resp.ID) != "1" {
+		t.Fatalf("set response = %+v", resp)
+	}
+	h.mu.Lock()
+	got := h.updates[6]
+	h.mu.Unlock()
+	if got.Color == nil || *got.Color != [3]int{255, 0, 0} || got.Text != "REC" || got.Image != "rec.png" || got.TextColor != nil {
+		t.Errorf("handler got %+v", got)
+	}
+
+	// String ids are echoed back unchanged
+	if resp := c.call(`{"id": "abc", "cmd": "ping"}`); !resp.OK || string(resp.ID) != `"abc"` {
+		t.Errorf("ping response = %+v", resp)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	s := NewServer(&recordingHandler{updates: make(map[int]KeyUpdate)})
+	defer s.Close()
+	c := newTestClient(t, s)
+
+	tests := []struct {
+		line    string
+		wantErr string
+	}{
+		{`{"id": 1, "cmd": "set", "text": "x"}`, "set requires key"},
+		{`{"id": 2, "cmd": "set", "key": -1}`, "no such key"},
+		{`{"id": 3, "cmd": "reboot"}`, `unknown command "reboot"`},
+		{`{"id": 4, "cmd": `, "invalid JSON"},
+		{`not json`, "invalid JSON"},
+		{`{"id": 5, "cmd": "set", "key": "six"}`, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		resp := c.call(tt.line)
+		if resp.OK || !strings.Contains(resp.Error, tt.wantErr) {
+			t.Errorf("%s: response = %+v, want error containing %q", tt.line, resp, tt.wantErr)
+		}
+	}
+
+	// A malformed frame does not drop the connection
+	if resp := c.call(`{"id": 6, "cmd": "ping"}`); !resp.OK || string(resp.ID) != "6" {
+		t.Errorf("ping after errors = %+v", resp)
+	}
+}
+
+func TestKeyEventStreaming(t *testing.T) {
+	s := NewServer(&recordingHandler{updates: make(map[int]KeyUpdate)})
+	defer s.Close()
+	subscriber := newTestClient(t, s)
+	other := newTestClient(t, s)
+
+	if resp := subscriber.call(`{"id": 1, "cmd": "subscribe"}`); !resp.OK {
+		t.Fatalf("subscribe = %+v", resp)
+	}
+	if resp := other.call(`{"id": 1, "cmd": "ping"}`); !resp.OK {
+		t.Fatalf("ping = %+v", resp)
+	}
+
+	s.PublishKey(6, true)
+	s.PublishKey(6, false)
+	for _, want := range []Event{{Event: "key", Key: 6, Pressed: true}, {Event: "key", Key: 6, Pressed: false}} {
+		var ev Event
+		subscriber.recv(&ev)
+		if ev != want {
+			t.Errorf("event = %+v, want %+v", ev, want)
+		}
+	}
+
+	// Clients that never subscribed, and ones that unsubscribed, get no events
+	if resp := subscriber.call(`{"id": 2, "cmd": "unsubscribe"}`); !resp.OK {
+		t.Fatalf("unsubscribe = %+v", resp)
+	}
+	s.PublishKey(7, true)
+	for _, c := range []*testClient{subscriber, other} {
+		if resp := c.call(`{"id": 3, "cmd": "ping"}`); !resp.OK || string(resp.ID) != "3" {
+			t.Errorf("expected only the ping response, got %+v", resp)
+		}
+	}
+}
+
+func TestCloseDisconnectsClients(t *testing.T) {
+	s := NewServer(&recordingHandler{updates: make(map[int]KeyUpdate)})
+	c := newTestClient(t, s)
+	if resp := c.call(`{"cmd": "ping"}`); !resp.OK {
+		t.Fatalf("ping = %+v", resp)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := c.r.ReadByte(); err != io.EOF {
+		t.Errorf("read after Close = %v, want EOF", err)
+	}
+
+	// New connections are refused once closed
+	late := newTestClient(t, s)
+	late.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := late.r.ReadByte(); err != io.EOF {
+		t.Errorf("read on connection after Close = %v, want EOF", err)
+	}
+}
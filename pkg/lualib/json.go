@@ -41,17 +41,40 @@ func jsonEncode(L *lua.LState) int {
 // jsonDecode decodes a JSON string into a Lua value.
 // Lua: json.decode(str) -> value, err
 func jsonDecode(L *lua.LState) int {
-	var result interface{}
-	if err := json.Unmarshal([]byte(L.CheckString(1)), &result); err != nil {
+	v, err := DecodeJSON(L, []byte(L.CheckString(1)))
+	if err != nil {
 		L.Push(lua.LNil)
 		L.Push(lua.LString(err.Error()))
 		return 2
 	}
-	L.Push(goToLua(L, result))
+	L.Push(v)
 	L.Push(lua.LNil)
 	return 2
 }
 
+// DecodeJSON decodes JSON data into a Lua value, the same way json.decode
+// does. Other modules use it to hand JSON straight to scripts.
+func DecodeJSON(L *lua.LState, data []byte) (lua.LValue, error) {
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return lua.LNil, err
+	}
+	return goToLua(L, result), nil
+}
+
+// ToGo converts a Lua value to plain Go values (nil, bool, float64, string,
+// []interface{}, map[string]interface{}) the way json.encode sees it. The
+// result shares nothing with the Lua state, so it can cross between VMs.
+func ToGo(v lua.LValue) interface{} {
+	return luaToGo(v)
+}
+
+// FromGo converts values produced by ToGo or json.Unmarshal into a Lua
+// value owned by L.
+func FromGo(L *lua.LState, v interface{}) lua.LValue {
+	return goToLua(L, v)
+}
+
 // luaToGo converts a Lua value to a Go value suitable for json.Marshal.
 func luaToGo(v lua.LValue) interface{} {
 	switch val := v.(type) {
@@ -2,64 +2,222 @@
 package lualib
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	lua "github.com/yuin/gopher-lua"
 )
 
-// RegisterLog preloads the "log" module into the given Lua state.
+// Level is a log severity. Messages below a Logger's level are dropped.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+// String returns the lower-case level name used in config and JSON output.
+func (l Level) String() string {
+	if l >= 0 && int(l) < len(levelNames) {
+		return levelNames[l]
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel parses "debug", "info", "warn" (or "warning") and "error".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Logger is where the Lua log module writes. It is safe for concurrent use
+// by every script; the level can be changed while scripts run.
+type Logger struct {
+	out    *log.Logger   // human-readable lines
+	raw    *log.Logger   // JSON lines, no prefix or timestamp
+	level  *atomic.Int32 // shared with loggers made by ForScript
+	script string
+}
+
+// NewLogger creates a logger writing to w with the "[SCRIPT] " prefix.
+func NewLogger(w io.Writer, level Level) *Logger {
+	lw := &lockedWriter{w: w}
+	l := &Logger{
+		out:   log.New(lw, "[SCRIPT] ", log.LstdFlags),
+		raw:   log.New(lw, "", 0),
+		level: new(atomic.Int32),
+	}
+	l.SetLevel(level)
+	return l
+}
+
+// ForScript returns a logger that shares l's output and level but tags
+// JSON entries with the given script name. A nil l uses the default logger.
+func (l *Logger) ForScript(name string) *Logger {
+	if l == nil {
+		l = defaultLogger
+	}
+	c := *l
+	c.script = name
+	return &c
+}
+
+// lockedWriter serialises writes so text and JSON lines never interleave.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	return lw.w.Write(p)
+}
+
+// defaultLogger is used when RegisterLog is given nil. It keeps the
+// original behaviour of printing everything to stdout.
+var defaultLogger = NewLogger(os.Stdout, LevelDebug)
+
+// SetLevel changes the minimum level that is written.
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// Enabled reports whether messages at level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= Level(l.level.Load())
+}
+
+// RegisterLog preloads the "log" module into the given Lua state, writing to
+// logger (stdout, unfiltered, when nil).
 // Lua scripts access it via: local log = require("log")
-func RegisterLog(L *lua.LState) {
-	L.PreloadModule("log", logLoader(log.New(os.Stdout, "[SCRIPT] ", log.LstdFlags)))
+func RegisterLog(L *lua.LState, logger *Logger) {
+	if logger == nil {
+		logger = defaultLogger
+	}
+	L.PreloadModule("log", logLoader(logger))
 }
 
 // RegisterLogWithPrefix preloads the "log" module using a custom logger prefix.
 func RegisterLogWithPrefix(L *lua.LState, prefix string) {
-	L.PreloadModule("log", logLoader(log.New(os.Stdout, prefix, log.LstdFlags)))
+	l := NewLogger(os.Stdout, LevelDebug)
+	l.out.SetPrefix(prefix)
+	L.PreloadModule("log", logLoader(l))
 }
 
-func logLoader(logger *log.Logger) lua.LGFunction {
+func logLoader(logger *Logger) lua.LGFunction {
 	return func(L *lua.LState) int {
 		mod := L.NewTable()
 		L.SetFuncs(mod, map[string]lua.LGFunction{
-			"info":   makeLogFunc(logger, "[INFO]"),
-			"warn":   makeLogFunc(logger, "[WARN]"),
-			"error":  makeLogFunc(logger, "[ERROR]"),
-			"debug":  makeLogFunc(logger, "[DEBUG]"),
+			"info":   makeLogFunc(logger, LevelInfo, "[INFO]"),
+			"warn":   makeLogFunc(logger, LevelWarn, "[WARN]"),
+			"error":  makeLogFunc(logger, LevelError, "[ERROR]"),
+			"debug":  makeLogFunc(logger, LevelDebug, "[DEBUG]"),
 			"printf": logPrintf(logger),
-			"print":  makeLogFunc(logger, ""),
+			"print":  makeLogFunc(logger, LevelInfo, ""),
+			"json":   logJSON(logger),
 		})
 		L.Push(mod)
 		return 1
 	}
 }
 
-func makeLogFunc(logger *log.Logger, level string) lua.LGFunction {
+// makeLogFunc returns a log function for one level; print and printf log at
+// info level without a tag. Any number of values of any type are accepted
+// and joined with spaces.
+func makeLogFunc(logger *Logger, level Level, tag string) lua.LGFunction {
 	return func(L *lua.LState) int {
-		msg := L.CheckString(1)
-		if level == "" {
-			logger.Println(msg)
-		} else {
-			logger.Println(level, msg)
+		if !logger.Enabled(level) {
+			return 0
+		}
+		parts := make([]string, 0, L.GetTop()+1)
+		if tag != "" {
+			parts = append(parts, tag)
+		}
+		for i := 1; i <= L.GetTop(); i++ {
+			parts = append(parts, luaValueToString(L, L.Get(i)))
 		}
+		logger.out.Println(strings.Join(parts, " "))
 		return 0
 	}
 }
 
-func logPrintf(logger *log.Logger) lua.LGFunction {
+func logPrintf(logger *Logger) lua.LGFunction {
 	return func(L *lua.LState) int {
-		format := L.CheckString(1)
-		args := make([]interface{}, L.GetTop()-1)
+		if !logger.Enabled(LevelInfo) {
+			return 0
+		}
+		format := luaValueToString(L, L.Get(1))
+		args := make([]interface{}, 0, L.GetTop())
 		for i := 2; i <= L.GetTop(); i++ {
-			args[i-2] = luaArgToInterface(L.Get(i))
+			args = append(args, luaArgToInterface(L.Get(i)))
 		}
-		logger.Println(fmt.Sprintf(format, args...))
+		logger.out.Println(fmt.Sprintf(format, args...))
 		return 0
 	}
 }
 
+// logJSON writes one JSON object per line with the time, level, script
+// name and the given fields. level defaults to "info".
+// Lua: log.json(fields [, level])
+func logJSON(logger *Logger) lua.LGFunction {
+	return func(L *lua.LState) int {
+		fields := L.CheckTable(1)
+		level, err := ParseLevel(L.OptString(2, "info"))
+		if err != nil {
+			L.ArgError(2, err.Error())
+			return 0
+		}
+		if !logger.Enabled(level) {
+			return 0
+		}
+		entry := struct {
+			Time   string      `json:"time"`
+			Level  string      `json:"level"`
+			Script string      `json:"script,omitempty"`
+			Fields interface{} `json:"fields"`
+		}{
+			Time:   time.Now().Format(time.RFC3339Nano),
+			Level:  level.String(),
+			Script: logger.script,
+			Fields: luaToGo(fields),
+		}
+		data, err := json.Marshal(entry)
+		if err != nil {
+			L.RaiseError("log.json: %v", err)
+			return 0
+		}
+		logger.raw.Println(string(data))
+		return 0
+	}
+}
+
+// luaValueToString formats v the way Lua's tostring does, honouring
+// __tostring metamethods.
+func luaValueToString(L *lua.LState, v lua.LValue) string {
+	return L.ToStringMeta(v).String()
+}
+
 func luaArgToInterface(v lua.LValue) interface{} {
 	switch val := v.(type) {
 	case *lua.LNilType:
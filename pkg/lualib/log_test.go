@@ -0,0 +1,149 @@
+package lualib
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runLog(t *testing.T, logger *Logger, script string) {
+	t.Helper()
+	L := lua.NewState()
+	defer L.Close()
+	RegisterLog(L, logger)
+	if err := L.DoString(`local log = require("log")` + "\n" + script); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogSuppressesDebugAtInfo(t *testing.T) {
+	var buf bytes.Buffer
+	runLog(t, NewLogger(&buf, LevelInfo), `
+		log.debug("hidden")
+		log.info("shown")
+		log.printf("n=%v", 3)
+	`)
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug message written at info level: %q", out)
+	}
+	for _, want := range []string{"[SCRIPT] ", "[INFO] shown", "n=3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q: %q", want, out)
+		}
+	}
+}
+
+func TestLogLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LevelError)
+	runLog(t, logger, `log.info("a") log.warn("b") log.print("c") log.error("d")`)
+	if got := strings.Count(buf.String(), "\n"); got != 1 || !strings.Contains(buf.String(), "[ERROR] d") {
+		t.Errorf("at error level got %q", buf.String())
+	}
+
+	buf.Reset()
+	logger.SetLevel(LevelDebug)
+	runLog(t, logger, `log.debug("e")`)
+	if !strings.Contains(buf.String(), "[DEBUG] e") {
+		t.Errorf("debug not written after SetLevel: %q", buf.String())
+	}
+}
+
+func TestLogConcurrentStates(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LevelInfo)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runLog(t, logger, `for i = 1, 50 do log.info("line") end`)
+		}()
+	}
+	wg.Wait()
+
+	if got := strings.Count(buf.String(), "[INFO] line\n"); got != 400 {
+		t.Errorf("got %d complete lines, want 400", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]Level{
+		"debug": LevelDebug, "INFO": LevelInfo, "": LevelInfo,
+		"warning": LevelWarn, "error": LevelError,
+	} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) should fail")
+	}
+}
+
+func TestLogNonStringArgs(t *testing.T) {
+	var buf bytes.Buffer
+	runLog(t, NewLogger(&buf, LevelDebug), `
+		log.info(42)
+		log.warn("count", 1.5, true, nil)
+		log.error(setmetatable({}, {__tostring = function() return "boom" end}))
+		log.debug({})
+		log.print("a", 2)
+		log.printf(7)
+	`)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{"[INFO] 42", "[WARN] count 1.5 true nil", "[ERROR] boom", "[DEBUG] table: ", "a 2", "7"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d: %q", len(lines), len(want), buf.String())
+	}
+	for i, w := range want {
+		if !strings.Contains(lines[i], w) {
+			t.Errorf("line %d = %q, want it to contain %q", i, lines[i], w)
+		}
+	}
+}
+
+func TestLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	runLog(t, NewLogger(&buf, LevelInfo).ForScript("clock"), `
+		log.json({event = "tick", count = 3, tags = {"a", "b"}})
+		log.json({event = "hidden"}, "debug")
+		log.json({event = "oops"}, "error")
+	`)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+
+	var entry struct {
+		Time   string                 `json:"time"`
+		Level  string                 `json:"level"`
+		Script string                 `json:"script"`
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry.Time); err != nil {
+		t.Errorf("time %q: %v", entry.Time, err)
+	}
+	if entry.Level != "info" || entry.Script != "clock" {
+		t.Errorf("level/script = %q/%q", entry.Level, entry.Script)
+	}
+	if entry.Fields["event"] != "tick" || entry.Fields["count"] != 3.0 || len(entry.Fields["tags"].([]interface{})) != 2 {
+		t.Errorf("fields = %v", entry.Fields)
+	}
+	if !strings.Contains(lines[1], `"level":"error"`) {
+		t.Errorf("second line = %q", lines[1])
+	}
+}
@@ -2,10 +2,13 @@
 package lualib
 
 import (
+	"strconv"
 	"strings"
 	"unicode"
 
 	lua "github.com/yuin/gopher-lua"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
 // RegisterStrings preloads the "strings" module into the given Lua state.
@@ -17,6 +20,8 @@ func RegisterStrings(L *lua.LState) {
 func stringsLoader(L *lua.LState) int {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
 		"split":      stringsSplit,
+		"splitn":     stringsSplitN,
+		"fields":     stringsFields,
 		"trim":       stringsTrim,
 		"startswith": stringsStartsWith,
 		"endswith":   stringsEndsWith,
@@ -26,6 +31,7 @@ func stringsLoader(L *lua.LState) int {
 		"replace":    stringsReplace,
 		"upper":      stringsUpper,
 		"lower":      stringsLower,
+		"template":   stringsTemplate,
 	})
 	L.Push(mod)
 	return 1
@@ -36,13 +42,36 @@ func stringsLoader(L *lua.LState) int {
 func stringsSplit(L *lua.LState) int {
 	str := L.CheckString(1)
 	sep := L.CheckString(2)
-	parts := strings.Split(str, sep)
-	tbl := L.NewTable()
+	L.Push(stringsArray(L, strings.Split(str, sep)))
+	return 1
+}
+
+// stringsSplitN splits str by sep into at most n parts; the last part holds
+// the unsplit remainder. n < 0 splits on every occurrence and n == 0 returns
+// an empty table.
+// Lua: strings.splitn(str, sep, n) -> table
+func stringsSplitN(L *lua.LState) int {
+	str := L.CheckString(1)
+	sep := L.CheckString(2)
+	n := L.CheckInt(3)
+	L.Push(stringsArray(L, strings.SplitN(str, sep, n)))
+	return 1
+}
+
+// stringsFields splits str around runs of whitespace, dropping empty parts.
+// Lua: strings.fields(str) -> table
+func stringsFields(L *lua.LState) int {
+	L.Push(stringsArray(L, strings.Fields(L.CheckString(1))))
+	return 1
+}
+
+// stringsArray converts parts to a Lua array table.
+func stringsArray(L *lua.LState, parts []string) *lua.LTable {
+	tbl := L.CreateTable(len(parts), 0)
 	for i, p := range parts {
 		tbl.RawSetInt(i+1, lua.LString(p))
 	}
-	L.Push(tbl)
-	return 1
+	return tbl
 }
 
 // stringsTrim removes leading and trailing whitespace.
@@ -80,10 +109,12 @@ func stringsCapitalize(L *lua.LState) int {
 	return 1
 }
 
-// stringsTitleCase title-cases a string (first letter of each word uppercased).
+// stringsTitleCase title-cases a string (first letter of each word uppercased,
+// the rest lowercased). Unicode-aware; "o'neil" becomes "O'neil".
 // Lua: strings.titlecase(str) -> str
 func stringsTitleCase(L *lua.LState) int {
-	L.Push(lua.LString(strings.Title(L.CheckString(1)))) //nolint:staticcheck
+	// A Caser keeps state between calls, so make one per call.
+	L.Push(lua.LString(cases.Title(language.Und).String(L.CheckString(1))))
 	return 1
 }
 
@@ -119,3 +150,46 @@ func stringsLower(L *lua.LState) int {
 	L.Push(lua.LString(strings.ToLower(L.CheckString(1))))
 	return 1
 }
+
+// stringsTemplate replaces each {name} in tmpl with tostring(values[name]);
+// {1}, {2}... index the table as an array. Placeholders with no value are
+// left as written so typos show up on the key. {{ and }} give literal braces.
+// Lua: strings.template(tmpl, values) -> str
+func stringsTemplate(L *lua.LState) int {
+	tmpl := L.CheckString(1)
+	values := L.CheckTable(2)
+
+	var b strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if (c == '{' || c == '}') && i+1 < len(tmpl) && tmpl[i+1] == c {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if c != '{' {
+			b.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(tmpl[i+1:], '}')
+		if end < 0 {
+			b.WriteString(tmpl[i:])
+			break
+		}
+		name := tmpl[i+1 : i+1+end]
+		var v lua.LValue = lua.LNil
+		if n, err := strconv.Atoi(name); err == nil {
+			v = values.RawGetInt(n)
+		} else if name != "" {
+			v = values.RawGetString(name)
+		}
+		if v == lua.LNil {
+			b.WriteString(tmpl[i : i+2+end])
+		} else {
+			b.WriteString(L.ToStringMeta(v).String())
+		}
+		i += 1 + end
+	}
+	L.Push(lua.LString(b.String()))
+	return 1
+}
@@ -0,0 +1,113 @@
+package lualib
+
+import (
+	"reflect"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestStringsTitleCase(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterStrings(L)
+
+	tests := []struct{ in, want string }{
+		{"hello world", "Hello World"},
+		{"the QUICK brown fox", "The Quick Brown Fox"},
+		{"o'neil's bar", "O'neil's Bar"},
+		{"éclair à la crème", "Éclair À La Crème"},
+		{"ärger über ölpreise", "Ärger Über Ölpreise"},
+		{"", ""},
+	}
+	for _, tc := range tests {
+		L.SetGlobal("input", lua.LString(tc.in))
+		if err := L.DoString(`result = require("strings").titlecase(input)`); err != nil {
+			t.Fatalf("titlecase(%q): %v", tc.in, err)
+		}
+		if got := L.GetGlobal("result").String(); got != tc.want {
+			t.Errorf("titlecase(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// luaStrings runs expr with the strings module loaded as "strings" and
+// returns the resulting array as a Go slice.
+func luaStrings(t *testing.T, expr string) []string {
+	t.Helper()
+	L := lua.NewState()
+	defer L.Close()
+	RegisterStrings(L)
+	if err := L.DoString(`local strings = require("strings"); result = ` + expr); err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+	tbl, ok := L.GetGlobal("result").(*lua.LTable)
+	if !ok {
+		t.Fatalf("%s: result is not a table", expr)
+	}
+	out := []string{}
+	for i := 1; i <= tbl.Len(); i++ {
+		out = append(out, tbl.RawGetInt(i).String())
+	}
+	return out
+}
+
+func TestStringsSplitN(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`strings.splitn("key=value=more", "=", 2)`, []string{"key", "value=more"}},
+		{`strings.splitn("a,b,c", ",", 1)`, []string{"a,b,c"}},
+		{`strings.splitn("a,b,c", ",", 5)`, []string{"a", "b", "c"}},
+		{`strings.splitn("a,b,c", ",", -1)`, []string{"a", "b", "c"}},
+		{`strings.splitn("a,b,c", ",", 0)`, []string{}},
+	}
+	for _, tc := range tests {
+		if got := luaStrings(t, tc.expr); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestStringsFields(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`strings.fields("  eth0   UP \t 192.168.1.2\n")`, []string{"eth0", "UP", "192.168.1.2"}},
+		{`strings.fields("single")`, []string{"single"}},
+		{`strings.fields(" \t\n ")`, []string{}},
+	}
+	for _, tc := range tests {
+		if got := luaStrings(t, tc.expr); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestStringsTemplate(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	RegisterStrings(L)
+
+	tests := []struct{ expr, want string }{
+		{`strings.template("{name}: {val}%", {name = "CPU", val = 42})`, "CPU: 42%"},
+		{`strings.template("{t}C", {t = 21.5})`, "21.5C"},
+		{`strings.template("{n}", {n = -3})`, "-3"},
+		{`strings.template("{on}", {on = false})`, "false"},
+		{`strings.template("{1} of {2}", {3, 10})`, "3 of 10"},
+		{`strings.template("{name} {missing}", {name = "CPU"})`, "CPU {missing}"},
+		{`strings.template("{} {", {})`, "{} {"},
+		{`strings.template("{{name}} = {name}", {name = "x"})`, "{name} = x"},
+		{`strings.template("", {a = 1})`, ""},
+	}
+	for _, tc := range tests {
+		if err := L.DoString(`local strings = require("strings"); result = ` + tc.expr); err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		if got := L.GetGlobal("result").String(); got != tc.want {
+			t.Errorf("%s = %q, want %q", tc.expr, got, tc.want)
+		}
+	}
+}
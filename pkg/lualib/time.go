@@ -7,6 +7,10 @@ import (
 	lua "github.com/yuin/gopher-lua"
 )
 
+// startTime anchors time.mono_ms(). time.Since uses the monotonic clock
+// reading, so the result is unaffected by wall-clock adjustments.
+var startTime = time.Now()
+
 // RegisterTime preloads the "time" module into the given Lua state.
 // Lua scripts access it via: local time = require("time")
 func RegisterTime(L *lua.LState) {
@@ -15,12 +19,17 @@ func RegisterTime(L *lua.LState) {
 
 func timeLoader(L *lua.LState) int {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
-		"now":       timeNow,
-		"timestamp": timeTimestamp,
-		"format":    timeFormat,
-		"parse":     timeParse,
-		"date":      timeDate,
-		"sleep":     timeSleep,
+		"now":            timeNow,
+		"now_ms":         timeNowMs,
+		"mono_ms":        timeMonoMs,
+		"timestamp":      timeTimestamp,
+		"format":         timeFormat,
+		"parse":          timeParse,
+		"parse_duration": timeParseDuration,
+		"add":            timeAdd,
+		"diff":           timeDiff,
+		"date":           timeDate,
+		"sleep":          timeSleep,
 	})
 	L.Push(mod)
 	return 1
@@ -33,6 +42,21 @@ func timeNow(L *lua.LState) int {
 	return 1
 }
 
+// timeNowMs returns current Unix timestamp in milliseconds.
+// Lua: time.now_ms() -> number
+func timeNowMs(L *lua.LState) int {
+	L.Push(lua.LNumber(time.Now().UnixMilli()))
+	return 1
+}
+
+// timeMonoMs returns milliseconds elapsed on the monotonic clock since the
+// process started. Use it for animation phases and interval measurement.
+// Lua: time.mono_ms() -> number
+func timeMonoMs(L *lua.LState) int {
+	L.Push(lua.LNumber(time.Since(startTime).Milliseconds()))
+	return 1
+}
+
 // timeTimestamp is an alias for time.now().
 // Lua: time.timestamp() -> number
 func timeTimestamp(L *lua.LState) int {
@@ -40,12 +64,29 @@ func timeTimestamp(L *lua.LState) int {
 	return 1
 }
 
+// optLocation reads an optional IANA timezone name (e.g. "UTC",
+// "America/New_York") at stack index n. Missing or empty means local time.
+func optLocation(L *lua.LState, n int) *time.Location {
+	name := L.OptString(n, "")
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		L.ArgError(n, "unknown timezone: "+name)
+		return nil
+	}
+	return loc
+}
+
 // timeFormat formats a Unix timestamp using a Go layout string.
-// Lua: time.format(timestamp, layout) -> string
+// tz is an optional IANA timezone name; defaults to local time.
+// Lua: time.format(timestamp, layout [, tz]) -> string
 func timeFormat(L *lua.LState) int {
 	ts := L.CheckNumber(1)
 	layout := L.CheckString(2)
-	L.Push(lua.LString(time.Unix(int64(ts), 0).Format(layout)))
+	loc := optLocation(L, 3)
+	L.Push(lua.LString(time.Unix(int64(ts), 0).In(loc).Format(layout)))
 	return 1
 }
 
@@ -65,11 +106,46 @@ func timeParse(L *lua.LState) int {
 	return 2
 }
 
+// timeParseDuration parses a Go duration string (e.g. "1h30m", "90s") and
+// returns its length in seconds (fractional for sub-second durations).
+// Lua: time.parse_duration(str) -> number, err
+func timeParseDuration(L *lua.LState) int {
+	d, err := time.ParseDuration(L.CheckString(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LNumber(d.Seconds()))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// timeAdd returns timestamp ts offset by the given number of seconds.
+// Lua: time.add(ts, seconds) -> number
+func timeAdd(L *lua.LState) int {
+	ts := L.CheckNumber(1)
+	seconds := L.CheckNumber(2)
+	L.Push(ts + seconds)
+	return 1
+}
+
+// timeDiff returns the number of seconds from ts2 to ts1 (ts1 - ts2).
+// Lua: time.diff(ts1, ts2) -> number
+func timeDiff(L *lua.LState) int {
+	ts1 := L.CheckNumber(1)
+	ts2 := L.CheckNumber(2)
+	L.Push(ts1 - ts2)
+	return 1
+}
+
 // timeDate returns a table with individual date/time components.
-// Lua: time.date([timestamp]) -> table{year,month,day,hour,minute,second,weekday,yearday}
+// tz is an optional IANA timezone name; defaults to local time.
+// Lua: time.date([timestamp [, tz]]) -> table{year,month,day,hour,minute,second,weekday,yearday}
 func timeDate(L *lua.LState) int {
 	ts := L.OptNumber(1, lua.LNumber(time.Now().Unix()))
-	t := time.Unix(int64(ts), 0)
+	loc := optLocation(L, 2)
+	t := time.Unix(int64(ts), 0).In(loc)
 
 	tbl := L.NewTable()
 	tbl.RawSetString("year", lua.LNumber(t.Year()))
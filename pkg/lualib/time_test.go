@@ -0,0 +1,192 @@
+package lualib
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runTime runs src with the time module loaded as "time" and returns the
+// state so the test can read the globals it set.
+func runTime(t *testing.T, src string) (*lua.LState, error) {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	RegisterTime(L)
+	return L, L.DoString(`local time = require("time")` + "\n" + src)
+}
+
+// 2023-11-14 22:13:20 UTC
+const testTimestamp = 1700000000
+
+func TestTimeFormatZones(t *testing.T) {
+	tests := []struct {
+		tz   string
+		want string
+	}{
+		{"UTC", "2023-11-14 22:13:20 UTC"},
+		{"America/New_York", "2023-11-14 17:13:20 EST"},
+		{"Asia/Kolkata", "2023-11-15 03:43:20 IST"},
+		{"", time.Unix(testTimestamp, 0).Format("2006-01-02 15:04:05 MST")},
+	}
+	for _, tt := range tests {
+		L, err := runTime(t, `result = time.format(1700000000, "2006-01-02 15:04:05 MST", "`+tt.tz+`")`)
+		if err != nil {
+			t.Fatalf("tz %q: %v", tt.tz, err)
+		}
+		if got := L.GetGlobal("result").String(); got != tt.want {
+			t.Errorf("tz %q: format = %q, want %q", tt.tz, got, tt.want)
+		}
+	}
+}
+
+func TestTimeDateZones(t *testing.T) {
+	tests := []struct {
+		tz                     string
+		year, month, day, hour int
+		weekday                int
+	}{
+		{"UTC", 2023, 11, 14, 22, 2},
+		{"America/New_York", 2023, 11, 14, 17, 2},
+		{"Pacific/Auckland", 2023, 11, 15, 11, 3},
+	}
+	for _, tt := range tests {
+		L, err := runTime(t, `d = time.date(1700000000, "`+tt.tz+`")`)
+		if err != nil {
+			t.Fatalf("tz %q: %v", tt.tz, err)
+		}
+		d := L.GetGlobal("d").(*lua.LTable)
+		for field, want := range map[string]int{
+			"year": tt.year, "month": tt.month, "day": tt.day, "hour": tt.hour,
+			"minute": 13, "second": 20, "weekday": tt.weekday,
+		} {
+			if got := d.RawGetString(field); got != lua.LNumber(want) {
+				t.Errorf("tz %q: %s = %v, want %d", tt.tz, field, got, want)
+			}
+		}
+	}
+}
+
+func TestTimeInvalidZone(t *testing.T) {
+	for _, src := range []string{
+		`time.format(1700000000, "15:04", "Mars/Olympus_Mons")`,
+		`time.date(1700000000, "Mars/Olympus_Mons")`,
+		`time.date(1700000000, "utc+5")`,
+	} {
+		if _, err := runTime(t, src); err == nil || !strings.Contains(err.Error(), "unknown timezone") {
+			t.Errorf("%s: err = %v, want unknown timezone", src, err)
+		}
+	}
+}
+
+func TestTimeParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"1h30m", 5400, false},
+		{"90s", 90, false},
+		{"1.5s", 1.5, false},
+		{"250ms", 0.25, false},
+		{"-2m", -120, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"1d", 0, true},
+		{"soon", 0, true},
+		{"10", 0, true},
+	}
+	for _, tt := range tests {
+		L, err := runTime(t, `d, err = time.parse_duration("`+tt.in+`")`)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		}
+		d, errVal := L.GetGlobal("d"), L.GetGlobal("err")
+		if tt.wantErr {
+			if d != lua.LNil || errVal == lua.LNil {
+				t.Errorf("%q: got %v, %v; want nil and an error", tt.in, d, errVal)
+			}
+			continue
+		}
+		if d != lua.LNumber(tt.want) || errVal != lua.LNil {
+			t.Errorf("%q: got %v, %v; want %v", tt.in, d, errVal, tt.want)
+		}
+	}
+}
+
+func TestTimeAddDiff(t *testing.T) {
+	L, err := runTime(t, `
+		local deadline = time.add(1700000000, time.parse_duration("1h30m"))
+		later = time.diff(deadline, 1700000000)
+		earlier = time.diff(1700000000, deadline)
+		back = time.add(deadline, -5400)
+		fraction = time.diff(time.add(10, 0.5), 10)
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]lua.LNumber{
+		"later":    5400,
+		"earlier":  -5400,
+		"back":     testTimestamp,
+		"fraction": 0.5,
+	} {
+		if got := L.GetGlobal(name); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	for _, src := range []string{`time.add("soon", 5)`, `time.diff(1, nil)`} {
+		if _, err := runTime(t, src); err == nil {
+			t.Errorf("%s: expected an argument error", src)
+		}
+	}
+}
+
+func TestTimeNowMs(t *testing.T) {
+	before := time.Now().UnixMilli()
+	L, err := runTime(t, `ms = time.now_ms(); s = time.now()`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().UnixMilli()
+	ms := int64(L.GetGlobal("ms").(lua.LNumber))
+	if ms < before || ms > after {
+		t.Errorf("now_ms = %d, want between %d and %d", ms, before, after)
+	}
+	// now_ms is in milliseconds, not seconds
+	if s := int64(L.GetGlobal("s").(lua.LNumber)); ms/1000 < s-1 || ms/1000 > s {
+		t.Errorf("now_ms = %d does not match now() = %d", ms, s)
+	}
+}
+
+func TestTimeMonoMs(t *testing.T) {
+	L, err := runTime(t, `
+		samples = {}
+		for i = 1, 5 do
+			samples[i] = time.mono_ms()
+			time.sleep(20)
+		end
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := L.GetGlobal("samples").(*lua.LTable)
+	prev := lua.LNumber(-1)
+	for i := 1; i <= samples.Len(); i++ {
+		ms := samples.RawGetInt(i).(lua.LNumber)
+		if ms < 0 || ms <= prev {
+			t.Fatalf("mono_ms not increasing: sample %d = %v after %v", i, ms, prev)
+		}
+		// 20ms sleeps should be seen as ~20ms steps, not whole seconds
+		if prev >= 0 && (ms-prev < 15 || ms-prev > 1000) {
+			t.Errorf("mono_ms step %d = %vms, want about 20ms", i, ms-prev)
+		}
+		prev = ms
+	}
+	if prev > lua.LNumber(time.Since(startTime).Milliseconds()) {
+		t.Errorf("mono_ms = %v is ahead of the process clock", prev)
+	}
+}
@@ -0,0 +1,216 @@
+package scripting
+
+// animation.go – frame animations declared by passive(). A script returns
+// { animation = "frames/*.png", fps = 10 } and the manager cycles its key
+// through the matching files, in natural order (frame2 before frame10),
+// independently of the passive FPS. The rest of the appearance (text,
+// filters, ...) is drawn on every frame. Animations run on content keys
+// while the script is visible and stop when passive() returns an appearance
+// without one.
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAnimationFPS = 10
+	maxAnimationFPS     = 30
+
+	// animationTick is how often running animations are checked for a
+	// frame change.
+	animationTick = time.Second / maxAnimationFPS
+)
+
+// animationNow is the clock used for frame timing; tests replace it.
+var animationNow = time.Now
+
+// keyAnimation is the running animation of one script.
+type keyAnimation struct {
+	pattern string
+	frames  []string
+	fps     float64
+	start   time.Time
+	shown   int           // frame last drawn, -1 = none
+	base    KeyAppearance // appearance drawn with each frame
+}
+
+// frameAt returns the frame index due at now.
+func (a *keyAnimation) frameAt(now time.Time) int {
+	n := int(now.Sub(a.start).Seconds() * a.fps)
+	return n % len(a.frames)
+}
+
+// applyAnimation starts, updates or stops scriptPath's animation to match a
+// new passive appearance, and sets appearance.Image to the current frame.
+func (m *ScriptManager) applyAnimation(scriptPath string, appearance *KeyAppearance) {
+	if appearance.Animation == "" {
+		m.animMu.Lock()
+		delete(m.animations, scriptPath)
+		m.animMu.Unlock()
+		return
+	}
+
+	fps := appearance.FPS
+	if fps <= 0 {
+		fps = defaultAnimationFPS
+	}
+	fps = min(fps, maxAnimationFPS)
+
+	m.animMu.Lock()
+	anim := m.animations[scriptPath]
+	m.animMu.Unlock()
+
+	if anim == nil || anim.pattern != appearance.Animation {
+		frames, err := animationFrames(appearance.Animation)
+		if err != nil {
+			fmt.Printf("[!] %s: animation: %v\n", filepath.Base(scriptPath), err)
+			m.animMu.Lock()
+			delete(m.animations, scriptPath)
+			m.animMu.Unlock()
+			return
+		}
+		// Preload so frame changes never wait on disk
+		for _, f := range frames {
+			if _, err := m.images.Load(f); err != nil {
+				fmt.Printf("[!] %s: animation frame: %v\n", filepath.Base(scriptPath), err)
+			}
+		}
+		anim = &keyAnimation{pattern: appearance.Animation, frames: frames, start: animationNow()}
+	}
+
+	now := animationNow()
+	m.animMu.Lock()
+	anim.fps = fps
+	anim.base = *appearance
+	anim.shown = anim.frameAt(now)
+	if m.animations == nil {
+		m.animations = make(map[string]*keyAnimation)
+	}
+	m.animations[scriptPath] = anim
+	start := !m.animRunning && m.ctx != nil
+	if start {
+		m.animRunning = true
+	}
+	appearance.Image = anim.frames[anim.shown]
+	m.animMu.Unlock()
+
+	if start {
+		go m.animationLoop()
+	}
+}
+
+// animationFrames returns the files matching pattern in natural order.
+func animationFrames(pattern string) ([]string, error) {
+	frames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames match %s", pattern)
+	}
+	sort.Slice(frames, func(i, j int) bool { return naturalLess(frames[i], frames[j]) })
+	return frames, nil
+}
+
+// naturalLess compares strings treating runs of digits as numbers, so
+// "frame2" sorts before "frame10".
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da != "" && db != "" {
+			na, _ := strconv.Atoi(da)
+			nb, _ := strconv.Atoi(db)
+			if na != nb {
+				return na < nb
+			}
+			a, b = a[len(da):], b[len(db):]
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// digitPrefix returns the leading run of ASCII digits of s.
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// animationLoop draws animation frames until no animation is left or the
+// manager shuts down.
+func (m *ScriptManager) animationLoop() {
+	ticker := time.NewTicker(animationTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			m.animMu.Lock()
+			m.animRunning = false
+			m.animMu.Unlock()
+			return
+		case <-ticker.C:
+			if !m.stepAnimations(animationNow()) {
+				return
+			}
+		}
+	}
+}
+
+// stepAnimations draws the frames that changed since the last step. Hidden
+// scripts' animations are dropped. It reports whether any animation is
+// left; when none is, the loop is marked stopped.
+func (m *ScriptManager) stepAnimations(now time.Time) bool {
+	m.mu.RLock()
+	visible := make(map[string]int, len(m.visibleScripts))
+	for k, v := range m.visibleScripts {
+		visible[k] = v
+	}
+	callback := m.onKeyUpdate
+	m.mu.RUnlock()
+
+	type frame struct {
+		path       string
+		appearance KeyAppearance
+	}
+	var due []frame
+
+	m.animMu.Lock()
+	for path, anim := range m.animations {
+		key, ok := visible[path]
+		if !ok {
+			delete(m.animations, path)
+			continue
+		}
+		idx := anim.frameAt(now)
+		if idx == anim.shown || !m.ownsKey(path, key) {
+			continue
+		}
+		anim.shown = idx
+		a := anim.base
+		a.Image = anim.frames[idx]
+		due = append(due, frame{path, a})
+	}
+	left := len(m.animations) > 0
+	if !left {
+		m.animRunning = false
+	}
+	m.animMu.Unlock()
+
+	if callback != nil {
+		for _, f := range due {
+			m.deliverUpdate(f.path, &f.appearance, callback)
+		}
+	}
+	return left
+}
@@ -0,0 +1,98 @@
+package scripting
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPassiveAnimationCyclesFrames(t *testing.T) {
+	dir := t.TempDir()
+	frameDir := filepath.Join(dir, "frames")
+	if err := os.Mkdir(frameDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"f1.png", "f2.png", "f10.png"} {
+		f, err := os.Create(filepath.Join(frameDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+		f.Close()
+	}
+
+	now := time.Unix(1000, 0)
+	animationNow = func() time.Time { return now }
+	defer func() { animationNow = time.Now }()
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetImageCache(NewImageCache(10))
+	var drawn []string
+	m.SetKeyUpdateCallback(func(keyIndex int, a *KeyAppearance) {
+		if keyIndex != 3 || a.Text != "spin" {
+			t.Errorf("frame drawn on key %d with text %q", keyIndex, a.Text)
+		}
+		drawn = append(drawn, filepath.Base(a.Image))
+	})
+	m.visibleScripts = map[string]int{"anim.lua": 3}
+
+	// The passive appearance itself carries the first frame.
+	r, err := NewScriptRunner(writeScript(t, dir, "anim.lua", `
+		return { passive = function() return { animation = "frames/*.png", fps = 10, text = "spin" } end }
+	`), nil, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	appearance, err := r.RunPassive(PassiveContext{Key: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(frameDir, "*.png"); appearance.Animation != want {
+		t.Fatalf("animation = %q, want %q", appearance.Animation, want)
+	}
+	m.batchUpdate("anim.lua", appearance)
+	if filepath.Base(appearance.Image) != "f1.png" {
+		t.Errorf("first frame = %s, want f1.png", appearance.Image)
+	}
+
+	for _, step := range []time.Duration{50, 100, 150, 200, 300} {
+		now = time.Unix(1000, 0).Add(step * time.Millisecond)
+		m.stepAnimations(now)
+	}
+	if want := []string{"f2.png", "f10.png", "f1.png"}; !reflect.DeepEqual(drawn, want) {
+		t.Errorf("frames drawn = %v, want %v", drawn, want)
+	}
+
+	// An appearance without an animation stops it.
+	m.batchUpdate("anim.lua", &KeyAppearance{Text: "still"})
+	if m.stepAnimations(now.Add(time.Second)) {
+		t.Error("animation still running after passive dropped it")
+	}
+
+	// So does the script leaving the page.
+	m.batchUpdate("anim.lua", &KeyAppearance{Animation: filepath.Join(frameDir, "*.png"), Text: "spin"})
+	m.visibleScripts = map[string]int{}
+	if m.stepAnimations(now.Add(2 * time.Second)) {
+		t.Error("animation still running for a hidden script")
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	got := []string{"f10.png", "f2.png", "f1.png", "a.png"}
+	want := []string{"a.png", "f1.png", "f2.png", "f10.png"}
+	for i := range got {
+		for j := i + 1; j < len(got); j++ {
+			if naturalLess(got[j], got[i]) {
+				got[i], got[j] = got[j], got[i]
+			}
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("natural order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package scripting
+
+// appinfo.go – app metadata reported to scripts by the nomad module.
+
+// SetVersion sets the app version reported by nomad.version().
+func (m *ScriptManager) SetVersion(version string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.version = version
+}
+
+// AppVersion implements modules.AppInfo.
+func (m *ScriptManager) AppVersion() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
+// SetCurrentPath records the folder shown on the deck, for
+// nomad.current_path(). The app calls it on every page change.
+func (m *ScriptManager) SetCurrentPath(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.currentPath = path
+}
+
+// CurrentPath implements modules.AppInfo.
+func (m *ScriptManager) CurrentPath() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.currentPath
+}
@@ -0,0 +1,46 @@
+package scripting
+
+import (
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestNomadGlobalReportsManagerState(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+	m.SetVersion("v1.2.3")
+	m.SetCurrentPath(dir + "/apps")
+
+	path := writeScript(t, dir, "meta.lua", `
+		version = nomad.version()
+		current_path = nomad.current_path()
+		config_dir = nomad.config_dir()
+		same = require("nomad") == nomad
+	`)
+	r, err := NewScriptRunner(path, nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	want := map[string]lua.LValue{
+		"version":      lua.LString("v1.2.3"),
+		"current_path": lua.LString(dir + "/apps"),
+		"config_dir":   lua.LString(dir),
+		"same":         lua.LTrue,
+	}
+	for name, v := range want {
+		if got := r.L.GetGlobal(name); got != v {
+			t.Errorf("%s = %v, want %v", name, got, v)
+		}
+	}
+
+	m.SetCurrentPath(dir)
+	if err := r.L.DoString(`current_path = nomad.current_path()`); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.L.GetGlobal("current_path"); got != lua.LString(dir) {
+		t.Errorf("after navigation current_path = %v, want %s", got, dir)
+	}
+}
@@ -0,0 +1,161 @@
+package scripting
+
+import (
+	"fmt"
+	"image/color"
+	"runtime"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// bootFrameInterval is the delay between _boot.lua frame(n) calls.
+const bootFrameInterval = 50 * time.Millisecond
+
+// Boot progress colours for the built-in progress bar.
+var (
+	bootProgressDone    = color.RGBA{0, 160, 60, 255}
+	bootProgressPending = color.RGBA{20, 20, 20, 255}
+)
+
+// bootResult is the outcome of loading one script at boot. runner is nil
+// when the script failed to load or was only scanned (deferred); fresh
+// is set when Boot created the runner and so should start its background
+// worker.
+type bootResult struct {
+	runner *ScriptRunner
+	fresh  bool
+	err    error
+}
+
+// bootScripts records and loads the scripts on a pool of at most maxLoads
+// workers, returning one result per path in the same order. Boot progress
+// is reported from the calling goroutine as each script finishes.
+func (m *ScriptManager) bootScripts(paths []string) []bootResult {
+	m.mu.RLock()
+	workers := m.maxLoads
+	m.mu.RUnlock()
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(paths))
+
+	results := make([]bootResult, len(paths))
+	jobs := make(chan int)
+	done := make(chan struct{})
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] = m.bootScript(paths[i])
+				done <- struct{}{}
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for n := 1; n <= len(paths); n++ {
+		<-done
+		m.reportBootProgress(n, len(paths))
+	}
+	return results
+}
+
+// bootScript records a script found by Boot and loads it, unless its top
+// level can wait until it is first needed (see lazy.go).
+func (m *ScriptManager) bootScript(scriptPath string) bootResult {
+	info := scanScript(scriptPath)
+	deferred := !info.eager && (m.lazy || !info.background)
+	if deferred {
+		// Catch syntax errors now without running the script
+		info.loadErr = CheckScript(scriptPath)
+	}
+	m.mu.Lock()
+	m.scripts[scriptPath] = info
+	m.mu.Unlock()
+
+	if deferred {
+		return bootResult{err: info.loadErr}
+	}
+	runner, fresh, err := m.openRunner(scriptPath)
+	return bootResult{runner: runner, fresh: fresh, err: err}
+}
+
+// reportBootProgress is called by Boot after each script is processed.
+// If _boot.lua defines progress(done, total) it is called; otherwise, unless
+// _boot.lua is animating with frame(n), a bar that fills the keys in index
+// order is drawn on the deck.
+func (m *ScriptManager) reportBootProgress(done, total int) {
+	if m.bootRunner != nil {
+		err := m.bootRunner.callModuleFunc("progress", lua.LNumber(done), lua.LNumber(total))
+		if err != nil {
+			fmt.Printf("[!] Boot progress error: %v\n", err)
+		}
+		return
+	}
+	m.renderBootProgress(done, total)
+}
+
+// renderBootProgress lights done/total of the keys. Only keys whose state
+// changed since the previous call are redrawn.
+func (m *ScriptManager) renderBootProgress(done, total int) {
+	if m.device == nil || m.device.Model.PixelSize == 0 || total == 0 {
+		return
+	}
+	keys := m.device.Model.Keys
+	lit := done * keys / total
+
+	for i := 0; i < keys; i++ {
+		wasLit := i < m.bootLit
+		isLit := i < lit
+		if m.bootDrawn && wasLit == isLit {
+			continue
+		}
+		c := bootProgressPending
+		if isLit {
+			c = bootProgressDone
+		}
+		m.device.SetKeyColor(i, c)
+	}
+	m.bootLit = lit
+	m.bootDrawn = true
+}
+
+// startBootFrames calls frame(n) on the boot script every bootFrameInterval
+// in a goroutine, so the animation keeps running while Boot loads scripts.
+// The returned function stops the animation and waits for the current frame
+// to finish. It is a no-op when the boot script does not define frame().
+func (m *ScriptManager) startBootFrames() (stop func()) {
+	runner := m.bootRunner
+	if runner == nil || runner.module.RawGetString("frame").Type() != lua.LTFunction {
+		return func() {}
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(bootFrameInterval)
+		defer ticker.Stop()
+		for n := 0; ; n++ {
+			if err := runner.callModuleFunc("frame", lua.LNumber(n)); err != nil {
+				fmt.Printf("[!] Boot frame error: %v\n", err)
+				return
+			}
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+	}
+}
@@ -0,0 +1,396 @@
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestBootModuleScript(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+	m.bootScriptPath = writeScript(t, dir, "_boot.lua", `
+		local boot = {}
+		function boot.boot()
+			state.booted = true
+		end
+		-- frame() keeps the runner open after boot() returns
+		function boot.frame(n) end
+		return boot
+	`)
+
+	m.runBootAnimation()
+
+	runner := m.bootRunner
+	if runner == nil {
+		t.Fatal("boot runner was not kept open")
+	}
+	defer runner.Close()
+	if runner.module == nil || runner.module.RawGetString("boot").Type() != lua.LTFunction {
+		t.Fatal("module table was not captured from the script's return value")
+	}
+	if got := runner.state.RawGetString("booted"); got != lua.LTrue {
+		t.Errorf("state.booted = %v, want true", got)
+	}
+}
+
+func TestBrokenScriptShowsErrorTile(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "good.lua", `
+		local script = {}
+		function script.trigger() end
+		return script
+	`)
+	broken := writeScript(t, dir, "broken.lua", `
+		local script = {}
+		function script.trigger(
+		return script
+	`)
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	m := NewScriptManager(dev, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	if m.LoadError(broken) == nil {
+		t.Fatal("LoadError is nil for a script with a syntax error")
+	}
+
+	nav := streamdeck.NewNavigator(dev, dir)
+	nav.SetScriptValidator(m.IsUsableScript)
+	nav.SetScriptError(m.LoadError)
+	if err := nav.RenderPage(); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Script != broken || page.Items[0].LoadErr == nil {
+		t.Fatalf("items = %+v, want the broken script first with its load error", page.Items)
+	}
+	if page.Items[1].LoadErr != nil {
+		t.Errorf("good.lua has load error %v", page.Items[1].LoadErr)
+	}
+	if !strings.Contains(nav.Describe(), "[error] broken | good") {
+		t.Errorf("Describe() = %q, want an error tile for broken.lua", nav.Describe())
+	}
+
+	keys := nav.GetContentKeys()
+	if bytes.Equal(dev.KeyData(keys[0]), dev.KeyData(keys[1])) || dev.KeyData(keys[0]) == nil {
+		t.Error("the broken script's key was not drawn as an error tile")
+	}
+}
+
+func TestBootDefersTopLevel(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "load.log")
+	// script writes name.lua, which logs its name from the top level.
+	// EAGER_LOAD is only matched at the start of a line, so it goes in head.
+	script := func(name, head, body string) string {
+		return writeScript(t, dir, name+".lua", fmt.Sprintf(`%s
+		local file = require("file")
+		local system = require("system")
+		file.append(%q, %q)
+		local script = {}
+		function script.trigger(state) end
+		%s
+		return script
+	`, head, logPath, name+"\n", body))
+	}
+	button := script("button", "", "")
+	script("eager", "EAGER_LOAD = true", "")
+	script("worker", "", "function script.background(state) while true do system.sleep(1000) end end")
+	boom := writeScript(t, dir, "boom.lua", "local script = { trigger = function() end }\n"+
+		"error(\"no config\")\n"+
+		"return script\n")
+
+	m := NewScriptManager(nil, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	logged := func() string {
+		data, _ := os.ReadFile(logPath)
+		return string(data)
+	}
+	got := logged()
+	if !strings.Contains(got, "eager") || !strings.Contains(got, "worker") {
+		t.Errorf("EAGER_LOAD and background scripts did not run at boot:\n%s", got)
+	}
+	if strings.Contains(got, "button") {
+		t.Errorf("button script's top level ran at boot:\n%s", got)
+	}
+	if loaded(m, button) != nil || !m.IsUsableScript(button) {
+		t.Error("deferred button script is loaded or not usable")
+	}
+	if m.LoadError(boom) != nil {
+		t.Errorf("top level of boom.lua ran at boot: %v", m.LoadError(boom))
+	}
+
+	// The top level runs on first trigger or when shown
+	if err := m.TriggerScript(button, 0, EventTap); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logged(), "button") {
+		t.Error("button script's top level did not run on trigger")
+	}
+	m.SetVisibleScripts(map[string]int{boom: 0})
+	var se *ScriptError
+	if !errors.As(m.LoadError(boom), &se) || se.Kind != RuntimeError || se.Line != 2 {
+		t.Errorf("LoadError(boom.lua) = %v, want a runtime error on line 2", m.LoadError(boom))
+	}
+}
+
+func TestBootLoadsScriptsInParallel(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < 20; i++ {
+		src := `
+			local script = {}
+			function script.trigger() end
+			return script
+		`
+		if i%4 == 0 {
+			src = `
+				local system = require("system")
+				local script = {}
+				function script.background(state)
+					while true do system.sleep(1000) end
+				end
+				return script
+			`
+		}
+		paths = append(paths, writeScript(t, dir, fmt.Sprintf("s%02d.lua", i), src))
+	}
+	broken := writeScript(t, dir, "broken.lua", `return {`)
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetMaxConcurrentLoads(3)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	for i, path := range paths {
+		r := m.GetRunner(path)
+		if r == nil {
+			t.Fatalf("%s was not loaded", filepath.Base(path))
+		}
+		if i%4 != 0 {
+			continue
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for !r.Status().BackgroundRunning {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: background worker not started", r.ScriptName)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	if m.GetRunner(broken) != nil || m.LoadError(broken) == nil {
+		t.Error("broken.lua loaded without an error")
+	}
+}
+
+// BenchmarkBoot compares loading scripts that do some work at load time one
+// at a time against loading them on a worker per CPU. EAGER_LOAD makes Boot
+// run their top level instead of deferring it.
+func BenchmarkBoot(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 32; i++ {
+		src := `EAGER_LOAD = true
+			local n = 0
+			for i = 1, 200000 do n = n + i end
+			local script = {}
+			function script.trigger() end
+			return script
+		`
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("s%02d.lua", i)), []byte(src), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, bc := range []struct {
+		name  string
+		loads int
+	}{
+		{"serial", 1},
+		{"parallel", 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				m := NewScriptManager(nil, dir, 0)
+				m.SetMaxConcurrentLoads(bc.loads)
+				if err := m.Boot(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+				m.Shutdown()
+			}
+		})
+	}
+}
+
+func TestBootFramesWhileScriptsLoad(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "boot.log") // file.append is confined to the config dir
+	writeScript(t, dir, "_boot.lua", fmt.Sprintf(`
+		local file = require("file")
+		local boot = {}
+		function boot.frame(n) file.append(%[1]q, "frame " .. n .. "\n") end
+		function boot.finish() file.append(%[1]q, "finish\n") end
+		return boot
+	`, logPath))
+	writeScript(t, dir, "slow.lua", fmt.Sprintf(`EAGER_LOAD = true
+		local file = require("file")
+		file.append(%[1]q, "load start\n")
+		local start = os.clock()
+		while os.clock() - start < 0.3 do end
+		file.append(%[1]q, "load end\n")
+		local script = {}
+		function script.trigger() end
+		return script
+	`, logPath))
+
+	m := NewScriptManager(nil, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		switch line {
+		case "load start":
+			start = i
+		case "load end":
+			end = i
+		}
+	}
+	if start < 0 || end < 0 {
+		t.Fatalf("slow script did not load:\n%s", data)
+	}
+	if frames := end - start - 1; frames < 2 {
+		t.Errorf("%d frames drawn while the slow script loaded, want several:\n%s", frames, data)
+	}
+	if lines[len(lines)-1] != "finish" {
+		t.Errorf("finish() was not the last call:\n%s", data)
+	}
+	if strings.Count(string(data), "finish") != 1 {
+		t.Errorf("finish() called more than once:\n%s", data)
+	}
+}
+
+func TestBootProgressBar(t *testing.T) {
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	m := NewScriptManager(dev, t.TempDir(), 0)
+	keys := dev.Model.Keys
+
+	ref := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	ref.SetKeyColor(0, bootProgressDone)
+	ref.SetKeyColor(1, bootProgressPending)
+	done, pending := ref.KeyData(0), ref.KeyData(1)
+
+	// Every write stores a newly encoded slice, so an unchanged backing
+	// array means the key was not redrawn.
+	var prev [][]byte
+	step := func(n, total, wantLit int, wantRedrawn []int) {
+		t.Helper()
+		m.reportBootProgress(n, total)
+		redrawn := map[int]bool{}
+		for i := 0; i < keys; i++ {
+			data := dev.KeyData(i)
+			want := pending
+			if i < wantLit {
+				want = done
+			}
+			if !bytes.Equal(data, want) {
+				t.Errorf("progress %d/%d: key %d lit = %v, want %v", n, total, i, bytes.Equal(data, done), i < wantLit)
+			}
+			if prev == nil || &prev[i][0] != &data[0] {
+				redrawn[i] = true
+			}
+		}
+		for _, i := range wantRedrawn {
+			if !redrawn[i] {
+				t.Errorf("progress %d/%d: key %d was not redrawn", n, total, i)
+			}
+			delete(redrawn, i)
+		}
+		for i := range redrawn {
+			t.Errorf("progress %d/%d: unchanged key %d was redrawn", n, total, i)
+		}
+		prev = make([][]byte, keys)
+		for i := range prev {
+			prev[i] = dev.KeyData(i)
+		}
+	}
+	span := func(from, to int) []int {
+		var s []int
+		for i := from; i < to; i++ {
+			s = append(s, i)
+		}
+		return s
+	}
+
+	step(0, 5, 0, span(0, keys)) // first call draws every key
+	step(1, 5, 3, span(0, 3))
+	step(1, 5, 3, nil)
+	step(2, 5, 6, span(3, 6))
+	step(5, 5, keys, span(6, keys))
+}
+
+func TestBootProgressCallback(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "progress.log")
+	writeScript(t, dir, "_boot.lua", fmt.Sprintf(`
+		local file = require("file")
+		local boot = {}
+		function boot.progress(done, total) file.append(%q, done .. "/" .. total .. "\n") end
+		return boot
+	`, logPath))
+	for _, name := range []string{"a.lua", "b.lua", "c.lua"} {
+		writeScript(t, dir, name, `return { trigger = function() end }`)
+	}
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	m := NewScriptManager(dev, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "1/3\n2/3\n3/3\n" {
+		t.Errorf("progress calls = %q, want one per script", got)
+	}
+	// progress() replaces the built-in bar; Boot only clears the keys
+	ref := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	ref.Clear()
+	for i := 0; i < dev.Model.Keys; i++ {
+		if !bytes.Equal(dev.KeyData(i), ref.KeyData(i)) {
+			t.Fatalf("key %d was drawn although _boot.lua handles progress", i)
+		}
+	}
+}
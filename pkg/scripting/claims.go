@@ -0,0 +1,133 @@
+package scripting
+
+// claims.go – key claims. A script can claim keys it is not shown on (e.g. a
+// "now playing" script drawing several keys from background). Claimed keys
+// are skipped by page rendering and by other scripts' passive updates, and
+// presses on them go to the claiming script's trigger.
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetClaimableKeys limits which keys scripts may claim, typically the
+// navigator's content keys. nil allows every key. Call before Boot.
+func (m *ScriptManager) SetClaimableKeys(keys []int) {
+	m.claimsMu.Lock()
+	defer m.claimsMu.Unlock()
+	if keys == nil {
+		m.claimable = nil
+		return
+	}
+	m.claimable = make(map[int]bool, len(keys))
+	for _, k := range keys {
+		m.claimable[k] = true
+	}
+}
+
+// SetKeyReleasedCallback sets the function called after a claimed key is
+// released so the app can draw its default content again.
+func (m *ScriptManager) SetKeyReleasedCallback(cb func(keyIndex int)) {
+	m.claimsMu.Lock()
+	defer m.claimsMu.Unlock()
+	m.onKeyReleased = cb
+}
+
+// ClaimKey gives scriptPath ownership of a key. Claiming a key the script
+// already holds is a no-op; a key held by another script is an error.
+func (m *ScriptManager) ClaimKey(scriptPath string, keyIndex int) error {
+	if m.device != nil && (keyIndex < 0 || keyIndex >= m.device.Model.Keys) {
+		return fmt.Errorf("key %d out of range", keyIndex)
+	}
+
+	m.claimsMu.Lock()
+	defer m.claimsMu.Unlock()
+	if m.claimable != nil && !m.claimable[keyIndex] {
+		return fmt.Errorf("key %d is reserved", keyIndex)
+	}
+	if owner, ok := m.claims[keyIndex]; ok && owner != scriptPath {
+		return fmt.Errorf("key %d is claimed by %s", keyIndex, owner)
+	}
+	if m.claims == nil {
+		m.claims = make(map[int]string)
+	}
+	m.claims[keyIndex] = scriptPath
+	return nil
+}
+
+// ReleaseKey gives up scriptPath's claim on a key. It reports whether the
+// script held the key.
+func (m *ScriptManager) ReleaseKey(scriptPath string, keyIndex int) bool {
+	m.claimsMu.Lock()
+	if m.claims[keyIndex] != scriptPath {
+		m.claimsMu.Unlock()
+		return false
+	}
+	delete(m.claims, keyIndex)
+	cb := m.onKeyReleased
+	m.claimsMu.Unlock()
+
+	if cb != nil {
+		cb(keyIndex)
+	}
+	return true
+}
+
+// releaseClaims drops every claim held by scriptPath. Runners call it when
+// they close.
+func (m *ScriptManager) releaseClaims(scriptPath string) {
+	m.claimsMu.Lock()
+	var released []int
+	for key, owner := range m.claims {
+		if owner == scriptPath {
+			delete(m.claims, key)
+			released = append(released, key)
+		}
+	}
+	cb := m.onKeyReleased
+	m.claimsMu.Unlock()
+
+	if cb == nil {
+		return
+	}
+	sort.Ints(released)
+	for _, key := range released {
+		cb(key)
+	}
+}
+
+// KeyClaimant returns the script that has claimed a key, or "".
+func (m *ScriptManager) KeyClaimant(keyIndex int) string {
+	m.claimsMu.RLock()
+	defer m.claimsMu.RUnlock()
+	return m.claims[keyIndex]
+}
+
+// ownsKey reports whether scriptPath may draw on a key: the key is either
+// unclaimed or claimed by scriptPath itself.
+func (m *ScriptManager) ownsKey(scriptPath string, keyIndex int) bool {
+	owner := m.KeyClaimant(keyIndex)
+	return owner == "" || owner == scriptPath
+}
+
+// hasClaims reports whether scriptPath holds any claim.
+func (m *ScriptManager) hasClaims(scriptPath string) bool {
+	m.claimsMu.RLock()
+	defer m.claimsMu.RUnlock()
+	for _, owner := range m.claims {
+		if owner == scriptPath {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptClaims adapts the manager's claims to one script for the
+// streamdeck module (modules.KeyClaimer).
+type scriptClaims struct {
+	m    *ScriptManager
+	path string
+}
+
+func (c scriptClaims) ClaimKey(keyIndex int) error  { return c.m.ClaimKey(c.path, keyIndex) }
+func (c scriptClaims) ReleaseKey(keyIndex int) bool { return c.m.ReleaseKey(c.path, keyIndex) }
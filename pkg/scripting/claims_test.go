@@ -0,0 +1,100 @@
+package scripting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClaimPrecedence(t *testing.T) {
+	m := NewScriptManager(nil, t.TempDir(), 0)
+
+	if err := m.ClaimKey("a.lua", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ClaimKey("a.lua", 3); err != nil {
+		t.Errorf("re-claiming own key: %v", err)
+	}
+	if err := m.ClaimKey("b.lua", 3); err == nil {
+		t.Error("b.lua claimed a key held by a.lua")
+	}
+	if m.ReleaseKey("b.lua", 3) {
+		t.Error("b.lua released a key it does not hold")
+	}
+	if got := m.KeyClaimant(3); got != "a.lua" {
+		t.Errorf("claimant = %q, want a.lua", got)
+	}
+
+	if !m.ReleaseKey("a.lua", 3) {
+		t.Fatal("a.lua could not release its key")
+	}
+	if err := m.ClaimKey("b.lua", 3); err != nil {
+		t.Errorf("claim after release: %v", err)
+	}
+}
+
+func TestClaimableKeys(t *testing.T) {
+	m := NewScriptManager(nil, t.TempDir(), 0)
+	m.SetClaimableKeys([]int{1, 2})
+
+	if err := m.ClaimKey("a.lua", 0); err == nil {
+		t.Error("claimed a reserved key")
+	}
+	if err := m.ClaimKey("a.lua", 2); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestClaimedKeySkipsPassiveUpdates(t *testing.T) {
+	m := NewScriptManager(nil, t.TempDir(), 0)
+
+	var drawn []int
+	m.SetKeyUpdateCallback(func(keyIndex int, _ *KeyAppearance) {
+		drawn = append(drawn, keyIndex)
+	})
+	m.visibleScripts = map[string]int{"page.lua": 4, "other.lua": 5}
+
+	if err := m.ClaimKey("player.lua", 4); err != nil {
+		t.Fatal(err)
+	}
+	m.batchUpdate("page.lua", &KeyAppearance{Text: "page"})
+	m.batchUpdate("other.lua", &KeyAppearance{Text: "other"})
+	m.processBatchedUpdates(10)
+
+	if !reflect.DeepEqual(drawn, []int{5}) {
+		t.Errorf("drawn keys = %v, want [5]", drawn)
+	}
+	if len(m.passiveBatch) != 0 {
+		t.Errorf("dropped update was re-queued: %v", m.passiveBatch)
+	}
+}
+
+func TestClaimsReleasedOnClose(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+
+	var released []int
+	m.SetKeyReleasedCallback(func(keyIndex int) { released = append(released, keyIndex) })
+
+	r, err := NewScriptRunner(writeScript(t, dir, "player.lua", `
+		local deck = require("streamdeck")
+		assert(deck.claim_key(2))
+		assert(deck.claim_key(7))
+		assert(deck.release_key(2))
+		assert(not deck.release_key(9))
+		return {}
+	`), nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.KeyClaimant(7); got != r.ScriptPath {
+		t.Errorf("claimant of 7 = %q", got)
+	}
+
+	r.Close()
+	if m.KeyClaimant(7) != "" {
+		t.Error("claim survived Close")
+	}
+	if !reflect.DeepEqual(released, []int{2, 7}) {
+		t.Errorf("released = %v, want [2 7]", released)
+	}
+}
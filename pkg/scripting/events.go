@@ -0,0 +1,44 @@
+package scripting
+
+// events.go – the in-process event bus behind the Lua events module. Every
+// runner the manager creates publishes and subscribes through it, so
+// scripts can signal each other (e.g. a mute key and a mute indicator).
+
+// eventSubs holds the subscribers of one topic, keyed by subscription id.
+type eventSubs map[int]func(data interface{})
+
+// Publish delivers data to every subscriber of topic. It implements
+// modules.EventBus.
+func (m *ScriptManager) Publish(topic string, data interface{}) {
+	m.eventsMu.RLock()
+	defer m.eventsMu.RUnlock()
+	for _, deliver := range m.eventSubs[topic] {
+		deliver(data)
+	}
+}
+
+// Subscribe registers deliver for topic and returns a function that removes
+// it. It implements modules.EventBus.
+func (m *ScriptManager) Subscribe(topic string, deliver func(data interface{})) func() {
+	m.eventsMu.Lock()
+	defer m.eventsMu.Unlock()
+	if m.eventSubs == nil {
+		m.eventSubs = make(map[string]eventSubs)
+	}
+	if m.eventSubs[topic] == nil {
+		m.eventSubs[topic] = make(eventSubs)
+	}
+	m.nextEventSub++
+	id := m.nextEventSub
+	m.eventSubs[topic][id] = deliver
+
+	return func() {
+		// Holding the write lock waits out any Publish still delivering.
+		m.eventsMu.Lock()
+		defer m.eventsMu.Unlock()
+		delete(m.eventSubs[topic], id)
+		if len(m.eventSubs[topic]) == 0 {
+			delete(m.eventSubs, topic)
+		}
+	}
+}
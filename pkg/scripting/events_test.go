@@ -0,0 +1,100 @@
+package scripting
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func writeScript(t *testing.T, dir, name, src string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEventsBetweenScripts(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+
+	indicator, err := NewScriptRunner(writeScript(t, dir, "indicator.lua", `
+		local events = require("events")
+		events.subscribe("mute", function(data, topic)
+			state.muted = data.muted
+			state.topic = topic
+			state.count = (state.count or 0) + 1
+		end)
+		return {}
+	`), nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indicator.Close()
+
+	button, err := NewScriptRunner(writeScript(t, dir, "button.lua", `
+		local events = require("events")
+		return {
+			trigger = function()
+				events.publish("mute", {muted = true})
+				events.publish("other", {muted = false})
+			end,
+		}
+	`), nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer button.Close()
+
+	if err := button.callModuleFunc("trigger"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		indicator.luaMu.Lock()
+		muted := indicator.state.RawGetString("muted")
+		topic := indicator.state.RawGetString("topic")
+		count := indicator.state.RawGetString("count")
+		indicator.luaMu.Unlock()
+
+		if muted == lua.LTrue {
+			if topic.String() != "mute" || count.String() != "1" {
+				t.Errorf("topic = %v, count = %v", topic, count)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("indicator never received the mute event")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventsUnsubscribeOnClose(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+
+	r, err := NewScriptRunner(writeScript(t, dir, "sub.lua", `
+		local events = require("events")
+		local stop = events.subscribe("a", function() end)
+		events.subscribe("b", function() end)
+		stop()
+		return {}
+	`), nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.eventSubs); got != 1 {
+		t.Errorf("after unsubscribe: %d topics, want 1", got)
+	}
+	r.Close()
+	if got := len(m.eventSubs); got != 0 {
+		t.Errorf("after Close: %d topics, want 0", got)
+	}
+	m.Publish("b", nil) // must not panic on the closed queue
+}
@@ -1,6 +1,10 @@
 package scripting
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"image"
 	"image/gif"
@@ -10,47 +14,106 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
 // ImageCache caches loaded images to avoid repeated disk/network reads.
+// It is bounded both by estimated memory (maxSize, in MB) and entry count.
 type ImageCache struct {
-	mu      sync.RWMutex
-	images  map[string]cacheEntry
-	maxSize int
+	mu         sync.RWMutex
+	images     map[string]cacheEntry
+	maxSize    int
+	maxEntries int
+	svgSize    int              // see SetSVGSize; 0 = DefaultSVGSize
+	now        func() time.Time // clock for access times and expiry
+
+	// Background fetches started by LoadNonBlocking
+	fetchMu  sync.Mutex
+	fetching map[string]bool
+	failed   map[string]fetchFailure
+}
+
+// fetchFailure remembers a failed background fetch so it is not retried
+// before minRemoteImageTTL has passed.
+type fetchFailure struct {
+	err error
+	at  time.Time
 }
 
+// DefaultImageCacheEntries is the entry bound used by NewImageCache.
+const DefaultImageCacheEntries = 500
+
 type cacheEntry struct {
 	image    image.Image
 	accessed time.Time
-	size     int // rough memory size estimate
+	expires  time.Time // zero = never expires
+	size     int       // rough memory size estimate
 }
 
-// NewImageCache creates a new image cache.
+// Remote image freshness. URL fetches without caching headers are kept for
+// DefaultRemoteImageTTL; no response is cached for less than
+// minRemoteImageTTL so passive scripts cannot hammer an endpoint every tick.
+const (
+	DefaultRemoteImageTTL = 5 * time.Minute
+	minRemoteImageTTL     = 1 * time.Second
+)
+
+// NewImageCache creates a new image cache bounded to maxSize MB and
+// DefaultImageCacheEntries entries.
 func NewImageCache(maxSize int) *ImageCache {
+	return NewImageCacheWithLimits(maxSize, DefaultImageCacheEntries)
+}
+
+// NewImageCacheWithLimits creates a new image cache bounded to maxSize MB and
+// maxEntries entries. Non-positive maxEntries uses DefaultImageCacheEntries.
+func NewImageCacheWithLimits(maxSize, maxEntries int) *ImageCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultImageCacheEntries
+	}
 	return &ImageCache{
-		images:  make(map[string]cacheEntry),
-		maxSize: maxSize,
+		images:     make(map[string]cacheEntry),
+		maxSize:    maxSize,
+		maxEntries: maxEntries,
+		now:        time.Now,
 	}
 }
 
-// Get retrieves an image from cache.
-func (c *ImageCache) Get(key string) (image.Image, bool) {
+// Len returns the number of cached images.
+func (c *ImageCache) Len() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return len(c.images)
+}
+
+// Get retrieves an image from cache. Expired entries are dropped and reported
+// as a miss.
+func (c *ImageCache) Get(key string) (image.Image, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	entry, ok := c.images[key]
-	if ok {
-		entry.accessed = time.Now()
-		c.images[key] = entry
-		return entry.image, true
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	now := c.now()
+	if !entry.expires.IsZero() && now.After(entry.expires) {
+		delete(c.images, key)
+		return nil, false
+	}
+	entry.accessed = now
+	c.images[key] = entry
+	return entry.image, true
 }
 
-// Set stores an image in cache with LRU eviction.
+// Set stores an image in cache with LRU eviction. The entry never expires.
 func (c *ImageCache) Set(key string, img image.Image) {
+	c.SetWithTTL(key, img, 0)
+}
+
+// SetWithTTL stores an image that expires after ttl (0 = never).
+func (c *ImageCache) SetWithTTL(key string, img image.Image, ttl time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -60,9 +123,15 @@ func (c *ImageCache) Set(key string, img image.Image) {
 
 	entry := cacheEntry{
 		image:    img,
-		accessed: time.Now(),
+		accessed: c.now(),
 		size:     size,
 	}
+	if ttl > 0 {
+		entry.expires = entry.accessed.Add(ttl)
+	}
+
+	// Replacing an entry should not count it against the limits
+	delete(c.images, key)
 
 	// Check if we need to evict
 	totalSize := 0
@@ -70,8 +139,8 @@ func (c *ImageCache) Set(key string, img image.Image) {
 		totalSize += e.size
 	}
 
-	// If adding this image would exceed cache size, evict oldest
-	for totalSize+size > c.maxSize*1024*1024 { // maxSize is in MB
+	// If adding this image would exceed cache size or entry count, evict oldest
+	for totalSize+size > c.maxSize*1024*1024 || len(c.images) >= c.maxEntries { // maxSize is in MB
 		if len(c.images) == 0 {
 			break
 		}
@@ -87,8 +156,8 @@ func (c *ImageCache) Set(key string, img image.Image) {
 		}
 
 		if oldestKey != "" {
-			delete(c.images, oldestKey)
 			totalSize -= c.images[oldestKey].size
+			delete(c.images, oldestKey)
 		}
 	}
 
@@ -102,22 +171,108 @@ func (c *ImageCache) Clear() {
 	c.images = make(map[string]cacheEntry)
 }
 
-// Global image cache
-var globalImageCache = NewImageCache(100)
+// IsRemoteImage reports whether an appearance image path is an http(s) URL.
+func IsRemoteImage(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// Cached reports whether path can be loaded without touching the network.
+// Local files and data URIs always can.
+func (c *ImageCache) Cached(path string) bool {
+	if !IsRemoteImage(path) {
+		return true
+	}
+	_, ok := c.Get(c.cacheKey(path))
+	return ok
+}
+
+// LoadNonBlocking is like Load but never waits on the network: a remote
+// image that is not cached is fetched in the background and reported as
+// pending. Call again later (e.g. on the next passive tick) to get it. A
+// failed fetch is returned as the error until it is retried, at most once
+// per minRemoteImageTTL.
+func (c *ImageCache) LoadNonBlocking(path string) (img image.Image, pending bool, err error) {
+	if !IsRemoteImage(path) {
+		img, err = c.Load(path)
+		return img, false, err
+	}
+	if img, ok := c.Get(c.cacheKey(path)); ok {
+		return img, false, nil
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	if c.fetching[path] {
+		return nil, true, nil
+	}
+	if f, ok := c.failed[path]; ok && c.now().Sub(f.at) < minRemoteImageTTL {
+		return nil, false, f.err
+	}
+	if c.fetching == nil {
+		c.fetching = make(map[string]bool)
+		c.failed = make(map[string]fetchFailure)
+	}
+	c.fetching[path] = true
+	delete(c.failed, path)
+
+	go func() {
+		_, err := c.Load(path)
+		c.fetchMu.Lock()
+		defer c.fetchMu.Unlock()
+		delete(c.fetching, path)
+		if err != nil {
+			c.failed[path] = fetchFailure{err: err, at: c.now()}
+		}
+	}()
+	return nil, true, nil
+}
+
+// defaultImageCacheMB bounds the package-level cache and a ScriptManager's
+// cache until SetImageCache replaces it.
+const defaultImageCacheMB = 100
+
+// globalImageCache backs the package-level LoadImage helpers. Long-lived
+// owners such as ScriptManager use their own cache instead.
+var globalImageCache = NewImageCache(defaultImageCacheMB)
 
-// LoadImage loads an image from a file path or URL.
-// Supports PNG, JPEG, and GIF formats.
-// Uses caching for repeated loads.
+// LoadImage loads an image from a file path, URL or base64 data URI
+// ("data:image/png;base64,...") using the package-level default cache.
+// Supports PNG, JPEG, GIF and SVG (rasterized, see SetSVGSize).
+// Files are cached until evicted; URLs are refreshed according to their
+// Cache-Control / Expires headers.
 func LoadImage(path string) (image.Image, error) {
+	return globalImageCache.Load(path)
+}
+
+// LoadImageWithTTL is like LoadImage but caches the result for ttl, overriding
+// any HTTP caching headers. A ttl of 0 keeps the LoadImage defaults.
+func LoadImageWithTTL(path string, ttl time.Duration) (image.Image, error) {
+	return globalImageCache.LoadWithTTL(path, ttl)
+}
+
+// Load loads an image from a file path, URL or data URI, caching the result
+// in c. See LoadImage.
+func (c *ImageCache) Load(path string) (image.Image, error) {
+	return c.LoadWithTTL(path, 0)
+}
+
+// LoadWithTTL is like Load but caches the result for ttl, overriding any HTTP
+// caching headers. A ttl of 0 keeps the Load defaults.
+func (c *ImageCache) LoadWithTTL(path string, ttl time.Duration) (image.Image, error) {
+	if strings.HasPrefix(path, "data:") {
+		return c.loadDataURI(path)
+	}
+
 	// Check cache first
-	if img, ok := globalImageCache.Get(path); ok {
+	key := c.cacheKey(path)
+	if img, ok := c.Get(key); ok {
 		return img, nil
 	}
 
 	var reader io.ReadCloser
 	var err error
 
-	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+	if IsRemoteImage(path) {
 		// Fetch from URL
 		client := &http.Client{Timeout: 10 * time.Second}
 		resp, err := client.Get(path)
@@ -129,6 +284,9 @@ func LoadImage(path string) (image.Image, error) {
 			return nil, fmt.Errorf("HTTP %d fetching image", resp.StatusCode)
 		}
 		reader = resp.Body
+		if ttl == 0 {
+			ttl = remoteImageTTL(resp.Header, c.now())
+		}
 	} else {
 		// Load from file
 		reader, err = os.Open(path)
@@ -142,12 +300,14 @@ func LoadImage(path string) (image.Image, error) {
 	ext := strings.ToLower(filepath.Ext(path))
 	var img image.Image
 
-	switch ext {
-	case ".png":
+	switch {
+	case isSVG(path):
+		img, err = rasterizeSVG(reader, c.svgPixels())
+	case ext == ".png":
 		img, err = png.Decode(reader)
-	case ".jpg", ".jpeg":
+	case ext == ".jpg" || ext == ".jpeg":
 		img, err = jpeg.Decode(reader)
-	case ".gif":
+	case ext == ".gif":
 		img, err = gif.Decode(reader)
 	default:
 		// Try to decode as any supported format
@@ -159,8 +319,80 @@ func LoadImage(path string) (image.Image, error) {
 	}
 
 	// Cache it
-	globalImageCache.Set(path, img)
+	c.SetWithTTL(key, img, ttl)
+
+	return img, nil
+}
+
+// remoteImageTTL derives a cache lifetime from HTTP response headers.
+// Cache-Control max-age wins over Expires; no-store / no-cache and past
+// expiry dates fall back to minRemoteImageTTL.
+func remoteImageTTL(h http.Header, now time.Time) time.Duration {
+	clamp := func(d time.Duration) time.Duration {
+		if d < minRemoteImageTTL {
+			return minRemoteImageTTL
+		}
+		return d
+	}
+
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return minRemoteImageTTL
+		case strings.HasPrefix(directive, "max-age="):
+			if secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				return clamp(time.Duration(secs) * time.Second)
+			}
+		}
+	}
+
+	if exp := h.Get("Expires"); exp != "" {
+		if t, err := http.ParseTime(exp); err == nil {
+			return clamp(t.Sub(now))
+		}
+		return minRemoteImageTTL // invalid Expires means already expired
+	}
+
+	return DefaultRemoteImageTTL
+}
+
+// loadDataURI decodes a base64 data URI. Results are cached under a hash of
+// the URI so passive scripts returning the same data every tick stay cheap.
+func (c *ImageCache) loadDataURI(uri string) (image.Image, error) {
+	sum := sha256.Sum256([]byte(uri))
+	key := "data:" + hex.EncodeToString(sum[:])
+	svg := strings.HasPrefix(uri, "data:image/svg+xml")
+	if svg {
+		key += "#" + strconv.Itoa(c.svgPixels())
+	}
+	if img, ok := c.Get(key); ok {
+		return img, nil
+	}
+
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(uri, "data:"), ",")
+	if !ok {
+		return nil, fmt.Errorf("malformed data URI")
+	}
+	if !strings.HasSuffix(meta, ";base64") {
+		return nil, fmt.Errorf("data URI must be base64 encoded")
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode data URI: %w", err)
+	}
+
+	var img image.Image
+	if svg {
+		img, err = rasterizeSVG(bytes.NewReader(data), c.svgPixels())
+	} else {
+		img, _, err = image.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
 
+	c.Set(key, img)
 	return img, nil
 }
 
@@ -0,0 +1,375 @@
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoadNonBlockingRemoteImage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		<-release
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	m := NewScriptManager(nil, t.TempDir(), 0)
+	m.SetImageCache(NewImageCache(10))
+	url := srv.URL + "/icon.png"
+	m.batchUpdate("weather.lua", &KeyAppearance{Image: url})
+
+	// Until the server answers, callers get "pending" straight away
+	for i := 0; i < 3; i++ {
+		img, pending, err := m.LoadImageNonBlocking(url)
+		if img != nil || !pending || err != nil {
+			t.Fatalf("before response: img=%v pending=%v err=%v", img, pending, err)
+		}
+	}
+	if !m.ImagePending("weather.lua") {
+		t.Error("ImagePending = false while the image is loading")
+	}
+
+	close(release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		img, pending, err := m.LoadImageNonBlocking(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pending {
+			if img == nil {
+				t.Fatal("resolved without an image")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("image never resolved")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.ImagePending("weather.lua") {
+		t.Error("ImagePending = true after the image loaded")
+	}
+	if requests != 1 {
+		t.Errorf("server saw %d requests, want 1", requests)
+	}
+}
+
+// pngDataURI encodes a w x h image of colour c as a base64 PNG data URI.
+func pngDataURI(t *testing.T, w, h int, c color.Color) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestLoadDataURI(t *testing.T) {
+	c := NewImageCache(10)
+	uri := pngDataURI(t, 3, 2, color.RGBA{R: 255, A: 255})
+
+	img, err := c.Load(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+		t.Errorf("decoded size = %v, want 3x2", img.Bounds())
+	}
+	if r, g, b, _ := img.At(1, 1).RGBA(); r>>8 != 255 || g != 0 || b != 0 {
+		t.Errorf("pixel = %d,%d,%d, want red", r>>8, g>>8, b>>8)
+	}
+	if c.Len() != 1 {
+		t.Errorf("cache holds %d entries, want 1", c.Len())
+	}
+	if again, err := c.Load(uri); err != nil || again != img {
+		t.Errorf("second load = %v, %v; want the cached image", again, err)
+	}
+	if !c.Cached(uri) {
+		t.Error("data URIs should always count as cached")
+	}
+
+	for _, bad := range []string{
+		"data:image/png;base64",
+		"data:image/png,iVBORw0KGgo=",
+		"data:image/png;base64,not base64!",
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("not a png")),
+	} {
+		if _, err := c.Load(bad); err == nil {
+			t.Errorf("Load(%.40q) succeeded, want an error", bad)
+		}
+	}
+}
+
+// fakeClock is a settable clock for ImageCache.now.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestRemoteImageExpires(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		n := requests
+		mu.Unlock()
+		// Each response is one pixel wider so a reload is visible
+		var buf bytes.Buffer
+		png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, n, 1)))
+		if r.URL.Path == "/max-age.png" {
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		ttl  time.Duration // passed to LoadWithTTL
+		want time.Duration // how long the image stays cached
+	}{
+		{"/max-age.png", 0, 60 * time.Second},
+		{"/plain.png", 0, DefaultRemoteImageTTL},
+		{"/max-age.png", 10 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		clock := &fakeClock{t: time.Now()}
+		c := NewImageCache(10)
+		c.now = clock.now
+		url := srv.URL + tt.path
+		load := func() int {
+			t.Helper()
+			img, err := c.LoadWithTTL(url, tt.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return img.Bounds().Dx()
+		}
+
+		first := load()
+		clock.advance(tt.want - time.Second)
+		if got := load(); got != first {
+			t.Errorf("%s ttl %v: reloaded after %v, want cached for %v", tt.path, tt.ttl, tt.want-time.Second, tt.want)
+		}
+		clock.advance(2 * time.Second)
+		if got := load(); got == first {
+			t.Errorf("%s ttl %v: still cached after %v", tt.path, tt.ttl, tt.want+time.Second)
+		}
+	}
+}
+
+func TestRemoteImageTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header map[string]string
+		want   time.Duration
+	}{
+		{nil, DefaultRemoteImageTTL},
+		{map[string]string{"Cache-Control": "max-age=120"}, 2 * time.Minute},
+		{map[string]string{"Cache-Control": "public, MAX-AGE=30"}, 30 * time.Second},
+		{map[string]string{"Cache-Control": "max-age=0"}, minRemoteImageTTL},
+		{map[string]string{"Cache-Control": "no-store"}, minRemoteImageTTL},
+		{map[string]string{"Cache-Control": "no-cache, max-age=600"}, minRemoteImageTTL},
+		{map[string]string{"Expires": now.Add(10 * time.Minute).Format(http.TimeFormat)}, 10 * time.Minute},
+		{map[string]string{"Expires": now.Add(-time.Hour).Format(http.TimeFormat)}, minRemoteImageTTL},
+		{map[string]string{"Expires": "0"}, minRemoteImageTTL},
+		{map[string]string{"Cache-Control": "max-age=90", "Expires": now.Add(time.Hour).Format(http.TimeFormat)}, 90 * time.Second},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.header {
+			h.Set(k, v)
+		}
+		if got := remoteImageTTL(h, now); got != tt.want {
+			t.Errorf("remoteImageTTL(%v) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestImageCacheEntryLimit(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	c := NewImageCacheWithLimits(100, 3)
+	c.now = clock.now
+	tiny := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	set := func(key string) {
+		clock.advance(time.Second)
+		c.Set(key, tiny)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		set(key)
+	}
+	clock.advance(time.Second)
+	c.Get("a") // a is now the most recently used
+	set("d")
+	set("e")
+
+	if c.Len() != 3 {
+		t.Errorf("cache holds %d entries, want 3", c.Len())
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": false, "d": true, "e": true} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("cached %q = %v, want %v", key, ok, want)
+		}
+	}
+
+	// Replacing an entry does not evict anything
+	set("e")
+	if c.Len() != 3 {
+		t.Errorf("after replacing an entry the cache holds %d, want 3", c.Len())
+	}
+}
+
+func TestImageCacheSizeLimit(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	c := NewImageCacheWithLimits(1, 100)
+	c.now = clock.now
+	big := image.NewRGBA(image.Rect(0, 0, 300, 300)) // ~0.34 MB
+
+	for _, key := range []string{"a", "b", "c"} {
+		clock.advance(time.Second)
+		c.Set(key, big)
+	}
+	if c.Len() != 2 {
+		t.Errorf("cache holds %d 0.34 MB images under a 1 MB limit, want 2", c.Len())
+	}
+	if _, ok := c.Get("a"); ok {
+		t.Error("the oldest image was not evicted")
+	}
+}
+
+func TestManagerImageCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "icon.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	png.Encode(f, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	f.Close()
+	ClearImageCache()
+
+	// Every manager gets its own cache by default
+	m1, m2 := NewScriptManager(nil, dir, 0), NewScriptManager(nil, dir, 0)
+	if _, err := m1.LoadImage(path); err != nil {
+		t.Fatal(err)
+	}
+	if m1.images.Len() != 1 || m2.images.Len() != 0 || globalImageCache.Len() != 0 {
+		t.Errorf("default caches: m1=%d m2=%d global=%d, want 1, 0, 0",
+			m1.images.Len(), m2.images.Len(), globalImageCache.Len())
+	}
+
+	c := NewImageCache(10)
+	m2.SetImageCache(c)
+	if err := m2.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m2.LoadImage(path); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("injected cache holds %d images, want 1", c.Len())
+	}
+	m2.Shutdown()
+	if c.Len() != 0 {
+		t.Errorf("Shutdown left %d images in the manager's cache", c.Len())
+	}
+	if m1.images.Len() != 1 {
+		t.Error("shutting down one manager cleared another's cache")
+	}
+}
+
+func TestLoadNonBlockingFailureBackoff(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{t: time.Now()}
+	c := NewImageCache(10)
+	c.now = clock.now
+	url := srv.URL + "/missing.png"
+
+	// poll resolves the fetch in flight and returns its error
+	poll := func() error {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			_, pending, err := c.LoadNonBlocking(url)
+			if !pending {
+				return err
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("fetch never finished")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	if err := poll(); err == nil {
+		t.Fatal("failed fetch returned no error")
+	}
+	// Within the backoff the cached error is returned without a new request,
+	// however much wall-clock time passes
+	time.Sleep(10 * time.Millisecond)
+	clock.advance(minRemoteImageTTL / 2)
+	if _, pending, err := c.LoadNonBlocking(url); pending || err == nil {
+		t.Fatalf("during backoff: pending=%v err=%v", pending, err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("requests during backoff = %d, want 1", n)
+	}
+
+	clock.advance(minRemoteImageTTL)
+	if err := poll(); err == nil {
+		t.Fatal("retried fetch returned no error")
+	}
+	if n := count(); n != 2 {
+		t.Errorf("requests after backoff = %d, want 2", n)
+	}
+}
@@ -0,0 +1,78 @@
+package scripting
+
+// input.go – routing dial and touch strip input to the scripts that asked
+// for it with streamdeck.on_dial / streamdeck.on_touch. The app calls
+// DispatchDial and DispatchTouch; every hook runs on its own script's VM.
+
+import "github.com/merith-tk/nomad/pkg/streamdeck"
+
+// inputHooks holds the registered dial and touch callbacks, keyed by
+// registration id.
+type inputHooks struct {
+	dial  map[int]map[int]func(streamdeck.DialEvent) // dial -> id -> hook
+	touch map[int]func(streamdeck.TouchEvent)
+	next  int
+}
+
+// OnDial registers deliver for events of one dial and returns a function
+// that removes it. It implements modules.InputHooks.
+func (m *ScriptManager) OnDial(dial int, deliver func(streamdeck.DialEvent)) func() {
+	m.inputMu.Lock()
+	defer m.inputMu.Unlock()
+	if m.input.dial == nil {
+		m.input.dial = make(map[int]map[int]func(streamdeck.DialEvent))
+	}
+	if m.input.dial[dial] == nil {
+		m.input.dial[dial] = make(map[int]func(streamdeck.DialEvent))
+	}
+	m.input.next++
+	id := m.input.next
+	m.input.dial[dial][id] = deliver
+
+	return func() {
+		m.inputMu.Lock()
+		defer m.inputMu.Unlock()
+		delete(m.input.dial[dial], id)
+		if len(m.input.dial[dial]) == 0 {
+			delete(m.input.dial, dial)
+		}
+	}
+}
+
+// OnTouch registers deliver for touch strip events and returns a function
+// that removes it. It implements modules.InputHooks.
+func (m *ScriptManager) OnTouch(deliver func(streamdeck.TouchEvent)) func() {
+	m.inputMu.Lock()
+	defer m.inputMu.Unlock()
+	if m.input.touch == nil {
+		m.input.touch = make(map[int]func(streamdeck.TouchEvent))
+	}
+	m.input.next++
+	id := m.input.next
+	m.input.touch[id] = deliver
+
+	return func() {
+		m.inputMu.Lock()
+		defer m.inputMu.Unlock()
+		delete(m.input.touch, id)
+	}
+}
+
+// DispatchDial delivers a dial event to every script hooked to that dial.
+func (m *ScriptManager) DispatchDial(ev streamdeck.DialEvent) {
+	m.inputMu.RLock()
+	defer m.inputMu.RUnlock()
+	for _, deliver := range m.input.dial[ev.Dial] {
+		deliver(ev)
+	}
+}
+
+// DispatchTouch delivers a touch strip event to every script hooked to the
+// strip.
+func (m *ScriptManager) DispatchTouch(ev streamdeck.TouchEvent) {
+	m.inputMu.RLock()
+	defer m.inputMu.RUnlock()
+	for _, deliver := range m.input.touch {
+		deliver(ev)
+	}
+}
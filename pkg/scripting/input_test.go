@@ -0,0 +1,70 @@
+package scripting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDialAndTouchReachLua(t *testing.T) {
+	dir := t.TempDir()
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x009a])
+	m := NewScriptManager(dev, dir, 0)
+
+	r, err := NewScriptRunner(writeScript(t, dir, "volume.lua", `
+		local deck = require("streamdeck")
+		state.volume = 50
+		deck.on_dial(1, function(delta, pressed)
+			state.volume = state.volume + delta
+			state.pressed = pressed
+		end)
+		deck.on_touch(function(gesture, x, y, end_x, end_y)
+			state.touch = gesture .. " " .. x .. "," .. y .. " " .. end_x .. "," .. end_y
+		end)
+		_, state.bad_dial = deck.on_dial(7, function() end)
+		return {}
+	`), dev, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.DispatchDial(streamdeck.DialEvent{Dial: 0, Delta: 10}) // not hooked
+	m.DispatchDial(streamdeck.DialEvent{Dial: 1, Delta: 3})
+	m.DispatchDial(streamdeck.DialEvent{Dial: 1, Delta: -1, Pressed: true})
+	m.DispatchTouch(streamdeck.TouchEvent{Gesture: streamdeck.TouchSwipe, X: 10, Y: 20, EndX: 300, EndY: 25})
+
+	read := func(key string) lua.LValue {
+		r.luaMu.Lock()
+		defer r.luaMu.Unlock()
+		return r.state.RawGetString(key)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for read("touch") == lua.LNil {
+		if time.Now().After(deadline) {
+			t.Fatal("touch event never reached the script")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := read("volume"); got != lua.LNumber(52) {
+		t.Errorf("volume = %v, want 52 (dial 1 turned +3 then -1)", got)
+	}
+	if got := read("pressed"); got != lua.LTrue {
+		t.Errorf("pressed = %v, want true", got)
+	}
+	if got := read("touch").String(); got != "swipe 10,20 300,25" {
+		t.Errorf("touch = %q", got)
+	}
+	if read("bad_dial") == lua.LNil {
+		t.Error("on_dial accepted a dial the model does not have")
+	}
+
+	// Closing the runner removes its hooks
+	r.Close()
+	if len(m.input.dial) != 0 || len(m.input.touch) != 0 {
+		t.Errorf("hooks left after Close: %d dials, %d touch", len(m.input.dial), len(m.input.touch))
+	}
+	m.DispatchDial(streamdeck.DialEvent{Dial: 1, Delta: 1}) // must not panic
+}
@@ -0,0 +1,321 @@
+package scripting
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Deferred and lazy loading
+//
+// Boot compiles every script it finds but only runs the top level of those
+// that need it from the start: scripts defining background() and scripts
+// that set the EAGER_LOAD global to true. For the rest a ScriptRunner (and
+// its LState) is created, and the top level run, the first time the script
+// becomes visible, is triggered or is asked for via GetRunner. In lazy mode
+// scripts with background() are deferred too, unless they set EAGER_LOAD.
+
+// eagerLoadPattern matches a top-level EAGER_LOAD = true.
+var eagerLoadPattern = regexp.MustCompile(`(?m)^EAGER_LOAD\s*=\s*true\b`)
+
+// buttonEntryPoints are the entry points that make a script worth a key:
+// a script defining none of them is a helper module and is not shown.
+var buttonEntryPoints = map[string]bool{
+	"background": true, "passive": true, "trigger": true, "trigger_release": true,
+	"t1_passive": true, "t1_trigger": true, "t2_passive": true, "t2_trigger": true,
+}
+
+// bootEntryPoints are the entry points that must run from boot, so a script
+// defining one is loaded then even though the rest are deferred.
+var bootEntryPoints = map[string]bool{"background": true}
+
+// scriptInfo is what Boot learns about a script without running it.
+type scriptInfo struct {
+	usable     bool  // Defines one of buttonEntryPoints
+	background bool  // Defines one of bootEntryPoints
+	eager      bool  // Sets EAGER_LOAD = true
+	loadErr    error // Why the last load failed, nil once it loads
+}
+
+// scanScript inspects a script's syntax tree for its entry points, without
+// running it. The result is only used until the script is actually loaded,
+// so anything it cannot tell (an unreadable file, a syntax error) counts as
+// usable and is settled by the load. Such a script is not marked
+// background; Boot reports why it does not compile instead.
+func scanScript(path string) scriptInfo {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return scriptInfo{usable: true}
+	}
+	info := scriptInfo{eager: eagerLoadPattern.Match(src)}
+	chunk, err := parse.Parse(bytes.NewReader(src), path)
+	if err != nil {
+		info.usable = true
+		return info
+	}
+	info.usable = namesEntryPoint(chunk, buttonEntryPoints)
+	info.background = namesEntryPoint(chunk, bootEntryPoints)
+	return info
+}
+
+// namesEntryPoint reports whether a chunk defines one of names in any of
+// the styles moduleFromResult accepts: a function declaration
+// ("function script.trigger(", or a global "function trigger("), an
+// assignment ("script.trigger = ...", "trigger = ...") or a table
+// constructor key ("return { trigger = ... }"). Function bodies are searched
+// too, so it errs towards usable.
+func namesEntryPoint(chunk []ast.Stmt, names map[string]bool) bool {
+	found := false
+	isEntry := func(e ast.Expr) bool {
+		switch e := e.(type) {
+		case *ast.IdentExpr:
+			return names[e.Value]
+		case *ast.AttrGetExpr:
+			key, ok := e.Key.(*ast.StringExpr)
+			return ok && names[key.Value]
+		}
+		return false
+	}
+
+	var stmts func([]ast.Stmt)
+	var exprs func(...ast.Expr)
+	exprs = func(list ...ast.Expr) {
+		for _, e := range list {
+			if found {
+				return
+			}
+			switch e := e.(type) {
+			case *ast.TableExpr:
+				for _, f := range e.Fields {
+					if key, ok := f.Key.(*ast.StringExpr); ok && names[key.Value] {
+						found = true
+						return
+					}
+					exprs(f.Key, f.Value)
+				}
+			case *ast.FunctionExpr:
+				stmts(e.Stmts)
+			case *ast.AttrGetExpr:
+				exprs(e.Object, e.Key)
+			case *ast.FuncCallExpr:
+				exprs(e.Func, e.Receiver)
+				exprs(e.Args...)
+			case *ast.LogicalOpExpr:
+				exprs(e.Lhs, e.Rhs)
+			case *ast.RelationalOpExpr:
+				exprs(e.Lhs, e.Rhs)
+			case *ast.StringConcatOpExpr:
+				exprs(e.Lhs, e.Rhs)
+			case *ast.ArithmeticOpExpr:
+				exprs(e.Lhs, e.Rhs)
+			case *ast.UnaryMinusOpExpr:
+				exprs(e.Expr)
+			case *ast.UnaryNotOpExpr:
+				exprs(e.Expr)
+			case *ast.UnaryLenOpExpr:
+				exprs(e.Expr)
+			}
+		}
+	}
+	stmts = func(list []ast.Stmt) {
+		for _, s := range list {
+			if found {
+				return
+			}
+			switch s := s.(type) {
+			case *ast.FuncDefStmt:
+				if s.Name.Receiver != nil {
+					found = names[s.Name.Method]
+				} else {
+					found = isEntry(s.Name.Func)
+				}
+				stmts(s.Func.Stmts)
+			case *ast.AssignStmt:
+				for _, lhs := range s.Lhs {
+					found = found || isEntry(lhs)
+				}
+				exprs(s.Lhs...)
+				exprs(s.Rhs...)
+			case *ast.LocalAssignStmt:
+				exprs(s.Exprs...)
+			case *ast.FuncCallStmt:
+				exprs(s.Expr)
+			case *ast.ReturnStmt:
+				exprs(s.Exprs...)
+			case *ast.DoBlockStmt:
+				stmts(s.Stmts)
+			case *ast.WhileStmt:
+				exprs(s.Condition)
+				stmts(s.Stmts)
+			case *ast.RepeatStmt:
+				exprs(s.Condition)
+				stmts(s.Stmts)
+			case *ast.IfStmt:
+				exprs(s.Condition)
+				stmts(s.Then)
+				stmts(s.Else)
+			case *ast.NumberForStmt:
+				exprs(s.Init, s.Limit, s.Step)
+				stmts(s.Stmts)
+			case *ast.GenericForStmt:
+				exprs(s.Exprs...)
+				stmts(s.Stmts)
+			}
+		}
+	}
+	stmts(chunk)
+	return found
+}
+
+// SetLazyLoad enables lazy script loading, which defers scripts with
+// background() like the rest. Call before Boot.
+func (m *ScriptManager) SetLazyLoad(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lazy = enabled
+}
+
+// SetUnloadPolicy controls when off-screen runners are closed to free their
+// LState. Runners off-screen for longer than idle are unloaded (0 = never),
+// and when more than maxLoaded runners are open the least recently visible
+// are unloaded first (0 = no limit). Unloaded scripts are reloaded with fresh
+// state the next time they are shown. Call before Boot.
+func (m *ScriptManager) SetUnloadPolicy(idle time.Duration, maxLoaded int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unloadAfter = idle
+	m.maxLoaded = maxLoaded
+}
+
+// loadRunner creates the runner for a script, registers it and starts its
+// background worker.
+func (m *ScriptManager) loadRunner(scriptPath string) (*ScriptRunner, error) {
+	runner, fresh, err := m.openRunner(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+	if fresh && runner.HasBackground() {
+		m.startBackground(runner)
+	}
+	return runner, nil
+}
+
+// openRunner loads a script and registers its runner like loadRunner, but
+// leaves starting its background worker to the caller. fresh is false when
+// another caller had already loaded the script.
+func (m *ScriptManager) openRunner(scriptPath string) (runner *ScriptRunner, fresh bool, err error) {
+	runner, err = NewScriptRunner(scriptPath, m.device, m.configDir, m.logger, m)
+	if err != nil {
+		m.mu.Lock()
+		if info, ok := m.scripts[scriptPath]; ok {
+			info.usable = false
+			info.loadErr = err
+			m.scripts[scriptPath] = info
+		}
+		m.mu.Unlock()
+		return nil, false, err
+	}
+	runner.SetRefreshCallback(m.requestRefresh)
+
+	m.mu.Lock()
+	// Remember the real entry points so IsUsableScript stays accurate
+	// after the runner is unloaded.
+	info := m.scripts[scriptPath]
+	info.usable = runner.usable()
+	info.loadErr = nil
+	m.scripts[scriptPath] = info
+	if existing := m.runners[scriptPath]; existing != nil {
+		// Another caller loaded it first
+		m.mu.Unlock()
+		runner.Close()
+		return existing, false, nil
+	}
+	m.runners[scriptPath] = runner
+	m.lastVisible[scriptPath] = time.Now()
+	m.mu.Unlock()
+	return runner, true, nil
+}
+
+// ensureRunner returns the runner for a script, loading it first if it is a
+// known script that has not been loaded yet (or was unloaded).
+func (m *ScriptManager) ensureRunner(scriptPath string) *ScriptRunner {
+	m.mu.RLock()
+	runner := m.runners[scriptPath]
+	_, known := m.scripts[scriptPath]
+	m.mu.RUnlock()
+
+	if runner != nil || !known {
+		return runner
+	}
+
+	runner, err := m.loadRunner(scriptPath)
+	if err != nil {
+		fmt.Printf("[!] Failed to load %s: %v\n", filepath.Base(scriptPath), err)
+		return nil
+	}
+	return runner
+}
+
+// unloadIdle closes runners that have been off-screen for longer than the
+// unload delay, then, if more than maxLoaded runners remain, the least
+// recently visible ones. Visible runners, runners with a background worker,
+// runners holding key claims or live hooks (see hasLiveHooks) and runners
+// driving a toggle key are never unloaded.
+func (m *ScriptManager) unloadIdle() {
+	m.mu.Lock()
+	if m.unloadAfter <= 0 && m.maxLoaded <= 0 {
+		m.mu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	var candidates []string
+	for path, runner := range m.runners {
+		if _, visible := m.visibleScripts[path]; visible {
+			m.lastVisible[path] = now
+			continue
+		}
+		if path == m.t1Script || path == m.t2Script {
+			continue
+		}
+		if runner.HasBackground() && !m.bgDisabled {
+			continue
+		}
+		// Claimed keys, subscriptions, watches, input hooks and running
+		// macros would be lost on unload
+		if m.hasClaims(path) || runner.hasLiveHooks() {
+			continue
+		}
+		candidates = append(candidates, path)
+	}
+
+	// Least recently visible first
+	sort.Slice(candidates, func(i, j int) bool {
+		return m.lastVisible[candidates[i]].Before(m.lastVisible[candidates[j]])
+	})
+
+	var unload []*ScriptRunner
+	for _, path := range candidates {
+		overLimit := m.maxLoaded > 0 && len(m.runners) > m.maxLoaded
+		expired := m.unloadAfter > 0 && now.Sub(m.lastVisible[path]) >= m.unloadAfter
+		if !overLimit && !expired {
+			continue
+		}
+		unload = append(unload, m.runners[path])
+		delete(m.runners, path)
+		delete(m.lastVisible, path)
+	}
+	m.mu.Unlock()
+
+	for _, runner := range unload {
+		fmt.Printf("[*] Unloading idle script: %s\n", runner.ScriptName)
+		runner.Close()
+	}
+}
@@ -0,0 +1,312 @@
+package scripting
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// scriptStyles are the ways a script can define its entry points, with
+// whether each makes the script a button.
+var scriptStyles = []struct {
+	name   string
+	src    string
+	usable bool
+}{
+	{"module_function", `
+		local script = {}
+		function script.trigger(state) end
+		return script
+	`, true},
+	{"module_assignment", `
+		local script = {}
+		script.passive = function(key, state) return nil end
+		return script
+	`, true},
+	{"module_method", `
+		local script = {}
+		function script:background() end
+		return script
+	`, true},
+	{"returned_table", `
+		return {
+			trigger = function(state) end,
+		}
+	`, true},
+	{"returned_local", `
+		local function trigger(state) end
+		return { ["trigger"] = trigger }
+	`, true},
+	{"legacy_globals", `
+		function passive(key, state) return { text = "hi" } end
+		function trigger(state) end
+	`, true},
+	{"release_only", `
+		return { trigger_release = function(state, ctx) end }
+	`, true},
+	{"toggle_only", `
+		local script = {}
+		function script.t1_trigger(state) end
+		script.t2_passive = function() return nil end
+		return script
+	`, true},
+	{"helper_module", `
+		local M = {}
+		function M.format(n) return tostring(n) end
+		M.triggered = false
+		return M
+	`, false},
+}
+
+func TestScanScriptStyles(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range scriptStyles {
+		path := writeScript(t, dir, tt.name+".lua", tt.src)
+		if got := scanScript(path).usable; got != tt.usable {
+			t.Errorf("%s: scanScript usable = %v, want %v", tt.name, got, tt.usable)
+		}
+	}
+
+	// What cannot be scanned is left to the load to decide
+	broken := writeScript(t, dir, "broken.lua", `return {`)
+	if !scanScript(broken).usable {
+		t.Error("a script with a syntax error was scanned as unusable")
+	}
+	if !scanScript(dir + "/missing.lua").usable {
+		t.Error("an unreadable script was scanned as unusable")
+	}
+}
+
+func TestLazyBootKeepsEveryScriptStyle(t *testing.T) {
+	dir := t.TempDir()
+	paths := make(map[string]string)
+	for _, tt := range scriptStyles {
+		paths[tt.name] = writeScript(t, dir, tt.name+".lua", tt.src)
+	}
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetLazyLoad(true)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	for _, tt := range scriptStyles {
+		path := paths[tt.name]
+		m.mu.RLock()
+		loaded := m.runners[path] != nil
+		m.mu.RUnlock()
+		if loaded {
+			t.Fatalf("%s was loaded at boot in lazy mode", tt.name)
+		}
+		if got := m.IsUsableScript(path); got != tt.usable {
+			t.Errorf("%s: usable before load = %v, want %v", tt.name, got, tt.usable)
+		}
+		if m.ensureRunner(path) == nil {
+			t.Fatalf("%s failed to load", tt.name)
+		}
+		if got := m.IsUsableScript(path); got != tt.usable {
+			t.Errorf("%s: usable after load = %v, want %v", tt.name, got, tt.usable)
+		}
+	}
+}
+
+func TestLazyRunnerWithSubscriptionStaysLoaded(t *testing.T) {
+	dir := t.TempDir()
+	listener := writeScript(t, dir, "listener.lua", `
+		local events = require("events")
+		local script = {}
+		events.subscribe("mute", function(data) script.muted = data end)
+		function script.trigger(state) end
+		return script
+	`)
+	plain := writeScript(t, dir, "plain.lua", `return { trigger = function() end }`)
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetLazyLoad(true)
+	m.SetUnloadPolicy(50*time.Millisecond, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	// Both are created on demand, then go off-screen
+	for _, path := range []string{listener, plain} {
+		if m.ensureRunner(path) == nil {
+			t.Fatalf("%s failed to load", filepath.Base(path))
+		}
+	}
+	time.Sleep(60 * time.Millisecond)
+	m.unloadIdle()
+
+	if loaded(m, listener) == nil {
+		t.Error("lazily loaded script with a subscription was unloaded")
+	}
+	if loaded(m, plain) != nil {
+		t.Error("lazily loaded idle script was not unloaded")
+	}
+}
+
+// loaded returns the runner currently registered for path, without loading it.
+func loaded(m *ScriptManager, path string) *ScriptRunner {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.runners[path]
+}
+
+func TestUnloadIdleOffscreenRunners(t *testing.T) {
+	dir := t.TempDir()
+	counter := `
+		local script = {}
+		function script.passive(key, state) return { text = tostring(state.count or 0) } end
+		function script.trigger(state) state.count = (state.count or 0) + 1 end
+		return script
+	`
+	page := writeScript(t, dir, "page.lua", counter)
+	other := writeScript(t, dir, "other.lua", counter)
+	worker := writeScript(t, dir, "worker.lua", `
+		local system = require("system")
+		local script = {}
+		function script.background(state)
+			while true do system.sleep(20) end
+		end
+		return script
+	`)
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetUnloadPolicy(50*time.Millisecond, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	m.SetVisibleScripts(map[string]int{page: 0})
+	if err := m.TriggerScript(page, 0, EventTap); err != nil {
+		t.Fatal(err)
+	}
+	first := loaded(m, page)
+
+	// Navigate away and let the idle period pass
+	m.SetVisibleScripts(map[string]int{other: 0})
+	time.Sleep(60 * time.Millisecond)
+	m.unloadIdle()
+
+	if loaded(m, page) != nil {
+		t.Fatal("off-screen runner was not unloaded")
+	}
+	first.mu.RLock()
+	closed := first.L == nil
+	first.mu.RUnlock()
+	if !closed {
+		t.Error("unloaded runner's LState was not closed")
+	}
+	if loaded(m, other) == nil {
+		t.Error("visible runner was unloaded")
+	}
+	if loaded(m, worker) == nil {
+		t.Error("runner with a background worker was unloaded")
+	}
+	if !m.IsUsableScript(page) {
+		t.Error("unloaded script no longer counts as a button")
+	}
+
+	// Navigating back recreates it with fresh state
+	m.SetVisibleScripts(map[string]int{page: 0})
+	second := loaded(m, page)
+	if second == nil || second == first {
+		t.Fatal("script was not reloaded when shown again")
+	}
+	if ap, err := second.RunPassive(PassiveContext{}); err != nil || ap == nil || ap.Text != "0" {
+		t.Errorf("reloaded passive = %v, %v; want fresh state", ap, err)
+	}
+}
+
+func TestUnloadLeastRecentlyVisible(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.lua", "b.lua", "c.lua", "d.lua"} {
+		paths = append(paths, writeScript(t, dir, name, `return { trigger = function() end }`))
+	}
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetUnloadPolicy(0, 2)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	// Show each script in turn; d ends up visible, c most recently hidden
+	for _, path := range paths {
+		m.SetVisibleScripts(map[string]int{path: 0})
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.unloadIdle()
+
+	for i, want := range []bool{false, false, true, true} {
+		if got := loaded(m, paths[i]) != nil; got != want {
+			t.Errorf("%s loaded = %v, want %v", filepath.Base(paths[i]), got, want)
+		}
+	}
+}
+
+func TestUnloadIdleKeepsLiveHooks(t *testing.T) {
+	dir := t.TempDir()
+	subscribed := writeScript(t, dir, "subscribed.lua", `
+		local events = require("events")
+		local script = {}
+		function script.trigger(state)
+			events.subscribe("tick", function() end)
+		end
+		return script
+	`)
+	watching := writeScript(t, dir, "watching.lua", `
+		local file = require("file")
+		local script = {}
+		function script.trigger(state)
+			assert(file.watch(CONFIG_DIR, function() end))
+		end
+		return script
+	`)
+	macro := writeScript(t, dir, "macro.lua", `
+		local macro = require("macro")
+		local script = {}
+		function script.trigger(state)
+			macro.run({ 60000 })
+		end
+		return script
+	`)
+	cancelled := writeScript(t, dir, "cancelled.lua", `
+		local events = require("events")
+		local script = {}
+		function script.trigger(state)
+			local unsubscribe = events.subscribe("tick", function() end)
+			unsubscribe()
+		end
+		return script
+	`)
+	paths := []string{subscribed, watching, macro, cancelled}
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetUnloadPolicy(50*time.Millisecond, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	for _, path := range paths {
+		m.SetVisibleScripts(map[string]int{path: 0})
+		if err := m.TriggerScript(path, 0, EventTap); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.SetVisibleScripts(nil)
+	time.Sleep(60 * time.Millisecond)
+	m.unloadIdle()
+
+	for i, want := range []bool{true, true, true, false} {
+		if got := loaded(m, paths[i]) != nil; got != want {
+			t.Errorf("%s loaded = %v, want %v", filepath.Base(paths[i]), got, want)
+		}
+	}
+}
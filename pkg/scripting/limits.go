@@ -0,0 +1,126 @@
+package scripting
+
+// limits.go – per-script resource limits. Call depth and data stack size are
+// enforced by the LState itself; memory is watched by the manager, which
+// aborts the scripts that are running while the heap is over the limit.
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ScriptLimits bounds what a single script may use. Zero fields keep the
+// gopher-lua default (or no limit, for MemoryMB).
+type ScriptLimits struct {
+	CallDepth  int // Maximum nested Lua calls (lua.CallStackSize)
+	StackSlots int // Maximum data stack slots (lua.RegistrySize)
+	MemoryMB   int // Abort running scripts while the Go heap exceeds this
+}
+
+// memoryCheckInterval is how often the memory watchdog samples the heap.
+var memoryCheckInterval = 250 * time.Millisecond
+
+// SetLimits sets the limits applied to every script loaded afterwards.
+// Call before Boot.
+func (m *ScriptManager) SetLimits(limits ScriptLimits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits = limits
+}
+
+// scriptLimits returns the limits for new runners.
+func (m *ScriptManager) scriptLimits() ScriptLimits {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.limits
+}
+
+// options returns the LState options enforcing the call depth and stack
+// limits. The data stack starts at the default size and grows up to
+// StackSlots, so a high limit costs nothing until a script uses it.
+func (l ScriptLimits) options() lua.Options {
+	opts := lua.Options{
+		CallStackSize: l.CallDepth,
+		RegistrySize:  lua.RegistrySize,
+	}
+	if l.StackSlots > 0 {
+		opts.RegistrySize = min(l.StackSlots, lua.RegistrySize)
+		opts.RegistryMaxSize = l.StackSlots
+	}
+	return opts
+}
+
+// armLimit gives the VM, and the background coroutine if any, a fresh
+// context after the memory watchdog cancelled the previous one. It is a
+// no-op without a memory limit. Callers hold luaMu and at least r.mu.RLock.
+func (r *ScriptRunner) armLimit() {
+	if r.limitCtx == nil || r.limitCtx.Err() == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.limitMu.Lock()
+	r.limitCtx, r.limitCancel = ctx, cancel
+	r.limitMu.Unlock()
+	r.L.SetContext(ctx)
+	if r.bgThread != nil {
+		r.bgThread.SetContext(ctx)
+	}
+}
+
+// abortIfRunning stops the Lua code the runner is executing, if any, and
+// reports whether it did. The interrupted call returns an error.
+func (r *ScriptRunner) abortIfRunning() bool {
+	if r.luaMu.TryLock() {
+		r.luaMu.Unlock()
+		return false // idle
+	}
+	r.limitMu.Lock()
+	cancel := r.limitCancel
+	r.limitMu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	return true
+}
+
+// memoryWatchdog samples the heap until ctx is done. While it is above
+// limitMB every runner executing Lua is aborted; the allocating script is
+// necessarily among them.
+func (m *ScriptManager) memoryWatchdog(ctx context.Context, limitMB int) {
+	limit := uint64(limitMB) << 20
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	var stats runtime.MemStats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		runtime.ReadMemStats(&stats)
+		if stats.HeapAlloc <= limit {
+			continue
+		}
+
+		m.mu.RLock()
+		runners := make([]*ScriptRunner, 0, len(m.runners))
+		for _, r := range m.runners {
+			runners = append(runners, r)
+		}
+		m.mu.RUnlock()
+
+		for _, r := range runners {
+			if r.abortIfRunning() {
+				fmt.Printf("[!] Aborted %s: memory limit of %d MB exceeded\n", r.ScriptName, limitMB)
+			}
+		}
+		runtime.GC()
+	}
+}
@@ -0,0 +1,97 @@
+package scripting
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestDeepRecursionIsAborted(t *testing.T) {
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+	m.SetLimits(ScriptLimits{CallDepth: 50})
+
+	r, err := NewScriptRunner(writeScript(t, dir, "recurse.lua", `
+		local function depth(n, limit)
+			if limit and n >= limit then return n end
+			return depth(n + 1, limit) + 0
+		end
+		local script = {}
+		function script.trigger(state, ctx)
+			state.depth = depth(0, state.limit)
+		end
+		return script
+	`), nil, dir, nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	err = r.RunTrigger(TriggerContext{})
+	if err == nil || !strings.Contains(err.Error(), "stack overflow") {
+		t.Fatalf("unbounded recursion: err = %v, want stack overflow", err)
+	}
+
+	// The runner stays usable for calls within the limit
+	r.state.RawSetString("limit", lua.LNumber(10))
+	if err := r.RunTrigger(TriggerContext{}); err != nil {
+		t.Fatalf("bounded recursion: %v", err)
+	}
+	if got := r.state.RawGetString("depth"); got != lua.LNumber(10) {
+		t.Errorf("depth = %v, want 10", got)
+	}
+}
+
+func TestMemoryLimitAbortsRunningScript(t *testing.T) {
+	defer func(d time.Duration) { memoryCheckInterval = d }(memoryCheckInterval)
+	memoryCheckInterval = 10 * time.Millisecond
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	limitMB := int(stats.HeapAlloc>>20) + 32
+
+	dir := t.TempDir()
+	m := NewScriptManager(nil, dir, 0)
+	m.SetLimits(ScriptLimits{MemoryMB: limitMB})
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	path := writeScript(t, dir, "hog.lua", `
+		local script = {}
+		function script.trigger(state, ctx)
+			if state.tame then return end
+			local t = {}
+			while true do
+				t[#t + 1] = string.rep("x", 1024) .. #t
+			end
+		end
+		return script
+	`)
+	r, err := m.loadRunner(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- r.RunTrigger(TriggerContext{}) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("allocating trigger returned without an error")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("allocating trigger was not aborted")
+	}
+
+	// Later calls get a fresh context
+	r.state.RawSetString("tame", lua.LTrue)
+	if err := r.RunTrigger(TriggerContext{}); err != nil {
+		t.Errorf("trigger after abort: %v", err)
+	}
+}
@@ -21,11 +21,16 @@ package scripting
 import (
 	"context"
 	"fmt"
+	"image"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/merith-tk/nomad/pkg/lualib"
+	"github.com/merith-tk/nomad/pkg/scripting/modules"
 	"github.com/merith-tk/nomad/pkg/streamdeck"
 	lua "github.com/yuin/gopher-lua"
 )
@@ -33,12 +38,20 @@ import (
 const (
 	// DefaultPassiveFPS is the default rate at which passive functions are called.
 	DefaultPassiveFPS = 10
+
+	// RecentPressWindow is how long after a trigger passive() sees ctx.pressed.
+	RecentPressWindow = 500 * time.Millisecond
 )
 
 // ScriptManager coordinates all script runners and the passive loop.
 type ScriptManager struct {
 	mu sync.RWMutex
 
+	// Held by page renders, read-held by key updates (see render.go);
+	// taken before mu, never after. rendering is set while a render holds it.
+	renderMu  sync.RWMutex
+	rendering atomic.Bool
+
 	device     *streamdeck.Device
 	configDir  string
 	passiveFPS int
@@ -46,6 +59,15 @@ type ScriptManager struct {
 	// All loaded script runners, keyed by script path
 	runners map[string]*ScriptRunner
 
+	// Every script found by Boot, loaded or not
+	scripts map[string]scriptInfo
+
+	// Lazy loading and unloading (see lazy.go)
+	lazy        bool
+	unloadAfter time.Duration
+	maxLoaded   int
+	lastVisible map[string]time.Time // script path -> last time it was on-screen
+
 	// Context for lifecycle management
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -59,8 +81,69 @@ type ScriptManager struct {
 	lastPassiveUpdate time.Time
 	passiveBatch      map[string]*KeyAppearance // batched updates
 
+	// Image each script's passive() last returned, for ImagePending
+	lastImage map[string]string
+
+	// Frame animations declared by passive() (see animation.go)
+	animMu      sync.Mutex
+	animations  map[string]*keyAnimation
+	animRunning bool
+
+	// Last trigger time per script, reported to passive() as ctx.pressed
+	lastPress map[string]time.Time
+
 	// Boot animation
 	bootScriptPath string
+	bootRunner     *ScriptRunner // kept open during Boot if it defines progress()
+	bootLit        int           // keys lit by the built-in progress bar
+	bootDrawn      bool
+
+	// Limits concurrently running background workers; nil = unlimited
+	bgSlots chan struct{}
+
+	// Scripts loaded at once by Boot; 0 = one per CPU
+	maxLoads int
+
+	// Skip starting background workers (scripting.enable_background: false)
+	bgDisabled bool
+
+	// Strip host-touching stdlib functions from new runners (see sandbox.go)
+	sandboxed bool
+
+	// Resource limits for new runners (see limits.go)
+	limits ScriptLimits
+
+	// Reported by the nomad module (see appinfo.go)
+	version     string
+	currentPath string
+
+	// Render and passive timings (see metrics.go)
+	metrics metricsState
+
+	// Scripts whose passive() is slow (see slowpassive.go)
+	slowMu sync.Mutex
+	slow   map[string]*slowPassive
+
+	// Image cache used for appearance images; cleared on Shutdown
+	images *ImageCache
+
+	// Destination of the Lua log module (nil = stdout, unfiltered)
+	logger *lualib.Logger
+
+	// Event bus shared by every runner's events module (see events.go)
+	eventsMu     sync.RWMutex
+	eventSubs    map[string]eventSubs
+	nextEventSub int
+
+	// Dial and touch strip hooks (see input.go)
+	inputMu sync.RWMutex
+	input   inputHooks
+
+	// Key claims (see claims.go); claimsMu is taken after mu, never before
+	claimsMu      sync.RWMutex
+	claims        map[int]string // key index -> claiming script path
+	claimable     map[int]bool   // nil = any key
+	onKeyReleased func(keyIndex int)
 
 	// Callback when passive wants to update a key
 	onKeyUpdate func(keyIndex int, appearance *KeyAppearance)
@@ -82,11 +165,95 @@ func NewScriptManager(dev *streamdeck.Device, configDir string, passiveFPS int)
 		configDir:      configDir,
 		passiveFPS:     passiveFPS,
 		runners:        make(map[string]*ScriptRunner),
+		scripts:        make(map[string]scriptInfo),
+		lastVisible:    make(map[string]time.Time),
 		visibleScripts: make(map[string]int),
 		passiveBatch:   make(map[string]*KeyAppearance),
+		lastImage:      make(map[string]string),
+		lastPress:      make(map[string]time.Time),
+		images:         NewImageCache(defaultImageCacheMB),
 	}
 }
 
+// SetBackgroundEnabled controls whether Boot starts background() workers.
+// Passive and trigger functions run either way. Call before Boot.
+func (m *ScriptManager) SetBackgroundEnabled(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bgDisabled = !enabled
+}
+
+// SetNetworkBlocked makes the http and weather modules refuse requests in
+// every script (security.block_network).
+func (m *ScriptManager) SetNetworkBlocked(blocked bool) {
+	modules.SetNetworkBlocked(blocked)
+}
+
+// SetLogger routes every script's log module through logger. Call before
+// Boot; runners keep the logger they were created with.
+func (m *ScriptManager) SetLogger(logger *lualib.Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logger = logger
+}
+
+// SetMaxBackgroundWorkers limits how many background workers run at once.
+// Scripts beyond the limit wait for a running worker to stop. n <= 0 removes
+// the limit. Call before Boot.
+func (m *ScriptManager) SetMaxBackgroundWorkers(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if n <= 0 {
+		m.bgSlots = nil
+		return
+	}
+	m.bgSlots = make(chan struct{}, n)
+}
+
+// SetMaxConcurrentLoads limits how many scripts Boot loads at once. Each
+// script loads into its own VM, so they can load in parallel. n <= 0 loads
+// one script per CPU at a time. Call before Boot.
+func (m *ScriptManager) SetMaxConcurrentLoads(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxLoads = max(n, 0)
+}
+
+// SetImageCache replaces the cache used by LoadImage. Call before Boot.
+func (m *ScriptManager) SetImageCache(c *ImageCache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images = c
+}
+
+// LoadImage loads an appearance image through the manager's image cache.
+func (m *ScriptManager) LoadImage(path string) (image.Image, error) {
+	m.mu.RLock()
+	c := m.images
+	m.mu.RUnlock()
+	return c.Load(path)
+}
+
+// LoadImageNonBlocking loads an appearance image without waiting on the
+// network; see ImageCache.LoadNonBlocking.
+func (m *ScriptManager) LoadImageNonBlocking(path string) (image.Image, bool, error) {
+	m.mu.RLock()
+	c := m.images
+	m.mu.RUnlock()
+	return c.LoadNonBlocking(path)
+}
+
+// ImagePending reports whether the image a script's passive() last returned
+// is a remote image that is not cached yet, so its key can show a loading
+// placeholder straight away when a page is drawn.
+func (m *ScriptManager) ImagePending(scriptPath string) bool {
+	m.mu.RLock()
+	path := m.lastImage[scriptPath]
+	c := m.images
+	m.mu.RUnlock()
+	return path != "" && !c.Cached(path)
+}
+
 // SetKeyUpdateCallback sets the callback for passive key updates.
 func (m *ScriptManager) SetKeyUpdateCallback(cb func(keyIndex int, appearance *KeyAppearance)) {
 	m.mu.Lock()
@@ -94,13 +261,20 @@ func (m *ScriptManager) SetKeyUpdateCallback(cb func(keyIndex int, appearance *K
 	m.onKeyUpdate = cb
 }
 
-// Boot scans the config directory and loads all scripts.
-// Runs boot animation if _boot.lua exists, then loads all scripts.
+// Boot scans the config directory, checks every script for syntax errors and
+// loads those that define background() or set EAGER_LOAD (in lazy mode, only
+// the latter); the rest load when first needed. Runs boot animation if
+// _boot.lua exists first.
 func (m *ScriptManager) Boot(ctx context.Context) error {
 	m.mu.Lock()
 	m.ctx, m.cancel = context.WithCancel(ctx)
+	bootCtx, memoryMB := m.ctx, m.limits.MemoryMB
 	m.mu.Unlock()
 
+	if memoryMB > 0 {
+		go m.memoryWatchdog(bootCtx, memoryMB)
+	}
+
 	// Check for boot animation script - runs synchronously
 	bootPath := filepath.Join(m.configDir, "_boot.lua")
 	if _, err := os.Stat(bootPath); err == nil {
@@ -110,52 +284,54 @@ func (m *ScriptManager) Boot(ctx context.Context) error {
 	}
 
 	// Scan for all .lua files recursively
+	found, err := findScripts(m.configDir)
+	if err != nil {
+		return fmt.Errorf("failed to scan config directory: %w", err)
+	}
 	var scriptPaths []string
-	err := filepath.Walk(m.configDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil // Skip errors
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if filepath.Ext(path) == ".lua" && filepath.Base(path) != "_boot.lua" {
+	for _, path := range found {
+		if filepath.Base(path) != "_boot.lua" {
 			scriptPaths = append(scriptPaths, path)
 		}
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("failed to scan config directory: %w", err)
 	}
 
 	fmt.Printf("[*] Found %d scripts to load...\n", len(scriptPaths))
 
-	// Load each script
+	// Animate _boot.lua frame() while scripts load
+	stopFrames := m.startBootFrames()
+	if m.bgDisabled {
+		fmt.Println("[*] Background workers disabled by config")
+	}
+
+	// Load the scripts in parallel, then report failures and start
+	// background workers in scan order so the log reads the same every boot.
+	results := m.bootScripts(scriptPaths)
 	loaded := 0
-	for _, scriptPath := range scriptPaths {
-		runner, err := NewScriptRunner(scriptPath, m.device, m.configDir)
-		if err != nil {
-			fmt.Printf("[!] Failed to load %s: %v\n", filepath.Base(scriptPath), err)
-			continue
+	for i, r := range results {
+		if r.err != nil {
+			fmt.Printf("[!] Failed to load %s: %v\n", filepath.Base(scriptPaths[i]), r.err)
 		}
+		if r.runner != nil {
+			loaded++
+		}
+	}
 
-		// Set refresh callback
-		runner.SetRefreshCallback(m.requestRefresh)
-
-		m.mu.Lock()
-		m.runners[scriptPath] = runner
-		m.mu.Unlock()
-
-		loaded++
+	fmt.Printf("[*] Loaded %d/%d scripts\n", loaded, len(scriptPaths))
 
-		// Start background worker if defined
-		if runner.HasBackground() {
-			fmt.Printf("[*] Starting background worker: %s\n", runner.ScriptName)
-			runner.StartBackground(m.ctx)
+	for _, r := range results {
+		if r.fresh && r.runner.HasBackground() {
+			m.startBackground(r.runner)
 		}
 	}
 
-	fmt.Printf("[*] Loaded %d/%d scripts\n", loaded, len(scriptPaths))
+	stopFrames()
+	if m.bootRunner != nil {
+		if err := m.bootRunner.callModuleFunc("finish"); err != nil {
+			fmt.Printf("[!] Boot finish error: %v\n", err)
+		}
+		m.bootRunner.Close()
+		m.bootRunner = nil
+	}
 
 	// Clear loading indicator
 	if m.device != nil {
@@ -165,30 +341,85 @@ func (m *ScriptManager) Boot(ctx context.Context) error {
 	return nil
 }
 
+// findScripts lists every .lua file under dir, recursively, in lexical
+// order. Unreadable entries are skipped.
+func findScripts(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".lua" {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// startBackground starts a runner's background worker unless background
+// workers are disabled. When the worker limit is reached the start is queued
+// until a running worker exits.
+func (m *ScriptManager) startBackground(runner *ScriptRunner) {
+	m.mu.RLock()
+	slots := m.bgSlots
+	ctx := m.ctx
+	disabled := m.bgDisabled
+	m.mu.RUnlock()
+
+	if disabled {
+		return
+	}
+	if slots == nil {
+		fmt.Printf("[*] Starting background worker: %s\n", runner.ScriptName)
+		runner.StartBackground(ctx)
+		return
+	}
+
+	if len(slots) == cap(slots) {
+		fmt.Printf("[*] Queued background worker: %s (limit %d reached)\n", runner.ScriptName, cap(slots))
+	}
+	go func() {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		defer func() { <-slots }()
+		// A slot can free up as Shutdown cancels ctx; don't start then
+		if ctx.Err() != nil {
+			return
+		}
+		fmt.Printf("[*] Starting background worker: %s\n", runner.ScriptName)
+		runner.StartBackground(ctx)
+		runner.waitBackground()
+	}()
+}
+
 // runBootAnimation runs the optional _boot.lua animation script.
 func (m *ScriptManager) runBootAnimation() {
 	if m.bootScriptPath == "" {
 		return
 	}
 
-	runner, err := NewScriptRunner(m.bootScriptPath, m.device, m.configDir)
+	runner, err := NewScriptRunner(m.bootScriptPath, m.device, m.configDir, m.logger, m)
 	if err != nil {
 		fmt.Printf("[!] Boot animation failed: %v\n", err)
 		return
 	}
-	defer runner.Close()
 
-	// Call the boot function from the module table
-	if runner.module == nil {
-		return
-	}
-	fn := runner.module.RawGetString("boot")
-	if fn.Type() != lua.LTFunction {
-		return
+	// Keep the runner open while scripts load if it animates or wants
+	// progress updates
+	if runner.module != nil && (runner.module.RawGetString("progress").Type() == lua.LTFunction ||
+		runner.module.RawGetString("frame").Type() == lua.LTFunction) {
+		m.bootRunner = runner
+	} else {
+		defer runner.Close()
 	}
 
-	runner.L.Push(fn)
-	if err := runner.L.PCall(0, 0, nil); err != nil {
+	// Call boot() from the table the script returned, through the runner's
+	// VM lock like the frame() and progress() hooks.
+	if err := runner.callModuleFunc("boot"); err != nil {
 		fmt.Printf("[!] Boot animation error: %v\n", err)
 	}
 }
@@ -208,13 +439,7 @@ func (m *ScriptManager) StartPassiveLoop() {
 
 // passiveLoop runs passive functions at the configured FPS.
 func (m *ScriptManager) passiveLoop() {
-	fps := m.passiveFPS
-	if fps <= 0 {
-		fps = DefaultPassiveFPS
-	}
-	interval := time.Second / time.Duration(fps)
-
-	ticker := time.NewTicker(interval)
+	ticker := time.NewTicker(m.passiveInterval())
 	defer ticker.Stop()
 
 	for {
@@ -225,8 +450,15 @@ func (m *ScriptManager) passiveLoop() {
 			m.mu.Unlock()
 			return
 		case <-ticker.C:
+			if m.rendering.Load() {
+				// The page is changing; next tick works from the new one
+				continue
+			}
+			start := time.Now()
 			m.runPassiveUpdate()
 			m.runTogglePassive() // always runs, even when no content scripts are visible
+			m.recordPassiveTick(time.Since(start))
+			m.unloadIdle()
 
 			// Process batched updates (limit to prevent blocking)
 			m.processBatchedUpdates(5) // Process up to 5 updates per tick
@@ -257,11 +489,15 @@ func (m *ScriptManager) runPassiveUpdate() {
 			runner := m.runners[scriptPath]
 			m.mu.RUnlock()
 
-			if runner == nil || !runner.HasPassive() {
+			if runner == nil || !runner.HasPassive() || !m.ownsKey(scriptPath, keyIndex) || !m.passiveDue(scriptPath) {
 				return
 			}
 
-			appearance, err := runner.RunPassive(keyIndex)
+			start := time.Now()
+			appearance, err := runner.RunPassive(m.passiveContext(scriptPath, keyIndex))
+			d := time.Since(start)
+			m.recordPassive(scriptPath, d)
+			m.checkSlowPassive(scriptPath, d)
 			if err != nil {
 				return
 			}
@@ -277,8 +513,14 @@ func (m *ScriptManager) runPassiveUpdate() {
 
 // runPassiveUpdate calls passive() on all visible content-key scripts concurrently. adds an update to the batch queue.
 func (m *ScriptManager) batchUpdate(scriptPath string, appearance *KeyAppearance) {
+	if m.rendering.Load() {
+		return // computed for the page being replaced
+	}
+	m.applyAnimation(scriptPath, appearance)
+
 	m.mu.Lock()
 	m.passiveBatch[scriptPath] = appearance
+	m.lastImage[scriptPath] = appearance.Image
 	m.mu.Unlock()
 }
 
@@ -294,7 +536,9 @@ func (m *ScriptManager) processBatchedUpdates(maxUpdates int) {
 	callback := m.onKeyUpdate
 	m.mu.Unlock()
 
-	if callback == nil {
+	// Mid-render the batch is for the old page: drop it rather than wait
+	// for the render and draw stale appearances.
+	if callback == nil || m.rendering.Load() {
 		return
 	}
 
@@ -305,13 +549,7 @@ func (m *ScriptManager) processBatchedUpdates(maxUpdates int) {
 			break
 		}
 
-		// Find the key index for this script
-		m.mu.RLock()
-		keyIndex, visible := m.visibleScripts[scriptPath]
-		m.mu.RUnlock()
-
-		if visible {
-			callback(keyIndex, appearance)
+		if m.deliverUpdate(scriptPath, appearance, callback) {
 			processed++
 		}
 	}
@@ -329,35 +567,68 @@ func (m *ScriptManager) processBatchedUpdates(maxUpdates int) {
 }
 
 // SetVisibleScripts updates which scripts are currently visible on the display.
-// Map is scriptPath -> keyIndex
+// Map is scriptPath -> keyIndex. Visible scripts that are not loaded are loaded.
 func (m *ScriptManager) SetVisibleScripts(scripts map[string]int) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	m.visibleScripts = make(map[string]int)
 	for k, v := range scripts {
 		m.visibleScripts[k] = v
+		m.lastVisible[k] = time.Now()
+	}
+	m.mu.Unlock()
+
+	// Load scripts that were deferred at boot or have been unloaded
+	for path := range scripts {
+		m.ensureRunner(path)
 	}
 }
 
-// GetRunner returns the runner for a script path.
+// GetRunner returns the runner for a script path, loading it first if it
+// has not been loaded yet.
 func (m *ScriptManager) GetRunner(scriptPath string) *ScriptRunner {
+	return m.ensureRunner(scriptPath)
+}
+
+// Status returns the health of every loaded script, sorted by path.
+func (m *ScriptManager) Status() []ScriptStatus {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.runners[scriptPath]
+	runners := make([]*ScriptRunner, 0, len(m.runners))
+	for _, runner := range m.runners {
+		runners = append(runners, runner)
+	}
+	m.mu.RUnlock()
+
+	statuses := make([]ScriptStatus, 0, len(runners))
+	for _, runner := range runners {
+		statuses = append(statuses, runner.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses
 }
 
 // IsUsableScript returns true if the script has been loaded and defines at least
-// one of background / passive / trigger. Used by the Navigator to filter the
-// button list so that helper-only scripts are not shown as buttons.
+// one of the buttonEntryPoints (background, passive, trigger, ...). Used by
+// the Navigator to filter the button list so that helper-only scripts are
+// not shown as buttons. Scripts that failed to load count as usable so their
+// error tile is shown.
 func (m *ScriptManager) IsUsableScript(scriptPath string) bool {
 	m.mu.RLock()
 	runner := m.runners[scriptPath]
+	info, known := m.scripts[scriptPath]
 	m.mu.RUnlock()
 	if runner == nil {
-		return false
+		// Not loaded (yet, or any more): use what was last learned about it
+		return known && (info.usable || info.loadErr != nil)
 	}
-	return runner.HasBackground() || runner.HasPassive() || runner.HasTrigger()
+	return runner.usable()
+}
+
+// LoadError returns why a script failed to load, or nil if it loaded (or
+// has not been tried yet).
+func (m *ScriptManager) LoadError(scriptPath string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.scripts[scriptPath].loadErr
 }
 
 // SetToggleScripts registers the .directory.lua script (and physical key indices)
@@ -444,21 +715,66 @@ func (m *ScriptManager) runTogglePassive() {
 		if err != nil || ap == nil {
 			continue
 		}
-		cb(e.key, ap)
+		m.deliverToggleUpdate(e.script, e.key, ap, cb)
+	}
+}
+
+// TriggerScript executes the trigger function for a script, passing the
+// pressed key and event kind through to Lua as trigger(state, ctx).
+func (m *ScriptManager) TriggerScript(scriptPath string, keyIndex int, event TriggerEvent) error {
+	m.mu.Lock()
+	m.lastPress[scriptPath] = time.Now()
+	m.mu.Unlock()
+
+	runner := m.ensureRunner(scriptPath)
+	if runner == nil {
+		return fmt.Errorf("script not loaded: %s", scriptPath)
 	}
+
+	tc := TriggerContext{Key: keyIndex, Event: event, Pressed: true}
+	tc.Col, tc.Row = m.keyPosition(keyIndex)
+	return runner.RunTrigger(tc)
 }
 
-// TriggerScript executes the trigger function for a script.
-func (m *ScriptManager) TriggerScript(scriptPath string) error {
+// ReleaseScript runs a script's trigger_release(state, ctx) for a key let go
+// after its press was passed to TriggerScript, and reports whether the hook
+// ran. Scripts without the hook, or no longer loaded, are left alone: a
+// release never loads a script.
+func (m *ScriptManager) ReleaseScript(scriptPath string, keyIndex int) (bool, error) {
 	m.mu.RLock()
 	runner := m.runners[scriptPath]
 	m.mu.RUnlock()
+	if runner == nil || !runner.HasTriggerRelease() {
+		return false, nil
+	}
 
-	if runner == nil {
-		return fmt.Errorf("script not loaded: %s", scriptPath)
+	tc := TriggerContext{Key: keyIndex, Event: EventRelease}
+	tc.Col, tc.Row = m.keyPosition(keyIndex)
+	return true, runner.RunTriggerRelease(tc)
+}
+
+// keyPosition returns the grid column and row of a key index.
+func (m *ScriptManager) keyPosition(keyIndex int) (col, row int) {
+	if m.device == nil {
+		return 0, 0
 	}
+	return m.device.KeyToCoord(keyIndex)
+}
 
-	return runner.RunTrigger()
+// passiveContext builds the ctx table contents for a passive() call.
+func (m *ScriptManager) passiveContext(scriptPath string, keyIndex int) PassiveContext {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	pc := PassiveContext{
+		Key:     keyIndex,
+		Pressed: time.Since(m.lastPress[scriptPath]) < RecentPressWindow,
+		T1:      m.t1Script != "",
+		T2:      m.t2Script != "",
+	}
+	_, pc.Visible = m.visibleScripts[scriptPath]
+	pc.Col, pc.Row = m.keyPosition(keyIndex)
+	return pc
 }
 
 // RefreshScript immediately runs passive() for one script and pushes the result
@@ -471,16 +787,16 @@ func (m *ScriptManager) RefreshScript(scriptPath string) {
 	callback := m.onKeyUpdate
 	m.mu.RUnlock()
 
-	if runner == nil || !visible || callback == nil || !runner.HasPassive() {
+	if runner == nil || !visible || callback == nil || !runner.HasPassive() || !m.ownsKey(scriptPath, keyIndex) {
 		return
 	}
 
-	appearance, err := runner.RunPassive(keyIndex)
+	appearance, err := runner.RunPassive(m.passiveContext(scriptPath, keyIndex))
 	if err != nil || appearance == nil {
 		return
 	}
 
-	callback(keyIndex, appearance)
+	m.deliverUpdate(scriptPath, appearance, callback)
 }
 
 // requestRefresh is called when a script wants a display refresh.
@@ -498,11 +814,17 @@ func (m *ScriptManager) Shutdown() {
 		m.cancel()
 	}
 
+	// Claims are released as runners close; nothing should be redrawn now
+	m.claimsMu.Lock()
+	m.onKeyReleased = nil
+	m.claimsMu.Unlock()
+
 	// Close all runners
 	for path, runner := range m.runners {
 		runner.Close()
 		delete(m.runners, path)
 	}
+	m.images.Clear()
 	m.mu.Unlock()
 
 	fmt.Println("[*] Script manager shutdown complete")
@@ -0,0 +1,196 @@
+package scripting
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// countRunning returns how many of the manager's scripts have a background
+// worker running.
+func countRunning(m *ScriptManager) int {
+	n := 0
+	for _, s := range m.Status() {
+		if s.BackgroundRunning {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBackgroundWorkerLimit(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 6; i++ {
+		writeScript(t, dir, fmt.Sprintf("bg%d.lua", i), `
+			local system = require("system")
+			local script = {}
+			function script.background(state)
+				while true do system.sleep(20) end
+			end
+			return script
+		`)
+	}
+
+	m := NewScriptManager(nil, dir, 0)
+	m.SetMaxBackgroundWorkers(2)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	waitRunning := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for countRunning(m) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d background workers running, want %d", countRunning(m), want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitRunning(2)
+	for i := 0; i < 20; i++ {
+		if n := countRunning(m); n > 2 {
+			t.Fatalf("%d background workers running, limit is 2", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Stopping a worker frees its slot for one of the queued scripts
+	m.mu.RLock()
+	var runners []*ScriptRunner
+	for _, r := range m.runners {
+		runners = append(runners, r)
+	}
+	m.mu.RUnlock()
+	var stopped *ScriptRunner
+	for _, r := range runners {
+		if r.Status().BackgroundRunning {
+			stopped = r
+			break
+		}
+	}
+	stopped.StopBackground()
+	stopped.waitBackground()
+	waitRunning(2)
+
+	// Queued workers give up once the manager shuts down
+	m.Shutdown()
+	time.Sleep(100 * time.Millisecond)
+	for _, r := range runners {
+		if r.Status().BackgroundRunning {
+			t.Errorf("%s: background worker running after Shutdown", r.ScriptName)
+		}
+	}
+}
+
+func TestStatusAggregation(t *testing.T) {
+	dir := t.TempDir()
+	writeScript(t, dir, "a_running.lua", `
+		local system = require("system")
+		local script = {}
+		function script.background(state)
+			while true do system.sleep(20) end
+		end
+		return script
+	`)
+	writeScript(t, dir, "b_failing.lua", `
+		RESTART_POLICY = "never"
+		local script = {}
+		function script.background(state)
+			error("boom")
+		end
+		return script
+	`)
+	buttonPath := writeScript(t, dir, "c_button.lua", `
+		local script = {}
+		function script.passive(state) return {} end
+		function script.trigger(state) end
+		return script
+	`)
+
+	m := NewScriptManager(nil, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+	// Button scripts are loaded when first shown
+	m.SetVisibleScripts(map[string]int{buttonPath: 0})
+
+	var statuses []ScriptStatus
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		statuses = m.Status()
+		if len(statuses) == 3 && statuses[0].BackgroundRunning && statuses[1].Restarts > 0 && !statuses[1].BackgroundRunning {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status never settled: %+v", statuses)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	running, failing, button := statuses[0], statuses[1], statuses[2]
+	for i, name := range []string{"a_running", "b_failing", "c_button"} {
+		if statuses[i].Name != name {
+			t.Errorf("status %d is %q, want %q (sorted by path)", i, statuses[i].Name, name)
+		}
+	}
+	if !running.HasBackground || running.Restarts != 0 || running.LastError != nil {
+		t.Errorf("running worker: %+v", running)
+	}
+	if failing.Restarts != 1 || failing.LastError == nil || !strings.Contains(failing.LastError.Error(), "boom") {
+		t.Errorf("failing worker: %+v", failing)
+	}
+	if button.HasBackground || button.BackgroundRunning || !button.HasPassive || !button.HasTrigger {
+		t.Errorf("button script: %+v", button)
+	}
+}
+
+func TestBackgroundDisabled(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		dir := t.TempDir()
+		path := writeScript(t, dir, "worker.lua", `
+			local system = require("system")
+			local script = {}
+			function script.background(state)
+				state.ran = true
+				while true do system.sleep(20) end
+			end
+			function script.passive(key, state) return { text = "idle" } end
+			function script.trigger(state) state.pressed = true end
+			return script
+		`)
+
+		m := NewScriptManager(nil, dir, 0)
+		m.SetBackgroundEnabled(false)
+		m.SetLazyLoad(lazy)
+		if err := m.Boot(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		r := m.GetRunner(path)
+		if r == nil {
+			t.Fatalf("lazy=%v: script did not load", lazy)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		if s := r.Status(); !s.HasBackground || s.BackgroundRunning {
+			t.Errorf("lazy=%v: status = %+v, want a background() that never started", lazy, s)
+		}
+		if r.state.RawGetString("ran") != lua.LNil {
+			t.Errorf("lazy=%v: background() ran", lazy)
+		}
+		// Passive and trigger still work
+		if ap, err := r.RunPassive(PassiveContext{}); err != nil || ap == nil || ap.Text != "idle" {
+			t.Errorf("lazy=%v: passive = %v, %v", lazy, ap, err)
+		}
+		if err := m.TriggerScript(path, 0, EventTap); err != nil || r.state.RawGetString("pressed") != lua.LTrue {
+			t.Errorf("lazy=%v: trigger did not run: %v", lazy, err)
+		}
+		m.Shutdown()
+	}
+}
@@ -0,0 +1,103 @@
+package scripting
+
+// metrics.go – timings for performance tuning: how long page renders (the
+// span the render lock is held), passive ticks and each script's passive()
+// take. Exposed through Metrics.
+
+import (
+	"sync"
+	"time"
+)
+
+// Timing accumulates the durations of one kind of operation.
+type Timing struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+	Last  time.Duration
+}
+
+// Avg returns the mean duration, or 0 if nothing was recorded.
+func (t Timing) Avg() time.Duration {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Count)
+}
+
+func (t *Timing) add(d time.Duration) {
+	t.Count++
+	t.Total += d
+	t.Last = d
+	if d > t.Max {
+		t.Max = d
+	}
+}
+
+// Metrics is a snapshot of the manager's timings.
+type Metrics struct {
+	Render      Timing            // LockRender to UnlockRender
+	PassiveTick Timing            // One passive loop tick, all scripts
+	Passive     map[string]Timing // passive() calls, by script path
+}
+
+// metricsState is the manager's running Metrics.
+type metricsState struct {
+	mu          sync.Mutex
+	renderStart time.Time
+	render      Timing
+	passiveTick Timing
+	passive     map[string]Timing
+}
+
+// Metrics returns a copy of the timings recorded so far.
+func (m *ScriptManager) Metrics() Metrics {
+	s := &m.metrics
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := Metrics{
+		Render:      s.render,
+		PassiveTick: s.passiveTick,
+		Passive:     make(map[string]Timing, len(s.passive)),
+	}
+	for path, t := range s.passive {
+		out.Passive[path] = t
+	}
+	return out
+}
+
+// recordRenderStart marks the start of a render; recordRenderEnd adds its
+// duration. Both are called with renderMu held.
+func (m *ScriptManager) recordRenderStart() {
+	m.metrics.mu.Lock()
+	m.metrics.renderStart = time.Now()
+	m.metrics.mu.Unlock()
+}
+
+func (m *ScriptManager) recordRenderEnd() {
+	s := &m.metrics
+	s.mu.Lock()
+	s.render.add(time.Since(s.renderStart))
+	s.mu.Unlock()
+}
+
+// recordPassiveTick adds the duration of a passive loop tick.
+func (m *ScriptManager) recordPassiveTick(d time.Duration) {
+	s := &m.metrics
+	s.mu.Lock()
+	s.passiveTick.add(d)
+	s.mu.Unlock()
+}
+
+// recordPassive adds the duration of one passive() call of a script.
+func (m *ScriptManager) recordPassive(scriptPath string, d time.Duration) {
+	s := &m.metrics
+	s.mu.Lock()
+	if s.passive == nil {
+		s.passive = make(map[string]Timing)
+	}
+	t := s.passive[scriptPath]
+	t.add(d)
+	s.passive[scriptPath] = t
+	s.mu.Unlock()
+}
@@ -0,0 +1,41 @@
+package scripting
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMetricsRecordTimings(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "slow.lua", `
+		local function passive()
+			local start = os.clock()
+			while os.clock() - start < 0.005 do end
+			return { text = "x" }
+		end
+		return { passive = passive }
+	`)
+	m := NewScriptManager(nil, dir, 0)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+	m.SetVisibleScripts(map[string]int{path: 1})
+
+	m.runPassiveUpdate()
+	m.runPassiveUpdate()
+
+	m.LockRender()
+	time.Sleep(2 * time.Millisecond)
+	m.UnlockRender()
+
+	metrics := m.Metrics()
+	passive := metrics.Passive[path]
+	if passive.Count != 2 || passive.Max < 5*time.Millisecond || passive.Avg() == 0 {
+		t.Errorf("passive timing = %+v, want 2 calls of at least 5ms", passive)
+	}
+	if metrics.Render.Count != 1 || metrics.Render.Last < 2*time.Millisecond {
+		t.Errorf("render timing = %+v, want 1 render of at least 2ms", metrics.Render)
+	}
+}
@@ -0,0 +1,166 @@
+package modules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// namedColors are exposed as {r, g, b} tables on the color module
+// (e.g. color.red) so they can be passed straight to appearance tables.
+var namedColors = map[string][3]int{
+	"black":   {0, 0, 0},
+	"white":   {255, 255, 255},
+	"red":     {255, 0, 0},
+	"green":   {0, 255, 0},
+	"blue":    {0, 0, 255},
+	"yellow":  {255, 255, 0},
+	"cyan":    {0, 255, 255},
+	"magenta": {255, 0, 255},
+	"orange":  {255, 136, 0},
+	"purple":  {128, 0, 128},
+	"gray":    {128, 128, 128},
+}
+
+// ColorModule provides colour parsing and conversion helpers to Lua scripts.
+type ColorModule struct{}
+
+// NewColorModule creates a new color module.
+func NewColorModule() *ColorModule {
+	return &ColorModule{}
+}
+
+// Loader returns the Lua module loader function.
+func (m *ColorModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"hex":  m.colorHex,
+		"hsv":  m.colorHSV,
+		"lerp": m.colorLerp,
+	})
+	for name, c := range namedColors {
+		mod.RawSetString(name, rgbTable(L, c[0], c[1], c[2]))
+	}
+	L.Push(mod)
+	return 1
+}
+
+// colorHex parses a "#rgb" or "#rrggbb" string (leading '#' optional).
+// Lua: color.hex(str) -> r, g, b | nil, err
+func (m *ColorModule) colorHex(L *lua.LState) int {
+	r, g, b, err := parseHexColor(L.CheckString(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LNumber(r))
+	L.Push(lua.LNumber(g))
+	L.Push(lua.LNumber(b))
+	return 3
+}
+
+// colorHSV converts hue (degrees, 0-360), saturation and value (0-1) to RGB.
+// Lua: color.hsv(h, s, v) -> r, g, b
+func (m *ColorModule) colorHSV(L *lua.LState) int {
+	r, g, b := hsvToRGB(
+		float64(L.CheckNumber(1)),
+		float64(L.CheckNumber(2)),
+		float64(L.CheckNumber(3)),
+	)
+	L.Push(lua.LNumber(r))
+	L.Push(lua.LNumber(g))
+	L.Push(lua.LNumber(b))
+	return 3
+}
+
+// colorLerp linearly interpolates between two {r, g, b} tables.
+// t is clamped to [0, 1]; 0 returns c1 and 1 returns c2.
+// Lua: color.lerp(c1, c2, t) -> {r, g, b}
+func (m *ColorModule) colorLerp(L *lua.LState) int {
+	c1 := tableToRGB(L.CheckTable(1))
+	c2 := tableToRGB(L.CheckTable(2))
+	t := math.Max(0, math.Min(1, float64(L.CheckNumber(3))))
+
+	var out [3]int
+	for i := range out {
+		out[i] = int(math.Round(float64(c1[i]) + (float64(c2[i])-float64(c1[i]))*t))
+	}
+	L.Push(rgbTable(L, out[0], out[1], out[2]))
+	return 1
+}
+
+// parseHexColor parses "#rgb" / "#rrggbb" (the '#' is optional).
+func parseHexColor(s string) (r, g, b int, err error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, 0, 0, fmt.Errorf("invalid hex colour %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hex colour %q", s)
+	}
+	return int(v >> 16 & 0xFF), int(v >> 8 & 0xFF), int(v & 0xFF), nil
+}
+
+// hsvToRGB converts HSV (h in degrees, s and v in 0-1) to 0-255 RGB.
+func hsvToRGB(h, s, v float64) (r, g, b int) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s = math.Max(0, math.Min(1, s))
+	v = math.Max(0, math.Min(1, v))
+
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	mm := v - c
+
+	var rf, gf, bf float64
+	switch {
+	case h < 60:
+		rf, gf, bf = c, x, 0
+	case h < 120:
+		rf, gf, bf = x, c, 0
+	case h < 180:
+		rf, gf, bf = 0, c, x
+	case h < 240:
+		rf, gf, bf = 0, x, c
+	case h < 300:
+		rf, gf, bf = x, 0, c
+	default:
+		rf, gf, bf = c, 0, x
+	}
+	return int(math.Round((rf + mm) * 255)),
+		int(math.Round((gf + mm) * 255)),
+		int(math.Round((bf + mm) * 255))
+}
+
+// tableToRGB reads a {r, g, b} array table, clamping each channel to 0-255.
+func tableToRGB(tbl *lua.LTable) [3]int {
+	return [3]int{
+		clampChannel(int(lua.LVAsNumber(tbl.RawGetInt(1)))),
+		clampChannel(int(lua.LVAsNumber(tbl.RawGetInt(2)))),
+		clampChannel(int(lua.LVAsNumber(tbl.RawGetInt(3)))),
+	}
+}
+
+// clampChannel limits a colour channel to 0-255 so out-of-range values
+// saturate instead of wrapping when converted to a byte.
+func clampChannel(v int) int {
+	return max(0, min(255, v))
+}
+
+// rgbTable builds a {r, g, b} array table.
+func rgbTable(L *lua.LState, r, g, b int) *lua.LTable {
+	tbl := L.NewTable()
+	tbl.RawSetInt(1, lua.LNumber(r))
+	tbl.RawSetInt(2, lua.LNumber(g))
+	tbl.RawSetInt(3, lua.LNumber(b))
+	return tbl
+}
@@ -0,0 +1,130 @@
+package modules
+
+import (
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runColor runs src with the color module loaded as "color".
+func runColor(t *testing.T, src string) *lua.LState {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	L.PreloadModule("color", NewColorModule().Loader)
+	if err := L.DoString(`local color = require("color")` + "\n" + src); err != nil {
+		t.Fatal(err)
+	}
+	return L
+}
+
+// rgbGlobal reads a {r, g, b} table global.
+func rgbGlobal(L *lua.LState, name string) [3]int {
+	tbl, ok := L.GetGlobal(name).(*lua.LTable)
+	if !ok {
+		return [3]int{-1, -1, -1}
+	}
+	return [3]int{int(lua.LVAsNumber(tbl.RawGetInt(1))), int(lua.LVAsNumber(tbl.RawGetInt(2))), int(lua.LVAsNumber(tbl.RawGetInt(3)))}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want [3]int
+		ok   bool
+	}{
+		{"#ff8800", [3]int{255, 136, 0}, true},
+		{"FF8800", [3]int{255, 136, 0}, true},
+		{"#f80", [3]int{255, 136, 0}, true},
+		{" #000 ", [3]int{0, 0, 0}, true},
+		{"#ffffff", [3]int{255, 255, 255}, true},
+		{"#ff88", [3]int{}, false},
+		{"#ff88001", [3]int{}, false},
+		{"#gg0000", [3]int{}, false},
+		{"#-10000", [3]int{}, false},
+		{"", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		r, g, b, err := parseHexColor(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseHexColor(%q) err = %v, want ok=%v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && [3]int{r, g, b} != tt.want {
+			t.Errorf("parseHexColor(%q) = %v, want %v", tt.in, [3]int{r, g, b}, tt.want)
+		}
+	}
+}
+
+func TestHSVToRGB(t *testing.T) {
+	tests := []struct {
+		h, s, v float64
+		want    [3]int
+	}{
+		{0, 1, 1, [3]int{255, 0, 0}},
+		{120, 1, 1, [3]int{0, 255, 0}},
+		{240, 1, 1, [3]int{0, 0, 255}},
+		{32, 1, 1, [3]int{255, 136, 0}},
+		{0, 0, 1, [3]int{255, 255, 255}},
+		{200, 0.5, 0, [3]int{0, 0, 0}},
+		// Out of range: hue wraps, saturation and value clamp
+		{360, 1, 1, [3]int{255, 0, 0}},
+		{480, 1, 1, [3]int{0, 255, 0}},
+		{-120, 1, 1, [3]int{0, 0, 255}},
+		{0, 2, 1, [3]int{255, 0, 0}},
+		{0, -1, 1, [3]int{255, 255, 255}},
+		{120, 1, 5, [3]int{0, 255, 0}},
+		{120, 1, -5, [3]int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		r, g, b := hsvToRGB(tt.h, tt.s, tt.v)
+		if [3]int{r, g, b} != tt.want {
+			t.Errorf("hsvToRGB(%v, %v, %v) = %v, want %v", tt.h, tt.s, tt.v, [3]int{r, g, b}, tt.want)
+		}
+	}
+}
+
+func TestColorModuleRoundTrip(t *testing.T) {
+	L := runColor(t, `
+		-- hsv -> hex string -> hex() gives the same colour back
+		local r, g, b = color.hsv(32, 1, 1)
+		hex = string.format("#%02x%02x%02x", r, g, b)
+		local r2, g2, b2 = color.hex(hex)
+		back = {r2, g2, b2}
+		orange = color.orange
+		bad, bad_err = color.hex("#12345")
+	`)
+	if hex := L.GetGlobal("hex").String(); hex != "#ff8800" {
+		t.Errorf("hsv(32, 1, 1) as hex = %s, want #ff8800", hex)
+	}
+	if got := rgbGlobal(L, "back"); got != [3]int{255, 136, 0} || got != rgbGlobal(L, "orange") {
+		t.Errorf("hex round trip = %v, want color.orange %v", got, rgbGlobal(L, "orange"))
+	}
+	if L.GetGlobal("bad") != lua.LNil || L.GetGlobal("bad_err") == lua.LNil {
+		t.Errorf("hex(#12345) = %v, %v; want nil, err", L.GetGlobal("bad"), L.GetGlobal("bad_err"))
+	}
+}
+
+func TestColorLerp(t *testing.T) {
+	L := runColor(t, `
+		start = color.lerp(color.black, color.white, 0)
+		finish = color.lerp(color.black, color.white, 1)
+		mid = color.lerp({0, 100, 200}, {100, 200, 0}, 0.5)
+		before = color.lerp(color.red, color.blue, -1)
+		after = color.lerp(color.red, color.blue, 3)
+		wild = color.lerp({-50, 300, 128}, {-50, 300, 128}, 0.5)
+	`)
+	tests := map[string][3]int{
+		"start":  {0, 0, 0},
+		"finish": {255, 255, 255},
+		"mid":    {50, 150, 100},
+		"before": {255, 0, 0},
+		"after":  {0, 0, 255},
+		"wild":   {0, 255, 128},
+	}
+	for name, want := range tests {
+		if got := rgbGlobal(L, name); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
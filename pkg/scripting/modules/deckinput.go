@@ -0,0 +1,163 @@
+package modules
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// inputQueueSize bounds the dial and touch events waiting for one script.
+const inputQueueSize = 64
+
+// InputHooks routes dial and touch strip events to scripts. deliver must
+// not block; once cancel returns it is never called again.
+type InputHooks interface {
+	OnDial(dial int, deliver func(streamdeck.DialEvent)) (cancel func())
+	OnTouch(deliver func(streamdeck.TouchEvent)) (cancel func())
+}
+
+// deckInput is the on_dial / on_touch state of a StreamDeckModule.
+type deckInput struct {
+	hooks  InputHooks   // nil disables on_dial and on_touch
+	invoke CallbackFunc // runs hooks on the owning VM
+
+	mu      sync.Mutex
+	queue   chan func() // created by the first hook
+	cancels []func()
+	closed  bool
+	live    atomic.Int32 // hooks not yet removed
+}
+
+// SetInput enables on_dial and on_touch. Hooks are registered with hooks
+// and their Lua callbacks run through invoke.
+func (m *StreamDeckModule) SetInput(hooks InputHooks, invoke CallbackFunc) {
+	m.input.hooks = hooks
+	m.input.invoke = invoke
+}
+
+// sdOnDial calls fn(delta, pressed) whenever dial i is turned (delta is the
+// number of detents, positive clockwise) or pushed / released (delta 0).
+// Lua: streamdeck.on_dial(i, fn) -> cancel | nil, err
+func (m *StreamDeckModule) sdOnDial(L *lua.LState) int {
+	dial := L.CheckInt(1)
+	fn := L.CheckFunction(2)
+	if m.device != nil && (dial < 0 || dial >= m.device.Model.Dials) {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("dial %d out of range (%d dials)", dial, m.device.Model.Dials)))
+		return 2
+	}
+	return m.input.hook(L, func(enqueue func(func())) func() {
+		return m.input.hooks.OnDial(dial, func(ev streamdeck.DialEvent) {
+			enqueue(func() {
+				m.input.invoke(fn, lua.LNumber(ev.Delta), lua.LBool(ev.Pressed))
+			})
+		})
+	})
+}
+
+// sdOnTouch calls fn(gesture, x, y, end_x, end_y) for every touch on the
+// strip. gesture is "tap", "long" or "swipe"; end_x / end_y differ from
+// x / y only for swipes.
+// Lua: streamdeck.on_touch(fn) -> cancel | nil, err
+func (m *StreamDeckModule) sdOnTouch(L *lua.LState) int {
+	fn := L.CheckFunction(1)
+	if m.device != nil && !m.device.Model.TouchStrip {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(m.device.Model.Name + " has no touch strip"))
+		return 2
+	}
+	return m.input.hook(L, func(enqueue func(func())) func() {
+		return m.input.hooks.OnTouch(func(ev streamdeck.TouchEvent) {
+			enqueue(func() {
+				m.input.invoke(fn, lua.LString(ev.Gesture),
+					lua.LNumber(ev.X), lua.LNumber(ev.Y),
+					lua.LNumber(ev.EndX), lua.LNumber(ev.EndY))
+			})
+		})
+	})
+}
+
+// hook registers a hook through register, which receives the function
+// queueing work for this script's VM, and pushes its cancel function.
+func (in *deckInput) hook(L *lua.LState, register func(enqueue func(func())) func()) int {
+	if in.hooks == nil || in.invoke == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("dial and touch input are not available here"))
+		return 2
+	}
+
+	in.mu.Lock()
+	if in.closed {
+		in.mu.Unlock()
+		L.Push(lua.LNil)
+		L.Push(lua.LString("script is closing"))
+		return 2
+	}
+	if in.queue == nil {
+		in.queue = make(chan func(), inputQueueSize)
+		go in.deliverLoop(in.queue)
+	}
+	queue := in.queue
+	in.mu.Unlock()
+
+	cancel := register(func(call func()) {
+		select {
+		case queue <- call:
+		default:
+			fmt.Println("[!] streamdeck: dropped input event, script is not keeping up")
+		}
+	})
+
+	in.live.Add(1)
+	var once sync.Once
+	remove := func() {
+		once.Do(func() {
+			cancel()
+			in.live.Add(-1)
+		})
+	}
+	in.mu.Lock()
+	in.cancels = append(in.cancels, remove)
+	in.mu.Unlock()
+
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		remove()
+		return 0
+	}))
+	return 1
+}
+
+// HasInputHooks reports whether the script has an on_dial or on_touch hook
+// that has not been removed.
+func (m *StreamDeckModule) HasInputHooks() bool {
+	return m.input.live.Load() > 0
+}
+
+// deliverLoop runs queued hook calls one at a time until the queue closes.
+func (in *deckInput) deliverLoop(queue <-chan func()) {
+	for call := range queue {
+		call()
+	}
+}
+
+// Close removes every dial and touch hook of the module and stops
+// delivering queued events. The owning runner calls it when it closes.
+func (m *StreamDeckModule) Close() {
+	in := &m.input
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.closed {
+		return
+	}
+	in.closed = true
+	for _, cancel := range in.cancels {
+		cancel()
+	}
+	in.cancels = nil
+	if in.queue != nil {
+		close(in.queue)
+	}
+}
@@ -0,0 +1,164 @@
+package modules
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/merith-tk/nomad/pkg/lualib"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// eventQueueSize bounds the events waiting for one script's callbacks.
+// Further events are dropped so a stuck script cannot block publishers.
+const eventQueueSize = 256
+
+// EventBus carries events between scripts. Data is plain Go values (see
+// lualib.ToGo) so it never references another script's Lua state.
+type EventBus interface {
+	Publish(topic string, data interface{})
+	// Subscribe calls deliver for every event on topic until cancel is
+	// called; once cancel returns, deliver is never called again. deliver
+	// must not block.
+	Subscribe(topic string, deliver func(data interface{})) (cancel func())
+}
+
+// queuedEvent is an event waiting to run on the subscriber's VM.
+type queuedEvent struct {
+	fn    *lua.LFunction
+	topic string
+	data  interface{}
+}
+
+// EventsModule lets scripts publish and subscribe to topics shared by every
+// script. Callbacks run on the subscribing runner's VM, in publish order.
+type EventsModule struct {
+	bus    EventBus     // nil disables the module
+	invoke CallbackFunc // runs callbacks on the owning VM
+
+	mu      sync.Mutex
+	L       *lua.LState
+	queue   chan queuedEvent // created by the first subscribe
+	cancels []func()
+	closed  bool
+	live    atomic.Int32 // subscriptions not yet cancelled
+}
+
+// NewEventsModule creates an events module publishing to bus. Callbacks are
+// delivered through invoke.
+func NewEventsModule(bus EventBus, invoke CallbackFunc) *EventsModule {
+	return &EventsModule{bus: bus, invoke: invoke}
+}
+
+// Loader returns the Lua module loader function.
+func (m *EventsModule) Loader(L *lua.LState) int {
+	m.mu.Lock()
+	m.L = L
+	m.mu.Unlock()
+
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"publish":   m.eventsPublish,
+		"subscribe": m.eventsSubscribe,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// eventsPublish sends data (any JSON-like value; functions and userdata
+// become strings) to every subscriber of topic, including this script.
+// Lua: events.publish(topic [, data])
+func (m *EventsModule) eventsPublish(L *lua.LState) int {
+	topic := L.CheckString(1)
+	if m.bus != nil {
+		m.bus.Publish(topic, lualib.ToGo(L.Get(2)))
+	}
+	return 0
+}
+
+// eventsSubscribe calls fn(data, topic) for every event published on topic.
+// It returns a function that cancels the subscription.
+// Lua: events.subscribe(topic, fn) -> unsubscribe | nil, err
+func (m *EventsModule) eventsSubscribe(L *lua.LState) int {
+	topic := L.CheckString(1)
+	fn := L.CheckFunction(2)
+
+	if m.bus == nil || m.invoke == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("events are not available here"))
+		return 2
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		L.Push(lua.LNil)
+		L.Push(lua.LString("script is closing"))
+		return 2
+	}
+	if m.queue == nil {
+		m.queue = make(chan queuedEvent, eventQueueSize)
+		go m.deliverLoop(m.queue)
+	}
+	queue := m.queue
+	m.mu.Unlock()
+
+	cancel := m.bus.Subscribe(topic, func(data interface{}) {
+		select {
+		case queue <- queuedEvent{fn: fn, topic: topic, data: data}:
+		default:
+			fmt.Printf("[!] events: dropped %q, subscriber is not keeping up\n", topic)
+		}
+	})
+
+	m.live.Add(1)
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			cancel()
+			m.live.Add(-1)
+		})
+	}
+	m.mu.Lock()
+	m.cancels = append(m.cancels, unsubscribe)
+	m.mu.Unlock()
+
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		unsubscribe()
+		return 0
+	}))
+	return 1
+}
+
+// Subscribed reports whether the script has a subscription that has not
+// been cancelled.
+func (m *EventsModule) Subscribed() bool {
+	return m.live.Load() > 0
+}
+
+// deliverLoop runs queued callbacks one at a time until the queue closes.
+func (m *EventsModule) deliverLoop(queue <-chan queuedEvent) {
+	for ev := range queue {
+		m.mu.Lock()
+		L := m.L
+		m.mu.Unlock()
+		m.invoke(ev.fn, lualib.FromGo(L, ev.data), lua.LString(ev.topic))
+	}
+}
+
+// Close cancels every subscription made through this module and stops
+// delivering queued events. The owning runner calls it when it closes.
+func (m *EventsModule) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.closed = true
+	for _, cancel := range m.cancels {
+		cancel()
+	}
+	m.cancels = nil
+	if m.queue != nil {
+		close(m.queue)
+	}
+}
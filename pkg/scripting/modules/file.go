@@ -1,41 +1,72 @@
 package modules
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/merith-tk/nomad/pkg/lualib"
 	lua "github.com/yuin/gopher-lua"
 )
 
-// checkFileAccess returns true if path is within the config directory.
-func checkFileAccess(path string, L *lua.LState) bool {
-	configDir := L.GetGlobal("CONFIG_DIR").String()
-	if configDir == "" {
+// checkAccess returns true if path is within the config directory. The
+// directory is held in Go, so scripts cannot widen it by reassigning the
+// CONFIG_DIR global.
+func (m *FileModule) checkAccess(path string) bool {
+	if m.configDir == "" {
 		return true
 	}
-	return filepath.HasPrefix(filepath.Clean(path), filepath.Clean(configDir))
+	return filepath.HasPrefix(filepath.Clean(path), filepath.Clean(m.configDir))
 }
 
 // FileModule provides file system operations for Lua scripts.
-type FileModule struct{}
+type FileModule struct {
+	configDir string       // file access is confined to this directory; "" allows all
+	invoke    CallbackFunc // delivers file.watch callbacks; nil disables watch
+
+	watchMu     sync.Mutex
+	watcher     *fsnotify.Watcher // created by the first file.watch
+	watches     []*fileWatch
+	pending     map[string]*time.Timer // debounce timers by watch and path
+	watchDone   chan struct{}
+	watchClosed bool
+}
 
-// NewFileModule creates a new file module.
-func NewFileModule() *FileModule {
-	return &FileModule{}
+// NewFileModule creates a new file module confined to configDir ("" allows
+// any path). invoke runs file.watch callbacks on the script's VM; pass nil
+// to disable watching.
+func NewFileModule(configDir string, invoke CallbackFunc) *FileModule {
+	return &FileModule{configDir: configDir, invoke: invoke}
 }
 
 // Loader returns the Lua module loader function.
 func (m *FileModule) Loader(L *lua.LState) int {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
-		"read":   m.fileRead,
-		"write":  m.fileWrite,
-		"append": m.fileAppend,
-		"exists": m.fileExists,
-		"mkdir":  m.fileMkdir,
-		"list":   m.fileList,
-		"remove": m.fileRemove,
-		"size":   m.fileSize,
-		"is_dir": m.fileIsDir,
+		"read":         m.fileRead,
+		"read_lines":   m.fileReadLines,
+		"read_json":    m.fileReadJSON,
+		"write":        m.fileWrite,
+		"write_atomic": m.fileWriteAtomic,
+		"append":       m.fileAppend,
+		"exists":       m.fileExists,
+		"mkdir":        m.fileMkdir,
+		"list":         m.fileList,
+		"glob":         m.fileGlob,
+		"walk":         m.fileWalk,
+		"remove":       m.fileRemove,
+		"copy":         m.fileCopy,
+		"move":         m.fileMove,
+		"rename":       m.fileRename,
+		"size":         m.fileSize,
+		"is_dir":       m.fileIsDir,
+		"watch":        m.fileWatch,
 	})
 	L.Push(mod)
 	return 1
@@ -45,7 +76,7 @@ func (m *FileModule) fileRead(L *lua.LState) int {
 	path := L.CheckString(1)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LNil)
 		L.Push(lua.LString("access denied"))
 		return 2
@@ -63,18 +94,87 @@ func (m *FileModule) fileRead(L *lua.LState) int {
 	return 2
 }
 
+// fileReadLines reads a file into an array of lines without their line
+// endings (\n or \r\n). A trailing newline does not add an empty line.
+// Lua: file.read_lines(path) -> table, err
+func (m *FileModule) fileReadLines(L *lua.LState) int {
+	path := L.CheckString(1)
+
+	if !m.checkAccess(path) {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	tbl := L.NewTable()
+	if text != "" || len(data) > 0 {
+		for i, line := range strings.Split(text, "\n") {
+			tbl.RawSetInt(i+1, lua.LString(strings.TrimSuffix(line, "\r")))
+		}
+	}
+	L.Push(tbl)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// fileReadJSON reads and decodes a JSON file.
+// Lua: file.read_json(path) -> value, err
+func (m *FileModule) fileReadJSON(L *lua.LState) int {
+	path := L.CheckString(1)
+
+	if !m.checkAccess(path) {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	v, err := lualib.DecodeJSON(L, data)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(path + ": " + err.Error()))
+		return 2
+	}
+	L.Push(v)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// fileWrite writes content to path, replacing it. With atomic set the write
+// goes through a temporary file (see file.write_atomic).
+// Lua: file.write(path, content [, atomic]) -> ok, err
 func (m *FileModule) fileWrite(L *lua.LState) int {
 	path := L.CheckString(1)
 	content := L.CheckString(2)
+	atomic := L.OptBool(3, false)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("access denied"))
 		return 2
 	}
 
-	err := os.WriteFile(path, []byte(content), 0644)
+	var err error
+	if atomic {
+		err = writeAtomic(path, []byte(content))
+	} else {
+		err = os.WriteFile(path, []byte(content), 0644)
+	}
 	if err != nil {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString(err.Error()))
@@ -86,12 +186,36 @@ func (m *FileModule) fileWrite(L *lua.LState) int {
 	return 2
 }
 
+// fileWriteAtomic writes content to a temporary file in the same directory
+// and renames it over path, so an interrupted write never leaves a
+// truncated file behind.
+// Lua: file.write_atomic(path, content) -> ok, err
+func (m *FileModule) fileWriteAtomic(L *lua.LState) int {
+	path := L.CheckString(1)
+	content := L.CheckString(2)
+
+	if !m.checkAccess(path) {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	if err := writeAtomic(path, []byte(content)); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
 func (m *FileModule) fileAppend(L *lua.LState) int {
 	path := L.CheckString(1)
 	content := L.CheckString(2)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("access denied"))
 		return 2
@@ -121,7 +245,7 @@ func (m *FileModule) fileExists(L *lua.LState) int {
 	path := L.CheckString(1)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		return 1
 	}
@@ -135,7 +259,7 @@ func (m *FileModule) fileMkdir(L *lua.LState) int {
 	path := L.CheckString(1)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("access denied"))
 		return 2
@@ -157,7 +281,7 @@ func (m *FileModule) fileList(L *lua.LState) int {
 	path := L.CheckString(1)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LNil)
 		L.Push(lua.LString("access denied"))
 		return 2
@@ -172,16 +296,7 @@ func (m *FileModule) fileList(L *lua.LState) int {
 
 	tbl := L.NewTable()
 	for i, entry := range entries {
-		entryTbl := L.NewTable()
-		entryTbl.RawSetString("name", lua.LString(entry.Name()))
-		entryTbl.RawSetString("is_dir", lua.LBool(entry.IsDir()))
-		// DirEntry.Info() is a cheap cached call for local filesystems
-		if info, err := entry.Info(); err == nil {
-			entryTbl.RawSetString("size", lua.LNumber(info.Size()))
-		} else {
-			entryTbl.RawSetString("size", lua.LNumber(0))
-		}
-		tbl.RawSetInt(i+1, entryTbl)
+		tbl.RawSetInt(i+1, fileEntry(L, filepath.Join(path, entry.Name()), entry))
 	}
 
 	L.Push(tbl)
@@ -189,11 +304,196 @@ func (m *FileModule) fileList(L *lua.LState) int {
 	return 2
 }
 
+// fileGlob returns the entries matching a filepath.Match pattern such as
+// CONFIG_DIR .. "/icons/*.png". Matches outside the config directory are
+// dropped.
+// Lua: file.glob(pattern) -> table, err
+func (m *FileModule) fileGlob(L *lua.LState) int {
+	pattern := L.CheckString(1)
+
+	if !m.checkAccess(pattern) {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	tbl := L.NewTable()
+	for _, path := range matches {
+		if !m.checkAccess(path) {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue
+		}
+		tbl.Append(fileEntry(L, path, fs.FileInfoToDirEntry(info)))
+	}
+	L.Push(tbl)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// errWalkStop ends a file.walk early without reporting an error.
+var errWalkStop = errors.New("walk stopped")
+
+// fileWalk calls fn(entry) for every file and directory below dir, in
+// lexical order. fn may return false to stop the walk. Unreadable
+// directories are skipped.
+// Lua: file.walk(dir, fn) -> ok, err
+func (m *FileModule) fileWalk(L *lua.LState) int {
+	root := L.CheckString(1)
+	fn := L.CheckFunction(2)
+
+	if !m.checkAccess(root) {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // skip what cannot be read
+		}
+		if path == root {
+			return nil
+		}
+		if err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, fileEntry(L, path, d)); err != nil {
+			return err
+		}
+		ret := L.Get(-1)
+		L.Pop(1)
+		if ret == lua.LFalse {
+			return errWalkStop
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errWalkStop) {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// fileEntry describes a directory entry as {name, path, is_dir, size}.
+func fileEntry(L *lua.LState, path string, d fs.DirEntry) *lua.LTable {
+	tbl := L.CreateTable(0, 4)
+	tbl.RawSetString("name", lua.LString(d.Name()))
+	tbl.RawSetString("path", lua.LString(path))
+	tbl.RawSetString("is_dir", lua.LBool(d.IsDir()))
+	// DirEntry.Info() is a cheap cached call for local filesystems
+	if info, err := d.Info(); err == nil {
+		tbl.RawSetString("size", lua.LNumber(info.Size()))
+	} else {
+		tbl.RawSetString("size", lua.LNumber(0))
+	}
+	return tbl
+}
+
+// fileCopy copies the file src to dst, streaming the content. dst is
+// replaced if it exists and takes src's permissions.
+// Lua: file.copy(src, dst) -> ok, err
+func (m *FileModule) fileCopy(L *lua.LState) int {
+	return m.twoPathOp(L, copyFile)
+}
+
+// fileMove moves src to dst, copying and deleting when a plain rename is not
+// possible (e.g. across filesystems).
+// Lua: file.move(src, dst) -> ok, err
+func (m *FileModule) fileMove(L *lua.LState) int {
+	return m.twoPathOp(L, moveFile)
+}
+
+// fileRename renames src to dst. Unlike file.move it never copies, so both
+// must be on the same filesystem.
+// Lua: file.rename(src, dst) -> ok, err
+func (m *FileModule) fileRename(L *lua.LState) int {
+	return m.twoPathOp(L, os.Rename)
+}
+
+// twoPathOp checks that both path arguments are inside the config
+// directory, then runs op.
+func (m *FileModule) twoPathOp(L *lua.LState, op func(src, dst string) error) int {
+	src := L.CheckString(1)
+	dst := L.CheckString(2)
+
+	if !m.checkAccess(src) || !m.checkAccess(dst) {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+
+	if err := op(src, dst); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// copyFile streams the regular file src to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// moveFile renames src to dst, falling back to copy and remove.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	// Cross-filesystem renames fail with a different error on each OS, so
+	// retry any failed rename of a regular file as copy and remove.
+	if fi, statErr := os.Stat(src); statErr != nil || !fi.Mode().IsRegular() {
+		return err
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
 func (m *FileModule) fileRemove(L *lua.LState) int {
 	path := L.CheckString(1)
 
 	// Check file access permissions
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString("access denied"))
 		return 2
@@ -214,7 +514,7 @@ func (m *FileModule) fileRemove(L *lua.LState) int {
 func (m *FileModule) fileSize(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LNumber(-1))
 		return 1
 	}
@@ -232,7 +532,7 @@ func (m *FileModule) fileSize(L *lua.LState) int {
 func (m *FileModule) fileIsDir(L *lua.LState) int {
 	path := L.CheckString(1)
 
-	if !checkFileAccess(path, L) {
+	if !m.checkAccess(path) {
 		L.Push(lua.LFalse)
 		return 1
 	}
@@ -246,3 +546,39 @@ func (m *FileModule) fileIsDir(L *lua.LState) int {
 	L.Push(lua.LBool(info.IsDir()))
 	return 1
 }
+
+// renameFile replaces the target in writeAtomic (replaced in tests).
+var renameFile = os.Rename
+
+// writeAtomic writes data to a temporary file next to path and renames it
+// over path, so readers see either the old or the new content, never a
+// partial write. An existing file's permissions are kept.
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, mode); err != nil {
+		return err
+	}
+	return renameFile(tmpName, path)
+}
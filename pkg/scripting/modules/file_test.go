@@ -0,0 +1,391 @@
+package modules
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// newFileState returns a Lua state with the file module confined to dir
+// and CONFIG_DIR set to it.
+func newFileState(t *testing.T, dir string) *lua.LState {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	L.SetGlobal("CONFIG_DIR", lua.LString(dir))
+	L.PreloadModule("file", NewFileModule(dir, nil).Loader)
+	return L
+}
+
+func TestFileReadLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts.txt")
+	os.WriteFile(path, []byte("alpha\r\nbeta\n\ngamma\n"), 0644)
+
+	L := newFileState(t, dir)
+	L.SetGlobal("path", lua.LString(path))
+	if err := L.DoString(`lines, err = require("file").read_lines(path)`); err != nil {
+		t.Fatal(err)
+	}
+	if e := L.GetGlobal("err"); e != lua.LNil {
+		t.Fatalf("err = %v", e)
+	}
+	lines := L.GetGlobal("lines").(*lua.LTable)
+	want := []string{"alpha", "beta", "", "gamma"}
+	if lines.Len() != len(want) {
+		t.Fatalf("got %d lines, want %d", lines.Len(), len(want))
+	}
+	for i, w := range want {
+		if got := lines.RawGetInt(i + 1).String(); got != w {
+			t.Errorf("line %d = %q, want %q", i+1, got, w)
+		}
+	}
+}
+
+func TestFileReadJSON(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "state.json")
+	bad := filepath.Join(dir, "broken.json")
+	os.WriteFile(good, []byte(`{"name": "deck", "keys": [1, 2, 3], "on": true}`), 0644)
+	os.WriteFile(bad, []byte(`{"name": `), 0644)
+
+	L := newFileState(t, dir)
+	L.SetGlobal("good", lua.LString(good))
+	L.SetGlobal("bad", lua.LString(bad))
+	if err := L.DoString(`
+		local file = require("file")
+		data, err = file.read_json(good)
+		bad_data, bad_err = file.read_json(bad)
+		_, denied = file.read_json("/etc/hostname")
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if e := L.GetGlobal("err"); e != lua.LNil {
+		t.Fatalf("err = %v", e)
+	}
+	data := L.GetGlobal("data").(*lua.LTable)
+	if got := data.RawGetString("name").String(); got != "deck" {
+		t.Errorf("name = %q", got)
+	}
+	if keys := data.RawGetString("keys").(*lua.LTable); keys.Len() != 3 || keys.RawGetInt(3) != lua.LNumber(3) {
+		t.Errorf("keys = %v", keys)
+	}
+	if data.RawGetString("on") != lua.LTrue {
+		t.Errorf("on = %v", data.RawGetString("on"))
+	}
+
+	if L.GetGlobal("bad_data") != lua.LNil || L.GetGlobal("bad_err") == lua.LNil {
+		t.Error("invalid JSON should return nil, err")
+	}
+	if got := L.GetGlobal("denied").String(); got != "access denied" {
+		t.Errorf("outside CONFIG_DIR: err = %q, want access denied", got)
+	}
+}
+
+func TestFileWatchFiresOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.txt")
+	os.WriteFile(path, []byte("idle"), 0644)
+
+	// Deliver callbacks to the test goroutine, which owns L.
+	type call struct {
+		fn   *lua.LFunction
+		args []lua.LValue
+	}
+	calls := make(chan call, 10)
+	mod := NewFileModule(dir, func(fn *lua.LFunction, args ...lua.LValue) {
+		calls <- call{fn, args}
+	})
+	defer mod.Close()
+
+	L := lua.NewState()
+	defer L.Close()
+	L.SetGlobal("CONFIG_DIR", lua.LString(dir))
+	L.SetGlobal("path", lua.LString(path))
+	L.PreloadModule("file", mod.Loader)
+	if err := L.DoString(`
+		ok, err = require("file").watch(path, function(p, op)
+			seen_path, seen_op = p, op
+		end)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("watch failed: %v", L.GetGlobal("err"))
+	}
+
+	// Unrelated files in the same directory must not fire.
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte("x"), 0644)
+	os.WriteFile(path, []byte("busy"), 0644)
+
+	select {
+	case c := <-calls:
+		if err := L.CallByParam(lua.P{Fn: c.fn, Protect: true}, c.args...); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback never fired")
+	}
+	if got := L.GetGlobal("seen_path").String(); got != path {
+		t.Errorf("callback path = %q, want %q", got, path)
+	}
+	if got := L.GetGlobal("seen_op").String(); got != "write" && got != "create" {
+		t.Errorf("callback op = %q", got)
+	}
+	select {
+	case c := <-calls:
+		t.Errorf("unexpected second callback %v", c.args)
+	case <-time.After(2 * watchDebounce):
+	}
+}
+
+func TestFileWatchSandboxed(t *testing.T) {
+	dir := t.TempDir()
+	mod := NewFileModule(dir, func(*lua.LFunction, ...lua.LValue) {})
+	defer mod.Close()
+
+	L := newFileState(t, dir)
+	L.PreloadModule("file", mod.Loader)
+	if err := L.DoString(`ok, err = require("file").watch("/etc/hostname", function() end)`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LFalse || L.GetGlobal("err").String() != "access denied" {
+		t.Fatalf("ok, err = %v, %v", L.GetGlobal("ok"), L.GetGlobal("err"))
+	}
+}
+
+func TestFileWriteAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	os.WriteFile(path, []byte("old"), 0600)
+
+	// At the moment of the rename the target must still hold the old
+	// content and the temp file, in the same directory, the full new one.
+	var renamed bool
+	renameFile = func(from, to string) error {
+		renamed = true
+		if filepath.Dir(from) != dir || to != path {
+			t.Errorf("rename %s -> %s, want a temp file in %s -> %s", from, to, dir, path)
+		}
+		if got, _ := os.ReadFile(path); string(got) != "old" {
+			t.Errorf("target before rename = %q, want old", got)
+		}
+		if got, _ := os.ReadFile(from); string(got) != `{"n": 2}` {
+			t.Errorf("temp file = %q, want the complete content", got)
+		}
+		return os.Rename(from, to)
+	}
+	defer func() { renameFile = os.Rename }()
+
+	L := newFileState(t, dir)
+	L.SetGlobal("path", lua.LString(path))
+	if err := L.DoString(`ok, err = require("file").write_atomic(path, '{"n": 2}')`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("write_atomic: %v", L.GetGlobal("err"))
+	}
+	if !renamed {
+		t.Fatal("target was written in place")
+	}
+	if got, _ := os.ReadFile(path); string(got) != `{"n": 2}` {
+		t.Fatalf("target = %q", got)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want the original 0600", fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
+
+func TestFileWriteAtomicFailureKeepsTarget(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.txt")
+	os.WriteFile(path, []byte("old"), 0644)
+
+	renameFile = func(string, string) error { return os.ErrPermission }
+	defer func() { renameFile = os.Rename }()
+
+	L := newFileState(t, dir)
+	L.SetGlobal("path", lua.LString(path))
+	if err := L.DoString(`ok, err = require("file").write(path, "new", true)`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LFalse {
+		t.Fatal("expected the failed rename to be reported")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Fatalf("target = %q, want it untouched", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
+
+// makeTree creates files (and their directories) under dir.
+func makeTree(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFileGlob(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir, "icons/play.png", "icons/stop.png", "icons/notes.txt", "icons/sub/deep.png")
+
+	L := newFileState(t, dir)
+	L.SetGlobal("pattern", lua.LString(filepath.Join(dir, "icons", "*.png")))
+	if err := L.DoString(`
+		local file = require("file")
+		matches, err = file.glob(pattern)
+		_, denied = file.glob("/etc/*")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	matches := L.GetGlobal("matches").(*lua.LTable)
+	var names []string
+	for i := 1; i <= matches.Len(); i++ {
+		e := matches.RawGetInt(i).(*lua.LTable)
+		names = append(names, e.RawGetString("name").String())
+		if p := e.RawGetString("path").String(); p != filepath.Join(dir, "icons", names[i-1]) {
+			t.Errorf("path = %q", p)
+		}
+		if e.RawGetString("size") != lua.LNumber(len("icons/"+names[i-1])) {
+			t.Errorf("%s size = %v", names[i-1], e.RawGetString("size"))
+		}
+	}
+	if want := []string{"play.png", "stop.png"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("glob = %v, want %v", names, want)
+	}
+	if got := L.GetGlobal("denied").String(); got != "access denied" {
+		t.Errorf("glob outside CONFIG_DIR: err = %q", got)
+	}
+}
+
+func TestFileWalk(t *testing.T) {
+	dir := t.TempDir()
+	makeTree(t, dir, "a.txt", "assets/b.png", "assets/icons/c.png", "z.txt")
+
+	L := newFileState(t, dir)
+	L.SetGlobal("dir", lua.LString(dir))
+	if err := L.DoString(`
+		local file = require("file")
+		visited = {}
+		ok, err = file.walk(dir, function(e)
+			local rel = e.path:sub(#dir + 2)
+			visited[#visited + 1] = rel .. (e.is_dir and "/" or "")
+		end)
+		stopped = 0
+		file.walk(dir, function(e)
+			stopped = stopped + 1
+			return false
+		end)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("walk: %v", L.GetGlobal("err"))
+	}
+	visited := L.GetGlobal("visited").(*lua.LTable)
+	var got []string
+	for i := 1; i <= visited.Len(); i++ {
+		got = append(got, filepath.ToSlash(visited.RawGetInt(i).String()))
+	}
+	want := []string{"a.txt", "assets/", "assets/b.png", "assets/icons/", "assets/icons/c.png", "z.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("walk visited %v, want %v", got, want)
+	}
+	if n := L.GetGlobal("stopped"); n != lua.LNumber(1) {
+		t.Errorf("returning false visited %v entries, want 1", n)
+	}
+}
+
+func TestFileCopyMoveRename(t *testing.T) {
+	dir := t.TempDir()
+	big := make([]byte, 3<<20) // larger than any single io.Copy buffer
+	for i := range big {
+		big[i] = byte(i % 251)
+	}
+	os.WriteFile(filepath.Join(dir, "big.bin"), big, 0640)
+	makeTree(t, dir, "move-me.txt", "rename-me.txt")
+
+	L := newFileState(t, dir)
+	L.SetGlobal("dir", lua.LString(dir))
+	if err := L.DoString(`
+		local file = require("file")
+		copy_ok, copy_err = file.copy(dir .. "/big.bin", dir .. "/big-copy.bin")
+		move_ok, move_err = file.move(dir .. "/move-me.txt", dir .. "/moved.txt")
+		rename_ok, rename_err = file.rename(dir .. "/rename-me.txt", dir .. "/renamed.txt")
+		_, copy_out = file.copy(dir .. "/big.bin", "/tmp/escaped.bin")
+		_, move_in = file.move("/etc/hostname", dir .. "/hostname")
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, op := range []string{"copy", "move", "rename"} {
+		if L.GetGlobal(op+"_ok") != lua.LTrue {
+			t.Fatalf("%s: %v", op, L.GetGlobal(op+"_err"))
+		}
+	}
+
+	got, _ := os.ReadFile(filepath.Join(dir, "big-copy.bin"))
+	if !bytes.Equal(got, big) {
+		t.Error("copy content differs from source")
+	}
+	if fi, _ := os.Stat(filepath.Join(dir, "big-copy.bin")); fi.Mode().Perm() != 0640 {
+		t.Errorf("copy mode = %v, want 0640", fi.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.bin")); err != nil {
+		t.Error("copy removed the source")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "move-me.txt")); !os.IsNotExist(err) {
+		t.Error("move left the source behind")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "moved.txt")); string(got) != "move-me.txt" {
+		t.Errorf("moved content = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "renamed.txt")); err != nil {
+		t.Error("rename target missing")
+	}
+
+	for _, name := range []string{"copy_out", "move_in"} {
+		if got := L.GetGlobal(name).String(); got != "access denied" {
+			t.Errorf("%s: err = %q, want access denied", name, got)
+		}
+	}
+	if _, err := os.Stat("/tmp/escaped.bin"); err == nil {
+		os.Remove("/tmp/escaped.bin")
+		t.Error("copy escaped the config directory")
+	}
+}
+
+func TestFileAccessIgnoresConfigDirGlobal(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0644)
+
+	L := newFileState(t, dir)
+	L.SetGlobal("outside", lua.LString(outside))
+	if err := L.DoString(`
+		CONFIG_DIR = "/"
+		data, err = require("file").read(outside)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("data") == lua.LString("secret") || L.GetGlobal("err").String() != "access denied" {
+		t.Errorf("read after CONFIG_DIR = \"/\": %v, %v; want access denied", L.GetGlobal("data"), L.GetGlobal("err"))
+	}
+}
@@ -47,6 +47,11 @@ func (m *HTTPModule) Loader(L *lua.LState) int {
 }
 
 func (m *HTTPModule) httpGet(L *lua.LState) int {
+	if networkBlocked.Load() {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(errNetworkBlocked.Error()))
+		return 2
+	}
 	url := L.CheckString(1)
 
 	resp, err := m.client.Get(url)
@@ -70,6 +75,11 @@ func (m *HTTPModule) httpGet(L *lua.LState) int {
 }
 
 func (m *HTTPModule) httpPost(L *lua.LState) int {
+	if networkBlocked.Load() {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(errNetworkBlocked.Error()))
+		return 2
+	}
 	url := L.CheckString(1)
 	contentType := L.CheckString(2)
 	body := L.CheckString(3)
@@ -95,6 +105,11 @@ func (m *HTTPModule) httpPost(L *lua.LState) int {
 }
 
 func (m *HTTPModule) httpRequest(L *lua.LState) int {
+	if networkBlocked.Load() {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(errNetworkBlocked.Error()))
+		return 2
+	}
 	method := L.CheckString(1)
 	url := L.CheckString(2)
 	headers := L.OptTable(3, nil)
@@ -0,0 +1,216 @@
+package modules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// macroStepKind is what one macro step does.
+type macroStepKind int
+
+const (
+	stepExec  macroStepKind = iota // Run a command and wait for it
+	stepStart                      // Start a command without waiting
+	stepSleep                      // Wait
+	stepCall                       // Call a Lua function on the script's VM
+)
+
+// macroStep is one parsed step of a macro.
+type macroStep struct {
+	kind    macroStepKind
+	command string
+	delay   time.Duration
+	fn      *lua.LFunction
+}
+
+// macroCommand builds the command for exec and start steps. It is a
+// variable so tests can stub it out.
+var macroCommand = func(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/c", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// MacroModule runs sequences of commands, delays and Lua calls. A macro runs
+// on its own goroutine, so a trigger that starts one returns at once and
+// delays never hold the script's VM; function steps run on the VM through
+// invoke, like event callbacks.
+type MacroModule struct {
+	invoke CallbackFunc
+
+	mu      sync.Mutex
+	ctx     context.Context // cancelled by Close
+	cancel  context.CancelFunc
+	running atomic.Int32 // macros whose goroutine has not finished
+}
+
+// errMacroCancelled is reported to on_done when a macro is cancelled.
+var errMacroCancelled = errors.New("cancelled")
+
+// NewMacroModule creates a macro module. Function steps and on_done are
+// called through invoke.
+func NewMacroModule(invoke CallbackFunc) *MacroModule {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &MacroModule{invoke: invoke, ctx: ctx, cancel: cancel}
+}
+
+// Loader returns the Lua module loader function.
+func (m *MacroModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"run": m.macroRun,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// macroRun starts a macro. Steps run in order; a failing exec step stops
+// the macro. on_done(err) is called when it finishes (err is nil on
+// success). The returned function cancels the macro.
+//
+// Steps are tables or shorthands:
+//
+//	{ exec = "cmd" }   or "cmd"   run a shell command and wait for it
+//	{ start = "cmd" }             start a command without waiting
+//	{ sleep = ms }     or ms      wait
+//	{ call = fn }      or fn      call fn() on the script's VM
+//
+// Lua: macro.run(steps [, on_done]) -> cancel | nil, err
+func (m *MacroModule) macroRun(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	onDone := L.OptFunction(2, nil)
+
+	steps, err := parseMacro(tbl)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	m.mu.Lock()
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		L.Push(lua.LNil)
+		L.Push(lua.LString("script is closing"))
+		return 2
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.mu.Unlock()
+
+	m.running.Add(1)
+	go func() {
+		defer m.running.Add(-1)
+		defer cancel()
+		err := m.runSteps(ctx, steps)
+		if onDone != nil && m.invoke != nil && m.ctx.Err() == nil {
+			if err != nil {
+				m.invoke(onDone, lua.LString(err.Error()))
+			} else {
+				m.invoke(onDone, lua.LNil)
+			}
+		}
+	}()
+
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		cancel()
+		return 0
+	}))
+	return 1
+}
+
+// parseMacro converts the Lua step list into macroSteps.
+func parseMacro(tbl *lua.LTable) ([]macroStep, error) {
+	steps := make([]macroStep, 0, tbl.Len())
+	for i := 1; i <= tbl.Len(); i++ {
+		step, err := parseMacroStep(tbl.RawGetInt(i))
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseMacroStep(v lua.LValue) (macroStep, error) {
+	switch v := v.(type) {
+	case lua.LString:
+		return macroStep{kind: stepExec, command: string(v)}, nil
+	case lua.LNumber:
+		return macroStep{kind: stepSleep, delay: time.Duration(v) * time.Millisecond}, nil
+	case *lua.LFunction:
+		return macroStep{kind: stepCall, fn: v}, nil
+	case *lua.LTable:
+		if s, ok := v.RawGetString("exec").(lua.LString); ok {
+			return macroStep{kind: stepExec, command: string(s)}, nil
+		}
+		if s, ok := v.RawGetString("start").(lua.LString); ok {
+			return macroStep{kind: stepStart, command: string(s)}, nil
+		}
+		if n, ok := v.RawGetString("sleep").(lua.LNumber); ok {
+			return macroStep{kind: stepSleep, delay: time.Duration(n) * time.Millisecond}, nil
+		}
+		if fn, ok := v.RawGetString("call").(*lua.LFunction); ok {
+			return macroStep{kind: stepCall, fn: fn}, nil
+		}
+		return macroStep{}, fmt.Errorf("needs exec, start, sleep or call")
+	}
+	return macroStep{}, fmt.Errorf("unsupported step type %s", v.Type())
+}
+
+// runSteps runs the steps in order until one fails or ctx is cancelled.
+func (m *MacroModule) runSteps(ctx context.Context, steps []macroStep) error {
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			return errMacroCancelled
+		}
+		switch step.kind {
+		case stepExec:
+			if out, err := macroCommand(ctx, step.command).CombinedOutput(); err != nil {
+				if ctx.Err() != nil {
+					return errMacroCancelled
+				}
+				return fmt.Errorf("step %d: %s: %v %s", i+1, step.command, err, out)
+			}
+		case stepStart:
+			cmd := macroCommand(context.Background(), step.command)
+			if err := cmd.Start(); err != nil {
+				return fmt.Errorf("step %d: %s: %v", i+1, step.command, err)
+			}
+			go cmd.Wait()
+		case stepSleep:
+			t := time.NewTimer(step.delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return errMacroCancelled
+			case <-t.C:
+			}
+		case stepCall:
+			if m.invoke != nil {
+				m.invoke(step.fn)
+			}
+		}
+	}
+	return nil
+}
+
+// Running reports whether a macro started by the script is still running.
+func (m *MacroModule) Running() bool {
+	return m.running.Load() > 0
+}
+
+// Close cancels running macros; on_done is not called for them. The
+// owning runner calls it when it closes.
+func (m *MacroModule) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancel()
+}
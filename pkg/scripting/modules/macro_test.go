@@ -0,0 +1,131 @@
+package modules
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// macroState runs a macro module on a fresh VM. mark(name) records the
+// time a step ran; done is signalled by on_done with its error (or "").
+type macroState struct {
+	L    *lua.LState
+	mu   sync.Mutex
+	mod  *MacroModule
+	at   map[string]time.Time
+	seq  []string
+	done chan string
+}
+
+func newMacroState(t *testing.T) *macroState {
+	t.Helper()
+	s := &macroState{L: lua.NewState(), at: make(map[string]time.Time), done: make(chan string, 1)}
+	t.Cleanup(s.L.Close)
+	s.mod = NewMacroModule(func(fn *lua.LFunction, args ...lua.LValue) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err := s.L.CallByParam(lua.P{Fn: fn, Protect: true}, args...); err != nil {
+			t.Errorf("callback: %v", err)
+		}
+	})
+	t.Cleanup(s.mod.Close)
+	s.L.PreloadModule("macro", s.mod.Loader)
+	s.L.SetGlobal("mark", s.L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		s.at[name] = time.Now()
+		s.seq = append(s.seq, name)
+		return 0
+	}))
+	s.L.SetGlobal("finish", s.L.NewFunction(func(L *lua.LState) int {
+		s.done <- L.OptString(1, "")
+		return 0
+	}))
+	return s
+}
+
+func (s *macroState) run(t *testing.T, src string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.L.DoString(src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (s *macroState) wait(t *testing.T) string {
+	t.Helper()
+	select {
+	case err := <-s.done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("macro did not finish")
+		return ""
+	}
+}
+
+func TestMacroRunsStepsInOrderWithDelays(t *testing.T) {
+	s := newMacroState(t)
+	s.run(t, `
+		local macro = require("macro")
+		cancel, err = macro.run({
+			function() mark("first") end,
+			{ sleep = 50 },
+			"exit 0",
+			{ call = function() mark("second") end },
+			30,
+			{ exec = "true" },
+			function() mark("third") end,
+		}, finish)
+	`)
+	if err := s.wait(t); err != "" {
+		t.Fatalf("macro failed: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.seq) != 3 || s.seq[0] != "first" || s.seq[1] != "second" || s.seq[2] != "third" {
+		t.Fatalf("steps ran as %v", s.seq)
+	}
+	if d := s.at["second"].Sub(s.at["first"]); d < 50*time.Millisecond {
+		t.Errorf("second step ran %v after the first, want >= 50ms", d)
+	}
+	if d := s.at["third"].Sub(s.at["second"]); d < 30*time.Millisecond {
+		t.Errorf("third step ran %v after the second, want >= 30ms", d)
+	}
+}
+
+func TestMacroStopsOnFailureAndCancel(t *testing.T) {
+	s := newMacroState(t)
+	s.run(t, `
+		local macro = require("macro")
+		macro.run({ "exit 3", function() mark("after") end }, finish)
+	`)
+	if err := s.wait(t); err == "" {
+		t.Error("failing exec step did not report an error")
+	}
+
+	s.run(t, `
+		local macro = require("macro")
+		cancel = macro.run({ { sleep = 10000 }, function() mark("late") end }, finish)
+		cancel()
+	`)
+	if err := s.wait(t); err != "cancelled" {
+		t.Errorf("cancelled macro reported %q", err)
+	}
+
+	s.run(t, `
+		local macro = require("macro")
+		bad, bad_err = macro.run({ { wait = 1 } })
+	`)
+	if s.L.GetGlobal("bad") != lua.LNil || s.L.GetGlobal("bad_err") == lua.LNil {
+		t.Error("invalid step did not return nil, err")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.seq) != 0 {
+		t.Errorf("steps after a failure or cancel ran: %v", s.seq)
+	}
+}
@@ -0,0 +1,19 @@
+package modules
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// errNetworkBlocked is returned by network modules when security.block_network
+// is enabled.
+var errNetworkBlocked = errors.New("network access is blocked by config")
+
+// networkBlocked is shared by every runner; scripts cannot change it.
+var networkBlocked atomic.Bool
+
+// SetNetworkBlocked enables or disables network access for the http and
+// weather modules in all scripts.
+func SetNetworkBlocked(blocked bool) {
+	networkBlocked.Store(blocked)
+}
@@ -0,0 +1,92 @@
+package modules
+
+import (
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// AppInfo supplies the app metadata the nomad module reports. The script
+// manager implements it.
+type AppInfo interface {
+	AppVersion() string
+	// CurrentPath is the folder shown on the deck (absolute path).
+	CurrentPath() string
+}
+
+// NomadModule exposes metadata about the running app to Lua scripts. It is
+// preloaded as "nomad" and also set as the global nomad.
+type NomadModule struct {
+	app       AppInfo // nil: version and current_path return nil
+	configDir string
+	device    *streamdeck.Device
+}
+
+// NewNomadModule creates a nomad module. app may be nil.
+func NewNomadModule(app AppInfo, configDir string, device *streamdeck.Device) *NomadModule {
+	return &NomadModule{app: app, configDir: configDir, device: device}
+}
+
+// Table builds the module table.
+func (m *NomadModule) Table(L *lua.LState) *lua.LTable {
+	return L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"version":      m.nomadVersion,
+		"config_dir":   m.nomadConfigDir,
+		"current_path": m.nomadCurrentPath,
+		"device":       m.nomadDevice,
+	})
+}
+
+// Loader returns the Lua module loader function.
+func (m *NomadModule) Loader(L *lua.LState) int {
+	L.Push(m.Table(L))
+	return 1
+}
+
+// nomadVersion returns the app version ("dev" for untagged builds).
+// Lua: nomad.version() -> string
+func (m *NomadModule) nomadVersion(L *lua.LState) int {
+	if m.app == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(lua.LString(m.app.AppVersion()))
+	return 1
+}
+
+// nomadConfigDir returns the config directory (same as CONFIG_DIR).
+// Lua: nomad.config_dir() -> string
+func (m *NomadModule) nomadConfigDir(L *lua.LState) int {
+	L.Push(lua.LString(m.configDir))
+	return 1
+}
+
+// nomadCurrentPath returns the absolute path of the folder on the deck.
+// Lua: nomad.current_path() -> string
+func (m *NomadModule) nomadCurrentPath(L *lua.LState) int {
+	if m.app == nil || m.app.CurrentPath() == "" {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(lua.LString(m.app.CurrentPath()))
+	return 1
+}
+
+// nomadDevice describes the connected deck. For model details such as dials
+// and image format see streamdeck.capabilities.
+// Lua: nomad.device() -> {name, product_id, serial, firmware, path} | nil, err
+func (m *NomadModule) nomadDevice(L *lua.LState) int {
+	if m.device == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("no device connected"))
+		return 2
+	}
+	info := m.device.Info
+	dev := L.NewTable()
+	dev.RawSetString("name", lua.LString(m.device.Model.Name))
+	dev.RawSetString("product_id", lua.LNumber(m.device.Model.ProductID))
+	dev.RawSetString("serial", lua.LString(info.Serial))
+	dev.RawSetString("firmware", lua.LString(info.Firmware))
+	dev.RawSetString("path", lua.LString(info.Path))
+	L.Push(dev)
+	return 1
+}
@@ -0,0 +1,68 @@
+package modules
+
+import (
+	"testing"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+type fakeAppInfo struct{ version, path string }
+
+func (f fakeAppInfo) AppVersion() string  { return f.version }
+func (f fakeAppInfo) CurrentPath() string { return f.path }
+
+func TestNomadAccessors(t *testing.T) {
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	app := fakeAppInfo{version: "v1.2.3", path: "/cfg/apps"}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("nomad", NewNomadModule(app, "/cfg", dev).Loader)
+	if err := L.DoString(`
+		local nomad = require("nomad")
+		version = nomad.version()
+		config_dir = nomad.config_dir()
+		current_path = nomad.current_path()
+		local dev = nomad.device()
+		device_name = dev.name
+		device_product = dev.product_id
+		device_path = dev.path
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]lua.LValue{
+		"version":        lua.LString("v1.2.3"),
+		"config_dir":     lua.LString("/cfg"),
+		"current_path":   lua.LString("/cfg/apps"),
+		"device_name":    lua.LString("Stream Deck MK.2"),
+		"device_product": lua.LNumber(0x0080),
+		"device_path":    lua.LString("virtual"),
+	}
+	for name, v := range want {
+		if got := L.GetGlobal(name); got != v {
+			t.Errorf("%s = %v, want %v", name, got, v)
+		}
+	}
+}
+
+func TestNomadWithoutAppOrDevice(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("nomad", NewNomadModule(nil, "/cfg", nil).Loader)
+	if err := L.DoString(`
+		local nomad = require("nomad")
+		version = nomad.version()
+		current_path = nomad.current_path()
+		device, err = nomad.device()
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("version") != lua.LNil || L.GetGlobal("current_path") != lua.LNil {
+		t.Error("version/current_path without app info are not nil")
+	}
+	if L.GetGlobal("device") != lua.LNil || L.GetGlobal("err") == lua.LNil {
+		t.Error("device() without a device did not return nil, err")
+	}
+}
@@ -0,0 +1,85 @@
+package modules
+
+import (
+	"math/rand/v2"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RandomModule provides random numbers and table helpers to Lua scripts.
+// Each runner gets its own generator so scripts don't share a sequence.
+type RandomModule struct {
+	rng *rand.Rand
+}
+
+// NewRandomModule creates a random module with a freshly seeded generator.
+func NewRandomModule() *RandomModule {
+	return &RandomModule{rng: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// Loader returns the Lua module loader function.
+func (m *RandomModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"int":     m.randomInt,
+		"float":   m.randomFloat,
+		"choice":  m.randomChoice,
+		"shuffle": m.randomShuffle,
+		"seed":    m.randomSeed,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// randomInt returns an integer in [min, max] (both inclusive).
+// Lua: random.int(min, max) -> number
+func (m *RandomModule) randomInt(L *lua.LState) int {
+	lo := L.CheckInt64(1)
+	hi := L.CheckInt64(2)
+	if lo > hi {
+		L.ArgError(2, "max must be >= min")
+		return 0
+	}
+	L.Push(lua.LNumber(lo + m.rng.Int64N(hi-lo+1)))
+	return 1
+}
+
+// randomFloat returns a float in [0, 1).
+// Lua: random.float() -> number
+func (m *RandomModule) randomFloat(L *lua.LState) int {
+	L.Push(lua.LNumber(m.rng.Float64()))
+	return 1
+}
+
+// randomChoice returns a random element of an array table, or nil if empty.
+// Lua: random.choice(tbl) -> value | nil
+func (m *RandomModule) randomChoice(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	n := tbl.Len()
+	if n == 0 {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(tbl.RawGetInt(1 + m.rng.IntN(n)))
+	return 1
+}
+
+// randomShuffle shuffles an array table in place and returns it.
+// Lua: random.shuffle(tbl) -> tbl
+func (m *RandomModule) randomShuffle(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	m.rng.Shuffle(tbl.Len(), func(i, j int) {
+		a, b := tbl.RawGetInt(i+1), tbl.RawGetInt(j+1)
+		tbl.RawSetInt(i+1, b)
+		tbl.RawSetInt(j+1, a)
+	})
+	L.Push(tbl)
+	return 1
+}
+
+// randomSeed reseeds the generator for a repeatable sequence.
+// Lua: random.seed(n)
+func (m *RandomModule) randomSeed(L *lua.LState) int {
+	seed := uint64(L.CheckInt64(1))
+	m.rng = rand.New(rand.NewPCG(seed, seed))
+	return 0
+}
@@ -0,0 +1,134 @@
+package modules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runRandom runs src with m loaded as "random".
+func runRandom(t *testing.T, m *RandomModule, src string) *lua.LState {
+	t.Helper()
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	L.PreloadModule("random", m.Loader)
+	if err := L.DoString(`local random = require("random")` + "\n" + src); err != nil {
+		t.Fatal(err)
+	}
+	return L
+}
+
+// numbers reads an array table of numbers.
+func numbers(L *lua.LState, name string) []float64 {
+	tbl := L.GetGlobal(name).(*lua.LTable)
+	out := make([]float64, 0, tbl.Len())
+	for i := 1; i <= tbl.Len(); i++ {
+		out = append(out, float64(tbl.RawGetInt(i).(lua.LNumber)))
+	}
+	return out
+}
+
+func TestRandomIntBounds(t *testing.T) {
+	tests := []struct{ lo, hi int }{{1, 6}, {-3, 3}, {7, 7}, {0, 1}}
+	for _, tt := range tests {
+		L := runRandom(t, NewRandomModule(), fmt.Sprintf(`
+			out = {}
+			for i = 1, 500 do out[i] = random.int(%d, %d) end
+		`, tt.lo, tt.hi))
+		seen := map[float64]bool{}
+		for _, v := range numbers(L, "out") {
+			if v < float64(tt.lo) || v > float64(tt.hi) || v != float64(int(v)) {
+				t.Fatalf("int(%d, %d) returned %v", tt.lo, tt.hi, v)
+			}
+			seen[v] = true
+		}
+		if len(seen) != tt.hi-tt.lo+1 {
+			t.Errorf("int(%d, %d) produced %d distinct values in 500 draws, want %d", tt.lo, tt.hi, len(seen), tt.hi-tt.lo+1)
+		}
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("random", NewRandomModule().Loader)
+	if err := L.DoString(`require("random").int(5, 1)`); err == nil || !strings.Contains(err.Error(), "max must be >= min") {
+		t.Errorf("int(5, 1): err = %v", err)
+	}
+}
+
+func TestRandomFloat(t *testing.T) {
+	L := runRandom(t, NewRandomModule(), `
+		out = {}
+		for i = 1, 500 do out[i] = random.float() end
+	`)
+	for _, v := range numbers(L, "out") {
+		if v < 0 || v >= 1 {
+			t.Fatalf("float() returned %v, want [0, 1)", v)
+		}
+	}
+}
+
+func TestRandomChoiceAndShuffle(t *testing.T) {
+	L := runRandom(t, NewRandomModule(), `
+		local items = {"red", "green", "blue"}
+		picks = {}
+		for i = 1, 100 do picks[i] = random.choice(items) end
+		empty = random.choice({})
+		list = {1, 2, 3, 4, 5, 6, 7, 8}
+		same = random.shuffle(list) == list
+	`)
+	picks := L.GetGlobal("picks").(*lua.LTable)
+	seen := map[string]bool{}
+	for i := 1; i <= picks.Len(); i++ {
+		v := picks.RawGetInt(i).String()
+		if v != "red" && v != "green" && v != "blue" {
+			t.Fatalf("choice returned %q, not an element of the input", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("choice picked only %v in 100 draws", seen)
+	}
+	if L.GetGlobal("empty") != lua.LNil {
+		t.Errorf("choice({}) = %v, want nil", L.GetGlobal("empty"))
+	}
+	if L.GetGlobal("same") != lua.LTrue {
+		t.Error("shuffle did not return the table it was given")
+	}
+	list := numbers(L, "list")
+	sort.Float64s(list)
+	for i, v := range list {
+		if v != float64(i+1) {
+			t.Fatalf("shuffle changed the elements: %v", numbers(L, "list"))
+		}
+	}
+}
+
+func TestRandomSeed(t *testing.T) {
+	src := `
+		random.seed(42)
+		out = {}
+		for i = 1, 10 do out[i] = random.int(1, 1000000) end
+	`
+	a := numbers(runRandom(t, NewRandomModule(), src), "out")
+	b := numbers(runRandom(t, NewRandomModule(), src), "out")
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("seeded sequences differ: %v vs %v", a, b)
+		}
+	}
+
+	// Unseeded modules each get their own sequence
+	src = `out = {}; for i = 1, 10 do out[i] = random.int(1, 1000000) end`
+	c := numbers(runRandom(t, NewRandomModule(), src), "out")
+	d := numbers(runRandom(t, NewRandomModule(), src), "out")
+	same := true
+	for i := range c {
+		same = same && c[i] == d[i]
+	}
+	if same {
+		t.Error("two runners produced the same random sequence")
+	}
+}
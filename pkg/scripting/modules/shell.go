@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"errors"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -23,6 +24,7 @@ func (m *ShellModule) Loader(L *lua.LState) int {
 		"exec_async": m.shellExecAsync,
 		"open":       m.shellOpen,
 		"terminal":   m.shellTerminal,
+		"prompt":     m.shellPrompt,
 	})
 	L.Push(mod)
 	return 1
@@ -160,3 +162,67 @@ func (m *ShellModule) shellTerminal(L *lua.LState) int {
 	L.Push(lua.LNil)
 	return 2
 }
+
+// errPromptCancelled is returned when the user dismisses the input dialog.
+var errPromptCancelled = errors.New("cancelled")
+
+// promptCommand builds the dialog command for the current OS. It is a
+// variable so the dialog can be stubbed out.
+var promptCommand = func(message, def string) (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "windows":
+		quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+		script := "Add-Type -AssemblyName Microsoft.VisualBasic; " +
+			"[Microsoft.VisualBasic.Interaction]::InputBox(" + quote(message) + ", 'Nomad', " + quote(def) + ")"
+		return exec.Command("powershell", "-NoProfile", "-Command", script), nil
+	case "darwin":
+		quote := func(s string) string {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+		}
+		script := "text returned of (display dialog " + quote(message) + " default answer " + quote(def) + ")"
+		return exec.Command("osascript", "-e", script), nil
+	default:
+		// Linux / BSD: first available dialog tool
+		if _, err := exec.LookPath("zenity"); err == nil {
+			return exec.Command("zenity", "--entry", "--title=Nomad", "--text="+message, "--entry-text="+def), nil
+		}
+		if _, err := exec.LookPath("kdialog"); err == nil {
+			return exec.Command("kdialog", "--title", "Nomad", "--inputbox", message, def), nil
+		}
+		if _, err := exec.LookPath("yad"); err == nil {
+			return exec.Command("yad", "--entry", "--title=Nomad", "--text="+message, "--entry-text="+def), nil
+		}
+		return nil, errors.New("no dialog tool found (install zenity or kdialog)")
+	}
+}
+
+// runPrompt shows an input dialog and returns the entered text.
+// Dialog tools exit non-zero when cancelled.
+func runPrompt(message, def string) (string, error) {
+	cmd, err := promptCommand(message, def)
+	if err != nil {
+		return "", err
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", errPromptCancelled
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// shellPrompt opens a desktop input dialog and blocks until it is answered.
+// Lua: shell.prompt(message[, default]) -> text | nil, err
+func (m *ShellModule) shellPrompt(L *lua.LState) int {
+	text, err := runPrompt(L.CheckString(1), L.OptString(2, ""))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(text))
+	return 1
+}
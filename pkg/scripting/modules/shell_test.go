@@ -0,0 +1,73 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// TestPromptHelperProcess stands in for the dialog tool when run by
+// stubPrompt. It answers with the message and default it was given, or
+// exits 1 like a cancelled dialog.
+func TestPromptHelperProcess(t *testing.T) {
+	if os.Getenv("NOMAD_PROMPT_HELPER") == "" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if os.Getenv("NOMAD_PROMPT_HELPER") == "cancel" {
+		os.Exit(1)
+	}
+	fmt.Printf("%s|%s\r\n", args[1], args[2])
+	os.Exit(0)
+}
+
+// stubPrompt makes shell.prompt run the helper process in the given mode.
+func stubPrompt(t *testing.T, mode string) {
+	t.Helper()
+	orig := promptCommand
+	t.Cleanup(func() { promptCommand = orig })
+	promptCommand = func(message, def string) (*exec.Cmd, error) {
+		if mode == "missing" {
+			return nil, errors.New("no dialog tool found")
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=TestPromptHelperProcess", "--", message, def)
+		cmd.Env = append(os.Environ(), "NOMAD_PROMPT_HELPER="+mode)
+		return cmd, nil
+	}
+}
+
+func TestShellPrompt(t *testing.T) {
+	tests := []struct {
+		mode     string
+		src      string
+		wantText lua.LValue
+		wantErr  string
+	}{
+		{"answer", `text, err = require("shell").prompt("Search for", "cats")`, lua.LString("Search for|cats"), ""},
+		{"answer", `text, err = require("shell").prompt("Name?")`, lua.LString("Name?|"), ""},
+		{"cancel", `text, err = require("shell").prompt("Search for")`, lua.LNil, "cancelled"},
+		{"missing", `text, err = require("shell").prompt("Search for")`, lua.LNil, "no dialog tool found"},
+	}
+	for _, tt := range tests {
+		stubPrompt(t, tt.mode)
+		L := lua.NewState()
+		L.PreloadModule("shell", NewShellModule().Loader)
+		if err := L.DoString(tt.src); err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		if got := L.GetGlobal("text"); got != tt.wantText {
+			t.Errorf("%s: text = %q, want %q", tt.mode, got, tt.wantText)
+		}
+		if got := L.GetGlobal("err"); (tt.wantErr == "" && got != lua.LNil) || (tt.wantErr != "" && got.String() != tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.mode, got, tt.wantErr)
+		}
+		L.Close()
+	}
+}
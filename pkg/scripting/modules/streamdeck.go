@@ -1,34 +1,69 @@
 package modules
 
 import (
+	"fmt"
+	"image"
 	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/merith-tk/nomad/pkg/streamdeck"
 	lua "github.com/yuin/gopher-lua"
 )
 
+// KeyClaimer records which keys a script has claimed. The script manager
+// implements it per script; claimed keys are left to the script to draw.
+type KeyClaimer interface {
+	ClaimKey(key int) error
+	ReleaseKey(key int) bool
+}
+
 // StreamDeckModule exposes Stream Deck hardware control to Lua scripts.
 type StreamDeckModule struct {
 	device *streamdeck.Device
+	claims KeyClaimer // nil disables claim_key
+	input  deckInput  // on_dial / on_touch (see deckinput.go)
 }
 
 // NewStreamDeckModule creates a new StreamDeck module bound to a device.
-func NewStreamDeckModule(device *streamdeck.Device) *StreamDeckModule {
-	return &StreamDeckModule{device: device}
+// claims backs claim_key/release_key and may be nil.
+func NewStreamDeckModule(device *streamdeck.Device, claims KeyClaimer) *StreamDeckModule {
+	return &StreamDeckModule{device: device, claims: claims}
 }
 
 // Loader returns the Lua module loader function.
 func (m *StreamDeckModule) Loader(L *lua.LState) int {
 	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
-		"set_color":      m.sdSetColor,
-		"set_brightness": m.sdSetBrightness,
-		"clear":          m.sdClear,
-		"clear_key":      m.sdClearKey,
-		"reset":          m.sdReset,
-		"get_model":      m.sdGetModel,
-		"get_keys":       m.sdGetKeys,
-		"get_layout":     m.sdGetLayout,
+		"set_color":         m.sdSetColor,
+		"set_all":           m.sdSetAll,
+		"set_image":         m.sdSetImage,
+		"set_image_bytes":   m.sdSetImageBytes,
+		"set_qr":            m.sdSetQR,
+		"fill_gradient":     m.sdFillGradient,
+		"set_row":           m.sdSetRow,
+		"set_col":           m.sdSetCol,
+		"set_brightness":    m.sdSetBrightness,
+		"get_brightness":    m.sdGetBrightness,
+		"adjust_brightness": m.sdAdjustBrightness,
+		"clear":             m.sdClear,
+		"clear_key":         m.sdClearKey,
+		"reset":             m.sdReset,
+		"identify":          m.sdIdentify,
+		"haptic":            m.sdHaptic,
+		"screenshot":        m.sdScreenshot,
+		"get_model":         m.sdGetModel,
+		"get_keys":          m.sdGetKeys,
+		"get_layout":        m.sdGetLayout,
+		"capabilities":      m.sdCapabilities,
+		"model_info":        m.sdModelInfo,
+		"on_dial":           m.sdOnDial,
+		"on_touch":          m.sdOnTouch,
+		"claim_key":         m.sdClaimKey,
+		"release_key":       m.sdReleaseKey,
 	})
+	mod.RawSetString("wave", m.loadWave(L))
 	L.Push(mod)
 	return 1
 }
@@ -43,16 +78,21 @@ func (m *StreamDeckModule) checkDevice(L *lua.LState) bool {
 }
 
 // sdSetColor sets a single key to a solid RGB color.
+// The colour may be given as three integers, a hex string or an {r, g, b} table.
 // Lua: streamdeck.set_color(key, r, g, b) -> ok, err
+// Lua: streamdeck.set_color(key, "#ff8800") -> ok, err
+// Lua: streamdeck.set_color(key, {r, g, b}) -> ok, err
 func (m *StreamDeckModule) sdSetColor(L *lua.LState) int {
 	if !m.checkDevice(L) {
 		return 2
 	}
 	key := L.CheckInt(1)
-	r := L.CheckInt(2)
-	g := L.CheckInt(3)
-	b := L.CheckInt(4)
-	c := color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}
+	c, err := checkColorArg(L, 2)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
 	if err := m.device.SetKeyColor(key, c); err != nil {
 		L.Push(lua.LFalse)
 		L.Push(lua.LString(err.Error()))
@@ -63,8 +103,185 @@ func (m *StreamDeckModule) sdSetColor(L *lua.LState) int {
 	return 2
 }
 
-// sdSetBrightness sets the global brightness (0-100).
-// Lua: streamdeck.set_brightness(percent) -> ok, err
+// sdSetImage draws an image file on a key. Relative paths are resolved
+// against the script's folder, like appearance images.
+// Lua: streamdeck.set_image(key, path) -> ok, err
+func (m *StreamDeckModule) sdSetImage(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	key := L.CheckInt(1)
+	path := L.CheckString(2)
+	if !filepath.IsAbs(path) {
+		if script, ok := L.GetGlobal("SCRIPT_PATH").(lua.LString); ok {
+			path = filepath.Join(filepath.Dir(string(script)), path)
+		}
+	}
+	if err := m.device.SetKeyImageFromFile(key, path); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdSetQR draws a QR code of data (a URL, WiFi string, ...) sized to a key.
+// Lua: streamdeck.set_qr(key, data) -> ok, err
+func (m *StreamDeckModule) sdSetQR(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	key := L.CheckInt(1)
+	data := L.CheckString(2)
+	if err := m.device.SetQR(key, data); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdFillGradient fills a key with a gradient between two colours (hex
+// strings or {r, g, b} tables). style is "horizontal" (the default, left to
+// right), "vertical" (top to bottom), "radial" (centre outwards) or an angle
+// in degrees clockwise from left-to-right.
+// Lua: streamdeck.fill_gradient(key, from, to [, style]) -> ok, err
+func (m *StreamDeckModule) sdFillGradient(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	key := L.CheckInt(1)
+	g, err := gradientArgs(L)
+	if err == nil {
+		err = m.device.FillGradient(key, g)
+	}
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// gradientArgs reads the from, to and style arguments of fill_gradient.
+func gradientArgs(L *lua.LState) (streamdeck.Gradient, error) {
+	var g streamdeck.Gradient
+	var err error
+	if g.From, err = colorValue(L.Get(2)); err != nil {
+		return g, err
+	}
+	if g.To, err = colorValue(L.Get(3)); err != nil {
+		return g, err
+	}
+	switch style := L.Get(4).(type) {
+	case lua.LNumber:
+		g.Angle = float64(style)
+	case lua.LString:
+		switch style {
+		case "horizontal":
+		case "vertical":
+			g.Angle = 90
+		case "radial":
+			g.Radial = true
+		default:
+			return g, fmt.Errorf("unknown gradient style %q", string(style))
+		}
+	case *lua.LNilType:
+	default:
+		return g, fmt.Errorf("gradient style must be a string or angle, got %s", style.Type())
+	}
+	return g, nil
+}
+
+// sdSetImageBytes draws encoded image bytes on a key. Bytes already in the
+// deck's format and key size (see capabilities) skip the decode and
+// re-encode; see Device.SetImageRaw.
+// Lua: streamdeck.set_image_bytes(key, bytes) -> ok, err
+func (m *StreamDeckModule) sdSetImageBytes(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	key := L.CheckInt(1)
+	data := L.CheckString(2)
+	if err := m.device.SetImageRaw(key, []byte(data)); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdSetAll sets every key to one colour in a single batch.
+// Lua: streamdeck.set_all(r, g, b) -> ok, err   (or a hex string / {r, g, b})
+func (m *StreamDeckModule) sdSetAll(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	return m.setKeys(L, m.device.Model.AllKeys(), 1)
+}
+
+// sdSetRow sets a zero-based row of keys to one colour.
+// Lua: streamdeck.set_row(row, r, g, b) -> ok, err
+func (m *StreamDeckModule) sdSetRow(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	row := L.CheckInt(1)
+	keys := m.device.RowKeys(row)
+	if keys == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(fmt.Sprintf("row %d out of range (0-%d)", row, m.device.Rows()-1)))
+		return 2
+	}
+	return m.setKeys(L, keys, 2)
+}
+
+// sdSetCol sets a zero-based column of keys to one colour.
+// Lua: streamdeck.set_col(col, r, g, b) -> ok, err
+func (m *StreamDeckModule) sdSetCol(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	col := L.CheckInt(1)
+	keys := m.device.ColKeys(col)
+	if keys == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(fmt.Sprintf("column %d out of range (0-%d)", col, m.device.Cols()-1)))
+		return 2
+	}
+	return m.setKeys(L, keys, 2)
+}
+
+// setKeys colours keys with the colour argument at stack index n.
+func (m *StreamDeckModule) setKeys(L *lua.LState, keys []int, n int) int {
+	c, err := checkColorArg(L, n)
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if err := m.device.SetKeysColor(keys, c); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdSetBrightness sets the global brightness, clamped to 0-100, and returns
+// the level applied. The level is truthy, so `if set_brightness(x)` checks
+// still work.
+// Lua: streamdeck.set_brightness(percent) -> level, err
 func (m *StreamDeckModule) sdSetBrightness(L *lua.LState) int {
 	if !m.checkDevice(L) {
 		return 2
@@ -74,7 +291,36 @@ func (m *StreamDeckModule) sdSetBrightness(L *lua.LState) int {
 		L.Push(lua.LString(err.Error()))
 		return 2
 	}
-	L.Push(lua.LTrue)
+	L.Push(lua.LNumber(m.device.Brightness()))
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdGetBrightness returns the brightness last set (0-100; 100 if never set).
+// Lua: streamdeck.get_brightness() -> number
+func (m *StreamDeckModule) sdGetBrightness(L *lua.LState) int {
+	if m.device == nil {
+		L.Push(lua.LNumber(0))
+		return 1
+	}
+	L.Push(lua.LNumber(m.device.Brightness()))
+	return 1
+}
+
+// sdAdjustBrightness changes the brightness by delta percentage points,
+// clamped to 0-100, and returns the new level.
+// Lua: streamdeck.adjust_brightness(delta) -> level, err
+func (m *StreamDeckModule) sdAdjustBrightness(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	level, err := m.device.AdjustBrightness(L.CheckInt(1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LNumber(level))
 	L.Push(lua.LNil)
 	return 2
 }
@@ -128,6 +374,63 @@ func (m *StreamDeckModule) sdReset(L *lua.LState) int {
 	return 2
 }
 
+// sdIdentify blinks the deck for a couple of seconds so it can be located.
+// Returns immediately; the blink runs in the background.
+// Lua: streamdeck.identify() -> ok, err
+func (m *StreamDeckModule) sdIdentify(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	m.device.Identify()
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdHaptic plays a vibration pattern ("short", "long" or "double"; default
+// "short") on decks with haptics. Other decks return false and an error.
+// Lua: streamdeck.haptic([pattern]) -> ok, err
+func (m *StreamDeckModule) sdHaptic(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	pattern, err := streamdeck.ParseHapticPattern(L.OptString(1, ""))
+	if err == nil {
+		err = m.device.Haptic(pattern)
+	}
+	if err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdScreenshot saves a PNG of what is currently shown on the deck.
+// Lua: streamdeck.screenshot(path) -> ok, err
+func (m *StreamDeckModule) sdScreenshot(L *lua.LState) int {
+	if !m.checkDevice(L) {
+		return 2
+	}
+	path := L.CheckString(1)
+	img := m.device.Snapshot()
+	if img == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("device has no display"))
+		return 2
+	}
+	if err := writePNG(path, img); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
 // sdGetModel returns the device model name.
 // Lua: streamdeck.get_model() -> string
 func (m *StreamDeckModule) sdGetModel(L *lua.LState) int {
@@ -158,7 +461,129 @@ func (m *StreamDeckModule) sdGetLayout(L *lua.LState) int {
 		L.Push(lua.LNumber(0))
 		return 2
 	}
-	L.Push(lua.LNumber(m.device.Model.Cols))
-	L.Push(lua.LNumber(m.device.Model.Rows))
+	L.Push(lua.LNumber(m.device.Cols()))
+	L.Push(lua.LNumber(m.device.Rows()))
 	return 2
 }
+
+// sdCapabilities describes the connected hardware in one table:
+// name, product_id, cols, rows, keys, pixel_size, image_format,
+// has_display, has_haptics, has_touch_strip and dials.
+// Lua: streamdeck.capabilities() -> table | nil, err
+func (m *StreamDeckModule) sdCapabilities(L *lua.LState) int {
+	if m.device == nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("no device connected"))
+		return 2
+	}
+	L.Push(modelTable(L, m.device.Model, m.device.Cols(), m.device.Rows()))
+	return 1
+}
+
+// sdModelInfo describes a known model by name (case-insensitive), in the
+// same form as capabilities, whether or not that model is connected.
+// Lua: streamdeck.model_info(name) -> table | nil, err
+func (m *StreamDeckModule) sdModelInfo(L *lua.LState) int {
+	name := L.CheckString(1)
+	model, ok := streamdeck.FindModel(name)
+	if !ok {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("unknown model %q (known: %s)", name, strings.Join(streamdeck.ListModelNames(), ", "))))
+		return 2
+	}
+	L.Push(modelTable(L, model, model.Cols, model.Rows))
+	return 1
+}
+
+// modelTable converts a model to the table returned by capabilities and
+// model_info. cols and rows are passed separately as a connected deck
+// reports them as mounted.
+func modelTable(L *lua.LState, model streamdeck.Model, cols, rows int) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("name", lua.LString(model.Name))
+	t.RawSetString("product_id", lua.LNumber(model.ProductID))
+	t.RawSetString("cols", lua.LNumber(cols))
+	t.RawSetString("rows", lua.LNumber(rows))
+	t.RawSetString("keys", lua.LNumber(model.Keys))
+	t.RawSetString("pixel_size", lua.LNumber(model.PixelSize))
+	t.RawSetString("image_format", lua.LString(model.ImageFormat))
+	t.RawSetString("has_display", lua.LBool(model.HasDisplay()))
+	t.RawSetString("has_haptics", lua.LBool(model.HasHaptics))
+	t.RawSetString("has_touch_strip", lua.LBool(model.TouchStrip))
+	t.RawSetString("dials", lua.LNumber(model.Dials))
+	return t
+}
+
+// sdClaimKey takes ownership of a key so page rendering and other scripts
+// leave it alone and presses on it run this script's trigger. Draw on it
+// with set_color etc.; claims end with release_key or when the script is
+// unloaded.
+// Lua: streamdeck.claim_key(key) -> ok, err
+func (m *StreamDeckModule) sdClaimKey(L *lua.LState) int {
+	key := L.CheckInt(1)
+	if m.claims == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("key claims are not available here"))
+		return 2
+	}
+	if err := m.claims.ClaimKey(key); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// sdReleaseKey gives a claimed key back to the page.
+// Lua: streamdeck.release_key(key) -> bool (false if not claimed by this script)
+func (m *StreamDeckModule) sdReleaseKey(L *lua.LState) int {
+	key := L.CheckInt(1)
+	L.Push(lua.LBool(m.claims != nil && m.claims.ReleaseKey(key)))
+	return 1
+}
+
+// checkColorArg reads a colour starting at stack index n. Accepts a hex string,
+// an {r, g, b} table, or three integers (r, g, b at n, n+1, n+2).
+func checkColorArg(L *lua.LState, n int) (color.RGBA, error) {
+	switch v := L.Get(n).(type) {
+	case lua.LString, *lua.LTable:
+		return colorValue(v)
+	default:
+		r := clampChannel(L.CheckInt(n))
+		g := clampChannel(L.CheckInt(n + 1))
+		b := clampChannel(L.CheckInt(n + 2))
+		return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}, nil
+	}
+}
+
+// colorValue converts a hex string or {r, g, b} table to a colour.
+func colorValue(v lua.LValue) (color.RGBA, error) {
+	switch v := v.(type) {
+	case lua.LString:
+		r, g, b, err := parseHexColor(string(v))
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		return color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 255}, nil
+	case *lua.LTable:
+		c := tableToRGB(v)
+		return color.RGBA{R: uint8(c[0]), G: uint8(c[1]), B: uint8(c[2]), A: 255}, nil
+	default:
+		return color.RGBA{}, fmt.Errorf("expected a colour, got %s", v.Type())
+	}
+}
+
+// writePNG encodes img as a PNG file at path.
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("png encode: %w", err)
+	}
+	return f.Close()
+}
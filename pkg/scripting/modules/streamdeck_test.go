@@ -0,0 +1,278 @@
+package modules
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestSetImageRelativeToScript(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "icon.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	L := lua.NewState()
+	defer L.Close()
+	L.SetGlobal("SCRIPT_PATH", lua.LString(filepath.Join(dir, "script.lua")))
+	L.PreloadModule("streamdeck", NewStreamDeckModule(dev, nil).Loader)
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		ok, err = deck.set_image(4, "icon.png")
+		missing_ok, missing_err = deck.set_image(4, "missing.png")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("set_image failed: %v", L.GetGlobal("err"))
+	}
+	if dev.KeyData(4) == nil {
+		t.Error("set_image did not draw the key")
+	}
+	if L.GetGlobal("missing_ok") != lua.LFalse || L.GetGlobal("missing_err") == lua.LNil {
+		t.Error("missing image did not return false, err")
+	}
+}
+
+func TestSetImageBytesWritesNativeJPEG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 72, 72)), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(dev, nil).Loader)
+	L.SetGlobal("data", lua.LString(buf.String()))
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		ok, err = deck.set_image_bytes(6, data)
+		bad_ok, bad_err = deck.set_image_bytes(6, "not an image")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("set_image_bytes failed: %v", L.GetGlobal("err"))
+	}
+	if !bytes.Equal(dev.KeyData(6), buf.Bytes()) {
+		t.Error("MK.2 JPEG bytes were not written raw")
+	}
+	if L.GetGlobal("bad_ok") != lua.LFalse || L.GetGlobal("bad_err") == lua.LNil {
+		t.Error("invalid bytes did not return false, err")
+	}
+}
+
+func TestSetBrightnessReturnsAppliedLevel(t *testing.T) {
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(dev, nil).Loader)
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		high, high_err = deck.set_brightness(150)
+		low = deck.set_brightness(-5)
+		mid = deck.set_brightness(40)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if high := L.GetGlobal("high"); high != lua.LNumber(100) || L.GetGlobal("high_err") != lua.LNil {
+		t.Errorf("set_brightness(150) = %v, %v; want 100, nil", high, L.GetGlobal("high_err"))
+	}
+	if low := L.GetGlobal("low"); low != lua.LNumber(0) {
+		t.Errorf("set_brightness(-5) = %v, want 0", low)
+	}
+	if mid := L.GetGlobal("mid"); mid != lua.LNumber(40) || dev.Brightness() != 40 {
+		t.Errorf("set_brightness(40) = %v (device %d), want 40", mid, dev.Brightness())
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		productID uint16
+		want      map[string]lua.LValue
+	}{
+		{0x0080, map[string]lua.LValue{
+			"name":            lua.LString("Stream Deck MK.2"),
+			"product_id":      lua.LNumber(0x0080),
+			"cols":            lua.LNumber(5),
+			"rows":            lua.LNumber(3),
+			"keys":            lua.LNumber(15),
+			"pixel_size":      lua.LNumber(72),
+			"image_format":    lua.LString("JPEG"),
+			"has_display":     lua.LTrue,
+			"has_haptics":     lua.LFalse,
+			"has_touch_strip": lua.LFalse,
+			"dials":           lua.LNumber(0),
+		}},
+		{0x0086, map[string]lua.LValue{
+			"name":            lua.LString("Stream Deck Pedal"),
+			"product_id":      lua.LNumber(0x0086),
+			"cols":            lua.LNumber(3),
+			"rows":            lua.LNumber(1),
+			"keys":            lua.LNumber(3),
+			"pixel_size":      lua.LNumber(0),
+			"image_format":    lua.LString(""),
+			"has_display":     lua.LFalse,
+			"has_haptics":     lua.LFalse,
+			"has_touch_strip": lua.LFalse,
+			"dials":           lua.LNumber(0),
+		}},
+	}
+
+	for _, tt := range tests {
+		dev := streamdeck.OpenVirtual(streamdeck.Models[tt.productID])
+		L := lua.NewState()
+		L.PreloadModule("streamdeck", NewStreamDeckModule(dev, nil).Loader)
+		if err := L.DoString(`caps = require("streamdeck").capabilities()`); err != nil {
+			t.Fatal(err)
+		}
+		caps, ok := L.GetGlobal("caps").(*lua.LTable)
+		if !ok {
+			t.Fatalf("%s: capabilities() = %v", dev.Model.Name, L.GetGlobal("caps"))
+		}
+		n := 0
+		caps.ForEach(func(lua.LValue, lua.LValue) { n++ })
+		if n != len(tt.want) {
+			t.Errorf("%s: %d fields, want %d", dev.Model.Name, n, len(tt.want))
+		}
+		for field, want := range tt.want {
+			if got := caps.RawGetString(field); got != want {
+				t.Errorf("%s: %s = %v, want %v", dev.Model.Name, field, got, want)
+			}
+		}
+		L.Close()
+	}
+}
+
+func TestFillGradient(t *testing.T) {
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(dev, nil).Loader)
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		ok, err = deck.fill_gradient(0, "#ff0000", {0, 0, 255}, "radial")
+		angle_ok = deck.fill_gradient(1, "#000000", "#ffffff", 45)
+		bad_ok, bad_err = deck.fill_gradient(2, "#000000", "#ffffff", "spiral")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if L.GetGlobal("ok") != lua.LTrue {
+		t.Fatalf("fill_gradient failed: %v", L.GetGlobal("err"))
+	}
+	if L.GetGlobal("angle_ok") != lua.LTrue {
+		t.Error("fill_gradient with an angle failed")
+	}
+	if dev.KeyData(0) == nil || dev.KeyData(1) == nil {
+		t.Error("fill_gradient did not draw the keys")
+	}
+	if L.GetGlobal("bad_ok") != lua.LFalse || L.GetGlobal("bad_err") == lua.LNil {
+		t.Error("unknown style did not return false, err")
+	}
+	if dev.KeyData(2) != nil {
+		t.Error("unknown style drew the key")
+	}
+}
+
+func TestModelInfo(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(nil, nil).Loader)
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		xl = deck.model_info("stream deck xl")
+		missing, err = deck.model_info("Stream Deck Max")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	xl, ok := L.GetGlobal("xl").(*lua.LTable)
+	if !ok {
+		t.Fatal("model_info returned no table for a known model")
+	}
+	for field, want := range map[string]lua.LValue{
+		"name":         lua.LString("Stream Deck XL"),
+		"cols":         lua.LNumber(8),
+		"rows":         lua.LNumber(4),
+		"keys":         lua.LNumber(32),
+		"pixel_size":   lua.LNumber(96),
+		"image_format": lua.LString("JPEG"),
+	} {
+		if got := xl.RawGetString(field); got != want {
+			t.Errorf("xl.%s = %v, want %v", field, got, want)
+		}
+	}
+	if L.GetGlobal("missing") != lua.LNil {
+		t.Error("model_info returned a table for an unknown model")
+	}
+	if err := L.GetGlobal("err").String(); !strings.Contains(err, "Stream Deck MK.2") {
+		t.Errorf("error %q does not list the known models", err)
+	}
+}
+
+func TestCheckColorArg(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	var got color.RGBA
+	var gotErr error
+	L.SetGlobal("color_arg", L.NewFunction(func(L *lua.LState) int {
+		got, gotErr = checkColorArg(L, 1)
+		return 0
+	}))
+
+	tests := []struct {
+		src  string
+		want color.RGBA
+	}{
+		{`color_arg(255, 136, 0)`, color.RGBA{255, 136, 0, 255}},
+		{`color_arg("#ff8800")`, color.RGBA{255, 136, 0, 255}},
+		{`color_arg("f80")`, color.RGBA{255, 136, 0, 255}},
+		{`color_arg({255, 136, 0})`, color.RGBA{255, 136, 0, 255}},
+		// Out-of-range channels saturate instead of wrapping
+		{`color_arg(300, -5, 0)`, color.RGBA{255, 0, 0, 255}},
+		{`color_arg({999, -1, 0})`, color.RGBA{255, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		if err := L.DoString(tt.src); err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		if gotErr != nil || got != tt.want {
+			t.Errorf("%s = %v, %v; want %v", tt.src, got, gotErr, tt.want)
+		}
+	}
+
+	if err := L.DoString(`color_arg("#ff88")`); err != nil {
+		t.Fatal(err)
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "invalid hex colour") {
+		t.Errorf("bad hex: err = %v, want invalid hex colour", gotErr)
+	}
+
+	// Arguments that are not a colour at all raise a Lua error
+	for _, src := range []string{`color_arg(true)`, `color_arg(255)`, `color_arg(255, "green", 0)`} {
+		err := L.DoString(src)
+		if err == nil || !strings.Contains(err.Error(), "expected") {
+			t.Errorf("%s: err = %v, want an argument error", src, err)
+		}
+	}
+}
@@ -11,6 +11,9 @@
 //	system     - OS detection, environment, sleep (yield), refresh
 //	streamdeck - direct hardware control (brightness, key colour, layout)
 //	file       - read/write files within the config directory
+//	color      - hex / HSV parsing, interpolation and named colours
+//	weather    - current conditions from Open-Meteo, cached
+//	random     - per-script random ints, floats, choice and shuffle
 //
 // The lualib package provides additional pure-Go stdlib replacements:
 //
@@ -0,0 +1,195 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// watchDebounce coalesces the burst of events editors produce for a single
+// save into one callback.
+var watchDebounce = 100 * time.Millisecond
+
+// CallbackFunc runs a Lua callback on the owning runner's VM. It is how
+// modules deliver events from their own goroutines safely.
+type CallbackFunc func(fn *lua.LFunction, args ...lua.LValue)
+
+// fileWatch is one file.watch registration.
+type fileWatch struct {
+	target string // absolute path being watched
+	isDir  bool   // report changes to any file inside target
+	fn     *lua.LFunction
+}
+
+// matches returns the path to report for an event on name, or "".
+func (w *fileWatch) matches(name string) string {
+	switch {
+	case name == w.target:
+		return name
+	case w.isDir && filepath.Dir(name) == w.target:
+		return name
+	}
+	return ""
+}
+
+// watchOp names an fsnotify operation for scripts.
+func watchOp(op fsnotify.Op) string {
+	switch {
+	case op.Has(fsnotify.Create):
+		return "create"
+	case op.Has(fsnotify.Write):
+		return "write"
+	case op.Has(fsnotify.Remove):
+		return "remove"
+	case op.Has(fsnotify.Rename):
+		return "rename"
+	}
+	return ""
+}
+
+// fileWatch watches a file (or the files in a directory) and calls
+// fn(path, op) after it changes; op is "create", "write", "remove" or
+// "rename". Files are watched through their directory so editors that save
+// by replacing the file keep working.
+// Lua: file.watch(path, fn) -> ok, err
+func (m *FileModule) fileWatch(L *lua.LState) int {
+	path := L.CheckString(1)
+	fn := L.CheckFunction(2)
+
+	if !m.checkAccess(path) {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("access denied"))
+		return 2
+	}
+	if m.invoke == nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString("file.watch is not available here"))
+		return 2
+	}
+
+	if err := m.addWatch(path, fn); err != nil {
+		L.Push(lua.LFalse)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	L.Push(lua.LNil)
+	return 2
+}
+
+// addWatch registers a watch, starting the watcher on first use.
+func (m *FileModule) addWatch(path string, fn *lua.LFunction) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	w := &fileWatch{target: abs, fn: fn}
+	dir := filepath.Dir(abs)
+	if fi, err := os.Stat(abs); err == nil && fi.IsDir() {
+		w.isDir = true
+		dir = abs
+	}
+
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	if m.watchClosed {
+		return errors.New("file module closed")
+	}
+	if m.watcher == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return fmt.Errorf("watch: %w", err)
+		}
+		m.watcher = watcher
+		m.pending = make(map[string]*time.Timer)
+		m.watchDone = make(chan struct{})
+		go m.watchLoop(watcher)
+	}
+	if err := m.watcher.Add(dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	m.watches = append(m.watches, w)
+	return nil
+}
+
+// watchLoop turns watcher events into debounced callbacks.
+func (m *FileModule) watchLoop(watcher *fsnotify.Watcher) {
+	defer close(m.watchDone)
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			op := watchOp(ev.Op)
+			if op == "" {
+				continue
+			}
+			m.watchMu.Lock()
+			for _, w := range m.watches {
+				if p := w.matches(ev.Name); p != "" {
+					m.schedule(w, p, op)
+				}
+			}
+			m.watchMu.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("[!] file.watch: %v\n", err)
+		}
+	}
+}
+
+// schedule (re)starts the debounce timer for a watch and path; the callback
+// gets the last op seen. Must be called with watchMu held.
+func (m *FileModule) schedule(w *fileWatch, path, op string) {
+	key := fmt.Sprintf("%p\x00%s", w, path)
+	if t, ok := m.pending[key]; ok {
+		t.Stop()
+	}
+	m.pending[key] = time.AfterFunc(watchDebounce, func() {
+		m.watchMu.Lock()
+		delete(m.pending, key)
+		closed := m.watchClosed
+		m.watchMu.Unlock()
+		if !closed {
+			m.invoke(w.fn, lua.LString(path), lua.LString(op))
+		}
+	})
+}
+
+// Watching reports whether the script has a file watch.
+func (m *FileModule) Watching() bool {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	return len(m.watches) > 0 && !m.watchClosed
+}
+
+// Close stops all file watches. The runner calls it when it is closed.
+func (m *FileModule) Close() error {
+	m.watchMu.Lock()
+	if m.watchClosed {
+		m.watchMu.Unlock()
+		return nil
+	}
+	m.watchClosed = true
+	watcher, done := m.watcher, m.watchDone
+	for key, t := range m.pending {
+		t.Stop()
+		delete(m.pending, key)
+	}
+	m.watchMu.Unlock()
+
+	if watcher == nil {
+		return nil
+	}
+	err := watcher.Close()
+	<-done
+	return err
+}
@@ -0,0 +1,146 @@
+package modules
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// maxWaveFPS caps streamdeck.wave; every frame may upload every key.
+const maxWaveFPS = 60
+
+// waveNow is the clock used by streamdeck.wave (replaced in tests).
+var waveNow = time.Now
+
+// waveChunk is the Lua half of streamdeck.wave. The loop has to live in Lua
+// because gopher-lua cannot resume a Go function after it yields; the Go
+// closures passed in do the scheduling and drawing. Yielding a number is the
+// same sleep request system.sleep makes.
+const waveChunk = `
+local new_clock, apply = ...
+return function(fn, fps, seconds)
+	local next_frame, wait = new_clock(fps, seconds)
+	while true do
+		local t = next_frame()
+		if t == nil then return end
+		apply(fn(t))
+		coroutine.yield(wait())
+	end
+end
+`
+
+// waveClock schedules frames on a fixed grid from start, so time spent in
+// fn and on USB writes does not accumulate as drift. Frames that are
+// already late are skipped rather than replayed.
+type waveClock struct {
+	start    time.Time
+	interval time.Duration
+	limit    time.Duration // 0 = run forever
+	frame    int           // index of the next frame
+}
+
+// next returns the animation time of the frame due now, or false once the
+// limit has passed.
+func (c *waveClock) next(now time.Time) (float64, bool) {
+	elapsed := now.Sub(c.start)
+	if c.limit > 0 && elapsed >= c.limit {
+		return 0, false
+	}
+	if n := int(elapsed / c.interval); n > c.frame {
+		c.frame = n
+	}
+	t := (time.Duration(c.frame) * c.interval).Seconds()
+	c.frame++
+	return t, true
+}
+
+// wait returns the milliseconds until the next frame is due (at least 1).
+func (c *waveClock) wait(now time.Time) int {
+	due := c.start.Add(time.Duration(c.frame) * c.interval)
+	return max(int(math.Ceil(float64(due.Sub(now))/float64(time.Millisecond))), 1)
+}
+
+// loadWave builds the streamdeck.wave function.
+func (m *StreamDeckModule) loadWave(L *lua.LState) lua.LValue {
+	chunk, err := L.LoadString(waveChunk)
+	if err != nil {
+		panic(fmt.Sprintf("streamdeck.wave: %v", err)) // constant source
+	}
+	L.Push(chunk)
+	L.Push(L.NewFunction(m.waveNewClock))
+	L.Push(L.NewFunction(m.waveApply))
+	L.Call(2, 1)
+	return L.Get(-1)
+}
+
+// waveNewClock returns next_frame() and wait() closures for one wave.
+func (m *StreamDeckModule) waveNewClock(L *lua.LState) int {
+	fps := float64(L.CheckNumber(1))
+	if fps <= 0 || fps > maxWaveFPS {
+		L.ArgError(1, fmt.Sprintf("fps must be between 0 and %d", maxWaveFPS))
+	}
+	c := &waveClock{
+		start:    waveNow(),
+		interval: time.Duration(float64(time.Second) / fps),
+		limit:    time.Duration(float64(L.OptNumber(2, 0)) * float64(time.Second)),
+	}
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		t, ok := c.next(waveNow())
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		L.Push(lua.LNumber(t))
+		return 1
+	}))
+	L.Push(L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LNumber(c.wait(waveNow())))
+		return 1
+	}))
+	return 2
+}
+
+// waveApply draws one frame: a table of key index -> colour. Keys sharing a
+// colour are written as one batch; missing keys are left unchanged.
+func (m *StreamDeckModule) waveApply(L *lua.LState) int {
+	tbl, ok := L.Get(1).(*lua.LTable)
+	if !ok || m.device == nil {
+		return 0
+	}
+
+	groups := make(map[color.RGBA][]int)
+	var order []color.RGBA
+	var bad error
+	tbl.ForEach(func(k, v lua.LValue) {
+		n, isNum := k.(lua.LNumber)
+		if !isNum || bad != nil {
+			return
+		}
+		key := int(n)
+		if key < 0 || key >= m.device.Model.Keys {
+			return
+		}
+		c, err := colorValue(v)
+		if err != nil {
+			bad = fmt.Errorf("key %d: %w", key, err)
+			return
+		}
+		if _, seen := groups[c]; !seen {
+			order = append(order, c)
+		}
+		groups[c] = append(groups[c], key)
+	})
+	if bad != nil {
+		L.RaiseError("streamdeck.wave: %v", bad)
+	}
+
+	for _, c := range order {
+		if err := m.device.SetKeysColor(groups[c], c); err != nil {
+			L.RaiseError("streamdeck.wave: %v", err)
+		}
+	}
+	return 0
+}
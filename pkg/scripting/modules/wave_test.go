@@ -0,0 +1,107 @@
+package modules
+
+import (
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// TestWaveCadence drives streamdeck.wave the way the background loop does:
+// resume, sleep for the yielded milliseconds, resume again. Each frame also
+// costs 7ms of work, which the helper must absorb instead of drifting.
+func TestWaveCadence(t *testing.T) {
+	now := time.Unix(1000, 0)
+	waveNow = func() time.Time { return now }
+	defer func() { waveNow = time.Now }()
+
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(nil, nil).Loader)
+
+	if err := L.DoString(`
+		local deck = require("streamdeck")
+		times = {}
+		wave = function()
+			deck.wave(function(t)
+				times[#times + 1] = t
+				return { [0] = {255, 0, 0} }
+			end, 20, 0.25)
+		end
+	`); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	co, _ := L.NewThread()
+	fn := L.GetGlobal("wave").(*lua.LFunction)
+	var waits []int
+	for i := 0; ; i++ {
+		if i > 20 {
+			t.Fatal("wave did not finish")
+		}
+		var st lua.ResumeState
+		var err error
+		var vals []lua.LValue
+		if i == 0 {
+			st, err, vals = L.Resume(co, fn)
+		} else {
+			st, err, vals = L.Resume(co, nil)
+		}
+		if err != nil {
+			t.Fatalf("resume: %v", err)
+		}
+		if st == lua.ResumeOK {
+			break
+		}
+		ms := int(vals[0].(lua.LNumber))
+		waits = append(waits, ms)
+		now = now.Add(time.Duration(ms) * time.Millisecond).Add(7 * time.Millisecond)
+	}
+
+	// 20 fps for 0.25s = frames at 0, 50, 100, 150, 200ms
+	times := L.GetGlobal("times").(*lua.LTable)
+	if times.Len() != 5 {
+		t.Fatalf("got %d frames, want 5", times.Len())
+	}
+	for i := 1; i <= times.Len(); i++ {
+		want := float64(i-1) * 0.05
+		if got := float64(times.RawGetInt(i).(lua.LNumber)); got < want-1e-9 || got > want+1e-9 {
+			t.Fatalf("frame %d at t=%v, want %v", i, got, want)
+		}
+	}
+	// The first wait is a full frame; after that the 7ms of work per
+	// frame is subtracted.
+	if waits[0] != 50 {
+		t.Fatalf("first wait %dms, want 50", waits[0])
+	}
+	for i, w := range waits[1:] {
+		if w != 43 {
+			t.Fatalf("wait %d = %dms, want 43", i+1, w)
+		}
+	}
+}
+
+func TestWaveClockSkipsLateFrames(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := &waveClock{start: start, interval: 100 * time.Millisecond}
+
+	if tm, _ := c.next(start); tm != 0 {
+		t.Fatalf("first frame t=%v, want 0", tm)
+	}
+	// Stalled for 350ms: jump to frame 3 rather than replaying 1 and 2.
+	if tm, _ := c.next(start.Add(350 * time.Millisecond)); tm != 0.3 {
+		t.Fatalf("late frame t=%v, want 0.3", tm)
+	}
+	if w := c.wait(start.Add(350 * time.Millisecond)); w != 50 {
+		t.Fatalf("wait %dms, want 50", w)
+	}
+}
+
+func TestWaveRejectsBadFPS(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+	L.PreloadModule("streamdeck", NewStreamDeckModule(nil, nil).Loader)
+	if err := L.DoString(`require("streamdeck").wave(function() end, 0)`); err == nil {
+		t.Fatal("expected an error for fps 0")
+	}
+}
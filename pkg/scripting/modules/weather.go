@@ -0,0 +1,158 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// weatherAPIURL is the Open-Meteo forecast endpoint (free, no API key).
+var weatherAPIURL = "https://api.open-meteo.com/v1/forecast"
+
+// weatherCacheTTL is how long a lookup for the same place is reused. Passive
+// scripts can call weather.current every tick without hitting the API.
+const weatherCacheTTL = 10 * time.Minute
+
+// weatherCodes maps WMO weather interpretation codes to short descriptions.
+var weatherCodes = map[int]string{
+	0: "Clear", 1: "Mostly clear", 2: "Partly cloudy", 3: "Overcast",
+	45: "Fog", 48: "Rime fog",
+	51: "Light drizzle", 53: "Drizzle", 55: "Heavy drizzle",
+	56: "Freezing drizzle", 57: "Freezing drizzle",
+	61: "Light rain", 63: "Rain", 65: "Heavy rain",
+	66: "Freezing rain", 67: "Freezing rain",
+	71: "Light snow", 73: "Snow", 75: "Heavy snow", 77: "Snow grains",
+	80: "Light showers", 81: "Showers", 82: "Heavy showers",
+	85: "Snow showers", 86: "Heavy snow showers",
+	95: "Thunderstorm", 96: "Thunderstorm, hail", 99: "Thunderstorm, hail",
+}
+
+// currentWeather is the "current_weather" object of an Open-Meteo response.
+type currentWeather struct {
+	Temperature   float64 `json:"temperature"`
+	WindSpeed     float64 `json:"windspeed"`
+	WindDirection float64 `json:"winddirection"`
+	WeatherCode   int     `json:"weathercode"`
+	IsDay         int     `json:"is_day"`
+	Time          string  `json:"time"`
+}
+
+type weatherCacheEntry struct {
+	current currentWeather
+	expires time.Time
+}
+
+// The cache is shared by all runners so several weather keys for the same
+// place make a single request.
+var (
+	weatherCacheMu sync.Mutex
+	weatherCache   = make(map[string]weatherCacheEntry)
+)
+
+// WeatherModule provides current weather conditions via Open-Meteo.
+type WeatherModule struct {
+	client *http.Client
+}
+
+// NewWeatherModule creates a new weather module.
+func NewWeatherModule() *WeatherModule {
+	return &WeatherModule{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Loader returns the Lua module loader function.
+func (m *WeatherModule) Loader(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"current": m.weatherCurrent,
+	})
+	L.Push(mod)
+	return 1
+}
+
+// weatherCurrent returns the current conditions at a latitude / longitude.
+// units is "metric" (default: °C, km/h) or "imperial" (°F, mph).
+// Lua: weather.current(lat, lon[, units]) -> table | nil, err
+//
+//	{ temperature, wind_speed, wind_direction, code, description, is_day, time }
+func (m *WeatherModule) weatherCurrent(L *lua.LState) int {
+	lat := float64(L.CheckNumber(1))
+	lon := float64(L.CheckNumber(2))
+	units := L.OptString(3, "metric")
+	if units != "metric" && units != "imperial" {
+		L.ArgError(3, "units must be \"metric\" or \"imperial\"")
+		return 0
+	}
+
+	cw, err := m.fetchCurrent(lat, lon, units)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	tbl := L.NewTable()
+	tbl.RawSetString("temperature", lua.LNumber(cw.Temperature))
+	tbl.RawSetString("wind_speed", lua.LNumber(cw.WindSpeed))
+	tbl.RawSetString("wind_direction", lua.LNumber(cw.WindDirection))
+	tbl.RawSetString("code", lua.LNumber(cw.WeatherCode))
+	tbl.RawSetString("description", lua.LString(weatherCodes[cw.WeatherCode]))
+	tbl.RawSetString("is_day", lua.LBool(cw.IsDay == 1))
+	tbl.RawSetString("time", lua.LString(cw.Time))
+	L.Push(tbl)
+	return 1
+}
+
+// fetchCurrent returns cached conditions for the place, or queries the API.
+// Coordinates are rounded to two decimals (~1 km) for the cache key.
+func (m *WeatherModule) fetchCurrent(lat, lon float64, units string) (currentWeather, error) {
+	key := fmt.Sprintf("%.2f,%.2f,%s", lat, lon, units)
+
+	weatherCacheMu.Lock()
+	entry, ok := weatherCache[key]
+	weatherCacheMu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.current, nil
+	}
+
+	if networkBlocked.Load() {
+		return currentWeather{}, errNetworkBlocked
+	}
+
+	q := url.Values{}
+	q.Set("latitude", strconv.FormatFloat(lat, 'f', 4, 64))
+	q.Set("longitude", strconv.FormatFloat(lon, 'f', 4, 64))
+	q.Set("current_weather", "true")
+	if units == "imperial" {
+		q.Set("temperature_unit", "fahrenheit")
+		q.Set("windspeed_unit", "mph")
+	}
+
+	resp, err := m.client.Get(weatherAPIURL + "?" + q.Encode())
+	if err != nil {
+		return currentWeather{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return currentWeather{}, fmt.Errorf("weather API returned HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Current *currentWeather `json:"current_weather"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return currentWeather{}, fmt.Errorf("decode weather response: %w", err)
+	}
+	if body.Current == nil {
+		return currentWeather{}, fmt.Errorf("weather response has no current_weather")
+	}
+
+	weatherCacheMu.Lock()
+	weatherCache[key] = weatherCacheEntry{current: *body.Current, expires: time.Now().Add(weatherCacheTTL)}
+	weatherCacheMu.Unlock()
+	return *body.Current, nil
+}
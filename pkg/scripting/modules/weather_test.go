@@ -0,0 +1,138 @@
+package modules
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// stubTransport answers every request with a canned status and body and
+// records the requested URLs.
+type stubTransport struct {
+	mu     sync.Mutex
+	status int
+	body   string
+	urls   []string
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urls = append(s.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: s.status,
+		Body:       io.NopCloser(strings.NewReader(s.body)),
+		Header:     make(http.Header),
+		Request:    req,
+	}, nil
+}
+
+func (s *stubTransport) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.urls...)
+}
+
+// newStubWeather returns a weather module whose requests go to a stub, and a
+// Lua state with it loaded as "weather". The shared cache starts empty.
+func newStubWeather(t *testing.T, status int, body string) (*stubTransport, *lua.LState) {
+	t.Helper()
+	weatherCacheMu.Lock()
+	weatherCache = make(map[string]weatherCacheEntry)
+	weatherCacheMu.Unlock()
+
+	stub := &stubTransport{status: status, body: body}
+	m := NewWeatherModule()
+	m.client.Transport = stub
+	L := lua.NewState()
+	t.Cleanup(L.Close)
+	L.PreloadModule("weather", m.Loader)
+	return stub, L
+}
+
+const openMeteoBody = `{"latitude": 52.52, "longitude": 13.41, "current_weather": {
+	"temperature": 21.5, "windspeed": 12.3, "winddirection": 270,
+	"weathercode": 61, "is_day": 1, "time": "2024-06-01T12:00"}}`
+
+func TestWeatherCurrent(t *testing.T) {
+	stub, L := newStubWeather(t, http.StatusOK, openMeteoBody)
+	if err := L.DoString(`
+		local weather = require("weather")
+		w, err = weather.current(52.52, 13.405)
+		again = weather.current(52.5201, 13.4049)
+		imperial = weather.current(52.52, 13.405, "imperial")
+	`); err != nil {
+		t.Fatal(err)
+	}
+	w, ok := L.GetGlobal("w").(*lua.LTable)
+	if !ok {
+		t.Fatalf("weather.current = %v, %v", L.GetGlobal("w"), L.GetGlobal("err"))
+	}
+	for field, want := range map[string]lua.LValue{
+		"temperature":    lua.LNumber(21.5),
+		"wind_speed":     lua.LNumber(12.3),
+		"wind_direction": lua.LNumber(270),
+		"code":           lua.LNumber(61),
+		"description":    lua.LString("Light rain"),
+		"is_day":         lua.LTrue,
+		"time":           lua.LString("2024-06-01T12:00"),
+	} {
+		if got := w.RawGetString(field); got != want {
+			t.Errorf("%s = %v, want %v", field, got, want)
+		}
+	}
+
+	// The nearby second lookup is served from the cache; other units are not
+	urls := stub.requests()
+	if len(urls) != 2 {
+		t.Fatalf("made %d requests, want 2: %v", len(urls), urls)
+	}
+	for _, want := range []string{"latitude=52.5200", "longitude=13.4050", "current_weather=true"} {
+		if !strings.Contains(urls[0], want) {
+			t.Errorf("request %s missing %s", urls[0], want)
+		}
+	}
+	if strings.Contains(urls[0], "fahrenheit") || !strings.Contains(urls[1], "temperature_unit=fahrenheit") || !strings.Contains(urls[1], "windspeed_unit=mph") {
+		t.Errorf("unit parameters wrong: %v", urls)
+	}
+}
+
+func TestWeatherErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		blocked bool
+		wantErr string
+	}{
+		{"http error", http.StatusServiceUnavailable, "", false, "HTTP 503"},
+		{"bad json", http.StatusOK, "{", false, "decode weather response"},
+		{"no current", http.StatusOK, `{"latitude": 1}`, false, "no current_weather"},
+		{"blocked", http.StatusOK, openMeteoBody, true, "blocked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub, L := newStubWeather(t, tt.status, tt.body)
+			SetNetworkBlocked(tt.blocked)
+			defer SetNetworkBlocked(false)
+			if err := L.DoString(`w, err = require("weather").current(1, 2)`); err != nil {
+				t.Fatal(err)
+			}
+			if L.GetGlobal("w") != lua.LNil || !strings.Contains(L.GetGlobal("err").String(), tt.wantErr) {
+				t.Errorf("got %v, %v; want nil, %q", L.GetGlobal("w"), L.GetGlobal("err"), tt.wantErr)
+			}
+			if tt.blocked && len(stub.requests()) != 0 {
+				t.Error("request made while the network is blocked")
+			}
+		})
+	}
+
+	_, L := newStubWeather(t, http.StatusOK, openMeteoBody)
+	if err := L.DoString(`require("weather").current(1, 2, "kelvin")`); err == nil || !strings.Contains(err.Error(), "units must be") {
+		t.Errorf("bad units: err = %v", err)
+	}
+}
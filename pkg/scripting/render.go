@@ -0,0 +1,59 @@
+package scripting
+
+// render.go – the render lock. A page change clears the visible scripts,
+// draws the new page and sets its visible scripts; passive, animation and
+// refresh updates wait for all three steps so an update computed for the
+// old page is never drawn over the new one. The passive loop does not wait:
+// while a render is in progress it drops its updates and recomputes them
+// from the new visible set on the next tick.
+
+// LockRender suspends script key updates until UnlockRender. Hold it across
+// SetVisibleScripts(nil), the page render and the SetVisibleScripts call
+// that follows.
+func (m *ScriptManager) LockRender() {
+	m.renderMu.Lock()
+	m.rendering.Store(true)
+	m.recordRenderStart()
+}
+
+// UnlockRender resumes script key updates suspended by LockRender.
+func (m *ScriptManager) UnlockRender() {
+	m.recordRenderEnd()
+	m.rendering.Store(false)
+	m.renderMu.Unlock()
+}
+
+// deliverUpdate passes a script's appearance to callback for the key the
+// script is visible on now, under the render lock. It reports whether the
+// script is visible; updates for hidden scripts are dropped.
+func (m *ScriptManager) deliverUpdate(scriptPath string, appearance *KeyAppearance, callback func(int, *KeyAppearance)) bool {
+	m.renderMu.RLock()
+	defer m.renderMu.RUnlock()
+
+	m.mu.RLock()
+	keyIndex, visible := m.visibleScripts[scriptPath]
+	m.mu.RUnlock()
+
+	// A key claimed by another script is left to that script
+	if visible && m.ownsKey(scriptPath, keyIndex) {
+		callback(keyIndex, appearance)
+	}
+	return visible
+}
+
+// deliverToggleUpdate passes a t1_passive / t2_passive appearance to
+// callback under the render lock, unless the page changed and key is no
+// longer driven by scriptPath.
+func (m *ScriptManager) deliverToggleUpdate(scriptPath string, keyIndex int, appearance *KeyAppearance, callback func(int, *KeyAppearance)) {
+	m.renderMu.RLock()
+	defer m.renderMu.RUnlock()
+
+	m.mu.RLock()
+	current := (m.t1Script == scriptPath && m.t1Key == keyIndex) ||
+		(m.t2Script == scriptPath && m.t2Key == keyIndex)
+	m.mu.RUnlock()
+
+	if current && m.ownsKey(scriptPath, keyIndex) {
+		callback(keyIndex, appearance)
+	}
+}
@@ -0,0 +1,54 @@
+package scripting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRenderSkipsPassiveUpdates(t *testing.T) {
+	m := NewScriptManager(nil, t.TempDir(), 0)
+
+	drawn := make(chan int, 10)
+	m.SetKeyUpdateCallback(func(keyIndex int, _ *KeyAppearance) {
+		drawn <- keyIndex
+	})
+	m.visibleScripts = map[string]int{"page.lua": 4}
+
+	process := func() {
+		t.Helper()
+		done := make(chan struct{})
+		go func() {
+			m.processBatchedUpdates(10)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("processBatchedUpdates blocked on the render")
+		}
+	}
+
+	m.batchUpdate("page.lua", &KeyAppearance{Text: "queued before"})
+	m.LockRender()
+	m.batchUpdate("page.lua", &KeyAppearance{Text: "computed during"})
+	process()
+	if len(drawn) != 0 {
+		t.Errorf("key %d drawn mid-render", <-drawn)
+	}
+	if len(m.passiveBatch) != 0 {
+		t.Errorf("stale updates kept for after the render: %v", m.passiveBatch)
+	}
+
+	m.visibleScripts = map[string]int{"page.lua": 6}
+	m.UnlockRender()
+	m.batchUpdate("page.lua", &KeyAppearance{Text: "after"})
+	process()
+	var got []int
+	for len(drawn) > 0 {
+		got = append(got, <-drawn)
+	}
+	if !reflect.DeepEqual(got, []int{6}) {
+		t.Errorf("drawn keys after the render = %v, want [6]", got)
+	}
+}
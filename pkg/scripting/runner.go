@@ -57,10 +57,73 @@ const (
 
 // KeyAppearance defines how a key should look (returned by passive).
 type KeyAppearance struct {
-	Color     [3]int // RGB color (0-255)
-	Text      string // Text to display
-	TextColor [3]int // Text color RGB
-	Image     string // Path to image file (future)
+	Color     [3]int  // RGB color (0-255)
+	Text      string  // Text to display; drawn as a label over Image when both are set
+	TextColor [3]int  // Text color RGB
+	Outline   *[3]int // Text outline RGB; nil = no outline
+	Image     string  // Path, URL or data URI of a background image
+	Animation string  // Glob of frame images cycled on the key (see animation.go)
+	FPS       float64 // Animation frame rate; 0 = 10
+
+	// Optional filters applied to the rendered key (see streamdeck.ImageFilter)
+	Brightness float64 // Multiplier; 0 or 1 = unchanged
+	Contrast   float64 // Multiplier around mid-grey; 0 or 1 = unchanged
+	Invert     bool
+	Grayscale  bool
+}
+
+// Filter returns the image adjustments requested by the appearance.
+func (a *KeyAppearance) Filter() streamdeck.ImageFilter {
+	return streamdeck.ImageFilter{
+		Brightness: a.Brightness,
+		Contrast:   a.Contrast,
+		Invert:     a.Invert,
+		Grayscale:  a.Grayscale,
+	}
+}
+
+// TriggerEvent identifies the kind of key press that fired a trigger.
+type TriggerEvent string
+
+const (
+	EventTap    TriggerEvent = "tap"    // Short press
+	EventLong   TriggerEvent = "long"   // Press held past the long-press threshold
+	EventDouble TriggerEvent = "double" // Two presses in quick succession
+
+	EventRelease TriggerEvent = "release" // Key let go, passed to trigger_release()
+)
+
+// TriggerContext describes the key press passed to trigger(state, ctx).
+type TriggerContext struct {
+	Key   int // Physical key index
+	Col   int
+	Row   int
+	Event TriggerEvent
+	// The key is down: true for presses, false for EventRelease
+	Pressed bool
+}
+
+// PassiveContext describes the key passed to passive(key, state, ctx).
+type PassiveContext struct {
+	Key     int // Physical key index
+	Col     int
+	Row     int
+	Visible bool // Key is on the current page
+	Pressed bool // Key was pressed within the last RecentPressWindow
+	T1      bool // A script is driving the T1 toggle key
+	T2      bool // A script is driving the T2 toggle key
+}
+
+// ScriptStatus is a snapshot of one script's health, for diagnostics.
+type ScriptStatus struct {
+	Path              string
+	Name              string
+	HasBackground     bool
+	HasPassive        bool
+	HasTrigger        bool
+	BackgroundRunning bool
+	Restarts          int
+	LastError         error
 }
 
 // ScriptRunner manages a single Lua script's lifecycle.
@@ -83,9 +146,10 @@ type ScriptRunner struct {
 	module *lua.LTable
 
 	// Function availability
-	hasBackground bool
-	hasPassive    bool
-	hasTrigger    bool
+	hasBackground     bool
+	hasPassive        bool
+	hasTrigger        bool
+	hasTriggerRelease bool
 
 	// T1 / T2 toggle-key functions (driven by .directory.lua of the current folder)
 	hasT1Passive bool
@@ -97,8 +161,10 @@ type ScriptRunner struct {
 	bgCtx         context.Context
 	bgCancel      context.CancelFunc
 	bgRunning     bool
+	bgDone        chan struct{} // closed when backgroundLoop exits
 	bgRestarts    int
 	restartPolicy RestartPolicy
+	lastErr       error // Most recent background error
 
 	// Background coroutine support
 	bgThread       *lua.LState // Coroutine for background function
@@ -106,21 +172,51 @@ type ScriptRunner struct {
 	bgSleepUntil   time.Time      // When to resume from sleep
 	bgFunc         *lua.LFunction // Cached background function
 
+	// Memory limit context (see limits.go); nil without a memory limit.
+	// limitMu guards limitCancel, which the manager's watchdog reads.
+	limitMu     sync.Mutex
+	limitCtx    context.Context
+	limitCancel context.CancelFunc
+
 	// Device access
 	device    *streamdeck.Device
 	configDir string
 
 	// Refresh callback (called when script wants display update)
 	onRefresh func()
+
+	// File module instance; owns file.watch watchers closed with the runner
+	fileMod *modules.FileModule
+
+	// Destination of the log module
+	logger *lualib.Logger
+
+	// Manager that created the runner (nil in isolation); provides the
+	// event bus and key claims
+	mgr       *ScriptManager
+	eventsMod *modules.EventsModule
+	deckMod   *modules.StreamDeckModule
+	macroMod  *modules.MacroModule
 }
 
-// NewScriptRunner creates a runner for a Lua script.
-func NewScriptRunner(scriptPath string, dev *streamdeck.Device, configDir string) (*ScriptRunner, error) {
+// NewScriptRunner creates a runner for a Lua script. The script's log module
+// writes to logger (stdout, unfiltered, when nil). Events and key claims go
+// through mgr; with a nil mgr they are unavailable. A script that does not
+// parse, or whose top level raises an error, is reported as a *ScriptError.
+func NewScriptRunner(scriptPath string, dev *streamdeck.Device, configDir string, logger *lualib.Logger, mgr *ScriptManager) (*ScriptRunner, error) {
+	// Compile first so a syntax error is reported without creating a state
+	proto, err := compileScript(scriptPath)
+	if err != nil {
+		return nil, err
+	}
+
 	r := &ScriptRunner{
 		ScriptPath:    scriptPath,
 		ScriptName:    filepath.Base(scriptPath[:len(scriptPath)-4]), // Remove .lua
 		device:        dev,
 		configDir:     configDir,
+		logger:        logger,
+		mgr:           mgr,
 		restartPolicy: RestartAlways,
 		tablePool: sync.Pool{
 			New: func() interface{} {
@@ -129,8 +225,16 @@ func NewScriptRunner(scriptPath string, dev *streamdeck.Device, configDir string
 		},
 	}
 
-	// Create Lua state
-	r.L = lua.NewState()
+	// Create Lua state with the manager's limits
+	var limits ScriptLimits
+	if mgr != nil {
+		limits = mgr.scriptLimits()
+	}
+	r.L = lua.NewState(limits.options())
+	if limits.MemoryMB > 0 {
+		r.limitCtx, r.limitCancel = context.WithCancel(context.Background())
+		r.L.SetContext(r.limitCtx)
+	}
 
 	// Create shared state table (persists across all function calls)
 	r.state = r.L.NewTable()
@@ -138,26 +242,32 @@ func NewScriptRunner(scriptPath string, dev *streamdeck.Device, configDir string
 
 	// Register modules and set globals
 	r.registerModules()
+	if mgr != nil && mgr.isSandboxed() {
+		sandboxState(r.L)
+	}
 
-	// Load the script (defines functions or returns module)
-	if err := r.L.DoFile(scriptPath); err != nil {
+	// Run the top level (defines functions or returns module)
+	r.L.Push(r.L.NewFunctionFromProto(proto))
+	if err := r.L.PCall(0, lua.MultRet, nil); err != nil {
 		r.L.Close()
-		return nil, fmt.Errorf("failed to load script %s: %w", scriptPath, err)
+		return nil, runtimeScriptError(scriptPath, err)
 	}
 
-	// Script must return a module table
-	result := r.L.Get(-1)
-	if result.Type() != lua.LTTable {
+	// Module scripts return a table of entry points; legacy scripts define
+	// them as globals (see moduleFromResult)
+	module, err := r.moduleFromResult(r.L.Get(-1))
+	if err != nil {
 		r.L.Close()
-		return nil, fmt.Errorf("script %s must return a table (got %s)", filepath.Base(scriptPath), result.Type())
+		return nil, fmt.Errorf("script %s %w", filepath.Base(scriptPath), err)
 	}
-	r.L.Pop(1)
-	r.module = result.(*lua.LTable)
+	r.L.SetTop(0)
+	r.module = module
 
 	// Detect available functions
 	r.hasBackground = r.module.RawGetString("background").Type() == lua.LTFunction
 	r.hasPassive = r.module.RawGetString("passive").Type() == lua.LTFunction
 	r.hasTrigger = r.module.RawGetString("trigger").Type() == lua.LTFunction
+	r.hasTriggerRelease = r.module.RawGetString("trigger_release").Type() == lua.LTFunction
 	r.hasT1Passive = r.module.RawGetString("t1_passive").Type() == lua.LTFunction
 	r.hasT1Trigger = r.module.RawGetString("t1_trigger").Type() == lua.LTFunction
 	r.hasT2Passive = r.module.RawGetString("t2_passive").Type() == lua.LTFunction
@@ -183,27 +293,94 @@ func NewScriptRunner(scriptPath string, dev *streamdeck.Device, configDir string
 	return r, nil
 }
 
+// entryPoints are the functions the runner looks up on a script.
+var entryPoints = []string{
+	"background", "passive", "trigger", "trigger_release", "on_error",
+	"t1_passive", "t1_trigger", "t2_passive", "t2_trigger",
+	"boot", "frame", "progress",
+}
+
+// moduleFromResult builds the module table from what the script returned.
+// A returned table is used as is; entry points it lacks are taken from
+// global functions of the same name, so both styles (and mixtures) work.
+// A script that returns nothing gets a fresh table filled from globals.
+func (r *ScriptRunner) moduleFromResult(result lua.LValue) (*lua.LTable, error) {
+	var module *lua.LTable
+	switch v := result.(type) {
+	case *lua.LTable:
+		module = v
+	case *lua.LNilType:
+		module = r.L.NewTable()
+	default:
+		return nil, fmt.Errorf("must return a table or nothing (got %s)", result.Type())
+	}
+
+	for _, name := range entryPoints {
+		if module.RawGetString(name).Type() == lua.LTFunction {
+			continue
+		}
+		if fn := r.L.GetGlobal(name); fn.Type() == lua.LTFunction {
+			module.RawSetString(name, fn)
+		}
+	}
+	return module, nil
+}
+
 // registerModules adds all available modules to the Lua state.
 func (r *ScriptRunner) registerModules() {
+	// Manager-backed services; nil interfaces disable them
+	var bus modules.EventBus
+	var claims modules.KeyClaimer
+	var input modules.InputHooks
+	var app modules.AppInfo
+	if r.mgr != nil {
+		bus = r.mgr
+		claims = scriptClaims{m: r.mgr, path: r.ScriptPath}
+		input = r.mgr
+		app = r.mgr
+	}
+
 	// Device/system modules (need runtime context)
 	shellMod := modules.NewShellModule()
 	httpMod := modules.NewHTTPModule()
 	systemMod := modules.NewSystemModule(r.requestRefresh)
-	sdMod := modules.NewStreamDeckModule(r.device)
-	fileMod := modules.NewFileModule()
+	r.deckMod = modules.NewStreamDeckModule(r.device, claims)
+	if input != nil {
+		r.deckMod.SetInput(input, r.invokeCallback)
+	}
+	r.fileMod = modules.NewFileModule(r.configDir, r.invokeCallback)
+	colorMod := modules.NewColorModule()
+	weatherMod := modules.NewWeatherModule()
+	randomMod := modules.NewRandomModule()
+	r.eventsMod = modules.NewEventsModule(bus, r.invokeCallback)
+	nomadMod := modules.NewNomadModule(app, r.configDir, r.device)
+	r.macroMod = modules.NewMacroModule(r.invokeCallback)
 
 	r.L.PreloadModule("shell", shellMod.Loader)
 	r.L.PreloadModule("http", httpMod.Loader)
 	r.L.PreloadModule("system", systemMod.Loader)
-	r.L.PreloadModule("streamdeck", sdMod.Loader)
-	r.L.PreloadModule("file", fileMod.Loader)
+	r.L.PreloadModule("streamdeck", r.deckMod.Loader)
+	r.L.PreloadModule("file", r.fileMod.Loader)
+	r.L.PreloadModule("color", colorMod.Loader)
+	r.L.PreloadModule("weather", weatherMod.Loader)
+	r.L.PreloadModule("random", randomMod.Loader)
+	r.L.PreloadModule("events", r.eventsMod.Loader)
+	r.L.PreloadModule("macro", r.macroMod.Loader)
+
+	// nomad is also a global; require("nomad") returns the same table
+	nomad := nomadMod.Table(r.L)
+	r.L.SetGlobal("nomad", nomad)
+	r.L.PreloadModule("nomad", func(L *lua.LState) int {
+		L.Push(nomad)
+		return 1
+	})
 
 	// Go-native stdlib (lualib) - zero disk I/O on require()
 	lualib.RegisterUtils(r.L)
 	lualib.RegisterStrings(r.L)
 	lualib.RegisterJSON(r.L)
 	lualib.RegisterTime(r.L)
-	lualib.RegisterLog(r.L)
+	lualib.RegisterLog(r.L, r.logger.ForScript(r.ScriptName))
 
 	// Set globals
 	r.L.SetGlobal("SCRIPT_PATH", lua.LString(r.ScriptPath))
@@ -252,6 +429,15 @@ func (r *ScriptRunner) HasPassive() bool { return r.hasPassive }
 // HasTrigger returns true if script defines trigger().
 func (r *ScriptRunner) HasTrigger() bool { return r.hasTrigger }
 
+// HasTriggerRelease returns true if script defines trigger_release().
+func (r *ScriptRunner) HasTriggerRelease() bool { return r.hasTriggerRelease }
+
+// usable reports whether the script defines any of buttonEntryPoints.
+func (r *ScriptRunner) usable() bool {
+	return r.hasBackground || r.hasPassive || r.hasTrigger || r.hasTriggerRelease ||
+		r.hasT1Passive || r.hasT1Trigger || r.hasT2Passive || r.hasT2Trigger
+}
+
 // HasT1Passive returns true if script defines t1_passive().
 func (r *ScriptRunner) HasT1Passive() bool { return r.hasT1Passive }
 
@@ -278,11 +464,23 @@ func (r *ScriptRunner) StartBackground(parentCtx context.Context) {
 
 	r.bgCtx, r.bgCancel = context.WithCancel(parentCtx)
 	r.bgRunning = true
+	r.bgDone = make(chan struct{})
 	r.mu.Unlock()
 
 	go r.backgroundLoop()
 }
 
+// waitBackground blocks until the current background worker exits.
+// Returns immediately if no worker has been started.
+func (r *ScriptRunner) waitBackground() {
+	r.mu.RLock()
+	done := r.bgDone
+	r.mu.RUnlock()
+	if done != nil {
+		<-done
+	}
+}
+
 // backgroundLoop runs the background function as a coroutine with restart logic.
 func (r *ScriptRunner) backgroundLoop() {
 	defer func() {
@@ -293,6 +491,7 @@ func (r *ScriptRunner) backgroundLoop() {
 		}
 		r.bgThread = nil
 		r.bgFunc = nil
+		close(r.bgDone)
 		r.mu.Unlock()
 	}()
 
@@ -310,6 +509,7 @@ func (r *ScriptRunner) backgroundLoop() {
 			fmt.Printf("[!] Background error in %s: %v\n", r.ScriptName, err)
 
 			r.mu.Lock()
+			r.lastErr = err
 			r.bgRestarts++
 			if r.bgThreadCancel != nil {
 				r.bgThreadCancel()
@@ -320,6 +520,8 @@ func (r *ScriptRunner) backgroundLoop() {
 			restarts := r.bgRestarts
 			r.mu.Unlock()
 
+			r.runOnError(err)
+
 			// Check restart policy
 			switch policy {
 			case RestartNever:
@@ -381,6 +583,104 @@ func (r *ScriptRunner) backgroundLoop() {
 	}
 }
 
+// runOnError calls the script's optional on_error(err, state) hook so it can
+// react to a background failure (e.g. flag state for passive to show red).
+func (r *ScriptRunner) runOnError(bgErr error) {
+	r.luaMu.Lock()
+	defer r.luaMu.Unlock()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.L == nil {
+		return
+	}
+	r.armLimit()
+
+	fn := r.module.RawGetString("on_error")
+	if fn.Type() != lua.LTFunction {
+		return
+	}
+
+	r.L.Push(fn)
+	r.L.Push(lua.LString(bgErr.Error()))
+	r.L.Push(r.state)
+	if err := r.L.PCall(2, 0, nil); err != nil {
+		fmt.Printf("[!] on_error failed in %s: %v\n", r.ScriptName, err)
+	}
+}
+
+// callModuleFunc calls fnName(args...) from the module table, ignoring any
+// return values. Missing functions are not an error.
+func (r *ScriptRunner) callModuleFunc(fnName string, args ...lua.LValue) error {
+	r.luaMu.Lock()
+	defer r.luaMu.Unlock()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.L == nil {
+		return nil
+	}
+	r.armLimit()
+
+	fn := r.module.RawGetString(fnName)
+	if fn.Type() != lua.LTFunction {
+		return nil
+	}
+
+	r.L.Push(fn)
+	for _, arg := range args {
+		r.L.Push(arg)
+	}
+	return r.L.PCall(len(args), 0, nil)
+}
+
+// invokeCallback calls a Lua callback registered by a module (e.g.
+// file.watch) from another goroutine, serialised with every other use of
+// the VM. Errors are logged; a closed runner drops the call.
+func (r *ScriptRunner) invokeCallback(fn *lua.LFunction, args ...lua.LValue) {
+	r.luaMu.Lock()
+	defer r.luaMu.Unlock()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.L == nil {
+		return
+	}
+	r.armLimit()
+
+	r.L.Push(fn)
+	for _, arg := range args {
+		r.L.Push(arg)
+	}
+	if err := r.L.PCall(len(args), 0, nil); err != nil {
+		fmt.Printf("[!] Callback error in %s: %v\n", r.ScriptName, err)
+	}
+}
+
+// LastError returns the most recent background error, or nil if the
+// background worker has not failed.
+func (r *ScriptRunner) LastError() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lastErr
+}
+
+// Status reports the runner's loaded functions and background health.
+func (r *ScriptRunner) Status() ScriptStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return ScriptStatus{
+		Path:              r.ScriptPath,
+		Name:              r.ScriptName,
+		HasBackground:     r.hasBackground,
+		HasPassive:        r.hasPassive,
+		HasTrigger:        r.hasTrigger,
+		BackgroundRunning: r.bgRunning,
+		Restarts:          r.bgRestarts,
+		LastError:         r.lastErr,
+	}
+}
+
 // runBackgroundCoroutine runs or resumes the background coroutine.
 // Returns: (finished bool, sleepMs int, err error)
 func (r *ScriptRunner) runBackgroundCoroutine() (bool, int, error) {
@@ -417,6 +717,9 @@ func (r *ScriptRunner) runBackgroundCoroutine() (bool, int, error) {
 	// Acquire the Lua VM lock for the duration of Resume so that RunTrigger /
 	// RunPassive cannot enter the same LState concurrently.
 	r.luaMu.Lock()
+	r.mu.RLock()
+	r.armLimit()
+	r.mu.RUnlock()
 
 	// Resume the coroutine (this may take time)
 	var status lua.ResumeState
@@ -511,9 +814,25 @@ func (r *ScriptRunner) parseAppearance(tbl *lua.LTable) *KeyAppearance {
 		appearance.TextColor = [3]int{255, 255, 255}
 	}
 
+	// outline = {r, g, b}, or true for black
+	switch v := r.L.GetField(tbl, "outline"); v.Type() {
+	case lua.LTTable:
+		oTbl := v.(*lua.LTable)
+		appearance.Outline = &[3]int{
+			int(lua.LVAsNumber(r.L.RawGetInt(oTbl, 1))),
+			int(lua.LVAsNumber(r.L.RawGetInt(oTbl, 2))),
+			int(lua.LVAsNumber(r.L.RawGetInt(oTbl, 3))),
+		}
+	case lua.LTBool:
+		if lua.LVAsBool(v) {
+			appearance.Outline = &[3]int{0, 0, 0}
+		}
+	}
+
 	if imgVal := r.L.GetField(tbl, "image"); imgVal.Type() == lua.LTString {
 		imgPath := imgVal.String()
-		if strings.HasPrefix(imgPath, "http://") || strings.HasPrefix(imgPath, "https://") {
+		if strings.HasPrefix(imgPath, "http://") || strings.HasPrefix(imgPath, "https://") ||
+			strings.HasPrefix(imgPath, "data:") {
 			appearance.Image = imgPath
 		} else if !filepath.IsAbs(imgPath) {
 			appearance.Image = filepath.Join(filepath.Dir(r.ScriptPath), imgPath)
@@ -522,12 +841,32 @@ func (r *ScriptRunner) parseAppearance(tbl *lua.LTable) *KeyAppearance {
 		}
 	}
 
+	if v := r.L.GetField(tbl, "animation"); v.Type() == lua.LTString {
+		appearance.Animation = v.String()
+		if !filepath.IsAbs(appearance.Animation) {
+			appearance.Animation = filepath.Join(filepath.Dir(r.ScriptPath), appearance.Animation)
+		}
+	}
+	if v := r.L.GetField(tbl, "fps"); v.Type() == lua.LTNumber {
+		appearance.FPS = float64(v.(lua.LNumber))
+	}
+
+	if v := r.L.GetField(tbl, "brightness"); v.Type() == lua.LTNumber {
+		appearance.Brightness = float64(v.(lua.LNumber))
+	}
+	if v := r.L.GetField(tbl, "contrast"); v.Type() == lua.LTNumber {
+		appearance.Contrast = float64(v.(lua.LNumber))
+	}
+	appearance.Invert = lua.LVAsBool(r.L.GetField(tbl, "invert"))
+	appearance.Grayscale = lua.LVAsBool(r.L.GetField(tbl, "grayscale"))
+
 	return appearance
 }
 
-// runNamedPassive calls fnName(keyIndex, state) and returns the parsed appearance.
+// runNamedPassive calls fnName(keyIndex, state[, ctx]) and returns the parsed
+// appearance. ctx is omitted when pc is nil.
 // It tries to acquire luaMu; if held, it returns (nil, nil) to skip this tick.
-func (r *ScriptRunner) runNamedPassive(fnName string, keyIndex int) (*KeyAppearance, error) {
+func (r *ScriptRunner) runNamedPassive(fnName string, keyIndex int, pc *PassiveContext) (*KeyAppearance, error) {
 	if !r.luaMu.TryLock() {
 		return nil, nil // Lua VM busy – skip this tick
 	}
@@ -535,6 +874,10 @@ func (r *ScriptRunner) runNamedPassive(fnName string, keyIndex int) (*KeyAppeara
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	if r.L == nil {
+		return nil, nil // runner closed
+	}
+	r.armLimit()
 
 	fn := r.module.RawGetString(fnName)
 	if fn.Type() != lua.LTFunction {
@@ -544,8 +887,23 @@ func (r *ScriptRunner) runNamedPassive(fnName string, keyIndex int) (*KeyAppeara
 	r.L.Push(fn)
 	r.L.Push(lua.LNumber(keyIndex))
 	r.L.Push(r.state)
+	nargs := 2
+	if pc != nil {
+		ctx := r.L.NewTable()
+		ctx.RawSetString("key", lua.LNumber(pc.Key))
+		ctx.RawSetString("col", lua.LNumber(pc.Col))
+		ctx.RawSetString("row", lua.LNumber(pc.Row))
+		ctx.RawSetString("visible", lua.LBool(pc.Visible))
+		ctx.RawSetString("pressed", lua.LBool(pc.Pressed))
+		toggles := r.L.NewTable()
+		toggles.RawSetString("t1", lua.LBool(pc.T1))
+		toggles.RawSetString("t2", lua.LBool(pc.T2))
+		ctx.RawSetString("toggles", toggles)
+		r.L.Push(ctx)
+		nargs = 3
+	}
 
-	if err := r.L.PCall(2, 1, nil); err != nil {
+	if err := r.L.PCall(nargs, 1, nil); err != nil {
 		return nil, err
 	}
 
@@ -559,13 +917,14 @@ func (r *ScriptRunner) runNamedPassive(fnName string, keyIndex int) (*KeyAppeara
 	return r.parseAppearance(ret.(*lua.LTable)), nil
 }
 
-// RunPassive calls passive(key, state) and returns appearance.
+// RunPassive calls passive(key, state, ctx) and returns appearance.
+// Scripts that declare passive(key, state) simply ignore the context table.
 // Uses TryLock on luaMu to avoid blocking if background or trigger is using the Lua VM.
-func (r *ScriptRunner) RunPassive(keyIndex int) (*KeyAppearance, error) {
+func (r *ScriptRunner) RunPassive(pc PassiveContext) (*KeyAppearance, error) {
 	if !r.hasPassive {
 		return nil, nil
 	}
-	return r.runNamedPassive("passive", keyIndex)
+	return r.runNamedPassive("passive", pc.Key, &pc)
 }
 
 // RunT1Passive calls t1_passive(key, state) for the T1 toggle key.
@@ -573,7 +932,7 @@ func (r *ScriptRunner) RunT1Passive(keyIndex int) (*KeyAppearance, error) {
 	if !r.hasT1Passive {
 		return nil, nil
 	}
-	return r.runNamedPassive("t1_passive", keyIndex)
+	return r.runNamedPassive("t1_passive", keyIndex, nil)
 }
 
 // RunT2Passive calls t2_passive(key, state) for the T2 toggle key.
@@ -581,16 +940,21 @@ func (r *ScriptRunner) RunT2Passive(keyIndex int) (*KeyAppearance, error) {
 	if !r.hasT2Passive {
 		return nil, nil
 	}
-	return r.runNamedPassive("t2_passive", keyIndex)
+	return r.runNamedPassive("t2_passive", keyIndex, nil)
 }
 
-// runNamedTrigger calls fnName(state). Acquires luaMu.
-func (r *ScriptRunner) runNamedTrigger(fnName string) error {
+// runNamedTrigger calls fnName(state[, ctx]). Acquires luaMu.
+// ctx is omitted when tc is nil.
+func (r *ScriptRunner) runNamedTrigger(fnName string, tc *TriggerContext) error {
 	r.luaMu.Lock()
 	defer r.luaMu.Unlock()
 
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	if r.L == nil {
+		return nil // runner closed
+	}
+	r.armLimit()
 
 	fn := r.module.RawGetString(fnName)
 	if fn.Type() != lua.LTFunction {
@@ -599,19 +963,40 @@ func (r *ScriptRunner) runNamedTrigger(fnName string) error {
 
 	r.L.Push(fn)
 	r.L.Push(r.state)
+	nargs := 1
+	if tc != nil {
+		ctx := r.L.NewTable()
+		ctx.RawSetString("key", lua.LNumber(tc.Key))
+		ctx.RawSetString("col", lua.LNumber(tc.Col))
+		ctx.RawSetString("row", lua.LNumber(tc.Row))
+		ctx.RawSetString("event", lua.LString(tc.Event))
+		ctx.RawSetString("pressed", lua.LBool(tc.Pressed))
+		r.L.Push(ctx)
+		nargs = 2
+	}
 
-	if err := r.L.PCall(1, 0, nil); err != nil {
+	if err := r.L.PCall(nargs, 0, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
-// RunTrigger calls trigger(state).
-func (r *ScriptRunner) RunTrigger() error {
+// RunTrigger calls trigger(state, ctx). Scripts that declare trigger(state)
+// simply ignore the context table.
+func (r *ScriptRunner) RunTrigger(tc TriggerContext) error {
 	if !r.hasTrigger {
 		return nil
 	}
-	return r.runNamedTrigger("trigger")
+	return r.runNamedTrigger("trigger", &tc)
+}
+
+// RunTriggerRelease calls trigger_release(state, ctx) when a key whose
+// press triggered the script is let go.
+func (r *ScriptRunner) RunTriggerRelease(tc TriggerContext) error {
+	if !r.hasTriggerRelease {
+		return nil
+	}
+	return r.runNamedTrigger("trigger_release", &tc)
 }
 
 // RunT1Trigger calls t1_trigger(state).
@@ -619,7 +1004,7 @@ func (r *ScriptRunner) RunT1Trigger() error {
 	if !r.hasT1Trigger {
 		return nil
 	}
-	return r.runNamedTrigger("t1_trigger")
+	return r.runNamedTrigger("t1_trigger", nil)
 }
 
 // RunT2Trigger calls t2_trigger(state).
@@ -627,12 +1012,37 @@ func (r *ScriptRunner) RunT2Trigger() error {
 	if !r.hasT2Trigger {
 		return nil
 	}
-	return r.runNamedTrigger("t2_trigger")
+	return r.runNamedTrigger("t2_trigger", nil)
+}
+
+// hasLiveHooks reports whether the script is waiting on something it set
+// up itself: an events subscription, a file watch, an on_dial / on_touch
+// hook or a running macro. Closing the runner would silently drop them.
+func (r *ScriptRunner) hasLiveHooks() bool {
+	return (r.eventsMod != nil && r.eventsMod.Subscribed()) ||
+		(r.fileMod != nil && r.fileMod.Watching()) ||
+		(r.deckMod != nil && r.deckMod.HasInputHooks()) ||
+		(r.macroMod != nil && r.macroMod.Running())
 }
 
 // Close shuts down the runner and releases resources.
 func (r *ScriptRunner) Close() {
 	r.StopBackground()
+	if r.fileMod != nil {
+		r.fileMod.Close()
+	}
+	if r.eventsMod != nil {
+		r.eventsMod.Close()
+	}
+	if r.deckMod != nil {
+		r.deckMod.Close()
+	}
+	if r.macroMod != nil {
+		r.macroMod.Close()
+	}
+	if r.mgr != nil {
+		r.mgr.releaseClaims(r.ScriptPath)
+	}
 
 	r.mu.Lock()
 	if r.L != nil {
@@ -640,4 +1050,10 @@ func (r *ScriptRunner) Close() {
 		r.L = nil
 	}
 	r.mu.Unlock()
+
+	r.limitMu.Lock()
+	if r.limitCancel != nil {
+		r.limitCancel()
+	}
+	r.limitMu.Unlock()
 }
@@ -0,0 +1,289 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merith-tk/nomad/pkg/streamdeck"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const counterBody = `
+	local function passive(key, state)
+		return { text = tostring(state.count or 0), color = {0, key, 0} }
+	end
+	local function trigger(state, ctx)
+		state.count = (state.count or 0) + ctx.key
+	end
+`
+
+func TestModuleAndGlobalScriptsBehaveAlike(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"module.lua": counterBody + `return { passive = passive, trigger = trigger }`,
+		"global.lua": counterBody + `_G.passive = passive; _G.trigger = trigger`,
+		"mixed.lua":  counterBody + `_G.trigger = trigger; return { passive = passive }`,
+	}
+
+	var want *KeyAppearance
+	for name, src := range scripts {
+		r, err := NewScriptRunner(writeScript(t, dir, name, src), nil, dir, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		defer r.Close()
+
+		if !r.HasPassive() || !r.HasTrigger() || r.HasBackground() {
+			t.Errorf("%s: passive=%v trigger=%v background=%v", name, r.HasPassive(), r.HasTrigger(), r.HasBackground())
+		}
+		for i := 0; i < 2; i++ {
+			if err := r.RunTrigger(TriggerContext{Key: 3, Event: EventTap}); err != nil {
+				t.Fatalf("%s: trigger: %v", name, err)
+			}
+		}
+		got, err := r.RunPassive(PassiveContext{Key: 3})
+		if err != nil || got == nil {
+			t.Fatalf("%s: passive = %v, %v", name, got, err)
+		}
+		if got.Text != "6" {
+			t.Errorf("%s: text = %q, want 6", name, got.Text)
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: appearance %+v differs from %+v", name, got, want)
+		}
+	}
+}
+
+func TestScriptReturningNonTable(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewScriptRunner(writeScript(t, dir, "bad.lua", `return 42`), nil, dir, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "must return a table") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestScriptSyntaxError(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "broken.lua", "local script = {}\n"+
+		"function script.trigger(\n"+
+		"return script\n")
+
+	for name, err := range map[string]error{
+		"CheckScript": CheckScript(path),
+		"NewScriptRunner": func() error {
+			_, err := NewScriptRunner(path, nil, dir, nil, nil)
+			return err
+		}(),
+	} {
+		var se *ScriptError
+		if !errors.As(err, &se) {
+			t.Fatalf("%s: error %v is not a *ScriptError", name, err)
+		}
+		if se.Kind != SyntaxError || se.Line != 3 {
+			t.Errorf("%s: kind = %v, line = %d; want syntax error on line 3", name, se.Kind, se.Line)
+		}
+		if !strings.Contains(err.Error(), "broken.lua line 3: syntax error") {
+			t.Errorf("%s: message %q", name, err)
+		}
+	}
+}
+
+func TestScriptRuntimeErrorAtTopLevel(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "boom.lua", "local script = {}\n"+
+		"\n"+
+		"error(\"no config\")\n"+
+		"return script\n")
+
+	// Checking only compiles, so the top level does not run
+	if err := CheckScript(path); err != nil {
+		t.Fatalf("CheckScript: %v", err)
+	}
+
+	_, err := NewScriptRunner(path, nil, dir, nil, nil)
+	var se *ScriptError
+	if !errors.As(err, &se) {
+		t.Fatalf("error %v is not a *ScriptError", err)
+	}
+	if se.Kind != RuntimeError || se.Line != 3 || !strings.Contains(se.Message, "no config") {
+		t.Errorf("got %+v, want a runtime error on line 3", se)
+	}
+}
+
+func TestSandboxRemovesHostAccess(t *testing.T) {
+	dir := t.TempDir()
+	path := writeScript(t, dir, "probe.lua", `
+		state.execute = os.execute ~= nil
+		state.io = io ~= nil
+		state.dofile = dofile ~= nil
+		state.time = os.time ~= nil
+		state.require_io = pcall(require, "io")
+		state.loaded_io = package.loaded.io ~= nil
+		state.require_remove = require("os").remove ~= nil
+		state.loaded_exit = package.loaded.os.exit ~= nil
+		state.loadlib = package.loadlib ~= nil
+		return {}
+	`)
+
+	for _, sandboxed := range []bool{false, true} {
+		m := NewScriptManager(nil, dir, 0)
+		m.SetSandboxed(sandboxed)
+		r, err := NewScriptRunner(path, nil, dir, nil, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"execute", "io", "dofile", "require_io", "loaded_io", "require_remove", "loaded_exit", "loadlib"} {
+			if got := r.state.RawGetString(name) == lua.LTrue; got == sandboxed {
+				t.Errorf("sandboxed=%v: %s present = %v", sandboxed, name, got)
+			}
+		}
+		if r.state.RawGetString("time") != lua.LTrue {
+			t.Errorf("sandboxed=%v: os.time was removed", sandboxed)
+		}
+		r.Close()
+	}
+}
+
+func TestPassiveContext(t *testing.T) {
+	dir := t.TempDir()
+	dev := streamdeck.OpenVirtual(streamdeck.Models[0x0080])
+	m := NewScriptManager(dev, dir, 0)
+
+	withCtx := writeScript(t, dir, "ctx.lua", `
+		local script = {}
+		function script.passive(key, state, ctx)
+			return { text = string.format("%d %d/%d/%d %s %s %s %s", key, ctx.key, ctx.col, ctx.row,
+				tostring(ctx.visible), tostring(ctx.pressed), tostring(ctx.toggles.t1), tostring(ctx.toggles.t2)) }
+		end
+		function script.trigger(state, ctx) end
+		return script
+	`)
+	twoArgs := writeScript(t, dir, "plain.lua", `
+		local script = {}
+		function script.passive(key, state)
+			return { text = "key " .. key }
+		end
+		return script
+	`)
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+
+	passive := func(path string, key int) string {
+		t.Helper()
+		r := m.GetRunner(path)
+		if r == nil {
+			t.Fatalf("%s did not load: %v", path, m.LoadError(path))
+		}
+		ap, err := r.RunPassive(m.passiveContext(path, key))
+		if err != nil || ap == nil {
+			t.Fatalf("%s: passive = %v, %v", path, ap, err)
+		}
+		return ap.Text
+	}
+
+	// Off-page, never pressed, no toggle scripts
+	if got := passive(withCtx, 7); got != "7 7/2/1 false false false false" {
+		t.Errorf("idle ctx = %q", got)
+	}
+
+	m.SetVisibleScripts(map[string]int{withCtx: 7, twoArgs: 8})
+	m.SetToggleScripts(filepath.Join(dir, ".directory.lua"), 0, "", 0)
+	if err := m.TriggerScript(withCtx, 7, EventTap); err != nil {
+		t.Fatal(err)
+	}
+	if got := passive(withCtx, 7); got != "7 7/2/1 true true true false" {
+		t.Errorf("ctx after press = %q", got)
+	}
+
+	m.lastPress[withCtx] = time.Now().Add(-2 * RecentPressWindow)
+	if got := passive(withCtx, 7); !strings.HasPrefix(got, "7 7/2/1 true false") {
+		t.Errorf("ctx after the press window = %q", got)
+	}
+
+	// Scripts written against passive(key, state) ignore the extra argument
+	if got := passive(twoArgs, 8); got != "key 8" {
+		t.Errorf("two-argument passive = %q", got)
+	}
+}
+
+func TestOnErrorHook(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewScriptRunner(writeScript(t, dir, "flaky.lua", `
+		RESTART_POLICY = "once"
+		local script = {}
+		local runs = 0
+		function script.background(state)
+			runs = runs + 1
+			error("fail " .. runs)
+		end
+		function script.on_error(err, state)
+			state.errors = state.errors or {}
+			table.insert(state.errors, err)
+		end
+		return script
+	`), nil, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.LastError() != nil {
+		t.Errorf("LastError before running = %v, want nil", r.LastError())
+	}
+	r.StartBackground(context.Background())
+	r.waitBackground()
+
+	errs, ok := r.state.RawGetString("errors").(*lua.LTable)
+	if !ok || errs.Len() != 2 {
+		t.Fatalf("on_error recorded %v, want both failures", r.state.RawGetString("errors"))
+	}
+	for i, want := range []string{"fail 1", "fail 2"} {
+		if got := errs.RawGetInt(i + 1).String(); !strings.Contains(got, want) {
+			t.Errorf("error %d = %q, want %q", i+1, got, want)
+		}
+	}
+	if err := r.LastError(); err == nil || !strings.Contains(err.Error(), "fail 2") {
+		t.Errorf("LastError = %v, want the second failure", err)
+	}
+	if s := r.Status(); s.Restarts != 2 || s.BackgroundRunning {
+		t.Errorf("status = %+v, want 2 restarts and stopped", s)
+	}
+}
+
+func TestOnErrorHookFailureIsContained(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewScriptRunner(writeScript(t, dir, "broken_hook.lua", `
+		RESTART_POLICY = "never"
+		local script = {}
+		function script.background(state) error("boom") end
+		function script.on_error(err, state) error("hook broke") end
+		function script.trigger(state) state.triggered = true end
+		return script
+	`), nil, dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.StartBackground(context.Background())
+	r.waitBackground()
+	if err := r.LastError(); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("LastError = %v, want the background error, not the hook's", err)
+	}
+	// The VM is still usable after the hook failed
+	if err := r.RunTrigger(TriggerContext{Event: EventTap, Pressed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if r.state.RawGetString("triggered") != lua.LTrue {
+		t.Error("trigger did not run after on_error failed")
+	}
+}
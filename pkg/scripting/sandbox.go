@@ -0,0 +1,62 @@
+package scripting
+
+// sandbox.go – stripping the parts of the Lua standard library that reach
+// the host directly. Sandboxed scripts use the file and shell modules, which
+// apply the config-dir and command restrictions, instead.
+
+import lua "github.com/yuin/gopher-lua"
+
+// sandboxRemoved lists, per library table ("" for globals), the functions
+// removed from sandboxed scripts.
+var sandboxRemoved = map[string][]string{
+	"":        {"dofile", "loadfile"},
+	"os":      {"execute", "remove", "rename", "exit", "tmpname", "setenv"},
+	"package": {"loadlib"},
+}
+
+// SetSandboxed removes io and the host-touching os, package and global
+// functions (os.execute, os.remove, dofile, ...) from every script loaded
+// afterwards (security.sandbox_stdlib). Call before Boot.
+func (m *ScriptManager) SetSandboxed(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sandboxed = on
+}
+
+// isSandboxed reports whether new runners are sandboxed.
+func (m *ScriptManager) isSandboxed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sandboxed
+}
+
+// sandboxState removes the io library and the functions in sandboxRemoved
+// from L. Libraries are stripped both as globals and in package.loaded, so
+// require("io") and require("os") cannot bring them back.
+func sandboxState(L *lua.LState) {
+	loaded, _ := L.GetField(L.Get(lua.RegistryIndex), "_LOADED").(*lua.LTable)
+	L.SetGlobal("io", lua.LNil)
+	if loaded != nil {
+		loaded.RawSetString("io", lua.LNil)
+	}
+
+	for lib, names := range sandboxRemoved {
+		tables := []*lua.LTable{L.G.Global}
+		if lib != "" {
+			tables = nil
+			if t, ok := L.GetGlobal(lib).(*lua.LTable); ok {
+				tables = append(tables, t)
+			}
+			if loaded != nil {
+				if t, ok := loaded.RawGetString(lib).(*lua.LTable); ok {
+					tables = append(tables, t)
+				}
+			}
+		}
+		for _, tbl := range tables {
+			for _, name := range names {
+				tbl.RawSetString(name, lua.LNil)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scripting
+
+// scripterror.go – compiling scripts ahead of running them, so a script that
+// cannot be parsed is reported (with its line) before any of its top-level
+// code has run.
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// ScriptErrorKind tells a script that could not be parsed from one that
+// failed while its top level ran.
+type ScriptErrorKind int
+
+const (
+	SyntaxError  ScriptErrorKind = iota // The script does not parse
+	RuntimeError                        // The top-level chunk raised an error
+)
+
+func (k ScriptErrorKind) String() string {
+	if k == SyntaxError {
+		return "syntax error"
+	}
+	return "runtime error"
+}
+
+// ScriptError is a load failure of a script, returned by NewScriptRunner and
+// CheckScript.
+type ScriptError struct {
+	Path    string
+	Kind    ScriptErrorKind
+	Line    int // 0 if unknown
+	Message string
+}
+
+func (e *ScriptError) Error() string {
+	name := filepath.Base(e.Path)
+	if e.Line > 0 {
+		return fmt.Sprintf("%s line %d: %s: %s", name, e.Line, e.Kind, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", name, e.Kind, e.Message)
+}
+
+// CheckScript parses and compiles a script without running it. Boot uses it
+// for deferred scripts so syntax errors show up before the script is first
+// loaded.
+func CheckScript(path string) error {
+	_, err := compileScript(path)
+	return err
+}
+
+// compileScript compiles a script file. Syntax errors are returned as a
+// *ScriptError with the offending line.
+func compileScript(path string) (*lua.FunctionProto, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	chunk, err := parse.Parse(bufio.NewReader(f), path)
+	if err != nil {
+		var pe *parse.Error
+		if errors.As(err, &pe) {
+			msg := pe.Message
+			if pe.Token != "" {
+				msg += fmt.Sprintf(" near '%s'", pe.Token)
+			}
+			return nil, &ScriptError{Path: path, Kind: SyntaxError, Line: pe.Pos.Line, Message: msg}
+		}
+		return nil, &ScriptError{Path: path, Kind: SyntaxError, Message: err.Error()}
+	}
+	proto, err := lua.Compile(chunk, path)
+	if err != nil {
+		return nil, &ScriptError{Path: path, Kind: SyntaxError, Message: err.Error()}
+	}
+	return proto, nil
+}
+
+// runtimeScriptError converts an error raised by a script's top level into
+// a *ScriptError, taking the line from the "path:line: message" prefix Lua
+// puts on errors.
+func runtimeScriptError(path string, err error) *ScriptError {
+	msg := err.Error()
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) && apiErr.Object != nil {
+		msg = apiErr.Object.String()
+	}
+	se := &ScriptError{Path: path, Kind: RuntimeError, Message: msg}
+	if rest, ok := strings.CutPrefix(msg, path+":"); ok {
+		if lineStr, text, ok := strings.Cut(rest, ": "); ok {
+			if line, err := strconv.Atoi(lineStr); err == nil {
+				se.Line = line
+				se.Message = text
+			}
+		}
+	}
+	return se
+}
@@ -0,0 +1,98 @@
+package scripting
+
+// slowpassive.go – spotting passive() functions that hold up the passive
+// loop. Every tick waits for the slowest script, so one doing blocking I/O
+// delays every key. Slow calls are reported, and a script that stays slow is
+// demoted to a fraction of the ticks until it speeds up again.
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// slowPassiveStreak is how many slow passive() calls in a row demote a
+	// script.
+	slowPassiveStreak = 5
+
+	// demotedPassiveEvery is how often a demoted script runs: on one tick
+	// in this many.
+	demotedPassiveEvery = 4
+)
+
+// slowOutput is where slow passive() warnings are written.
+var slowOutput io.Writer = os.Stdout
+
+// slowPassive tracks one script's slow passive() calls.
+type slowPassive struct {
+	streak  int  // consecutive slow calls
+	demoted bool // runs on one tick in demotedPassiveEvery
+	skipped int  // ticks skipped since the last demoted run
+}
+
+// passiveInterval is the time between passive loop ticks.
+func (m *ScriptManager) passiveInterval() time.Duration {
+	fps := m.passiveFPS
+	if fps <= 0 {
+		fps = DefaultPassiveFPS
+	}
+	return time.Second / time.Duration(fps)
+}
+
+// passiveDue reports whether a script's passive() should run this tick. It
+// is false on the ticks a demoted script sits out.
+func (m *ScriptManager) passiveDue(scriptPath string) bool {
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+	s := m.slow[scriptPath]
+	if s == nil || !s.demoted {
+		return true
+	}
+	s.skipped++
+	if s.skipped < demotedPassiveEvery {
+		return false
+	}
+	s.skipped = 0
+	return true
+}
+
+// checkSlowPassive records how long a passive() call took. A call over half
+// the tick interval is slow: the first of a streak is reported, and a long
+// streak demotes the script. A fast call ends the streak and the demotion.
+func (m *ScriptManager) checkSlowPassive(scriptPath string, d time.Duration) {
+	name := filepath.Base(scriptPath)
+	threshold := m.passiveInterval() / 2
+
+	m.slowMu.Lock()
+	defer m.slowMu.Unlock()
+	s := m.slow[scriptPath]
+
+	if d <= threshold {
+		if s != nil && s.demoted {
+			fmt.Fprintf(slowOutput, "[*] %s passive() is fast again; back to every tick\n", name)
+		}
+		delete(m.slow, scriptPath)
+		return
+	}
+
+	if s == nil {
+		if m.slow == nil {
+			m.slow = make(map[string]*slowPassive)
+		}
+		s = &slowPassive{}
+		m.slow[scriptPath] = s
+	}
+	s.streak++
+	if s.streak == 1 {
+		fmt.Fprintf(slowOutput, "[!] %s passive() took %v (limit %v); it delays every key's updates\n",
+			name, d.Round(time.Millisecond), threshold)
+	}
+	if s.streak == slowPassiveStreak && !s.demoted {
+		s.demoted = true
+		fmt.Fprintf(slowOutput, "[!] %s passive() was slow %d times in a row; running it every %d ticks\n",
+			name, slowPassiveStreak, demotedPassiveEvery)
+	}
+}
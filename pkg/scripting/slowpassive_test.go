@@ -0,0 +1,54 @@
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSlowPassiveWarnsAndDemotes(t *testing.T) {
+	var out bytes.Buffer
+	defer func(w io.Writer) { slowOutput = w }(slowOutput)
+	slowOutput = &out
+
+	dir := t.TempDir()
+	path := writeScript(t, dir, "slow.lua", `
+		local function passive()
+			local start = os.clock()
+			while os.clock() - start < 0.02 do end
+			return { text = "x" }
+		end
+		return { passive = passive }
+	`)
+	m := NewScriptManager(nil, dir, 100) // 10ms ticks: 5ms limit
+	if err := m.Boot(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Shutdown()
+	m.SetVisibleScripts(map[string]int{path: 1})
+
+	m.runPassiveUpdate()
+	if !strings.Contains(out.String(), "[!] slow.lua passive() took") {
+		t.Fatalf("no slow warning:\n%s", out.String())
+	}
+
+	for i := 1; i < slowPassiveStreak; i++ {
+		m.runPassiveUpdate()
+	}
+	if n := strings.Count(out.String(), "passive() took"); n != 1 {
+		t.Errorf("warned %d times for one slow streak", n)
+	}
+	if !strings.Contains(out.String(), "running it every") {
+		t.Fatalf("script not demoted after %d slow calls:\n%s", slowPassiveStreak, out.String())
+	}
+
+	calls := m.Metrics().Passive[path].Count
+	for i := 0; i < demotedPassiveEvery; i++ {
+		m.runPassiveUpdate()
+	}
+	if got := m.Metrics().Passive[path].Count - calls; got != 1 {
+		t.Errorf("demoted script ran %d times in %d ticks, want 1", got, demotedPassiveEvery)
+	}
+}
@@ -0,0 +1,63 @@
+package scripting
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">
+<circle cx="5" cy="5" r="4" fill="#ff0000"/>
+</svg>`
+
+func TestLoadSVGRasterizesAtKeySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.svg")
+	if err := os.WriteFile(path, []byte(testSVG), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewImageCache(10)
+	c.SetSVGSize(72)
+	img, err := c.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 72 || b.Dy() != 72 {
+		t.Fatalf("bounds = %v, want 72x72", b)
+	}
+	r, g, _, a := img.At(36, 36).RGBA()
+	if a == 0 || r < 0xf000 || g > 0x1000 {
+		t.Fatalf("centre pixel = %v, want opaque red", img.At(36, 36))
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Fatalf("corner pixel = %v, want transparent", img.At(0, 0))
+	}
+
+	if _, ok := c.Get(path + "#72"); !ok {
+		t.Fatal("SVG not cached at its raster size")
+	}
+	c.SetSVGSize(96)
+	if _, ok := c.Get(c.cacheKey(path)); ok {
+		t.Fatal("cache hit for a different raster size")
+	}
+	img, err = c.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 96 {
+		t.Fatalf("bounds after resize = %v, want 96x96", b)
+	}
+}
+
+func TestLoadSVGDataURI(t *testing.T) {
+	c := NewImageCache(10)
+	c.SetSVGSize(72)
+	img, err := c.Load("data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString([]byte(testSVG)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 72 || b.Dy() != 72 {
+		t.Fatalf("bounds = %v, want 72x72", b)
+	}
+}
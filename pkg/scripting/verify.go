@@ -0,0 +1,45 @@
+package scripting
+
+import (
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// VerifyResult is what Verify found out about one script.
+type VerifyResult struct {
+	Path      string
+	Functions []string // entry points the script defines, e.g. "passive"
+	Err       error    // syntax or load error; nil if the script loaded
+}
+
+// Verify loads every .lua file under the config directory, _boot.lua
+// included, and reports load errors and the entry points each defines. Only
+// the top level of each script runs: no boot animation, background workers
+// or passive updates. Runners are closed once inspected, so Verify is meant
+// for a manager that is not booted (see --verify). The error is only for a
+// config directory that cannot be scanned.
+func (m *ScriptManager) Verify() ([]VerifyResult, error) {
+	paths, err := findScripts(m.configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan config directory: %w", err)
+	}
+
+	results := make([]VerifyResult, 0, len(paths))
+	for _, path := range paths {
+		res := VerifyResult{Path: path}
+		runner, err := NewScriptRunner(path, m.device, m.configDir, m.logger, m)
+		if err != nil {
+			res.Err = err
+		} else {
+			for _, name := range entryPoints {
+				if runner.module.RawGetString(name).Type() == lua.LTFunction {
+					res.Functions = append(res.Functions, name)
+				}
+			}
+			runner.Close()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
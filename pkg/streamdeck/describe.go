@@ -0,0 +1,86 @@
+package streamdeck
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Describe returns a plain-text layout of the current page for screen
+// readers, automation and tests: a header with the folder and page number,
+// then one line per row listing each key's label left to right.
+//
+// Folders end in "/", unused keys are "-", scripts that failed to load are
+// prefixed "[error]", and keys claimed by a script or showing the error tile
+// are marked in brackets.
+func (n *Navigator) Describe() string {
+	page, err := n.LoadPage()
+	if err != nil {
+		return fmt.Sprintf("error: %v\n", err)
+	}
+
+	labels := make([]string, n.dev.Model.Keys)
+	for i := range labels {
+		labels[i] = "-"
+	}
+	if KeyBack < len(labels) {
+		if n.IsAtRoot() && !n.inFavorites {
+			labels[KeyBack] = "Settings"
+		} else {
+			labels[KeyBack] = "Back"
+		}
+	}
+	t1, t2 := n.ToggleKeys()
+	if t1 < len(labels) {
+		labels[t1] = "T1"
+	}
+	if t2 < len(labels) {
+		labels[t2] = "T2"
+	}
+	for i, item := range page.Items {
+		if i >= len(n.contentKeys) {
+			break
+		}
+		label := item.Name
+		if item.IsFolder {
+			label += "/"
+		} else if item.LoadErr != nil {
+			label = "[error] " + label
+		}
+		labels[n.contentKeys[i]] = label
+	}
+	if n.readErr != nil && len(page.Items) < len(n.contentKeys) {
+		labels[n.contentKeys[len(page.Items)]] = "[error]"
+	}
+	for i := range labels {
+		if n.isClaimed(i) {
+			labels[i] = "[claimed]"
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (page %d/%d)\n", n.describePath(), page.PageIndex+1, page.TotalPages)
+	cols := n.dev.Cols()
+	for row := 0; row < n.dev.Rows(); row++ {
+		start := row * cols
+		end := min(start+cols, len(labels))
+		if start >= end {
+			break
+		}
+		fmt.Fprintf(&b, "row %d: %s\n", row+1, strings.Join(labels[start:end], " | "))
+	}
+	return b.String()
+}
+
+// describePath names the current page for Describe: "favorites", or the
+// folder relative to the root with a leading slash.
+func (n *Navigator) describePath() string {
+	if n.inFavorites {
+		return "favorites"
+	}
+	rel, err := filepath.Rel(n.rootPath, n.currentDir)
+	if err != nil || rel == "." {
+		return "/"
+	}
+	return "/" + filepath.ToSlash(rel)
+}
@@ -3,12 +3,16 @@ package streamdeck
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/jpeg"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sstallion/go-hid"
 )
@@ -21,15 +25,93 @@ func min(a, b int) int {
 	return b
 }
 
+// Write errors returned (wrapped) by SetImage, WriteKeyData and Clear.
+// Use errors.Is to tell a disconnected device from a flaky write.
+var (
+	ErrDeviceGone     = errors.New("stream deck disconnected")
+	ErrTransientWrite = errors.New("transient HID write failure")
+)
+
+// errClosed is returned for I/O on a device after Close.
+var errClosed = fmt.Errorf("%w: device closed", ErrDeviceGone)
+
+// Retry policy for a single HID page write. The delay doubles per attempt.
+const (
+	writeRetries    = 3
+	writeRetryDelay = 5 * time.Millisecond
+)
+
+// hidDevice is the subset of *hid.Device used by Device. It is an interface
+// so tests can substitute a fake device.
+type hidDevice interface {
+	Write(p []byte) (int, error)
+	ReadWithTimeout(p []byte, timeout time.Duration) (int, error)
+	SendFeatureReport(p []byte) (int, error)
+	GetFeatureReport(p []byte) (int, error)
+	Close() error
+}
+
 // Device represents an opened Stream Deck device.
 type Device struct {
-	hid   *hid.Device
+	hid   hidDevice
 	Info  DeviceInfo
 	Model Model
 	mu    sync.Mutex // protects HID operations
 
 	// Performance settings
 	jpegQuality int
+
+	// keyData holds the last encoded image written to each key, so callers
+	// can restore a key after temporarily drawing over it. Guarded by mu.
+	keyData map[int][]byte
+
+	// brightness is the last value passed to SetBrightness, restored after
+	// Identify. Guarded by mu.
+	brightness    int
+	brightnessSet bool
+
+	// Key polling settings (see SetKeyPolling). Guarded by mu.
+	pollInterval time.Duration
+	readTimeout  time.Duration
+	pollSet      bool
+
+	// blockingReads selects blocking key reads (see SetBlockingReads);
+	// guarded by mu. readMu serialises those reads.
+	blockingReads bool
+	readMu        sync.Mutex
+
+	// closed is set by Close; written holding both mu and readMu, so either
+	// lock is enough to read it. closing is set first, so the blocking
+	// reader does not start another read while Close waits for readMu.
+	closed  bool
+	closing atomic.Bool
+
+	// Running Identify sequence, if any.
+	identifyMu     sync.Mutex
+	identifyCancel func()
+	identifyDone   chan struct{}
+
+	// Crossfades in progress, by key (see FadeImage).
+	fadeMu sync.Mutex
+	fades  map[int]*fade
+
+	// Encoded images loaded by SetKeyImageFromFile.
+	fileImages fileImageCache
+
+	// HID traffic counters (see WriteStats).
+	stats writeCounters
+
+	// rotation is the mounting rotation in clockwise quarter turns (see
+	// SetRotation).
+	rotation atomic.Int32
+
+	// Raw report dump (see EnableReportTrace).
+	trace reportTrace
+
+	// Where ListenKeys sends dial and touch events (see SetInputEvents).
+	// Guarded by mu.
+	dialEvents  chan<- DialEvent
+	touchEvents chan<- TouchEvent
 }
 
 // KeyEvent represents a key press or release event.
@@ -63,13 +145,7 @@ func Open(path string) (*Device, error) {
 		return nil, fmt.Errorf("failed to get product ID: %w", err)
 	}
 
-	model, _ := LookupModel(productID)
-	if model.ProductID == 0 {
-		model = Model{
-			Name:      fmt.Sprintf("Unknown Stream Deck (PID: 0x%04X)", productID),
-			ProductID: productID,
-		}
-	}
+	model := ResolveModel(productID, product)
 
 	d := &Device{
 		hid:   dev,
@@ -105,6 +181,16 @@ func OpenWithConfig(path string, jpegQuality int) (*Device, error) {
 	return d, nil
 }
 
+// UseModel makes the device behave as the known model m: its layout, key
+// size and image format. It is for variants ResolveModel does not know or
+// guessed wrong; the device keeps its own product ID. Call right after
+// opening.
+func (d *Device) UseModel(m Model) {
+	m = m.Forced(d.Model.ProductID)
+	d.Model = m
+	d.Info.Model = m
+}
+
 // OpenFirst opens the first Stream Deck device found.
 func OpenFirst() (*Device, error) {
 	devices, err := Enumerate()
@@ -117,10 +203,20 @@ func OpenFirst() (*Device, error) {
 	return Open(devices[0].Path)
 }
 
-// Close closes the device.
+// Close closes the device. It waits for a blocking key read in progress
+// (see SetBlockingReads) to time out, as hidapi must not free a handle
+// another thread is reading from; later reads and writes fail with
+// ErrDeviceGone.
 func (d *Device) Close() error {
+	d.closing.Store(true)
+	d.readMu.Lock()
+	defer d.readMu.Unlock()
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
 	if d.hid != nil {
 		return d.hid.Close()
 	}
@@ -129,23 +225,76 @@ func (d *Device) Close() error {
 
 // SetBrightness sets the brightness of the Stream Deck (0-100).
 func (d *Device) SetBrightness(percent int) error {
-	if percent < 0 {
-		percent = 0
+	percent = clampPercent(percent)
+
+	d.mu.Lock()
+	d.brightness = percent
+	d.brightnessSet = true
+	d.mu.Unlock()
+
+	return d.writeBrightness(percent)
+}
+
+// Brightness returns the brightness last set with SetBrightness, or 100 (the
+// power-on level) if it was never set. The hardware cannot be queried.
+func (d *Device) Brightness() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.brightnessSet {
+		return 100
 	}
-	if percent > 100 {
-		percent = 100
+	return d.brightness
+}
+
+// AdjustBrightness changes the brightness by delta percentage points,
+// clamped to 0-100, and returns the new level.
+func (d *Device) AdjustBrightness(delta int) (int, error) {
+	d.mu.Lock()
+	current := 100
+	if d.brightnessSet {
+		current = d.brightness
 	}
+	percent := clampPercent(current + delta)
+	d.brightness = percent
+	d.brightnessSet = true
+	d.mu.Unlock()
 
+	return percent, d.writeBrightness(percent)
+}
+
+// clampPercent limits a brightness to 0-100.
+func clampPercent(percent int) int {
+	return max(0, min(100, percent))
+}
+
+// writeBrightness sends the brightness feature report without recording the
+// value, so temporary changes (e.g. Identify) don't overwrite the user's level.
+func (d *Device) writeBrightness(percent int) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	data := make([]byte, 32)
-	data[0] = 0x03
-	data[1] = 0x08
-	data[2] = byte(percent)
+	if _, err := d.sendFeatureReport(brightnessReport(d.Model, percent)); err != nil {
+		return fmt.Errorf("set brightness on %s: %w", d.Model.Name, err)
+	}
+	return nil
+}
 
-	_, err := d.hid.SendFeatureReport(data)
-	return err
+// brightnessReport builds the brightness feature report for a model. The
+// first-generation Original and Mini use a longer report with a different
+// command; every later model uses 0x03 0x08.
+func brightnessReport(m Model, percent int) []byte {
+	switch m.ProductID {
+	case 0x0060, 0x0063:
+		data := make([]byte, 17)
+		copy(data, []byte{0x05, 0x55, 0xaa, 0xd1, 0x01, byte(percent)})
+		return data
+	default:
+		data := make([]byte, 32)
+		data[0] = 0x03
+		data[1] = 0x08
+		data[2] = byte(percent)
+		return data
+	}
 }
 
 // Reset resets the Stream Deck to its default state.
@@ -153,12 +302,27 @@ func (d *Device) Reset() error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	data := make([]byte, 32)
-	data[0] = 0x03
-	data[1] = 0x02
+	if _, err := d.sendFeatureReport(resetReport(d.Model)); err != nil {
+		return fmt.Errorf("reset %s: %w", d.Model.Name, err)
+	}
+	return nil
+}
 
-	_, err := d.hid.SendFeatureReport(data)
-	return err
+// resetReport builds the reset feature report for a model: 0x0b 0x63 on the
+// first-generation Original and Mini, 0x03 0x02 on later models.
+func resetReport(m Model) []byte {
+	switch m.ProductID {
+	case 0x0060, 0x0063:
+		data := make([]byte, 17)
+		data[0] = 0x0b
+		data[1] = 0x63
+		return data
+	default:
+		data := make([]byte, 32)
+		data[0] = 0x03
+		data[1] = 0x02
+		return data
+	}
 }
 
 // SetImage sets the image on a specific key.
@@ -176,6 +340,7 @@ func (d *Device) SetImage(keyIndex int, img image.Image) error {
 		return err
 	}
 
+	d.cancelFade(keyIndex)
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	return d.writeImageData(keyIndex, imageData)
@@ -186,6 +351,8 @@ func (d *Device) SetImage(keyIndex int, img image.Image) error {
 //
 //	data, err := dev.EncodeKeyImage(img)   // concurrent-safe, no lock
 //	dev.WriteKeyData(keyIndex, data)        // serialised HID write
+//
+// WriteKeyDataBatch writes a whole page of such frames under one lock.
 func (d *Device) EncodeKeyImage(img image.Image) ([]byte, error) {
 	if d.Model.PixelSize == 0 {
 		return nil, fmt.Errorf("device does not support images")
@@ -200,13 +367,59 @@ func (d *Device) WriteKeyData(keyIndex int, imageData []byte) error {
 	if keyIndex < 0 || keyIndex >= d.Model.Keys {
 		return fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
 	}
+	d.cancelFade(keyIndex)
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	return d.writeImageData(keyIndex, imageData)
 }
 
-// prepareImage resizes and rotates the image for Stream Deck display.
+// KeyFrame is one key's pre-encoded image, as written by WriteKeyDataBatch.
+type KeyFrame struct {
+	Key  int
+	Data []byte
+}
+
+// WriteKeyDataBatch writes several pre-encoded key images, taking the HID
+// lock once for the whole batch instead of once per key. A key that fails
+// does not stop the others; the returned error joins one "write key N"
+// error per failed key. Writing stops early if the device has gone away.
+func (d *Device) WriteKeyDataBatch(frames []KeyFrame) error {
+	for _, f := range frames {
+		d.cancelFade(f.Key)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var errs []error
+	for _, f := range frames {
+		if f.Key < 0 || f.Key >= d.Model.Keys {
+			errs = append(errs, fmt.Errorf("write key %d: key index out of range (0-%d)", f.Key, d.Model.Keys-1))
+			continue
+		}
+		if err := d.writeImageData(f.Key, f.Data); err != nil {
+			errs = append(errs, fmt.Errorf("write key %d: %w", f.Key, err))
+			if errors.Is(err, ErrDeviceGone) {
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// prepareImage resizes and rotates the image for Stream Deck display,
+// turning it against the deck's mounting rotation so it appears upright.
 func (d *Device) prepareImage(src image.Image) image.Image {
+	dst := d.prepareUnrotated(src)
+	if turns := d.quarterTurns(); turns != 0 {
+		return rotateQuarterTurns(dst, 4-turns)
+	}
+	return dst
+}
+
+// prepareUnrotated resizes the image and rotates it 180 degrees, as the
+// hardware expects, ignoring the mounting rotation.
+func (d *Device) prepareUnrotated(src image.Image) *image.RGBA {
 	size := d.Model.PixelSize
 	bounds := src.Bounds()
 
@@ -275,8 +488,23 @@ func (d *Device) encodeImage(img image.Image) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// KeyData returns the last encoded image written to a key, or nil if the key
+// has not been drawn since the device was opened. The result can be passed
+// back to WriteKeyData to restore the key.
+func (d *Device) KeyData(keyIndex int) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.keyData[keyIndex]
+}
+
 // writeImageData writes raw image data to a key.
+// Must be called with d.mu held.
 func (d *Device) writeImageData(keyIndex int, imageData []byte) error {
+	if d.keyData == nil {
+		d.keyData = make(map[int][]byte)
+	}
+	d.keyData[keyIndex] = imageData
+
 	// Stream Deck MK.2/V2 uses 1024 byte pages with 8 byte header
 	pageSize := 1024
 	headerSize := 8
@@ -296,9 +524,9 @@ func (d *Device) writeImageData(keyIndex int, imageData []byte) error {
 
 		// Build the report
 		report := make([]byte, pageSize)
-		report[0] = 0x02           // Report ID for image
-		report[1] = 0x07           // Command
-		report[2] = byte(keyIndex) // Key index
+		report[0] = 0x02                          // Report ID for image
+		report[1] = 0x07                          // Command
+		report[2] = byte(d.physicalKey(keyIndex)) // Key index
 		if isLastPage {
 			report[3] = 0x01 // Last page flag
 		} else {
@@ -311,15 +539,55 @@ func (d *Device) writeImageData(keyIndex int, imageData []byte) error {
 
 		copy(report[headerSize:], chunk)
 
-		_, err := d.hid.Write(report)
-		if err != nil {
+		if err := d.writeReport(report); err != nil {
 			return fmt.Errorf("write page %d: %w", page, err)
 		}
 	}
 
+	d.stats.keyImages.Add(1)
 	return nil
 }
 
+// writeReport writes one HID report, retrying transient failures with a
+// doubling backoff. The returned error wraps ErrDeviceGone or
+// ErrTransientWrite alongside the underlying HID error.
+// Must be called with d.mu held.
+func (d *Device) writeReport(report []byte) error {
+	if d.closed {
+		return errClosed
+	}
+	delay := writeRetryDelay
+	var err error
+	for attempt := 0; attempt <= writeRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if _, err = d.hid.Write(report); err == nil {
+			d.stats.reports.Add(1)
+			return nil
+		}
+		if isDeviceGone(err) {
+			d.stats.errors.Add(1)
+			return fmt.Errorf("%w: %w", ErrDeviceGone, err)
+		}
+	}
+	d.stats.errors.Add(1)
+	return fmt.Errorf("%w after %d attempts: %w", ErrTransientWrite, writeRetries+1, err)
+}
+
+// isDeviceGone reports whether a HID error means the device was unplugged.
+// hidapi only surfaces error strings, so this matches the platform messages.
+func isDeviceGone(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"no such device", "device not configured", "not connected", "disconnected"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // Clear clears all keys on the Stream Deck (sets them to black).
 func (d *Device) Clear() error {
 	if d.Model.PixelSize == 0 {
@@ -345,6 +613,40 @@ func (d *Device) SetKeyColor(keyIndex int, c color.Color) error {
 	return d.SetImage(keyIndex, img)
 }
 
+// SetKeysColor sets several keys to the same solid color. The image is
+// encoded once and all keys are written under a single lock, so the update
+// lands as one batch instead of interleaving with other writers.
+func (d *Device) SetKeysColor(keys []int, c color.Color) error {
+	if d.Model.PixelSize == 0 {
+		return fmt.Errorf("device does not support images")
+	}
+	for _, k := range keys {
+		if k < 0 || k >= d.Model.Keys {
+			return fmt.Errorf("key index %d out of range (0-%d)", k, d.Model.Keys-1)
+		}
+	}
+
+	size := d.Model.PixelSize
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	imageData, err := d.encodeImage(img) // solid colour: no rotation needed
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		d.cancelFade(k)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, k := range keys {
+		if err := d.writeImageData(k, imageData); err != nil {
+			return fmt.Errorf("key %d: %w", k, err)
+		}
+	}
+	return nil
+}
+
 // ResizeImage scales an image to fit the device's key size.
 // Maintains aspect ratio and centers the image.
 // OPTIMIZATION: Use Lanczos3 resampling for better quality at similar speed
@@ -0,0 +1,409 @@
+package streamdeck
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHID records written reports and fails the first failWrites writes
+// with writeErr. Writes addressed to a key in failKeys always fail.
+// Feature reports are recorded in features.
+type fakeHID struct {
+	writes     [][]byte
+	features   [][]byte
+	failWrites int
+	failKeys   map[byte]bool
+	writeErr   error
+}
+
+func (f *fakeHID) Write(p []byte) (int, error) {
+	if f.failKeys[p[2]] {
+		return 0, f.writeErr
+	}
+	if f.failWrites > 0 {
+		f.failWrites--
+		return 0, f.writeErr
+	}
+	f.writes = append(f.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (f *fakeHID) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) { return 0, nil }
+func (f *fakeHID) GetFeatureReport(p []byte) (int, error)                       { return len(p), nil }
+func (f *fakeHID) Close() error                                                 { return nil }
+
+func (f *fakeHID) SendFeatureReport(p []byte) (int, error) {
+	f.features = append(f.features, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func TestWriteKeyDataRetriesTransientFailure(t *testing.T) {
+	fake := &fakeHID{failWrites: 1, writeErr: errors.New("timed out")}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+
+	if err := d.WriteKeyData(3, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("WriteKeyData: %v", err)
+	}
+	if len(fake.writes) != 1 {
+		t.Fatalf("got %d successful writes, want 1", len(fake.writes))
+	}
+	if key := fake.writes[0][2]; key != 3 {
+		t.Fatalf("report addressed key %d, want 3", key)
+	}
+}
+
+func TestWriteKeyDataTypedErrors(t *testing.T) {
+	flaky := &fakeHID{failWrites: writeRetries + 1, writeErr: errors.New("timed out")}
+	d := &Device{hid: flaky, Model: Models[0x0080]}
+	if err := d.WriteKeyData(0, []byte{1}); !errors.Is(err, ErrTransientWrite) {
+		t.Fatalf("exhausted retries: got %v, want ErrTransientWrite", err)
+	}
+
+	gone := &fakeHID{failWrites: writeRetries + 1, writeErr: errors.New("No such device")}
+	d = &Device{hid: gone, Model: Models[0x0080]}
+	if err := d.WriteKeyData(0, []byte{1}); !errors.Is(err, ErrDeviceGone) {
+		t.Fatalf("unplugged: got %v, want ErrDeviceGone", err)
+	}
+	if gone.failWrites != writeRetries {
+		t.Fatalf("device-gone error was retried %d times", writeRetries-gone.failWrites)
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	fake := &fakeHID{failKeys: map[byte]bool{9: true}, writeErr: errors.New("No such device")}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+
+	big := make([]byte, 1500) // two reports
+	if err := d.WriteKeyData(1, big); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteKeyData(2, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteKeyData(9, []byte{1}); err == nil {
+		t.Fatal("write to a failing key succeeded")
+	}
+
+	want := WriteStats{Reports: 3, Errors: 1, KeyImages: 2}
+	if got := d.WriteStats(); got != want {
+		t.Errorf("WriteStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestIdentifyBlinksAndRestoresBrightness(t *testing.T) {
+	defer func(d time.Duration) { identifyInterval = d }(identifyInterval)
+	identifyInterval = time.Millisecond
+
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+	if err := d.SetBrightness(40); err != nil {
+		t.Fatalf("SetBrightness: %v", err)
+	}
+	fake.features = nil
+
+	select {
+	case <-d.Identify():
+	case <-time.After(time.Second):
+		t.Fatal("Identify did not finish")
+	}
+
+	var got []byte
+	for _, r := range fake.features {
+		if r[0] != 0x03 || r[1] != 0x08 {
+			t.Fatalf("unexpected feature report % x", r[:2])
+		}
+		got = append(got, r[2])
+	}
+	want := []byte{0, 100, 0, 100, 0, 100, 0, 100, 40}
+	if string(got) != string(want) {
+		t.Fatalf("brightness sequence = %v, want %v", got, want)
+	}
+}
+
+func TestIdentifyInterruptsPreviousSequence(t *testing.T) {
+	defer func(d time.Duration) { identifyInterval = d }(identifyInterval)
+	identifyInterval = time.Hour
+
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+	first := d.Identify()
+	second := d.Identify()
+
+	select {
+	case <-first:
+	case <-time.After(time.Second):
+		t.Fatal("second Identify did not interrupt the first")
+	}
+
+	d.identifyMu.Lock()
+	d.identifyCancel()
+	d.identifyMu.Unlock()
+	<-second
+}
+
+func TestSnapshotComposesKeys(t *testing.T) {
+	model := Model{Name: "Fake 2x2", Cols: 2, Rows: 2, Keys: 4, PixelSize: 4, ImageFormat: "BMP"}
+	d := &Device{hid: &fakeHID{}, Model: model}
+
+	// Top-left pixel of key 0 marked white so orientation can be checked.
+	key0 := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(key0, key0.Bounds(), &image.Uniform{color.RGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
+	key0.Set(0, 0, color.White)
+	if err := d.SetImage(0, key0); err != nil {
+		t.Fatalf("SetImage: %v", err)
+	}
+	if err := d.SetKeyColor(3, color.RGBA{0, 0, 255, 255}); err != nil {
+		t.Fatalf("SetKeyColor: %v", err)
+	}
+
+	snap := d.Snapshot()
+	want := image.Rect(0, 0, 2*4+3*snapshotGap, 2*4+3*snapshotGap)
+	if snap.Bounds() != want {
+		t.Fatalf("bounds = %v, want %v", snap.Bounds(), want)
+	}
+
+	at := func(key, x, y int) color.RGBA {
+		col, row := key%2, key/2
+		return color.RGBAModel.Convert(snap.At(
+			snapshotGap+col*(4+snapshotGap)+x,
+			snapshotGap+row*(4+snapshotGap)+y)).(color.RGBA)
+	}
+	checks := []struct {
+		name string
+		got  color.RGBA
+		want color.RGBA
+	}{
+		{"key 0 marker", at(0, 0, 0), color.RGBA{255, 255, 255, 255}},
+		{"key 0 fill", at(0, 3, 3), color.RGBA{255, 0, 0, 255}},
+		{"key 3 fill", at(3, 1, 1), color.RGBA{0, 0, 255, 255}},
+		{"undrawn key 1", at(1, 1, 1), snapshotBackground},
+		{"gap", color.RGBAModel.Convert(snap.At(0, 0)).(color.RGBA), snapshotBackground},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestHapticFeatureReport(t *testing.T) {
+	fake := &fakeHID{}
+	model := Models[0x0080]
+	model.HasHaptics = true
+	d := &Device{hid: fake, Model: model}
+
+	if err := d.Haptic(HapticDouble); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.features) != 1 {
+		t.Fatalf("got %d feature reports, want 1", len(fake.features))
+	}
+	want := make([]byte, 32)
+	want[0], want[1], want[2] = 0x03, 0x0A, byte(HapticDouble)
+	if !bytes.Equal(fake.features[0], want) {
+		t.Errorf("report = % x, want % x", fake.features[0], want)
+	}
+
+	plain := &fakeHID{}
+	d = &Device{hid: plain, Model: Models[0x0080]}
+	if err := d.Haptic(HapticShort); !errors.Is(err, ErrNoHaptics) {
+		t.Errorf("unsupported model: got %v, want ErrNoHaptics", err)
+	}
+	if len(plain.features) != 0 {
+		t.Error("unsupported model was sent a feature report")
+	}
+}
+
+func TestParseHapticPattern(t *testing.T) {
+	for in, want := range map[string]HapticPattern{"": HapticShort, "long": HapticLong, " Double ": HapticDouble} {
+		if got, err := ParseHapticPattern(in); err != nil || got != want {
+			t.Errorf("ParseHapticPattern(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseHapticPattern("buzz"); err == nil {
+		t.Error("ParseHapticPattern accepted an unknown pattern")
+	}
+}
+
+func TestOpenVirtual(t *testing.T) {
+	d := OpenVirtual(Models[0x0080])
+	if err := d.SetKeyColor(4, color.RGBA{255, 0, 0, 255}); err != nil {
+		t.Fatal(err)
+	}
+	if d.KeyData(4) == nil {
+		t.Error("virtual device did not record the key image")
+	}
+	keys, err := d.ReadKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, pressed := range keys {
+		if pressed {
+			t.Errorf("virtual key %d reported pressed", i)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetBrightnessReportPerModel(t *testing.T) {
+	gen1 := make([]byte, 17)
+	copy(gen1, []byte{0x05, 0x55, 0xaa, 0xd1, 0x01, 60})
+	mk2 := make([]byte, 32)
+	copy(mk2, []byte{0x03, 0x08, 60})
+
+	for _, tc := range []struct {
+		pid  uint16
+		want []byte
+	}{
+		{0x0060, gen1}, // Original
+		{0x0063, gen1}, // Mini
+		{0x0080, mk2},  // MK.2
+		{0x006d, mk2},  // Original V2
+	} {
+		fake := &fakeHID{}
+		d := &Device{hid: fake, Model: Models[tc.pid]}
+		if err := d.SetBrightness(60); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.features) != 1 || !bytes.Equal(fake.features[0], tc.want) {
+			t.Errorf("%s: report % x, want % x", Models[tc.pid].Name, fake.features, tc.want)
+		}
+	}
+}
+
+func TestResetReportPerModel(t *testing.T) {
+	gen1 := make([]byte, 17)
+	copy(gen1, []byte{0x0b, 0x63})
+	mk2 := make([]byte, 32)
+	copy(mk2, []byte{0x03, 0x02})
+
+	for _, tc := range []struct {
+		pid  uint16
+		want []byte
+	}{
+		{0x0060, gen1}, // Original
+		{0x0063, gen1}, // Mini
+		{0x0080, mk2},  // MK.2
+		{0x006c, mk2},  // XL
+	} {
+		fake := &fakeHID{}
+		d := &Device{hid: fake, Model: Models[tc.pid]}
+		if err := d.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if len(fake.features) != 1 || !bytes.Equal(fake.features[0], tc.want) {
+			t.Errorf("%s: report % x, want % x", Models[tc.pid].Name, fake.features, tc.want)
+		}
+	}
+}
+
+func TestBrightnessTracking(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+
+	if got := d.Brightness(); got != 100 {
+		t.Errorf("initial brightness = %d, want 100", got)
+	}
+	if err := d.SetBrightness(150); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Brightness(); got != 100 {
+		t.Errorf("after SetBrightness(150) = %d, want 100", got)
+	}
+	if err := d.SetBrightness(40); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct{ delta, want int }{{10, 50}, {-30, 20}, {-50, 0}, {250, 100}} {
+		got, err := d.AdjustBrightness(tc.delta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want || d.Brightness() != tc.want {
+			t.Errorf("AdjustBrightness(%d) = %d (Brightness %d), want %d", tc.delta, got, d.Brightness(), tc.want)
+		}
+	}
+	last := fake.features[len(fake.features)-1]
+	if last[2] != 100 {
+		t.Errorf("last report sent brightness %d, want 100", last[2])
+	}
+}
+
+func TestWriteKeyDataBatch(t *testing.T) {
+	fake := &fakeHID{failKeys: map[byte]bool{2: true}, writeErr: errors.New("timed out")}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+
+	err := d.WriteKeyDataBatch([]KeyFrame{
+		{Key: 0, Data: []byte{1}},
+		{Key: 2, Data: []byte{2}},
+		{Key: 99, Data: []byte{3}},
+		{Key: 4, Data: []byte{4}},
+	})
+	if !errors.Is(err, ErrTransientWrite) {
+		t.Fatalf("got %v, want ErrTransientWrite for key 2", err)
+	}
+	for _, want := range []string{"write key 2:", "write key 99:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+	if got := writtenKeys(fake); !reflect.DeepEqual(got, map[int]int{0: 1, 4: 1}) {
+		t.Errorf("wrote keys %v, want 0 and 4 once each", got)
+	}
+	if got := d.KeyData(4); !bytes.Equal(got, []byte{4}) {
+		t.Errorf("KeyData(4) = %v, want [4]", got)
+	}
+
+	gone := &fakeHID{failKeys: map[byte]bool{1: true}, writeErr: errors.New("No such device")}
+	d = &Device{hid: gone, Model: Models[0x0080]}
+	err = d.WriteKeyDataBatch([]KeyFrame{{Key: 1, Data: []byte{1}}, {Key: 3, Data: []byte{3}}})
+	if !errors.Is(err, ErrDeviceGone) {
+		t.Fatalf("got %v, want ErrDeviceGone", err)
+	}
+	if len(gone.writes) != 0 {
+		t.Errorf("kept writing after the device was gone: %v", writtenKeys(gone))
+	}
+}
+
+// discardHID accepts and drops every report, so benchmarks do not grow a
+// record of writes.
+type discardHID struct{ fakeHID }
+
+func (discardHID) Write(p []byte) (int, error) { return len(p), nil }
+
+// BenchmarkPageWrite compares writing a full Stream Deck XL page one key at
+// a time, locking the device per key, against a single WriteKeyDataBatch.
+func BenchmarkPageWrite(b *testing.B) {
+	model := Models[0x006c]
+	frames := make([]KeyFrame, model.Keys)
+	for i := range frames {
+		frames[i] = KeyFrame{Key: i, Data: make([]byte, 3000)}
+	}
+
+	b.Run("per-key", func(b *testing.B) {
+		d := &Device{hid: &discardHID{}, Model: model}
+		for i := 0; i < b.N; i++ {
+			for _, f := range frames {
+				if err := d.WriteKeyData(f.Key, f.Data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		d := &Device{hid: &discardHID{}, Model: model}
+		for i := 0; i < b.N; i++ {
+			if err := d.WriteKeyDataBatch(frames); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -31,14 +31,7 @@ func Enumerate() ([]DeviceInfo, error) {
 	var devices []DeviceInfo
 
 	err := hid.Enumerate(VendorID, 0x0000, func(info *hid.DeviceInfo) error {
-		model, known := LookupModel(info.ProductID)
-		if !known {
-			// Unknown Stream Deck variant
-			model = Model{
-				Name:      fmt.Sprintf("Unknown Stream Deck (PID: 0x%04X)", info.ProductID),
-				ProductID: info.ProductID,
-			}
-		}
+		model := ResolveModel(info.ProductID, info.ProductStr)
 
 		devInfo := DeviceInfo{
 			Path:         info.Path,
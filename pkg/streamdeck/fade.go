@@ -0,0 +1,158 @@
+package streamdeck
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"time"
+)
+
+const (
+	// fadeFrameInterval is the target time between crossfade frames.
+	fadeFrameInterval = 40 * time.Millisecond
+
+	// maxFadeFrames caps the intermediate frames of one crossfade, since
+	// every frame is a full image upload.
+	maxFadeFrames = 12
+)
+
+// fade is a crossfade running on one key.
+type fade struct {
+	stop chan struct{} // closed to abandon the fade
+	done chan struct{} // closed when the fade goroutine exits
+}
+
+// CrossfadeFrames returns n frames blending from into to, evenly spaced and
+// excluding both end points. The frames take the bounds of to; from must be
+// at least as large.
+func CrossfadeFrames(from, to image.Image, n int) []*image.RGBA {
+	tb, fb := to.Bounds(), from.Bounds()
+	frames := make([]*image.RGBA, n)
+	for i := range frames {
+		t := uint32(i+1) * 256 / uint32(n+1) // weight of to, out of 256
+		dst := image.NewRGBA(image.Rect(0, 0, tb.Dx(), tb.Dy()))
+		for y := 0; y < tb.Dy(); y++ {
+			for x := 0; x < tb.Dx(); x++ {
+				r1, g1, b1, a1 := from.At(fb.Min.X+x, fb.Min.Y+y).RGBA()
+				r2, g2, b2, a2 := to.At(tb.Min.X+x, tb.Min.Y+y).RGBA()
+				o := dst.PixOffset(x, y)
+				dst.Pix[o+0] = uint8((r1*(256-t) + r2*t) >> 16)
+				dst.Pix[o+1] = uint8((g1*(256-t) + g2*t) >> 16)
+				dst.Pix[o+2] = uint8((b1*(256-t) + b2*t) >> 16)
+				dst.Pix[o+3] = uint8((a1*(256-t) + a2*t) >> 16)
+			}
+		}
+		frames[i] = dst
+	}
+	return frames
+}
+
+// FadeImage crossfades a key from its current image to img over duration.
+// The fade runs in the background; the returned channel is closed once the
+// final image is on the key or the fade was superseded by another write to
+// the same key. Keys that have not been drawn yet or already show img, or a
+// non-positive duration, switch immediately.
+func (d *Device) FadeImage(keyIndex int, img image.Image, duration time.Duration) (<-chan struct{}, error) {
+	if keyIndex < 0 || keyIndex >= d.Model.Keys {
+		return nil, fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
+	}
+	if d.Model.PixelSize == 0 {
+		return nil, fmt.Errorf("device does not support images")
+	}
+
+	// Blend in the device's (rotated) orientation so the previous frame
+	// can be used exactly as stored.
+	to := d.prepareImage(img)
+	final, err := d.encodeImage(to)
+	if err != nil {
+		return nil, err
+	}
+
+	d.cancelFade(keyIndex)
+	d.mu.Lock()
+	prev := d.keyData[keyIndex]
+	d.mu.Unlock()
+
+	var from image.Image
+	if prev != nil && duration > 0 && !bytes.Equal(prev, final) {
+		from, _, _ = image.Decode(bytes.NewReader(prev))
+	}
+	if from == nil || from.Bounds().Dx() < to.Bounds().Dx() || from.Bounds().Dy() < to.Bounds().Dy() {
+		d.mu.Lock()
+		err := d.writeImageData(keyIndex, final)
+		d.mu.Unlock()
+		done := make(chan struct{})
+		close(done)
+		return done, err
+	}
+
+	steps := min(max(int(duration/fadeFrameInterval)-1, 1), maxFadeFrames)
+	frames := CrossfadeFrames(from, to, steps)
+
+	f := &fade{stop: make(chan struct{}), done: make(chan struct{})}
+	d.fadeMu.Lock()
+	if d.fades == nil {
+		d.fades = make(map[int]*fade)
+	}
+	d.fades[keyIndex] = f
+	d.fadeMu.Unlock()
+
+	go d.runFade(keyIndex, f, frames, final, duration/time.Duration(steps+1))
+	return f.done, nil
+}
+
+// runFade writes each frame, then the final image, one interval apart.
+func (d *Device) runFade(keyIndex int, f *fade, frames []*image.RGBA, final []byte, interval time.Duration) {
+	defer func() {
+		d.fadeMu.Lock()
+		if d.fades[keyIndex] == f {
+			delete(d.fades, keyIndex)
+		}
+		d.fadeMu.Unlock()
+		close(f.done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for i := 0; i <= len(frames); i++ {
+		data := final
+		if i < len(frames) {
+			var err error
+			if data, err = d.encodeImage(frames[i]); err != nil {
+				return
+			}
+		}
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Checking stop under mu means a write that cancelled this fade
+		// always lands after our last frame.
+		d.mu.Lock()
+		select {
+		case <-f.stop:
+			d.mu.Unlock()
+			return
+		default:
+		}
+		err := d.writeImageData(keyIndex, data)
+		d.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// cancelFade abandons any crossfade running on keyIndex. Writers call it
+// before taking mu so the fade cannot overwrite their image.
+func (d *Device) cancelFade(keyIndex int) {
+	d.fadeMu.Lock()
+	defer d.fadeMu.Unlock()
+	if f, ok := d.fades[keyIndex]; ok {
+		close(f.stop)
+		delete(d.fades, keyIndex)
+	}
+}
@@ -0,0 +1,121 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+)
+
+func solid(size int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	return img
+}
+
+func TestCrossfadeFramesInterpolate(t *testing.T) {
+	frames := CrossfadeFrames(solid(4, color.Black), solid(4, color.White), 3)
+	if len(frames) != 3 {
+		t.Fatalf("got %d frames, want 3", len(frames))
+	}
+	prev := uint8(0)
+	for i, f := range frames {
+		v := f.RGBAAt(2, 2).R
+		if v <= prev || v == 255 {
+			t.Fatalf("frame %d value %d, want strictly between %d and 255", i, v, prev)
+		}
+		prev = v
+	}
+	if mid := frames[1].RGBAAt(0, 0).R; mid < 120 || mid > 135 {
+		t.Fatalf("middle frame value %d, want about 128", mid)
+	}
+}
+
+// completedImages counts images fully written to key, i.e. reports
+// carrying the last-page flag.
+func completedImages(fake *fakeHID, key byte) int {
+	n := 0
+	for _, w := range fake.writes {
+		if w[2] == key && w[3] == 0x01 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFadeImageWritesIntermediateFrames(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+	size := d.Model.PixelSize
+
+	if err := d.SetImage(2, solid(size, color.Black)); err != nil {
+		t.Fatalf("SetImage: %v", err)
+	}
+	done, err := d.FadeImage(2, solid(size, color.White), 4*fadeFrameInterval)
+	if err != nil {
+		t.Fatalf("FadeImage: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("fade never finished")
+	}
+
+	// 1 initial image + 3 intermediate frames + the final image
+	if got := completedImages(fake, 2); got != 5 {
+		t.Fatalf("key written %d times, want 5", got)
+	}
+	img, _, err := image.Decode(bytes.NewReader(d.KeyData(2)))
+	if err != nil {
+		t.Fatalf("decode final: %v", err)
+	}
+	if r, _, _, _ := img.At(size/2, size/2).RGBA(); r>>8 < 250 {
+		t.Fatalf("final image red %d, want white", r>>8)
+	}
+}
+
+func TestFadeImageFirstDrawIsImmediate(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+
+	done, err := d.FadeImage(0, solid(d.Model.PixelSize, color.White), time.Second)
+	if err != nil {
+		t.Fatalf("FadeImage: %v", err)
+	}
+	select {
+	case <-done:
+	default:
+		t.Fatal("fade on an undrawn key should complete immediately")
+	}
+	if got := completedImages(fake, 0); got != 1 {
+		t.Fatalf("key written %d times, want 1", got)
+	}
+}
+
+func TestFadeImageSupersededBySetImage(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+	size := d.Model.PixelSize
+
+	d.SetImage(1, solid(size, color.Black))
+	done, err := d.FadeImage(1, solid(size, color.White), time.Second)
+	if err != nil {
+		t.Fatalf("FadeImage: %v", err)
+	}
+	want := solid(size, color.RGBA{255, 0, 0, 255})
+	if err := d.SetImage(1, want); err != nil {
+		t.Fatalf("SetImage: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("superseded fade never stopped")
+	}
+
+	data, _ := d.EncodeKeyImage(want)
+	if !bytes.Equal(d.KeyData(1), data) {
+		t.Fatal("fade overwrote the newer image")
+	}
+}
@@ -0,0 +1,77 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+)
+
+// ImageFilter describes optional per-pixel adjustments applied to a key image.
+// The zero value leaves the image unchanged.
+type ImageFilter struct {
+	Brightness float64 // Multiplier; 0 or 1 = unchanged, <1 dims, >1 brightens
+	Contrast   float64 // Multiplier around mid-grey; 0 or 1 = unchanged
+	Invert     bool    // Invert RGB channels
+	Grayscale  bool    // Convert to luminance before other adjustments
+}
+
+// IsZero returns true if the filter would not change any pixel.
+func (f ImageFilter) IsZero() bool {
+	return (f.Brightness == 0 || f.Brightness == 1) &&
+		(f.Contrast == 0 || f.Contrast == 1) &&
+		!f.Invert && !f.Grayscale
+}
+
+// ApplyFilter returns a copy of src with the filter applied. The source image
+// is never modified. Alpha is preserved.
+func ApplyFilter(src image.Image, f ImageFilter) image.Image {
+	if f.IsZero() {
+		return src
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.RGBAModel.Convert(src.At(x, y)).(color.RGBA)
+			dst.SetRGBA(x, y, f.apply(c))
+		}
+	}
+	return dst
+}
+
+// apply transforms a single pixel: grayscale, contrast, brightness, invert.
+func (f ImageFilter) apply(c color.RGBA) color.RGBA {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+
+	if f.Grayscale {
+		// ITU-R BT.601 luma
+		l := 0.299*r + 0.587*g + 0.114*b
+		r, g, b = l, l, l
+	}
+	if f.Contrast != 0 && f.Contrast != 1 {
+		r = (r-128)*f.Contrast + 128
+		g = (g-128)*f.Contrast + 128
+		b = (b-128)*f.Contrast + 128
+	}
+	if f.Brightness != 0 && f.Brightness != 1 {
+		r *= f.Brightness
+		g *= f.Brightness
+		b *= f.Brightness
+	}
+	if f.Invert {
+		r, g, b = 255-r, 255-g, 255-b
+	}
+
+	return color.RGBA{R: clampByte(r), G: clampByte(g), B: clampByte(b), A: c.A}
+}
+
+// clampByte rounds v and clamps it to [0, 255].
+func clampByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,40 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestApplyFilter(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	src.SetRGBA(0, 0, color.RGBA{200, 100, 50, 255})
+
+	cases := []struct {
+		name   string
+		filter ImageFilter
+		want   color.RGBA
+	}{
+		{"zero", ImageFilter{}, color.RGBA{200, 100, 50, 255}},
+		{"dim", ImageFilter{Brightness: 0.5}, color.RGBA{100, 50, 25, 255}},
+		{"brighten clamps", ImageFilter{Brightness: 2}, color.RGBA{255, 200, 100, 255}},
+		{"invert", ImageFilter{Invert: true}, color.RGBA{55, 155, 205, 255}},
+		{"grayscale", ImageFilter{Grayscale: true}, color.RGBA{124, 124, 124, 255}},
+		{"contrast", ImageFilter{Contrast: 2}, color.RGBA{255, 72, 0, 255}},
+		{"grayscale then invert", ImageFilter{Grayscale: true, Invert: true}, color.RGBA{131, 131, 131, 255}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := ApplyFilter(src, tc.filter)
+			got := color.RGBAModel.Convert(out.At(0, 0)).(color.RGBA)
+			if got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if got := src.RGBAAt(0, 0); got != (color.RGBA{200, 100, 50, 255}) {
+		t.Fatalf("source image was modified: %v", got)
+	}
+}
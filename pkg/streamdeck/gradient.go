@@ -0,0 +1,64 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Gradient is a two-colour key fill. Linear gradients run from From to To
+// along Angle, in degrees clockwise from left-to-right (90 is top-to-bottom).
+// Radial gradients run from From at the centre to To at the edges, with the
+// corners beyond them also To.
+type Gradient struct {
+	From, To color.RGBA
+	Radial   bool
+	Angle    float64
+}
+
+// GradientImage renders g into a size x size image.
+func GradientImage(g Gradient, size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	half := float64(size) / 2
+	sin, cos := math.Sincos(g.Angle * math.Pi / 180)
+	// Span of the image projected on the gradient direction, so both
+	// end colours are reached whatever the angle.
+	span := (math.Abs(cos) + math.Abs(sin)) * float64(size)
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-half, float64(y)+0.5-half
+			var t float64
+			if g.Radial {
+				t = math.Hypot(dx, dy) / half
+			} else {
+				t = 0.5 + (dx*cos+dy*sin)/span
+			}
+			t = math.Max(0, math.Min(1, t))
+
+			o := img.PixOffset(x, y)
+			img.Pix[o+0] = lerpByte(g.From.R, g.To.R, t)
+			img.Pix[o+1] = lerpByte(g.From.G, g.To.G, t)
+			img.Pix[o+2] = lerpByte(g.From.B, g.To.B, t)
+			img.Pix[o+3] = lerpByte(g.From.A, g.To.A, t)
+		}
+	}
+	return img
+}
+
+// lerpByte blends a towards b by t in [0, 1].
+func lerpByte(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+}
+
+// FillGradient fills a key with a gradient.
+func (d *Device) FillGradient(keyIndex int, g Gradient) error {
+	if keyIndex < 0 || keyIndex >= d.Model.Keys {
+		return fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
+	}
+	if d.Model.PixelSize == 0 {
+		return fmt.Errorf("device does not support images")
+	}
+	return d.SetImage(keyIndex, GradientImage(g, d.Model.PixelSize))
+}
@@ -0,0 +1,70 @@
+package streamdeck
+
+import (
+	"image/color"
+	"testing"
+)
+
+var (
+	gradRed  = color.RGBA{255, 0, 0, 255}
+	gradBlue = color.RGBA{0, 0, 255, 255}
+)
+
+// nearColor reports whether c is within 8 of want in every channel.
+func nearColor(c, want color.RGBA) bool {
+	d := func(a, b uint8) bool { return int(a)-int(b) < 8 && int(b)-int(a) < 8 }
+	return d(c.R, want.R) && d(c.G, want.G) && d(c.B, want.B) && d(c.A, want.A)
+}
+
+func TestGradientImageRadial(t *testing.T) {
+	img := GradientImage(Gradient{From: gradRed, To: gradBlue, Radial: true}, 72)
+	if c := img.RGBAAt(36, 36); !nearColor(c, gradRed) {
+		t.Errorf("centre = %v, want red", c)
+	}
+	for _, p := range [][2]int{{0, 0}, {71, 0}, {0, 71}, {71, 71}} {
+		if c := img.RGBAAt(p[0], p[1]); c != gradBlue {
+			t.Errorf("corner %v = %v, want blue", p, c)
+		}
+	}
+	// Halfway out is a blend of both.
+	if c := img.RGBAAt(54, 36); c.R < 64 || c.B < 64 {
+		t.Errorf("midway = %v, want a red/blue blend", c)
+	}
+}
+
+func TestGradientImageLinear(t *testing.T) {
+	tests := []struct {
+		angle      float64
+		start, end [2]int
+	}{
+		{0, [2]int{0, 36}, [2]int{71, 36}},   // left to right
+		{90, [2]int{36, 0}, [2]int{36, 71}},  // top to bottom
+		{180, [2]int{71, 36}, [2]int{0, 36}}, // right to left
+		{45, [2]int{0, 0}, [2]int{71, 71}},   // top-left to bottom-right
+	}
+	for _, tc := range tests {
+		img := GradientImage(Gradient{From: gradRed, To: gradBlue, Angle: tc.angle}, 72)
+		if c := img.RGBAAt(tc.start[0], tc.start[1]); !nearColor(c, gradRed) {
+			t.Errorf("angle %v: start %v = %v, want red", tc.angle, tc.start, c)
+		}
+		if c := img.RGBAAt(tc.end[0], tc.end[1]); !nearColor(c, gradBlue) {
+			t.Errorf("angle %v: end %v = %v, want blue", tc.angle, tc.end, c)
+		}
+		if c := img.RGBAAt(36, 36); c.R < 100 || c.B < 100 {
+			t.Errorf("angle %v: centre = %v, want an even blend", tc.angle, c)
+		}
+	}
+}
+
+func TestFillGradient(t *testing.T) {
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+	if err := d.FillGradient(1, Gradient{From: gradRed, To: gradBlue}); err != nil {
+		t.Fatal(err)
+	}
+	if d.KeyData(1) == nil {
+		t.Error("gradient not written")
+	}
+	if err := d.FillGradient(15, Gradient{}); err == nil {
+		t.Error("expected an error for an out-of-range key")
+	}
+}
@@ -0,0 +1,59 @@
+package streamdeck
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoHaptics is returned by Haptic on models without a vibration motor.
+var ErrNoHaptics = errors.New("device does not support haptics")
+
+// HapticPattern selects one of the vibration patterns built into the device.
+type HapticPattern byte
+
+// Built-in haptic patterns.
+const (
+	HapticShort  HapticPattern = 0x01
+	HapticLong   HapticPattern = 0x02
+	HapticDouble HapticPattern = 0x03
+)
+
+// hapticPatterns maps the names used by scripts and config to patterns.
+var hapticPatterns = map[string]HapticPattern{
+	"short":  HapticShort,
+	"long":   HapticLong,
+	"double": HapticDouble,
+}
+
+// ParseHapticPattern maps "short", "long" or "double" to a HapticPattern.
+// An empty string means HapticShort.
+func ParseHapticPattern(s string) (HapticPattern, error) {
+	if s == "" {
+		return HapticShort, nil
+	}
+	p, ok := hapticPatterns[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknown haptic pattern %q (want short, long or double)", s)
+	}
+	return p, nil
+}
+
+// Haptic plays a vibration pattern. It returns ErrNoHaptics without touching
+// the device when the model has no haptics (Model.HasHaptics).
+func (d *Device) Haptic(pattern HapticPattern) error {
+	if !d.Model.HasHaptics {
+		return ErrNoHaptics
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	data := make([]byte, 32)
+	data[0] = 0x03
+	data[1] = 0x0A
+	data[2] = byte(pattern)
+
+	_, err := d.sendFeatureReport(data)
+	return err
+}
@@ -0,0 +1,70 @@
+package streamdeck
+
+import (
+	"context"
+	"time"
+)
+
+// Identify blink timing. Variables rather than constants so tests can run the
+// sequence quickly.
+var (
+	identifyBlinks   = 8
+	identifyInterval = 250 * time.Millisecond
+)
+
+// Identify blinks the whole deck by toggling the backlight off and on for about
+// two seconds, then restores the brightness last set with SetBrightness. Use it
+// to find which physical device is which when several are connected.
+//
+// Identify returns immediately; the returned channel is closed once the blink
+// sequence has finished. Calling Identify again while a sequence is running
+// interrupts it and starts a fresh one.
+func (d *Device) Identify() <-chan struct{} {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	d.identifyMu.Lock()
+	if d.identifyCancel != nil {
+		d.identifyCancel()
+	}
+	prev := d.identifyDone
+	d.identifyCancel = cancel
+	d.identifyDone = done
+	d.identifyMu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		// Let an interrupted sequence exit before we start writing.
+		if prev != nil {
+			<-prev
+		}
+		d.runIdentify(ctx)
+	}()
+	return done
+}
+
+// runIdentify performs the blink sequence until it completes or ctx is
+// cancelled. An interrupted sequence leaves restoring brightness to whichever
+// call superseded it.
+func (d *Device) runIdentify(ctx context.Context) {
+	ticker := time.NewTicker(identifyInterval)
+	defer ticker.Stop()
+
+	for i := 0; i < identifyBlinks; i++ {
+		level := 100
+		if i%2 == 0 {
+			level = 0
+		}
+		if err := d.writeBrightness(level); err != nil {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+
+	d.writeBrightness(d.Brightness())
+}
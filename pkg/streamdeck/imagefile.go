@@ -0,0 +1,113 @@
+package streamdeck
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFileImages bounds the per-device cache used by SetKeyImageFromFile.
+// When it is full the cache is emptied and starts over.
+const maxFileImages = 64
+
+// fileImage is a cached, encoded key image loaded from a file. It is reused
+// while the file's size and modification time are unchanged.
+type fileImage struct {
+	modTime time.Time
+	size    int64
+	data    []byte
+}
+
+// fileImageCache holds the encoded images of SetKeyImageFromFile, by path.
+type fileImageCache struct {
+	mu     sync.Mutex
+	images map[string]fileImage
+}
+
+// SetKeyImageFromFile draws a PNG, JPEG or GIF file on a key. The decoded,
+// resized and encoded image is cached until the file changes, so calling it
+// repeatedly with the same file only costs a stat and a HID write.
+func (d *Device) SetKeyImageFromFile(keyIndex int, path string) error {
+	if keyIndex < 0 || keyIndex >= d.Model.Keys {
+		return fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
+	}
+	data, err := d.encodeImageFile(path)
+	if err != nil {
+		return err
+	}
+	return d.WriteKeyData(keyIndex, data)
+}
+
+// SetImageRaw draws pre-encoded image bytes on a key. Data that is already
+// in the model's format (JPEG or BMP) at its key size is written untouched,
+// so like KeyData it must be rotated 180 degrees for the hardware. Anything
+// else decodable (PNG, GIF, or a JPEG of another size), and everything on a
+// deck with a mounting rotation, is decoded and drawn like SetImage.
+func (d *Device) SetImageRaw(keyIndex int, data []byte) error {
+	if keyIndex < 0 || keyIndex >= d.Model.Keys {
+		return fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
+	}
+	if d.Model.PixelSize == 0 {
+		return fmt.Errorf("device does not support images")
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image data: %w", err)
+	}
+	if d.quarterTurns() == 0 && strings.EqualFold(format, d.Model.ImageFormat) &&
+		cfg.Width == d.Model.PixelSize && cfg.Height == d.Model.PixelSize {
+		return d.WriteKeyData(keyIndex, data)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image data: %w", err)
+	}
+	return d.SetImage(keyIndex, img)
+}
+
+// encodeImageFile returns the encoded key image for a file, from the cache
+// when the file is unchanged.
+func (d *Device) encodeImageFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+
+	c := &d.fileImages
+	c.mu.Lock()
+	cached, ok := c.images[path]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.data, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	img, _, err := image.Decode(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+	data, err := d.EncodeKeyImage(img)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.images == nil || len(c.images) >= maxFileImages {
+		c.images = make(map[string]fileImage)
+	}
+	c.images[path] = fileImage{modTime: info.ModTime(), size: info.Size(), data: data}
+	c.mu.Unlock()
+	return data, nil
+}
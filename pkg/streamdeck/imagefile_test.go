@@ -0,0 +1,103 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestPNG writes a solid 16x16 PNG.
+func writeTestPNG(t *testing.T, path string, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	draw.Draw(img, img.Bounds(), &image.Uniform{c}, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSetKeyImageFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icon.png")
+	writeTestPNG(t, path, color.RGBA{255, 0, 0, 255})
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+
+	if err := d.SetKeyImageFromFile(2, path); err != nil {
+		t.Fatal(err)
+	}
+	red := d.KeyData(2)
+	if red == nil {
+		t.Fatal("key image not written")
+	}
+	if len(d.fileImages.images) != 1 {
+		t.Errorf("cache has %d entries, want 1", len(d.fileImages.images))
+	}
+	if err := d.SetKeyImageFromFile(3, path); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d.KeyData(3), red) {
+		t.Error("same file drew a different image")
+	}
+
+	// A changed file is decoded again.
+	writeTestPNG(t, path, color.RGBA{0, 0, 255, 255})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetKeyImageFromFile(2, path); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(d.KeyData(2), red) {
+		t.Error("changed file still drew the cached image")
+	}
+
+	if err := d.SetKeyImageFromFile(2, filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("missing file did not fail")
+	}
+	if err := d.SetKeyImageFromFile(99, path); err == nil {
+		t.Error("out-of-range key did not fail")
+	}
+}
+
+func TestSetImageRaw(t *testing.T) {
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+
+	// Native format and size: written untouched
+	var native bytes.Buffer
+	if err := jpeg.Encode(&native, image.NewRGBA(image.Rect(0, 0, 72, 72)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageRaw(1, native.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(d.KeyData(1), native.Bytes()) {
+		t.Error("72x72 JPEG was not written as-is")
+	}
+
+	// Wrong format: decoded and re-encoded as JPEG
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetImageRaw(2, small.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if _, format, err := image.DecodeConfig(bytes.NewReader(d.KeyData(2))); err != nil || format != "jpeg" {
+		t.Errorf("PNG was written as %q (%v), want re-encoded jpeg", format, err)
+	}
+
+	if err := d.SetImageRaw(3, []byte("not an image")); err == nil {
+		t.Error("garbage data did not fail")
+	}
+}
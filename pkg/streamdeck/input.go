@@ -0,0 +1,175 @@
+package streamdeck
+
+// input.go – input events beyond key presses, from the dials and LCD touch
+// strip of the Stream Deck + (see Model.Dials and Model.TouchStrip).
+
+import "context"
+
+// DialEvent is a turn or push of one dial.
+type DialEvent struct {
+	Dial    int  // Zero-based, left to right
+	Delta   int  // Detents turned, positive clockwise; 0 for a push or release
+	Pressed bool // The dial is pushed in
+}
+
+// TouchGesture names the kind of touch on the strip.
+type TouchGesture string
+
+const (
+	TouchTap   TouchGesture = "tap"   // Short touch
+	TouchLong  TouchGesture = "long"  // Touch held
+	TouchSwipe TouchGesture = "swipe" // Drag from (X, Y) to (EndX, EndY)
+)
+
+// TouchEvent is a touch on the LCD strip, in strip pixels.
+type TouchEvent struct {
+	Gesture    TouchGesture
+	X, Y       int
+	EndX, EndY int // Same as X, Y except for swipes
+}
+
+// Stream Deck + input report types, in the byte after the report ID. Other
+// models only send key reports.
+const (
+	reportKeys  = 0x00
+	reportTouch = 0x02
+	reportDial  = 0x03
+)
+
+// Dial report kinds, in report[4].
+const (
+	dialPush = 0x00 // report[5:] holds each dial's push state
+	dialTurn = 0x01 // report[5:] holds each dial's signed detent count
+)
+
+// touchGestures maps the touch report kind in report[4] to a gesture.
+var touchGestures = map[byte]TouchGesture{1: TouchTap, 2: TouchLong, 3: TouchSwipe}
+
+// inputReport is a decoded input report: the key states, or for a dial or
+// touch report (Stream Deck + only) the raw dial report or the touch event.
+type inputReport struct {
+	keys  []bool
+	dial  []byte
+	touch *TouchEvent
+}
+
+// parseInputReport decodes an input report by its type.
+func (d *Device) parseInputReport(report []byte) inputReport {
+	if (d.Model.Dials == 0 && !d.Model.TouchStrip) || len(report) < 5 {
+		return inputReport{keys: d.parseKeyReport(report)}
+	}
+	switch report[1] {
+	case reportDial:
+		// Copied: the blocking reader reuses its buffer
+		return inputReport{dial: append([]byte(nil), report...)}
+	case reportTouch:
+		return inputReport{touch: parseTouchReport(report)}
+	}
+	return inputReport{keys: d.parseKeyReport(report)}
+}
+
+// parseTouchReport decodes a touch report. Coordinates are little-endian
+// 16-bit values: X, Y at report[6:10] and, for swipes, the end point at
+// report[10:14]. It returns nil for an unknown gesture.
+func parseTouchReport(report []byte) *TouchEvent {
+	gesture, ok := touchGestures[report[4]]
+	if !ok {
+		return nil
+	}
+	u16 := func(i int) int {
+		if i+1 >= len(report) {
+			return 0
+		}
+		return int(report[i]) | int(report[i+1])<<8
+	}
+	ev := &TouchEvent{Gesture: gesture, X: u16(6), Y: u16(8)}
+	ev.EndX, ev.EndY = ev.X, ev.Y
+	if gesture == TouchSwipe {
+		ev.EndX, ev.EndY = u16(10), u16(12)
+	}
+	return ev
+}
+
+// dialEvents decodes a dial report into events. pressed holds each dial's
+// push state and is updated: a push report yields events only for the dials
+// whose state changed, and turns carry the current push state.
+func dialEvents(report []byte, pressed []bool) []DialEvent {
+	var events []DialEvent
+	for i := range pressed {
+		if 5+i >= len(report) {
+			break
+		}
+		v := report[5+i]
+		switch report[4] {
+		case dialPush:
+			if now := v != 0; now != pressed[i] {
+				pressed[i] = now
+				events = append(events, DialEvent{Dial: i, Pressed: now})
+			}
+		case dialTurn:
+			if v != 0 {
+				events = append(events, DialEvent{Dial: i, Delta: int(int8(v)), Pressed: pressed[i]})
+			}
+		}
+	}
+	return events
+}
+
+// SetInputEvents makes ListenKeys send dial and touch strip events (Stream
+// Deck + only) to dials and touches; a nil channel drops those events.
+// Call before ListenKeys. The channels are not closed.
+func (d *Device) SetInputEvents(dials chan<- DialEvent, touches chan<- TouchEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dialEvents = dials
+	d.touchEvents = touches
+}
+
+// inputEvents returns the channels set with SetInputEvents.
+func (d *Device) inputEvents() (chan<- DialEvent, chan<- TouchEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dialEvents, d.touchEvents
+}
+
+// listenState is what a ListenKeys goroutine remembers between reports.
+type listenState struct {
+	keys    []bool // Key states after the last key report
+	dials   []bool // Push state of each dial
+	dialCh  chan<- DialEvent
+	touchCh chan<- TouchEvent
+}
+
+func (d *Device) newListenState() *listenState {
+	st := &listenState{keys: make([]bool, d.Model.Keys), dials: make([]bool, d.Model.Dials)}
+	st.dialCh, st.touchCh = d.inputEvents()
+	return st
+}
+
+// sendInput sends the events of one decoded report: key changes to events,
+// dial and touch events to the SetInputEvents channels. It returns false if
+// ctx was cancelled.
+func sendInput(ctx context.Context, st *listenState, in inputReport, events chan<- KeyEvent) bool {
+	switch {
+	case in.keys != nil:
+		return sendKeyChanges(ctx, st.keys, in.keys, events)
+	case in.dial != nil:
+		for _, ev := range dialEvents(in.dial, st.dials) {
+			if st.dialCh == nil {
+				continue
+			}
+			select {
+			case st.dialCh <- ev:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	case in.touch != nil && st.touchCh != nil:
+		select {
+		case st.touchCh <- *in.touch:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
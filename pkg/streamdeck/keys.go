@@ -5,38 +5,105 @@ import (
 	"time"
 )
 
+// Default key polling (see SetKeyPolling).
+const (
+	DefaultPollInterval = 10 * time.Millisecond
+	DefaultReadTimeout  = 100 * time.Millisecond
+)
+
+// blockingReadTimeout bounds each read in blocking mode, so the reader
+// notices a cancelled context or a closed device. A report ends the wait at
+// once, so it adds no latency.
+const blockingReadTimeout = 500 * time.Millisecond
+
+// SetKeyPolling sets how ListenKeys and WaitForKeyPress poll for key events.
+// Call it before they start.
+//
+// readTimeout is how long each read waits for a report. A report ends the
+// wait at once, so it does not add latency, but the HID lock is held while
+// waiting: image writes queued behind a read can stall for up to readTimeout.
+// Values <= 0 use DefaultReadTimeout.
+//
+// interval is the pause between reads. Every pause adds up to interval of
+// latency to a key press; 0 reads back to back, which gives the lowest
+// latency at the cost of a thread that is always waiting in a read (cheap:
+// the read sleeps in the kernel) and more lock contention with writes.
+// Negative values use DefaultPollInterval.
+func (d *Device) SetKeyPolling(interval, readTimeout time.Duration) {
+	if interval < 0 {
+		interval = DefaultPollInterval
+	}
+	if readTimeout <= 0 {
+		readTimeout = DefaultReadTimeout
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pollInterval = interval
+	d.readTimeout = readTimeout
+	d.pollSet = true
+}
+
+// keyPolling returns the polling settings, falling back to the defaults.
+func (d *Device) keyPolling() (interval, readTimeout time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.pollSet {
+		return DefaultPollInterval, DefaultReadTimeout
+	}
+	return d.pollInterval, d.readTimeout
+}
+
 // ReadKeys reads the current state of all keys.
-// Returns a slice of booleans where true means the key is pressed.
+// Returns a slice of booleans where true means the key is pressed, or nil
+// when the report read was a dial or touch report (Stream Deck +).
 func (d *Device) ReadKeys() ([]bool, error) {
+	in, err := d.readInput()
+	return in.keys, err
+}
+
+// readInput reads and decodes one input report. A read that returns no
+// data counts as every key released.
+func (d *Device) readInput() (inputReport, error) {
+	_, timeout := d.keyPolling()
+
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	if d.closed {
+		return inputReport{}, errClosed
+	}
 
 	// Read buffer size depends on device, use generous buffer
 	buf := make([]byte, 512)
-	n, err := d.hid.ReadWithTimeout(buf, 100*time.Millisecond)
+	n, err := d.hid.ReadWithTimeout(buf, timeout)
 	if err != nil {
-		return nil, err
+		return inputReport{}, err
 	}
 	if n == 0 {
 		// No data available, return current state as all unpressed
-		return make([]bool, d.Model.Keys), nil
+		return inputReport{keys: make([]bool, d.Model.Keys)}, nil
 	}
+	d.traceReport("input", buf[:n])
 
+	return d.parseInputReport(buf[:n]), nil
+}
+
+// parseKeyReport decodes an input report into key states.
+func (d *Device) parseKeyReport(report []byte) []bool {
 	// Parse key states - format depends on device generation
 	// For MK.2/V2: first byte is report ID (0x01), then key states starting at offset 4
 	keys := make([]bool, d.Model.Keys)
 	keyOffset := 4 // MK.2/V2 offset
-	for i := 0; i < d.Model.Keys && keyOffset+i < n; i++ {
-		keys[i] = buf[keyOffset+i] != 0
+	for i := 0; i < d.Model.Keys && keyOffset+i < len(report); i++ {
+		keys[d.logicalKey(i)] = report[keyOffset+i] != 0
 	}
-
-	return keys, nil
+	return keys
 }
 
 // WaitForKeyPress blocks until a key is pressed or the context is cancelled.
 // Returns the index of the pressed key.
 func (d *Device) WaitForKeyPress(ctx context.Context) (int, error) {
 	prevState := make([]bool, d.Model.Keys)
+	interval, _ := d.keyPolling()
 
 	for {
 		select {
@@ -58,16 +125,28 @@ func (d *Device) WaitForKeyPress(ctx context.Context) (int, error) {
 		}
 
 		copy(prevState, keys)
-		time.Sleep(10 * time.Millisecond)
+		if interval > 0 {
+			time.Sleep(interval)
+		}
 	}
 }
 
 // ListenKeys starts listening for key events and sends them to the provided channel.
-// Closes the channel when context is cancelled.
+// Closes the channel when context is cancelled. Dial and touch events go to
+// the channels set with SetInputEvents.
+//
+// With SetBlockingReads(true) a goroutine waits in long reads and the
+// listener sleeps until input arrives; the channel is also closed when the
+// device is. Otherwise the device is polled (see SetKeyPolling).
 func (d *Device) ListenKeys(ctx context.Context, events chan<- KeyEvent) {
+	if d.blockingEnabled() {
+		go d.listenBlocking(ctx, events)
+		return
+	}
 	go func() {
 		defer close(events)
-		prevState := make([]bool, d.Model.Keys)
+		st := d.newListenState()
+		interval, _ := d.keyPolling()
 
 		for {
 			select {
@@ -76,48 +155,141 @@ func (d *Device) ListenKeys(ctx context.Context, events chan<- KeyEvent) {
 			default:
 			}
 
-			keys, err := d.ReadKeys()
+			in, err := d.readInput()
 			if err != nil {
+				// Don't spin on a failing device
+				time.Sleep(max(interval, DefaultPollInterval))
 				continue
 			}
 
-			// Detect state changes
-			for i, pressed := range keys {
-				if pressed != prevState[i] {
-					select {
-					case events <- KeyEvent{Key: i, Pressed: pressed}:
-					case <-ctx.Done():
-						return
-					}
-				}
+			if !sendInput(ctx, st, in, events) {
+				return
+			}
+			if interval > 0 {
+				time.Sleep(interval)
 			}
+		}
+	}()
+}
+
+// SetBlockingReads selects blocking reads for ListenKeys instead of polling.
+// Call before ListenKeys.
+func (d *Device) SetBlockingReads(on bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.blockingReads = on
+}
+
+// blockingEnabled reports whether SetBlockingReads(true) was called.
+func (d *Device) blockingEnabled() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.blockingReads
+}
 
-			copy(prevState, keys)
-			time.Sleep(10 * time.Millisecond)
+// listenBlocking is ListenKeys in blocking mode. Reads hold readMu rather
+// than mu, so image writes are not held up while no key is pressed, and
+// last at most blockingReadTimeout. The reading goroutine ends within that
+// time of ctx being cancelled or the device being closed; Close waits for
+// the read in progress.
+func (d *Device) listenBlocking(ctx context.Context, events chan<- KeyEvent) {
+	defer close(events)
+
+	reports := make(chan inputReport)
+	go func() {
+		defer close(reports)
+		buf := make([]byte, 512)
+		for {
+			if d.closing.Load() {
+				return
+			}
+			d.readMu.Lock()
+			if d.closed {
+				d.readMu.Unlock()
+				return
+			}
+			n, err := d.hid.ReadWithTimeout(buf, blockingReadTimeout)
+			d.readMu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				// Don't spin on a failing device
+				time.Sleep(DefaultPollInterval)
+				continue
+			}
+			if n == 0 {
+				continue
+			}
+			d.traceReport("input", buf[:n])
+			select {
+			case reports <- d.parseInputReport(buf[:n]):
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
+
+	st := d.newListenState()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case in, ok := <-reports:
+			if !ok {
+				return // device closed
+			}
+			if !sendInput(ctx, st, in, events) {
+				return
+			}
+		}
+	}
+}
+
+// sendKeyChanges sends an event for every key whose state differs from prev,
+// then updates prev. It returns false if ctx was cancelled.
+func sendKeyChanges(ctx context.Context, prev, keys []bool, events chan<- KeyEvent) bool {
+	for i, pressed := range keys {
+		if pressed != prev[i] {
+			select {
+			case events <- KeyEvent{Key: i, Pressed: pressed}:
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	copy(prev, keys)
+	return true
 }
 
 // KeyToCoord converts a key index to (col, row) coordinates.
 func (d *Device) KeyToCoord(keyIndex int) (col, row int) {
-	if d.Model.Cols == 0 {
+	cols := d.Cols()
+	if cols == 0 {
 		return 0, 0
 	}
-	return keyIndex % d.Model.Cols, keyIndex / d.Model.Cols
+	return keyIndex % cols, keyIndex / cols
 }
 
 // CoordToKey converts (col, row) coordinates to a key index.
 func (d *Device) CoordToKey(col, row int) int {
-	return row*d.Model.Cols + col
+	return row*d.Cols() + col
 }
 
-// Cols returns the number of columns on the device.
+// Cols returns the number of columns as the deck is mounted (see
+// SetRotation).
 func (d *Device) Cols() int {
+	if d.quarterTurns()%2 == 1 {
+		return d.Model.Rows
+	}
 	return d.Model.Cols
 }
 
-// Rows returns the number of rows on the device.
+// Rows returns the number of rows as the deck is mounted (see SetRotation).
 func (d *Device) Rows() int {
+	if d.quarterTurns()%2 == 1 {
+		return d.Model.Cols
+	}
 	return d.Model.Rows
 }
 
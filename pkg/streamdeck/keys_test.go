@@ -0,0 +1,311 @@
+package streamdeck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reportHID delivers queued input reports to ReadWithTimeout, like a deck
+// that reports each key change once.
+type reportHID struct {
+	*fakeHID
+	reports chan []byte
+}
+
+func newReportHID() *reportHID {
+	return &reportHID{fakeHID: &fakeHID{}, reports: make(chan []byte, 1)}
+}
+
+func (h *reportHID) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	select {
+	case r := <-h.reports:
+		return copy(p, r), nil
+	case <-time.After(timeout):
+		return 0, nil
+	}
+}
+
+// keyReport builds an MK.2 input report with key pressed or released.
+func keyReport(key int, pressed bool) []byte {
+	r := make([]byte, 4+15)
+	r[0] = 0x01
+	if pressed {
+		r[4+key] = 1
+	}
+	return r
+}
+
+func TestSetKeyPolling(t *testing.T) {
+	d := &Device{Model: Models[0x0080]}
+	if iv, to := d.keyPolling(); iv != DefaultPollInterval || to != DefaultReadTimeout {
+		t.Errorf("defaults = %v, %v", iv, to)
+	}
+	d.SetKeyPolling(0, 20*time.Millisecond)
+	if iv, to := d.keyPolling(); iv != 0 || to != 20*time.Millisecond {
+		t.Errorf("after SetKeyPolling(0, 20ms) = %v, %v", iv, to)
+	}
+	d.SetKeyPolling(-1, 0)
+	if iv, to := d.keyPolling(); iv != DefaultPollInterval || to != DefaultReadTimeout {
+		t.Errorf("out-of-range values = %v, %v, want the defaults", iv, to)
+	}
+}
+
+func TestListenKeysDeliversPress(t *testing.T) {
+	h := newReportHID()
+	d := &Device{hid: h, Model: Models[0x0080]}
+	d.SetKeyPolling(0, 5*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan KeyEvent, 4)
+	d.ListenKeys(ctx, events)
+
+	h.reports <- keyReport(7, true)
+	select {
+	case ev := <-events:
+		if ev.Key != 7 || !ev.Pressed {
+			t.Errorf("event = %+v, want key 7 pressed", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no key event")
+	}
+}
+
+func TestListenKeysDialAndTouch(t *testing.T) {
+	for _, blocking := range []bool{false, true} {
+		h := newReportHID()
+		d := &Device{hid: h, Model: Models[0x009a]}
+		d.SetKeyPolling(0, 5*time.Millisecond)
+		d.SetBlockingReads(blocking)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		events := make(chan KeyEvent, 4)
+		dials := make(chan DialEvent, 4)
+		touches := make(chan TouchEvent, 4)
+		d.SetInputEvents(dials, touches)
+		d.ListenKeys(ctx, events)
+
+		// Stream Deck + reports: type in byte 1, kind in byte 4
+		h.reports <- []byte{0x01, reportDial, 0x05, 0x00, dialTurn, 0, 0xfe, 0, 0}
+		h.reports <- []byte{0x01, reportDial, 0x05, 0x00, dialPush, 1, 0, 0, 0}
+		h.reports <- []byte{0x01, reportDial, 0x05, 0x00, dialPush, 0, 0, 0, 0}
+		h.reports <- []byte{0x01, reportTouch, 0x0e, 0x00, 1, 0, 0xfa, 0x00, 0x32, 0x00, 0, 0, 0, 0}
+		h.reports <- []byte{0x01, reportTouch, 0x0e, 0x00, 3, 0, 0x64, 0x00, 0x0a, 0x00, 0x58, 0x02, 0x14, 0x00}
+		h.reports <- []byte{0x01, reportKeys, 0x08, 0x00, 0, 0, 1, 0, 0, 0, 0, 0}
+
+		for _, want := range []DialEvent{
+			{Dial: 1, Delta: -2},
+			{Dial: 0, Pressed: true},
+			{Dial: 0, Pressed: false},
+		} {
+			select {
+			case ev := <-dials:
+				if ev != want {
+					t.Errorf("blocking=%v: dial event = %+v, want %+v", blocking, ev, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("blocking=%v: no dial event", blocking)
+			}
+		}
+		for _, want := range []TouchEvent{
+			{Gesture: TouchTap, X: 250, Y: 50, EndX: 250, EndY: 50},
+			{Gesture: TouchSwipe, X: 100, Y: 10, EndX: 600, EndY: 20},
+		} {
+			select {
+			case ev := <-touches:
+				if ev != want {
+					t.Errorf("blocking=%v: touch event = %+v, want %+v", blocking, ev, want)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("blocking=%v: no touch event", blocking)
+			}
+		}
+		// Dial and touch reports are not read as key changes
+		select {
+		case ev := <-events:
+			if ev.Key != 2 || !ev.Pressed {
+				t.Errorf("blocking=%v: key event = %+v, want key 2 pressed", blocking, ev)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("blocking=%v: no key event", blocking)
+		}
+		cancel()
+	}
+}
+
+// BenchmarkKeyEventLatency measures the time from a key report arriving to
+// ListenKeys delivering the event, for the default 10ms poll interval,
+// back-to-back polling and blocking reads.
+func BenchmarkKeyEventLatency(b *testing.B) {
+	for _, bc := range []struct {
+		name     string
+		interval time.Duration
+		blocking bool
+	}{
+		{"poll-10ms", DefaultPollInterval, false},
+		{"back-to-back", 0, false},
+		{"blocking", DefaultPollInterval, true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			h := &blockingHID{reportHID: newReportHID()}
+			d := &Device{hid: h, Model: Models[0x0080]}
+			d.SetKeyPolling(bc.interval, DefaultReadTimeout)
+			d.SetBlockingReads(bc.blocking)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events := make(chan KeyEvent, 16)
+			d.ListenKeys(ctx, events)
+
+			// waitFor drains events until key 0 reaches the given state.
+			waitFor := func(pressed bool) {
+				for ev := range events {
+					if ev.Key == 0 && ev.Pressed == pressed {
+						return
+					}
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.reports <- keyReport(0, true)
+				waitFor(true)
+
+				b.StopTimer()
+				h.reports <- keyReport(0, false)
+				waitFor(false)
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// blockingHID is a reportHID that counts reads and records whether Close
+// was called while a read was in progress. Each read is announced on
+// started, if set.
+type blockingHID struct {
+	*reportHID
+	mu            sync.Mutex
+	readCount     int
+	reading       bool
+	closedMidRead bool
+	started       chan struct{}
+}
+
+func (h *blockingHID) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	h.mu.Lock()
+	h.readCount++
+	h.reading = true
+	h.mu.Unlock()
+	if h.started != nil {
+		select {
+		case h.started <- struct{}{}:
+		default:
+		}
+	}
+	n, err := h.reportHID.ReadWithTimeout(p, timeout)
+	h.mu.Lock()
+	h.reading = false
+	h.mu.Unlock()
+	return n, err
+}
+
+func (h *blockingHID) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reading {
+		h.closedMidRead = true
+	}
+	return nil
+}
+
+func (h *blockingHID) reads() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.readCount
+}
+
+func TestBlockingReadsAvoidIdleWakeups(t *testing.T) {
+	idle := func(blocking bool) (*blockingHID, chan KeyEvent, context.CancelFunc) {
+		h := &blockingHID{reportHID: newReportHID()}
+		d := &Device{hid: h, Model: Models[0x0080]}
+		d.SetKeyPolling(DefaultPollInterval, 5*time.Millisecond)
+		d.SetBlockingReads(blocking)
+		ctx, cancel := context.WithCancel(context.Background())
+		events := make(chan KeyEvent, 4)
+		d.ListenKeys(ctx, events)
+		time.Sleep(150 * time.Millisecond)
+		return h, events, cancel
+	}
+
+	polled, _, cancel := idle(false)
+	cancel()
+	if n := polled.reads(); n < 5 {
+		t.Errorf("polling made %d reads in 150ms; expected regular wakeups", n)
+	}
+
+	blocked, events, cancel := idle(true)
+	defer cancel()
+	if n := blocked.reads(); n != 1 {
+		t.Errorf("blocking mode made %d reads while idle, want 1", n)
+	}
+
+	blocked.reports <- keyReport(3, true)
+	select {
+	case ev := <-events:
+		if ev.Key != 3 || !ev.Pressed {
+			t.Errorf("event = %+v, want key 3 pressed", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no key event in blocking mode")
+	}
+}
+
+func TestCloseWaitsForBlockingRead(t *testing.T) {
+	h := &blockingHID{reportHID: newReportHID(), started: make(chan struct{}, 1)}
+	d := &Device{hid: h, Model: Models[0x0080]}
+	d.SetBlockingReads(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan KeyEvent, 4)
+	d.ListenKeys(ctx, events)
+
+	select {
+	case <-h.started:
+	case <-time.After(time.Second):
+		t.Fatal("no read started")
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h.mu.Lock()
+	midRead := h.closedMidRead
+	h.mu.Unlock()
+	if midRead {
+		t.Fatal("Close closed the handle while a read was in progress")
+	}
+
+	// The listener stops with the device, without waiting for ctx or input
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("unexpected key event")
+		}
+	case <-time.After(2 * blockingReadTimeout):
+		t.Fatal("listener still running after Close")
+	}
+	if n := h.reads(); n != 1 {
+		t.Errorf("%d reads, want none after Close", n)
+	}
+
+	if _, err := d.ReadKeys(); !errors.Is(err, ErrDeviceGone) {
+		t.Errorf("ReadKeys after Close: got %v, want ErrDeviceGone", err)
+	}
+	if err := d.WriteKeyData(0, []byte{1}); !errors.Is(err, ErrDeviceGone) {
+		t.Errorf("WriteKeyData after Close: got %v, want ErrDeviceGone", err)
+	}
+}
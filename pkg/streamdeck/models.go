@@ -1,6 +1,12 @@
 // Package streamdeck provides a Go library for interfacing with Elgato Stream Deck devices.
 package streamdeck
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 // VendorID is the USB vendor ID for Elgato devices.
 const VendorID = 0x0fd9
 
@@ -13,6 +19,10 @@ type Model struct {
 	Keys        int
 	PixelSize   int
 	ImageFormat string // "JPEG" or "BMP"
+	HasHaptics  bool   // has a vibration motor (see Device.Haptic)
+	Dials       int    // rotary encoders below the keys
+	TouchStrip  bool   // has an LCD touch strip
+	StripWidth  int    // touch strip width in pixels
 }
 
 // Known Stream Deck models indexed by their USB Product ID.
@@ -25,11 +35,120 @@ var Models = map[uint16]Model{
 	0x0084: {Name: "Stream Deck XL V2", ProductID: 0x0084, Cols: 8, Rows: 4, Keys: 32, PixelSize: 96, ImageFormat: "JPEG"},
 	0x0086: {Name: "Stream Deck Pedal", ProductID: 0x0086, Cols: 3, Rows: 1, Keys: 3, PixelSize: 0, ImageFormat: ""},
 	0x0090: {Name: "Stream Deck Neo", ProductID: 0x0090, Cols: 4, Rows: 2, Keys: 8, PixelSize: 96, ImageFormat: "JPEG"},
-	0x009a: {Name: "Stream Deck +", ProductID: 0x009a, Cols: 4, Rows: 2, Keys: 8, PixelSize: 120, ImageFormat: "JPEG"},
+	0x009a: {Name: "Stream Deck +", ProductID: 0x009a, Cols: 4, Rows: 2, Keys: 8, PixelSize: 120, ImageFormat: "JPEG", Dials: 4, TouchStrip: true, StripWidth: 800},
+}
+
+// HasDisplay reports whether the keys have screens. The Pedal does not.
+func (m Model) HasDisplay() bool {
+	return m.PixelSize > 0
+}
+
+// AllKeys returns every key index, in order.
+func (m Model) AllKeys() []int {
+	keys := make([]int, m.Keys)
+	for i := range keys {
+		keys[i] = i
+	}
+	return keys
+}
+
+// RowKeys returns the key indices of a zero-based row, left to right, or nil
+// if the row does not exist.
+func (m Model) RowKeys(row int) []int {
+	if row < 0 || row >= m.Rows {
+		return nil
+	}
+	keys := make([]int, m.Cols)
+	for c := range keys {
+		keys[c] = row*m.Cols + c
+	}
+	return keys
+}
+
+// ColKeys returns the key indices of a zero-based column, top to bottom, or
+// nil if the column does not exist.
+func (m Model) ColKeys(col int) []int {
+	if col < 0 || col >= m.Cols {
+		return nil
+	}
+	keys := make([]int, m.Rows)
+	for r := range keys {
+		keys[r] = r*m.Cols + col
+	}
+	return keys
+}
+
+// FindModel returns the known model with the given name, ignoring case.
+func FindModel(name string) (Model, bool) {
+	for _, m := range Models {
+		if name != "" && strings.EqualFold(m.Name, name) {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// ListModelNames returns the names of the known models, sorted.
+func ListModelNames() []string {
+	names := make([]string, 0, len(Models))
+	for _, m := range Models {
+		names = append(names, m.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GuessModel picks the known model an unknown device most likely matches
+// from its HID product string: the model with the longest name found in
+// it, the newest (highest product ID) on a tie. New hardware revisions
+// usually keep their predecessor's name, layout and image format.
+func GuessModel(product string) (Model, bool) {
+	product = strings.ToLower(product)
+	var best Model
+	for _, m := range Models {
+		if !strings.Contains(product, strings.ToLower(m.Name)) {
+			continue
+		}
+		if len(m.Name) > len(best.Name) || (len(m.Name) == len(best.Name) && m.ProductID > best.ProductID) {
+			best = m
+		}
+	}
+	return best, best.Name != ""
+}
+
+// ResolveModel returns the model of a device: the known model for its
+// product ID, else a guess from its product string (see GuessModel), else
+// a display-less placeholder. Guessed and placeholder models keep the
+// device's own product ID.
+func ResolveModel(productID uint16, product string) Model {
+	if m, known := LookupModel(productID); known {
+		return m
+	}
+	if m, ok := GuessModel(product); ok {
+		return m.asProduct(productID, "guessed")
+	}
+	return Model{
+		Name:      fmt.Sprintf("Unknown Stream Deck (PID: 0x%04X)", productID),
+		ProductID: productID,
+	}
+}
+
+// Forced returns m for a device with another product ID whose model was
+// set by hand, e.g. from configuration. See Device.UseModel.
+func (m Model) Forced(productID uint16) Model {
+	return m.asProduct(productID, "forced")
+}
+
+// asProduct returns m for a device with another product ID, noting in the
+// name how the model was chosen.
+func (m Model) asProduct(productID uint16, how string) Model {
+	m.Name = fmt.Sprintf("%s (%s for PID 0x%04X)", m.Name, how, productID)
+	m.ProductID = productID
+	return m
 }
 
-// LookupModel returns the Model for a given product ID.
-// If the product ID is unknown, it returns a placeholder Model with basic info.
+// LookupModel returns the Model for a given product ID, and false if the
+// product ID is unknown (see ResolveModel for a fallback).
 func LookupModel(productID uint16) (Model, bool) {
 	model, known := Models[productID]
 	return model, known
@@ -0,0 +1,153 @@
+package streamdeck
+
+import (
+	"image/color"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestModelKeyGroups(t *testing.T) {
+	m := Models[0x0080] // 5x3
+
+	if got := m.AllKeys(); len(got) != 15 || got[0] != 0 || got[14] != 14 {
+		t.Fatalf("AllKeys = %v", got)
+	}
+	if got, want := m.RowKeys(1), []int{5, 6, 7, 8, 9}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("RowKeys(1) = %v, want %v", got, want)
+	}
+	if got, want := m.ColKeys(4), []int{4, 9, 14}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("ColKeys(4) = %v, want %v", got, want)
+	}
+	if m.RowKeys(3) != nil || m.ColKeys(-1) != nil {
+		t.Fatal("out-of-range row/column should return nil")
+	}
+}
+
+// writtenKeys returns how many complete images each key received.
+func writtenKeys(fake *fakeHID) map[int]int {
+	got := make(map[int]int)
+	for _, w := range fake.writes {
+		if w[3] == 0x01 {
+			got[int(w[2])]++
+		}
+	}
+	return got
+}
+
+func TestSetKeysColorBatches(t *testing.T) {
+	m := Models[0x0080]
+	tests := []struct {
+		name string
+		keys []int
+	}{
+		{"all", m.AllKeys()},
+		{"row", m.RowKeys(2)},
+		{"col", m.ColKeys(0)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeHID{}
+			d := &Device{hid: fake, Model: m}
+			if err := d.SetKeysColor(tc.keys, color.RGBA{0, 100, 255, 255}); err != nil {
+				t.Fatalf("SetKeysColor: %v", err)
+			}
+			got := writtenKeys(fake)
+			if len(got) != len(tc.keys) {
+				t.Fatalf("wrote %d keys, want %d", len(got), len(tc.keys))
+			}
+			for _, k := range tc.keys {
+				if got[k] != 1 {
+					t.Fatalf("key %d written %d times, want 1", k, got[k])
+				}
+			}
+			first := d.KeyData(tc.keys[0])
+			for _, k := range tc.keys[1:] {
+				if &d.KeyData(k)[0] != &first[0] {
+					t.Fatalf("key %d was encoded separately", k)
+				}
+			}
+		})
+	}
+}
+
+func TestSetKeysColorRejectsBadKey(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: Models[0x0080]}
+	if err := d.SetKeysColor([]int{0, 15}, color.White); err == nil {
+		t.Fatal("expected an error for key 15")
+	}
+	if len(fake.writes) != 0 {
+		t.Fatalf("%d reports written before validation failed", len(fake.writes))
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	if m := ResolveModel(0x0080, "anything"); m.Name != "Stream Deck MK.2" {
+		t.Errorf("known PID resolved to %q", m.Name)
+	}
+
+	// An unknown revision named like a known model takes its parameters.
+	m := ResolveModel(0x00a5, "Stream Deck XL")
+	if m.ProductID != 0x00a5 || m.Cols != 8 || m.Rows != 4 || m.PixelSize != 96 || m.ImageFormat != "JPEG" {
+		t.Errorf("guessed model = %+v, want XL parameters with PID 0x00a5", m)
+	}
+	// The longest matching name wins over shorter ones it contains.
+	if m, _ := GuessModel("Stream Deck Original V2 Rev B"); m.ProductID != 0x006d {
+		t.Errorf("guess for Original V2 = %q", m.Name)
+	}
+
+	m = ResolveModel(0x00a6, "Mystery Controller")
+	if m.HasDisplay() || m.ProductID != 0x00a6 {
+		t.Errorf("unguessable device = %+v, want a display-less placeholder", m)
+	}
+}
+
+func TestUseModelOnUnknownDevice(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: ResolveModel(0x00a6, "Mystery Controller")}
+	if err := d.SetKeyColor(0, color.Black); err == nil {
+		t.Fatal("drew on a device without a known display")
+	}
+
+	forced, ok := FindModel("stream deck mk.2")
+	if !ok {
+		t.Fatal("FindModel did not match case-insensitively")
+	}
+	d.UseModel(forced)
+	if d.Model.ProductID != 0x00a6 || d.Model.Keys != 15 || d.Info.Model.PixelSize != 72 {
+		t.Errorf("forced model = %+v", d.Model)
+	}
+	if err := d.SetKeyColor(14, color.Black); err != nil {
+		t.Fatal(err)
+	}
+	if got := writtenKeys(fake); got[14] != 1 {
+		t.Errorf("key images written = %v, want key 14", got)
+	}
+}
+
+func TestUseModelOverridesKnownDevice(t *testing.T) {
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+	d.UseModel(Models[0x006c])
+	if d.Model.ProductID != 0x0080 || d.Cols() != 8 || d.Rows() != 4 || d.Keys() != 32 || d.PixelSize() != 96 {
+		t.Errorf("effective model = %+v, want XL layout with the MK.2 PID", d.Model)
+	}
+	if err := d.SetKeyColor(31, color.Black); err != nil {
+		t.Errorf("key 31 of the forced XL: %v", err)
+	}
+}
+
+func TestListModelNames(t *testing.T) {
+	names := ListModelNames()
+	if len(names) != len(Models) {
+		t.Fatalf("ListModelNames() has %d names, want %d", len(names), len(Models))
+	}
+	if !sort.StringsAreSorted(names) {
+		t.Errorf("ListModelNames() = %v, want sorted", names)
+	}
+	for _, name := range names {
+		if _, ok := FindModel(name); !ok {
+			t.Errorf("FindModel(%q) failed for a listed name", name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package streamdeck
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -8,11 +9,10 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
-
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
-	"golang.org/x/image/math/fixed"
+	"time"
 )
 
 // PageItem represents an item on a page (folder or action).
@@ -21,6 +21,11 @@ type PageItem struct {
 	Path     string // Full path to the item
 	IsFolder bool   // True if this is a folder
 	Script   string // Path to lua script (if action)
+	LoadErr  error  // Why the script failed to load, if it did
+
+	modTime time.Time // used by SortByModTime
+	rawName string    // file name without .lua, including any "NN-" prefix
+	prefix  int       // numeric "NN-" / "NN_" prefix, or -1 if none
 }
 
 // Page represents a single page of items on the Stream Deck.
@@ -38,15 +43,45 @@ type Page struct {
 // Row 1: 5,6,7,8,9
 // Row 2: 10,11,12,13,14
 //
-// TODO: Reserved keys are currently hardcoded for MK.2 (5 cols x 3 rows).
-// These should be dynamically calculated based on the device model's row count
-// and column layout. Consider: ReservedKeys = [0, cols, cols*2, ...] for col 0.
+// The toggle keys move with the layout (other column counts, rotated
+// decks); use Navigator.ToggleKeys rather than KeyToggle1/KeyToggle2.
 const (
 	KeyBack    = 0  // Row 0, Col 0 - Navigate back
 	KeyToggle1 = 5  // Row 1, Col 0 - Reserved toggle (placeholder)
 	KeyToggle2 = 10 // Row 2, Col 0 - Reserved toggle (placeholder)
 )
 
+// SortMode controls the order of items on a page. Folders are always listed
+// before scripts, except where a manual _order file places them explicitly.
+type SortMode int
+
+const (
+	SortByName    SortMode = iota // Alphabetical (default)
+	SortByModTime                 // Most recently modified first
+	SortManual                    // Order listed in the folder's _order file, then by name
+)
+
+// orderFileName is the per-folder file listing item names for SortManual,
+// one per line. Blank lines and lines starting with '#' are ignored.
+const orderFileName = "_order"
+
+// favoritesFileName is the config-root file listing pinned script paths
+// (relative to the root, one per line) shown on the virtual FAVS page.
+const favoritesFileName = "_favorites"
+
+// ParseSortMode maps a config string ("name", "modified", "manual") to a
+// SortMode. Unknown values fall back to SortByName.
+func ParseSortMode(s string) SortMode {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "modified", "mtime", "time":
+		return SortByModTime
+	case "manual", "order":
+		return SortManual
+	default:
+		return SortByName
+	}
+}
+
 // Navigator manages folder-based navigation on a Stream Deck.
 type Navigator struct {
 	dev          *Device
@@ -59,6 +94,31 @@ type Navigator struct {
 	// scriptValidator is called for each .lua file; if set and returns false the
 	// file is hidden from the page (e.g. scripts with no recognised functions).
 	scriptValidator func(path string) bool
+
+	// showHidden reveals dot-files / dot-dirs. Underscore-prefixed control
+	// files (_boot.lua etc.) and .directory.lua stay hidden regardless.
+	showHidden bool
+
+	sortMode SortMode
+
+	// inFavorites is set while the virtual _favorites page is displayed.
+	inFavorites bool
+
+	// keyClaimed reports keys a script has claimed; RenderPage leaves them
+	// untouched.
+	keyClaimed func(keyIndex int) bool
+
+	// itemLoading reports script items whose image is still loading;
+	// RenderPage draws the loading placeholder for them.
+	itemLoading func(item *PageItem) bool
+
+	// scriptError reports why a script failed to load (nil if it loaded).
+	// Failed scripts are shown as error tiles.
+	scriptError func(path string) error
+
+	// readErr is the error that made LoadPage leave an unreadable folder.
+	// RenderPage shows an error tile until the user navigates.
+	readErr error
 }
 
 // NewNavigator creates a new navigator for the given device and root config path.
@@ -94,6 +154,12 @@ func (n *Navigator) calculateKeyLayout() {
 	}
 }
 
+// ToggleKeys returns the reserved T1 and T2 keys: column 0 of rows 1 and 2
+// in the device's current layout.
+func (n *Navigator) ToggleKeys() (t1, t2 int) {
+	return n.dev.CoordToKey(0, 1), n.dev.CoordToKey(0, 2)
+}
+
 // GetContentKeys returns the key indices available for page content.
 func (n *Navigator) GetContentKeys() []int {
 	keys := make([]int, len(n.contentKeys))
@@ -118,14 +184,61 @@ func (n *Navigator) SetScriptValidator(fn func(path string) bool) {
 	n.scriptValidator = fn
 }
 
+// SetKeyClaimed sets a function reporting keys owned by a script. Page
+// rendering skips those keys so the script's own drawing stays visible.
+func (n *Navigator) SetKeyClaimed(fn func(keyIndex int) bool) {
+	n.keyClaimed = fn
+}
+
+// isClaimed reports whether a script has claimed keyIndex.
+func (n *Navigator) isClaimed(keyIndex int) bool {
+	return n.keyClaimed != nil && n.keyClaimed(keyIndex)
+}
+
+// SetItemLoading sets a function reporting script items whose image is
+// still being fetched. Page rendering shows a loading placeholder on their
+// keys instead of the item name.
+func (n *Navigator) SetItemLoading(fn func(item *PageItem) bool) {
+	n.itemLoading = fn
+}
+
+// SetScriptError sets a function reporting why a script failed to load.
+// Page items for such scripts carry the error in LoadErr and render as an
+// error tile with the script name.
+func (n *Navigator) SetScriptError(fn func(path string) error) {
+	n.scriptError = fn
+}
+
+// loadError returns the load error recorded for a script, if any.
+func (n *Navigator) loadError(path string) error {
+	if n.scriptError == nil {
+		return nil
+	}
+	return n.scriptError(path)
+}
+
+// SetShowHidden controls whether dot-prefixed files and folders are listed.
+func (n *Navigator) SetShowHidden(show bool) {
+	n.showHidden = show
+}
+
+// SetSortMode sets the item ordering used by LoadPage.
+func (n *Navigator) SetSortMode(mode SortMode) {
+	n.sortMode = mode
+}
+
 // IsAtRoot returns true if we're at the root config directory.
+// The favorites page is not considered root.
 func (n *Navigator) IsAtRoot() bool {
-	return n.currentDir == n.rootPath
+	return n.currentDir == n.rootPath && !n.inFavorites
 }
 
 // CurrentDirScript returns the path to the .directory.lua inside the current
 // folder, or an empty string if no such file exists.
 func (n *Navigator) CurrentDirScript() string {
+	if n.inFavorites {
+		return ""
+	}
 	p := filepath.Join(n.currentDir, ".directory.lua")
 	if _, err := os.Stat(p); err == nil {
 		return p
@@ -133,8 +246,8 @@ func (n *Navigator) CurrentDirScript() string {
 	return ""
 }
 
-// LoadPage loads the current page and returns page info.
-func (n *Navigator) LoadPage() (*Page, error) {
+// readDirItems lists the visible, sorted items of the current directory.
+func (n *Navigator) readDirItems() ([]PageItem, error) {
 	entries, err := os.ReadDir(n.currentDir)
 	if err != nil {
 		return nil, fmt.Errorf("read dir %s: %w", n.currentDir, err)
@@ -144,27 +257,24 @@ func (n *Navigator) LoadPage() (*Page, error) {
 	var items []PageItem
 	for _, entry := range entries {
 		name := entry.Name()
-
-		// Skip underscore-prefixed entries (internal / private)
-		if len(name) > 0 && name[0] == '_' {
+		if n.isHidden(name) {
 			continue
 		}
 
-		// .directory.lua is a special per-folder passive script, not a button
-		if name == ".directory.lua" {
-			continue
-		}
-
-		// All other dot-files / dot-dirs are also hidden
-		if len(name) > 0 && name[0] == '.' {
-			continue
+		var modTime time.Time
+		if info, err := entry.Info(); err == nil {
+			modTime = info.ModTime()
 		}
 
 		if entry.IsDir() {
+			label, prefix := splitOrderPrefix(name)
 			item := PageItem{
-				Name:     name,
+				Name:     label,
 				Path:     filepath.Join(n.currentDir, name),
 				IsFolder: true,
+				modTime:  modTime,
+				rawName:  name,
+				prefix:   prefix,
 			}
 			// If the folder contains a .directory.lua, attach it so the
 			// passive loop can drive the button's appearance.
@@ -188,26 +298,75 @@ func (n *Navigator) LoadPage() (*Page, error) {
 			continue
 		}
 
+		rawName := name[:len(name)-4] // strip .lua
+		label, prefix := splitOrderPrefix(rawName)
 		items = append(items, PageItem{
-			Name:   name[:len(name)-4], // strip .lua
-			Path:   scriptPath,
-			Script: scriptPath,
+			Name:    label,
+			Path:    scriptPath,
+			Script:  scriptPath,
+			LoadErr: n.loadError(scriptPath),
+			modTime: modTime,
+			rawName: rawName,
+			prefix:  prefix,
 		})
 	}
 
-	// Sort: folders first, then alphabetically
-	sort.Slice(items, func(i, j int) bool {
-		if items[i].IsFolder != items[j].IsFolder {
-			return items[i].IsFolder
+	n.sortItems(items)
+
+	// At root, a non-empty _favorites file adds a virtual folder in front.
+	if n.IsAtRoot() {
+		if favs := n.LoadFavorites(); len(favs) > 0 {
+			fav := PageItem{Name: "FAVS", Path: n.favoritesPath(), IsFolder: true}
+			items = append([]PageItem{fav}, items...)
 		}
-		return items[i].Name < items[j].Name
-	})
+	}
+
+	return items, nil
+}
+
+// readDirItemsOrFallback reads the current directory. If it cannot be read
+// (deleted, permissions changed) the navigator moves up to the nearest
+// readable parent, or shows an empty root, and records the error for the
+// error tile instead of failing the page.
+func (n *Navigator) readDirItemsOrFallback() []PageItem {
+	for {
+		items, err := n.readDirItems()
+		if err == nil {
+			return items
+		}
+		n.readErr = err
+		n.pageIndex = 0
+		if n.IsAtRoot() {
+			fmt.Printf("[!] Navigation: %v\n", err)
+			return nil
+		}
+		parent := filepath.Dir(n.currentDir)
+		fmt.Printf("[!] Navigation: %v; falling back to %s\n", err, parent)
+		n.currentDir = parent
+	}
+}
+
+// LoadPage loads the current page and returns page info. An unreadable
+// folder is left for its nearest readable parent (see readDirItemsOrFallback).
+func (n *Navigator) LoadPage() (*Page, error) {
+	var items []PageItem
+	pagePath := n.currentDir
+	if n.inFavorites {
+		items = n.LoadFavorites()
+		pagePath = n.favoritesPath()
+	} else {
+		items = n.readDirItemsOrFallback()
+		pagePath = n.currentDir
+	}
 
 	// Calculate pagination using content keys only (excludes reserved column)
 	keysAvailable := n.ContentKeyCount()
 
 	totalPages := 1
-	if len(items) > keysAvailable {
+	if keysAvailable == 0 {
+		// No content keys (e.g. a single-column layout): nothing can be shown.
+		items = nil
+	} else if len(items) > keysAvailable {
 		totalPages = (len(items) + keysAvailable - 1) / keysAvailable
 	}
 
@@ -230,12 +389,14 @@ func (n *Navigator) LoadPage() (*Page, error) {
 
 	// Determine parent path
 	parentPath := ""
-	if !n.IsAtRoot() {
+	if n.inFavorites {
+		parentPath = n.currentDir
+	} else if !n.IsAtRoot() {
 		parentPath = filepath.Dir(n.currentDir)
 	}
 
 	return &Page{
-		Path:       n.currentDir,
+		Path:       pagePath,
 		Items:      pageItems,
 		ParentPath: parentPath,
 		PageIndex:  n.pageIndex,
@@ -243,8 +404,206 @@ func (n *Navigator) LoadPage() (*Page, error) {
 	}, nil
 }
 
-// NavigateInto enters a subdirectory.
+// isHidden reports whether a directory entry should be left off the page.
+func (n *Navigator) isHidden(name string) bool {
+	// Skip underscore-prefixed entries (internal / private)
+	if len(name) > 0 && name[0] == '_' {
+		return true
+	}
+
+	// .directory.lua is a special per-folder passive script, not a button
+	if name == ".directory.lua" {
+		return true
+	}
+
+	// All other dot-files / dot-dirs are hidden unless ShowHiddenFiles is set
+	return !n.showHidden && len(name) > 0 && name[0] == '.'
+}
+
+// Search walks the whole config tree and returns every visible script whose
+// label or root-relative path contains query (case-insensitive). An empty
+// query returns all scripts, giving a flattened view of the tree. Results are
+// ordered by relative path; Path holds the full script path.
+func (n *Navigator) Search(query string) []PageItem {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var results []PageItem
+	filepath.WalkDir(n.rootPath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+		if path == n.rootPath {
+			return nil
+		}
+		if n.isHidden(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".lua" {
+			return nil
+		}
+		if n.scriptValidator != nil && !n.scriptValidator(path) {
+			return nil
+		}
+
+		label, _ := splitOrderPrefix(strings.TrimSuffix(entry.Name(), ".lua"))
+		rel, _ := filepath.Rel(n.rootPath, path)
+		if query != "" &&
+			!strings.Contains(strings.ToLower(label), query) &&
+			!strings.Contains(strings.ToLower(filepath.ToSlash(rel)), query) {
+			return nil
+		}
+
+		results = append(results, PageItem{
+			Name:   label,
+			Path:   path,
+			Script: path,
+		})
+		return nil
+	})
+	return results
+}
+
+// sortItems orders items according to the navigator's SortMode.
+func (n *Navigator) sortItems(items []PageItem) {
+	var rank map[string]int
+	if n.sortMode == SortManual {
+		rank = n.readOrderFile()
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if rank != nil {
+			ra, okA := lookupRank(rank, a)
+			rb, okB := lookupRank(rank, b)
+			if okA != okB {
+				return okA // listed items come before unlisted ones
+			}
+			if okA && ra != rb {
+				return ra < rb
+			}
+		}
+		if a.IsFolder != b.IsFolder {
+			return a.IsFolder
+		}
+		if n.sortMode == SortByModTime && !a.modTime.Equal(b.modTime) {
+			return a.modTime.After(b.modTime)
+		}
+		if a.prefix >= 0 && b.prefix >= 0 && a.prefix != b.prefix {
+			return a.prefix < b.prefix // numeric, so 2- sorts before 10-
+		}
+		return a.rawName < b.rawName
+	})
+}
+
+// lookupRank finds an item in an _order map by its label or its raw name.
+func lookupRank(rank map[string]int, item PageItem) (int, bool) {
+	if r, ok := rank[item.rawName]; ok {
+		return r, true
+	}
+	r, ok := rank[item.Name]
+	return r, ok
+}
+
+// splitOrderPrefix strips a leading numeric ordering prefix such as "01-" or
+// "10_" from name, returning the display label and the prefix value (-1 when
+// there is no prefix). A name that is only a prefix is left untouched.
+func splitOrderPrefix(name string) (string, int) {
+	i := 0
+	for i < len(name) && name[i] >= '0' && name[i] <= '9' {
+		i++
+	}
+	if i == 0 || i+1 >= len(name) || (name[i] != '-' && name[i] != '_') {
+		return name, -1
+	}
+	prefix, err := strconv.Atoi(name[:i])
+	if err != nil {
+		return name, -1
+	}
+	return name[i+1:], prefix
+}
+
+// readOrderFile loads the current folder's _order file into a name → rank
+// map. Entries may be written with or without the .lua extension.
+func (n *Navigator) readOrderFile() map[string]int {
+	data, err := os.ReadFile(filepath.Join(n.currentDir, orderFileName))
+	if err != nil {
+		return nil
+	}
+	rank := make(map[string]int)
+	for _, line := range strings.Split(string(data), "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		name = strings.TrimSuffix(name, ".lua")
+		if _, dup := rank[name]; !dup {
+			rank[name] = len(rank)
+		}
+	}
+	return rank
+}
+
+// favoritesPath returns the path of the root _favorites file, which doubles
+// as the Path of the virtual favorites folder.
+func (n *Navigator) favoritesPath() string {
+	return filepath.Join(n.rootPath, favoritesFileName)
+}
+
+// LoadFavorites reads the root _favorites file and returns one PageItem per
+// listed script. Paths may be absolute or relative to the config root; blank
+// lines, '#' comments and entries that do not exist are skipped.
+func (n *Navigator) LoadFavorites() []PageItem {
+	data, err := os.ReadFile(n.favoritesPath())
+	if err != nil {
+		return nil
+	}
+
+	var items []PageItem
+	for _, line := range strings.Split(string(data), "\n") {
+		entry := strings.TrimSpace(line)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		path := filepath.FromSlash(entry)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(n.rootPath, path)
+		}
+		if filepath.Ext(path) != ".lua" {
+			path += ".lua"
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if n.scriptValidator != nil && !n.scriptValidator(path) {
+			continue
+		}
+		label, _ := splitOrderPrefix(strings.TrimSuffix(filepath.Base(path), ".lua"))
+		items = append(items, PageItem{
+			Name:    label,
+			Path:    path,
+			Script:  path,
+			LoadErr: n.loadError(path),
+		})
+	}
+	return items
+}
+
+// InFavorites returns true while the virtual favorites page is shown.
+func (n *Navigator) InFavorites() bool {
+	return n.inFavorites
+}
+
+// NavigateInto enters a subdirectory, or the favorites page when given the
+// virtual favorites folder path.
 func (n *Navigator) NavigateInto(path string) error {
+	if path == n.favoritesPath() {
+		n.inFavorites = true
+		n.pageIndex = 0
+		return nil
+	}
 	info, err := os.Stat(path)
 	if err != nil {
 		return err
@@ -253,23 +612,62 @@ func (n *Navigator) NavigateInto(path string) error {
 		return fmt.Errorf("not a directory: %s", path)
 	}
 	n.currentDir = path
+	n.inFavorites = false
+	n.pageIndex = 0
+	n.readErr = nil
+	return nil
+}
+
+// SetStartPath opens the navigator at a folder given relative to the root
+// (ui.start_path). The folder must exist inside the root; otherwise the
+// navigator stays where it is and an error is returned. "" means the root.
+func (n *Navigator) SetStartPath(rel string) error {
+	if rel == "" {
+		n.NavigateToRoot()
+		return nil
+	}
+	if filepath.IsAbs(rel) {
+		return fmt.Errorf("start path %q must be relative to the config directory", rel)
+	}
+	path := filepath.Join(n.rootPath, filepath.FromSlash(rel))
+	if r, err := filepath.Rel(n.rootPath, path); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("start path %q is outside the config directory", rel)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("start path %q is not a directory", rel)
+	}
+	n.currentDir = path
+	n.inFavorites = false
 	n.pageIndex = 0
+	n.readErr = nil
 	return nil
 }
 
 // NavigateBack goes to the parent directory.
 func (n *Navigator) NavigateBack() bool {
+	if n.inFavorites {
+		n.inFavorites = false
+		n.pageIndex = 0
+		return true
+	}
 	if n.IsAtRoot() {
 		return false
 	}
 	n.currentDir = filepath.Dir(n.currentDir)
 	n.pageIndex = 0
+	n.readErr = nil
 	return true
 }
 
 // NavigateToRoot returns to the root config directory.
 func (n *Navigator) NavigateToRoot() {
+	n.readErr = nil
 	n.currentDir = n.rootPath
+	n.inFavorites = false
 	n.pageIndex = 0
 }
 
@@ -306,43 +704,19 @@ func (n *Navigator) RenderPage() error {
 
 	totalKeys := n.dev.Model.Keys
 	type keyFrame struct {
-		index int
-		data  []byte
-		err   error
+		index   int
+		claimed bool // owned by a script; left as it is
+		data    []byte
+		err     error
 	}
 
 	frames := make([]keyFrame, totalKeys)
 	for i := range frames {
 		frames[i].index = i
+		frames[i].claimed = n.isClaimed(i)
 	}
 
-	// Build image for every key (nil = black / unused)
-	images := make([]image.Image, totalKeys)
-
-	// Reserved column
-	if !n.IsAtRoot() {
-		images[KeyBack] = n.createTextImage("<-", color.RGBA{100, 100, 100, 255})
-	} else {
-		// At root the back key doubles as the settings entry point
-		images[KeyBack] = n.CreateTextImageWithColors("SET", color.RGBA{120, 80, 0, 255}, color.RGBA{255, 200, 50, 255})
-	}
-	// T1 / T2: render a dim default; passive scripts from .directory.lua
-	// will paint over these via the key-update callback.
-	images[KeyToggle1] = n.createTextImage("T1", color.RGBA{30, 30, 30, 255})
-	images[KeyToggle2] = n.createTextImage("T2", color.RGBA{30, 30, 30, 255})
-
-	// Content keys
-	for i, item := range page.Items {
-		if i >= len(n.contentKeys) {
-			break
-		}
-		if item.IsFolder {
-			images[n.contentKeys[i]] = n.createTextImage(truncateName(item.Name, 8), color.RGBA{30, 80, 180, 255})
-		} else {
-			images[n.contentKeys[i]] = n.createTextImage(truncateName(item.Name, 8), color.RGBA{30, 130, 80, 255})
-		}
-	}
-	// Any remaining content keys (no item) stay nil → black
+	images := n.pageImages(page)
 
 	// Encode all keys concurrently
 	blackImg := func() image.Image {
@@ -358,6 +732,9 @@ func (n *Navigator) RenderPage() error {
 		i := i
 		go func() {
 			defer wg.Done()
+			if frames[i].claimed {
+				return
+			}
 			img := images[i]
 			if img == nil {
 				img = blackImg
@@ -369,17 +746,122 @@ func (n *Navigator) RenderPage() error {
 	}
 	wg.Wait()
 
-	// Write serially (HID is not goroutine-safe for concurrent writes)
+	// Write serially (HID is not goroutine-safe for concurrent writes), as
+	// one batch under a single device lock. A failed key does not stop the
+	// rest of the page from rendering, unless the device itself has gone
+	// away. Keys whose image could not be encoded show an error placeholder
+	// instead. Claimed keys are skipped.
+	var errs []error
+	var placeholder []byte
+	batch := make([]KeyFrame, 0, totalKeys)
 	for _, f := range frames {
-		if f.err != nil {
-			return fmt.Errorf("encode key %d: %w", f.index, f.err)
+		if f.claimed {
+			continue
 		}
-		if err := n.dev.WriteKeyData(f.index, f.data); err != nil {
-			return fmt.Errorf("write key %d: %w", f.index, err)
+		if f.err != nil {
+			errs = append(errs, fmt.Errorf("encode key %d: %w", f.index, f.err))
+			if placeholder == nil {
+				placeholder, _ = n.dev.EncodeKeyImage(n.errorImage())
+			}
+			if placeholder == nil {
+				continue
+			}
+			f.data = placeholder
 		}
+		batch = append(batch, KeyFrame{Key: f.index, Data: f.data})
+	}
+	if err := n.dev.WriteKeyDataBatch(batch); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// RenderKey redraws a single key of the current page, e.g. after a script
+// releases its claim on it.
+func (n *Navigator) RenderKey(keyIndex int) error {
+	if keyIndex < 0 || keyIndex >= n.dev.Model.Keys {
+		return fmt.Errorf("key %d out of range", keyIndex)
+	}
+	page, err := n.LoadPage()
+	if err != nil {
+		return err
+	}
+	img := n.pageImages(page)[keyIndex]
+	if img == nil {
+		return n.dev.SetKeyColor(keyIndex, color.Black)
+	}
+	return n.dev.SetImage(keyIndex, img)
+}
+
+// RenderLoadingKey draws the loading placeholder on a key, e.g. while a
+// script's remote image is fetched.
+func (n *Navigator) RenderLoadingKey(keyIndex int) error {
+	return n.dev.SetImage(keyIndex, n.loadingImage())
+}
+
+// loadingImage is the placeholder shown while a key's image loads.
+func (n *Navigator) loadingImage() image.Image {
+	return n.CreateTextImageWithColors("...", color.RGBA{40, 40, 40, 255}, color.RGBA{160, 160, 160, 255})
+}
+
+// errorImage is the tile shown for keys and pages that failed to load.
+func (n *Navigator) errorImage() image.Image {
+	return n.createTextImage("ERR", color.RGBA{160, 0, 0, 255})
+}
+
+// scriptErrorImage is the error tile captioned with the name of a script
+// that failed to load.
+func (n *Navigator) scriptErrorImage(name string) image.Image {
+	return LabelImage(n.errorImage(), truncateName(name, 10), color.White, nil)
+}
+
+// pageImages builds the default image of every key for page (nil = black /
+// unused).
+func (n *Navigator) pageImages(page *Page) []image.Image {
+	images := make([]image.Image, n.dev.Model.Keys)
+
+	// Reserved column
+	if !n.IsAtRoot() {
+		images[KeyBack] = n.createTextImage("<-", color.RGBA{100, 100, 100, 255})
+	} else {
+		// At root the back key doubles as the settings entry point
+		images[KeyBack] = n.CreateTextImageWithColors("SET", color.RGBA{120, 80, 0, 255}, color.RGBA{255, 200, 50, 255})
+	}
+	// T1 / T2: render a dim default; passive scripts from .directory.lua
+	// will paint over these via the key-update callback.
+	t1, t2 := n.ToggleKeys()
+	if t1 < len(images) {
+		images[t1] = n.createTextImage("T1", color.RGBA{30, 30, 30, 255})
+	}
+	if t2 < len(images) {
+		images[t2] = n.createTextImage("T2", color.RGBA{30, 30, 30, 255})
+	}
+
+	// Content keys
+	for i, item := range page.Items {
+		if i >= len(n.contentKeys) {
+			break
+		}
+		if item.IsFolder && item.Path == n.favoritesPath() {
+			images[n.contentKeys[i]] = n.CreateTextImageWithColors(item.Name, color.RGBA{120, 80, 0, 255}, color.RGBA{255, 200, 50, 255})
+		} else if item.IsFolder {
+			images[n.contentKeys[i]] = n.createTextImage(truncateName(item.Name, 8), color.RGBA{30, 80, 180, 255})
+		} else if item.LoadErr != nil {
+			images[n.contentKeys[i]] = n.scriptErrorImage(item.Name)
+		} else if n.itemLoading != nil && n.itemLoading(&item) {
+			images[n.contentKeys[i]] = n.loadingImage()
+		} else {
+			images[n.contentKeys[i]] = n.createTextImage(truncateName(item.Name, 8), color.RGBA{30, 130, 80, 255})
+		}
+	}
+	// After falling back from an unreadable folder, the first free content
+	// key shows an error tile.
+	if n.readErr != nil && len(page.Items) < len(n.contentKeys) {
+		images[n.contentKeys[len(page.Items)]] = n.errorImage()
+	}
+	// Any remaining content keys (no item) stay nil → black
+	return images
 }
 
 // renderReservedKeys renders the reserved column buttons (column 0).
@@ -396,8 +878,9 @@ func (n *Navigator) renderReservedKeys() {
 
 	// T1 / T2: render a dim default; passive scripts from .directory.lua
 	// will paint over these via the key-update callback.
-	n.dev.SetImage(KeyToggle1, n.createTextImage("T1", color.RGBA{30, 30, 30, 255}))
-	n.dev.SetImage(KeyToggle2, n.createTextImage("T2", color.RGBA{30, 30, 30, 255}))
+	t1, t2 := n.ToggleKeys()
+	n.dev.SetImage(t1, n.createTextImage("T1", color.RGBA{30, 30, 30, 255}))
+	n.dev.SetImage(t2, n.createTextImage("T2", color.RGBA{30, 30, 30, 255}))
 }
 
 // HandleKeyPress handles a key press and returns the action to take.
@@ -410,6 +893,7 @@ func (n *Navigator) HandleKeyPress(keyIndex int) (*PageItem, bool, error) {
 	}
 
 	// Check if this is a reserved key (column 0)
+	t1, t2 := n.ToggleKeys()
 	switch keyIndex {
 	case KeyBack:
 		if n.NavigateBack() {
@@ -417,7 +901,7 @@ func (n *Navigator) HandleKeyPress(keyIndex int) (*PageItem, bool, error) {
 		}
 		return nil, false, nil
 
-	case KeyToggle1, KeyToggle2:
+	case t1, t2:
 		// Reserved – handled upstream before HandleKeyPress is called.
 		return nil, false, nil
 	}
@@ -425,24 +909,64 @@ func (n *Navigator) HandleKeyPress(keyIndex int) (*PageItem, bool, error) {
 	// Check if this is a content key
 	for i, ck := range n.contentKeys {
 		if ck == keyIndex {
-			if i < len(page.Items) {
-				item := &page.Items[i]
-				if item.IsFolder {
-					if err := n.NavigateInto(item.Path); err != nil {
-						return nil, false, err
-					}
-					return nil, true, nil
-				}
-				// It's an action/script
-				return item, false, nil
-			}
-			return nil, false, nil // Empty key
+			return n.activateSlot(page, i)
 		}
 	}
 
 	return nil, false, nil
 }
 
+// activateSlot opens the folder in content slot i of page, or returns the
+// action there. Empty slots do nothing.
+func (n *Navigator) activateSlot(page *Page, i int) (*PageItem, bool, error) {
+	if i >= len(page.Items) {
+		return nil, false, nil // Empty key
+	}
+	item := &page.Items[i]
+	if item.IsFolder {
+		if err := n.NavigateInto(item.Path); err != nil {
+			return nil, false, err
+		}
+		return nil, true, nil
+	}
+	// It's an action/script
+	return item, false, nil
+}
+
+// TouchSlot returns the content slot for a tap at x on the touch strip.
+// The strip is split into regions equal parts, left to right, and region i
+// stands for content slot i of the page; regions <= 0 uses one region per
+// content key. ok is false if the model has no strip or x is off it.
+func (n *Navigator) TouchSlot(x, regions int) (slot int, ok bool) {
+	width := n.dev.Model.StripWidth
+	if width <= 0 || x < 0 || x >= width {
+		return 0, false
+	}
+	if regions <= 0 {
+		regions = len(n.contentKeys)
+	}
+	slot = x * regions / width
+	if slot >= len(n.contentKeys) {
+		return 0, false
+	}
+	return slot, true
+}
+
+// HandleTouch handles a tap at x on the touch strip like a press of the
+// content key in the slot TouchSlot maps it to, with the same results as
+// HandleKeyPress. Taps outside every region do nothing.
+func (n *Navigator) HandleTouch(x, regions int) (*PageItem, bool, error) {
+	slot, ok := n.TouchSlot(x, regions)
+	if !ok {
+		return nil, false, nil
+	}
+	page, err := n.LoadPage()
+	if err != nil {
+		return nil, false, err
+	}
+	return n.activateSlot(page, slot)
+}
+
 // GetVisibleScripts returns a map of script paths to key indices for visible scripts.
 // Includes both action scripts and folder .directory.lua passive scripts.
 func (n *Navigator) GetVisibleScripts() map[string]int {
@@ -479,13 +1003,6 @@ func (n *Navigator) CreateTextImageWithColors(text string, bgColor, textColor co
 	// Fill background
 	draw.Draw(img, img.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
 
-	// Draw text centered
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(textColor),
-		Face: basicfont.Face7x13,
-	}
-
 	// Calculate text position (roughly centered)
 	textWidth := len(text) * 7 // basicfont is ~7px wide per char
 	x := (size - textWidth) / 2
@@ -494,8 +1011,7 @@ func (n *Navigator) CreateTextImageWithColors(text string, bgColor, textColor co
 	}
 	y := size/2 + 4 // Center vertically
 
-	d.Dot = fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)}
-	d.DrawString(text)
+	drawText(img, text, x, y, textColor, nil)
 
 	return img
 }
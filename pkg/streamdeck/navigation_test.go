@@ -0,0 +1,608 @@
+package streamdeck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestNavigator builds a Navigator over a temp directory holding n scripts.
+// The Device has no HID handle; LoadPage and paging never touch the hardware.
+func newTestNavigator(t *testing.T, model Model, n int) *Navigator {
+	t.Helper()
+	root := t.TempDir()
+	for i := 0; i < n; i++ {
+		name := filepath.Join(root, fmt.Sprintf("item%03d.lua", i))
+		if err := os.WriteFile(name, []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewNavigator(&Device{Model: model}, root)
+}
+
+func TestLoadPagePagination(t *testing.T) {
+	models := []Model{
+		Models[0x0080], // MK.2: 5x3 → 12 content keys
+		Models[0x006c], // XL: 8x4 → 28 content keys
+		Models[0x0063], // Mini: 3x2 → 4 content keys
+	}
+
+	for _, model := range models {
+		perPage := (model.Cols - 1) * model.Rows
+		cases := []struct {
+			name  string
+			items int
+			pages int
+		}{
+			{"empty", 0, 1},
+			{"one", 1, 1},
+			{"exactly one page", perPage, 1},
+			{"one over", perPage + 1, 2},
+			{"exactly two pages", perPage * 2, 2},
+			{"many", perPage*3 + 2, 4},
+		}
+
+		for _, tc := range cases {
+			t.Run(model.Name+"/"+tc.name, func(t *testing.T) {
+				nav := newTestNavigator(t, model, tc.items)
+				if got := nav.ContentKeyCount(); got != perPage {
+					t.Fatalf("ContentKeyCount = %d, want %d", got, perPage)
+				}
+
+				page, err := nav.LoadPage()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.TotalPages != tc.pages {
+					t.Fatalf("TotalPages = %d, want %d", page.TotalPages, tc.pages)
+				}
+
+				// Walk every page and check the slice boundaries.
+				seen := 0
+				for p := 0; p < tc.pages; p++ {
+					page, err := nav.LoadPage()
+					if err != nil {
+						t.Fatal(err)
+					}
+					if page.PageIndex != p {
+						t.Fatalf("PageIndex = %d, want %d", page.PageIndex, p)
+					}
+					want := perPage
+					if rem := tc.items - p*perPage; rem < perPage {
+						want = rem
+					}
+					if len(page.Items) != want {
+						t.Fatalf("page %d has %d items, want %d", p, len(page.Items), want)
+					}
+					for i, item := range page.Items {
+						if wantName := fmt.Sprintf("item%03d", seen+i); item.Name != wantName {
+							t.Fatalf("page %d item %d = %q, want %q", p, i, item.Name, wantName)
+						}
+					}
+					seen += len(page.Items)
+
+					moved := nav.NextPage()
+					if last := p == tc.pages-1; moved == last {
+						t.Fatalf("NextPage on page %d returned %v", p, moved)
+					}
+				}
+				if seen != tc.items {
+					t.Fatalf("saw %d items across pages, want %d", seen, tc.items)
+				}
+
+				// Walk back to the first page; PrevPage must stop at 0.
+				for p := tc.pages - 1; p > 0; p-- {
+					if !nav.PrevPage() {
+						t.Fatalf("PrevPage from page %d returned false", p)
+					}
+				}
+				if nav.PrevPage() {
+					t.Fatal("PrevPage on first page returned true")
+				}
+			})
+		}
+	}
+}
+
+func TestLoadPageClampsPageIndex(t *testing.T) {
+	model := Models[0x0080]
+	nav := newTestNavigator(t, model, 5)
+	nav.pageIndex = 7
+
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.PageIndex != 0 || len(page.Items) != 5 {
+		t.Fatalf("got page %d with %d items, want page 0 with 5", page.PageIndex, len(page.Items))
+	}
+}
+
+func TestLoadPageNoContentKeys(t *testing.T) {
+	// A single-column layout leaves no content keys once column 0 is reserved.
+	model := Model{Name: "single column", Cols: 1, Rows: 3, Keys: 3, PixelSize: 72}
+	nav := newTestNavigator(t, model, 3)
+
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalPages != 1 || len(page.Items) != 0 {
+		t.Fatalf("got %d pages with %d items, want 1 page with 0", page.TotalPages, len(page.Items))
+	}
+}
+
+func TestLoadPageShowHidden(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 1)
+	for _, name := range []string{".hidden.lua", "_boot.lua", ".directory.lua"} {
+		if err := os.WriteFile(filepath.Join(nav.rootPath, name), []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(nav.rootPath, ".dotdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func() map[string]bool {
+		page, err := nav.LoadPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]bool)
+		for _, item := range page.Items {
+			got[item.Name] = true
+		}
+		return got
+	}
+
+	got := names()
+	if len(got) != 1 || !got["item000"] {
+		t.Fatalf("default visibility: got %v, want only item000", got)
+	}
+
+	nav.SetShowHidden(true)
+	got = names()
+	for _, want := range []string{"item000", ".hidden", ".dotdir"} {
+		if !got[want] {
+			t.Errorf("show hidden: %q missing from %v", want, got)
+		}
+	}
+	for _, hidden := range []string{"_boot", ".directory"} {
+		if got[hidden] {
+			t.Errorf("show hidden: control file %q should stay hidden", hidden)
+		}
+	}
+}
+
+func TestLoadPageSortModes(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 3) // item000, item001, item002
+	if err := os.Mkdir(filepath.Join(nav.rootPath, "folder"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	order := func() []string {
+		page, err := nav.LoadPage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	check := func(mode string, want ...string) {
+		t.Helper()
+		got := order()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", mode, got, want)
+		}
+	}
+
+	check("name", "folder", "item000", "item001", "item002")
+
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"item002.lua", "item000.lua", "item001.lua"} {
+		mtime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(filepath.Join(nav.rootPath, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nav.SetSortMode(SortByModTime)
+	check("modified", "folder", "item001", "item000", "item002")
+
+	orderFile := "# pinned first\nitem002\n\nfolder\nitem000.lua\n"
+	if err := os.WriteFile(filepath.Join(nav.rootPath, orderFileName), []byte(orderFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	nav.SetSortMode(SortManual)
+	check("manual", "item002", "folder", "item000", "item001")
+}
+
+func TestParseSortMode(t *testing.T) {
+	cases := map[string]SortMode{
+		"":         SortByName,
+		"name":     SortByName,
+		"Modified": SortByModTime,
+		"manual":   SortManual,
+		"bogus":    SortByName,
+	}
+	for in, want := range cases {
+		if got := ParseSortMode(in); got != want {
+			t.Errorf("ParseSortMode(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestLoadPageNumericPrefix(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 0)
+	for _, name := range []string{"10-last.lua", "02_second.lua", "1-first.lua", "plain.lua", "42.lua"} {
+		if err := os.WriteFile(filepath.Join(nav.rootPath, name), []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(nav.rootPath, "05-folder"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, item := range page.Items {
+		got = append(got, item.Name)
+	}
+	want := []string{"folder", "first", "second", "last", "42", "plain"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if base := filepath.Base(page.Items[1].Path); base != "1-first.lua" {
+		t.Fatalf("item path lost its prefix: %s", base)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 0)
+	files := []string{
+		"apps/browser.lua",
+		"apps/01-terminal.lua",
+		"system/cpu.lua",
+		"system/net/wifi.lua",
+		"system/net/notes.txt",
+		"_private/secret.lua",
+		".hidden/browser.lua",
+		"system/.directory.lua",
+	}
+	for _, f := range files {
+		path := filepath.Join(nav.rootPath, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(items []PageItem) []string {
+		var out []string
+		for _, item := range items {
+			rel, _ := filepath.Rel(nav.rootPath, item.Path)
+			out = append(out, item.Name+"="+filepath.ToSlash(rel))
+		}
+		return out
+	}
+	check := func(query string, want ...string) {
+		t.Helper()
+		got := names(nav.Search(query))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+
+	check("",
+		"terminal=apps/01-terminal.lua",
+		"browser=apps/browser.lua",
+		"cpu=system/cpu.lua",
+		"wifi=system/net/wifi.lua")
+	check("BROW", "browser=apps/browser.lua")
+	check("net/", "wifi=system/net/wifi.lua")
+	check("secret")
+}
+
+func TestFavorites(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 1) // item000.lua at root
+	for _, f := range []string{"apps/browser.lua", "system/02-cpu.lua"} {
+		path := filepath.Join(nav.rootPath, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("return {}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No _favorites file: no virtual folder.
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Items[0].Name == "FAVS" {
+		t.Fatal("FAVS folder shown without a _favorites file")
+	}
+
+	favs := "# pinned\nsystem/02-cpu.lua\n\napps/browser\napps/missing.lua\n"
+	if err := os.WriteFile(filepath.Join(nav.rootPath, favoritesFileName), []byte(favs), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := nav.LoadFavorites()
+	if len(loaded) != 2 || loaded[0].Name != "cpu" || loaded[1].Name != "browser" {
+		t.Fatalf("LoadFavorites = %+v", loaded)
+	}
+
+	page, err = nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := page.Items[0]; first.Name != "FAVS" || !first.IsFolder {
+		t.Fatalf("first root item = %+v, want FAVS folder", first)
+	}
+
+	// Pressing the FAVS key enters the favorites page.
+	keys := nav.GetContentKeys()
+	item, navigated, err := nav.HandleKeyPress(keys[0])
+	if err != nil || !navigated || item != nil {
+		t.Fatalf("HandleKeyPress(FAVS) = %v, %v, %v", item, navigated, err)
+	}
+	if !nav.InFavorites() || nav.IsAtRoot() {
+		t.Fatal("expected to be on the favorites page")
+	}
+
+	// Selecting a favorite returns it as an action to trigger directly.
+	item, navigated, err = nav.HandleKeyPress(keys[1])
+	if err != nil || navigated || item == nil {
+		t.Fatalf("HandleKeyPress(favorite) = %v, %v, %v", item, navigated, err)
+	}
+	if want := filepath.Join(nav.rootPath, "apps", "browser.lua"); item.Script != want {
+		t.Fatalf("favorite script = %s, want %s", item.Script, want)
+	}
+
+	// Back leaves favorites and returns to root.
+	if _, navigated, _ := nav.HandleKeyPress(KeyBack); !navigated || !nav.IsAtRoot() {
+		t.Fatal("back from favorites did not return to root")
+	}
+}
+
+func TestRenderPageContinuesPastFailedKey(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 3)
+	fake := &fakeHID{failKeys: map[byte]bool{7: true}, writeErr: errors.New("timed out")}
+	nav.dev.hid = fake
+
+	err := nav.RenderPage()
+	if !errors.Is(err, ErrTransientWrite) {
+		t.Fatalf("RenderPage error = %v, want ErrTransientWrite", err)
+	}
+
+	written := make(map[int]bool)
+	for _, report := range fake.writes {
+		written[int(report[2])] = true
+	}
+	for key := 0; key < nav.dev.Model.Keys; key++ {
+		if key == 7 {
+			continue
+		}
+		if !written[key] {
+			t.Errorf("key %d was not written after key 7 failed", key)
+		}
+	}
+}
+
+func TestSetStartPath(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 0)
+	root := nav.CurrentPath()
+	sub := filepath.Join(root, "apps", "media")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sub, "play.lua"), []byte("return {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "file.lua"), []byte("return {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nav.SetStartPath("apps/media"); err != nil {
+		t.Fatal(err)
+	}
+	if nav.CurrentPath() != sub || nav.IsAtRoot() {
+		t.Fatalf("current path = %s, want %s", nav.CurrentPath(), sub)
+	}
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "play" {
+		t.Errorf("start page items = %+v", page.Items)
+	}
+	if !nav.NavigateBack() || nav.CurrentPath() != filepath.Join(root, "apps") {
+		t.Errorf("back from start folder went to %s", nav.CurrentPath())
+	}
+
+	for _, bad := range []string{"missing", "file.lua", "..", "../outside", sub} {
+		nav.NavigateToRoot()
+		if err := nav.SetStartPath(bad); err == nil {
+			t.Errorf("SetStartPath(%q) succeeded", bad)
+		}
+		if nav.CurrentPath() != root {
+			t.Errorf("SetStartPath(%q) moved to %s", bad, nav.CurrentPath())
+		}
+	}
+}
+
+func TestRenderPageLoadingPlaceholder(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 2)
+	nav.dev.hid = &fakeHID{}
+	nav.SetItemLoading(func(item *PageItem) bool { return item.Name == "item001" })
+
+	if err := nav.RenderPage(); err != nil {
+		t.Fatal(err)
+	}
+
+	loading, err := nav.dev.EncodeKeyImage(nav.loadingImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := nav.GetContentKeys()
+	if !bytes.Equal(nav.dev.KeyData(keys[1]), loading) {
+		t.Error("loading item was not drawn with the placeholder")
+	}
+	if bytes.Equal(nav.dev.KeyData(keys[0]), loading) {
+		t.Error("loaded item was drawn with the placeholder")
+	}
+
+	if err := nav.RenderLoadingKey(keys[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(nav.dev.KeyData(keys[0]), loading) {
+		t.Error("RenderLoadingKey did not draw the placeholder")
+	}
+}
+
+func TestRenderPageFallsBackFromDeletedDir(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 2)
+	nav.dev.hid = &fakeHID{}
+	root := nav.CurrentPath()
+	parent := filepath.Join(root, "apps")
+	sub := filepath.Join(parent, "media")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := nav.NavigateInto(sub); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nav.RenderPage(); err != nil {
+		t.Fatalf("RenderPage after deleting the current dir: %v", err)
+	}
+	if nav.CurrentPath() != parent {
+		t.Fatalf("current path = %s, want parent %s", nav.CurrentPath(), parent)
+	}
+
+	// The parent is empty, so the error tile takes the first content key.
+	errTile, err := nav.dev.EncodeKeyImage(nav.errorImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(nav.dev.KeyData(nav.GetContentKeys()[0]), errTile) {
+		t.Error("error tile was not drawn after the fallback")
+	}
+
+	// Navigating clears the error.
+	if !nav.NavigateBack() || nav.CurrentPath() != root {
+		t.Fatalf("back went to %s", nav.CurrentPath())
+	}
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Items) != 3 {
+		t.Errorf("root items = %+v", page.Items)
+	}
+	if nav.readErr != nil {
+		t.Errorf("readErr not cleared: %v", nav.readErr)
+	}
+
+	// An unreadable root shows an empty page instead of failing.
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := nav.RenderPage(); err != nil {
+		t.Fatalf("RenderPage with a missing root: %v", err)
+	}
+	if !nav.IsAtRoot() {
+		t.Errorf("navigator left the root: %s", nav.CurrentPath())
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x0080], 2)
+	apps := filepath.Join(nav.CurrentPath(), "apps")
+	if err := os.Mkdir(apps, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(apps, "play.lua"), []byte("return {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "/ (page 1/1)\n" +
+		"row 1: Settings | apps/ | item000 | item001 | -\n" +
+		"row 2: T1 | - | - | - | -\n" +
+		"row 3: T2 | - | - | - | -\n"
+	if got := nav.Describe(); got != want {
+		t.Errorf("root description:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := nav.NavigateInto(apps); err != nil {
+		t.Fatal(err)
+	}
+	nav.SetKeyClaimed(func(key int) bool { return key == 14 })
+	want = "/apps (page 1/1)\n" +
+		"row 1: Back | play | - | - | -\n" +
+		"row 2: T1 | - | - | - | -\n" +
+		"row 3: T2 | - | - | - | [claimed]\n"
+	if got := nav.Describe(); got != want {
+		t.Errorf("subfolder description:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHandleTouchMapsStripToItems(t *testing.T) {
+	nav := newTestNavigator(t, Models[0x009a], 6) // Stream Deck +: 6 content keys, 800px strip
+	page, err := nav.LoadPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		x, regions int
+		item       int // index into page.Items, -1 for none
+	}{
+		{0, 4, 0},
+		{199, 4, 0},
+		{200, 4, 1},
+		{450, 4, 2},
+		{799, 4, 3},
+		{799, 0, 5}, // one region per content key
+		{400, 0, 3},
+		{800, 4, -1}, // off the strip
+		{-1, 4, -1},
+	}
+	for _, c := range cases {
+		item, navigated, err := nav.HandleTouch(c.x, c.regions)
+		if err != nil || navigated {
+			t.Fatalf("x=%d: navigated=%v err=%v", c.x, navigated, err)
+		}
+		if c.item < 0 {
+			if item != nil {
+				t.Errorf("x=%d regions=%d: got %s, want nothing", c.x, c.regions, item.Name)
+			}
+			continue
+		}
+		if item == nil || item.Path != page.Items[c.item].Path {
+			t.Errorf("x=%d regions=%d: got %v, want item %d (%s)", c.x, c.regions, item, c.item, page.Items[c.item].Name)
+		}
+	}
+
+	// Models without a strip ignore touches
+	mk2 := newTestNavigator(t, Models[0x0080], 3)
+	if _, ok := mk2.TouchSlot(10, 4); ok {
+		t.Error("TouchSlot mapped a touch on a deck without a strip")
+	}
+}
@@ -0,0 +1,75 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// qrQuietZone is the white margin around a key QR code, in modules. The
+// spec asks for 4 but phone readers cope with 2, and small keys need the
+// room for larger modules.
+const qrQuietZone = 2
+
+// qrMinModule is the smallest module size, in pixels, QRImage prefers.
+// Below it medium error correction is traded for a smaller code.
+const qrMinModule = 2
+
+// QRImage renders data as a black-on-white QR code filling a size x size
+// image. Modules are whole pixels so the code stays sharp; medium error
+// correction is used unless that would shrink modules below qrMinModule,
+// in which case the lower level's smaller code is used instead.
+func QRImage(data string, size int) (image.Image, error) {
+	var bits [][]bool
+	for _, level := range []qrcode.RecoveryLevel{qrcode.Medium, qrcode.Low} {
+		q, err := qrcode.New(data, level)
+		if err != nil {
+			return nil, fmt.Errorf("qr: %w", err)
+		}
+		q.DisableBorder = true
+		bits = q.Bitmap()
+		if size/(len(bits)+2*qrQuietZone) >= qrMinModule {
+			break
+		}
+	}
+
+	n := len(bits) + 2*qrQuietZone
+	module := size / n
+	if module < 1 {
+		return nil, fmt.Errorf("qr: %d bytes of data do not fit a %dpx key", len(data), size)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := (size - len(bits)*module) / 2
+	black := image.NewUniform(color.Black)
+	for y, row := range bits {
+		for x, set := range row {
+			if !set {
+				continue
+			}
+			r := image.Rect(x*module, y*module, (x+1)*module, (y+1)*module).Add(image.Pt(offset, offset))
+			draw.Draw(img, r, black, image.Point{}, draw.Src)
+		}
+	}
+	return img, nil
+}
+
+// SetQR draws data as a QR code sized to a key, e.g. a URL or a
+// "WIFI:T:WPA;S:name;P:password;;" network string.
+func (d *Device) SetQR(keyIndex int, data string) error {
+	if keyIndex < 0 || keyIndex >= d.Model.Keys {
+		return fmt.Errorf("key index %d out of range (0-%d)", keyIndex, d.Model.Keys-1)
+	}
+	if d.Model.PixelSize == 0 {
+		return fmt.Errorf("device does not support images")
+	}
+	img, err := QRImage(data, d.Model.PixelSize)
+	if err != nil {
+		return err
+	}
+	return d.SetImage(keyIndex, img)
+}
@@ -0,0 +1,69 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"strings"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	gozxingqr "github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// decodeQR reads the QR code in img.
+func decodeQR(t *testing.T, img image.Image) string {
+	t.Helper()
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := gozxingqr.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return res.GetText()
+}
+
+func TestQRImageDecodes(t *testing.T) {
+	for _, data := range []string{
+		"https://example.com/",
+		"WIFI:T:WPA;S:home-network;P:correct horse battery staple;;",
+	} {
+		img, err := QRImage(data, 72)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != 72 || b.Dy() != 72 {
+			t.Fatalf("bounds = %v, want 72x72", b)
+		}
+		if got := decodeQR(t, img); got != data {
+			t.Errorf("decoded %q, want %q", got, data)
+		}
+	}
+}
+
+func TestQRImageTooLong(t *testing.T) {
+	if _, err := QRImage(strings.Repeat("x", 1000), 72); err == nil {
+		t.Fatal("expected an error for data that cannot fit the key")
+	}
+}
+
+func TestSetQR(t *testing.T) {
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+	data := "https://example.com/"
+	if err := d.SetQR(4, data); err != nil {
+		t.Fatal(err)
+	}
+	// What reaches the key (JPEG, rotated for the hardware) still scans.
+	img, err := jpeg.Decode(bytes.NewReader(d.KeyData(4)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeQR(t, img); got != data {
+		t.Errorf("decoded %q, want %q", got, data)
+	}
+	if err := d.SetQR(99, data); err == nil {
+		t.Error("expected an error for an out-of-range key")
+	}
+}
@@ -0,0 +1,139 @@
+package streamdeck
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// Rotation support for decks mounted sideways or upside down. Everything
+// above the Device works in the rotated (logical) layout as the user sees
+// it: Cols, Rows, key indices, key events and the images drawn on keys.
+// The Device maps logical keys to physical ones when writing and reading,
+// and turns key images so they appear upright. The dials and touch strip
+// of the Stream Deck + are not remapped.
+
+// SetRotation sets how far the deck is mounted rotated clockwise: 0, 90,
+// 180 or 270 degrees. At 90 and 270 columns and rows swap, so call it
+// before creating a Navigator and before drawing any keys.
+func (d *Device) SetRotation(degrees int) error {
+	switch degrees {
+	case 0, 90, 180, 270:
+	default:
+		return fmt.Errorf("rotation must be 0, 90, 180 or 270 degrees, got %d", degrees)
+	}
+	d.rotation.Store(int32(degrees / 90))
+
+	// Cached file images were turned for the previous rotation.
+	d.fileImages.mu.Lock()
+	d.fileImages.images = nil
+	d.fileImages.mu.Unlock()
+	return nil
+}
+
+// Rotation returns the rotation set with SetRotation, in degrees.
+func (d *Device) Rotation() int {
+	return int(d.rotation.Load()) * 90
+}
+
+// quarterTurns returns the rotation in clockwise quarter turns (0-3).
+func (d *Device) quarterTurns() int {
+	return int(d.rotation.Load())
+}
+
+// RowKeys returns the key indices of a zero-based row as the deck is
+// mounted, left to right, or nil if the row does not exist.
+func (d *Device) RowKeys(row int) []int {
+	return d.layout().RowKeys(row)
+}
+
+// ColKeys returns the key indices of a zero-based column as the deck is
+// mounted, top to bottom, or nil if the column does not exist.
+func (d *Device) ColKeys(col int) []int {
+	return d.layout().ColKeys(col)
+}
+
+// layout returns the model with Cols and Rows as the deck is mounted.
+func (d *Device) layout() Model {
+	m := d.Model
+	m.Cols, m.Rows = d.Cols(), d.Rows()
+	return m
+}
+
+// physicalKey maps a logical key index to the key the hardware addresses.
+func (d *Device) physicalKey(key int) int {
+	turns := d.quarterTurns()
+	if turns == 0 || d.Model.Cols == 0 || key < 0 || key >= d.Model.Cols*d.Model.Rows {
+		return key
+	}
+	c, r := d.Model.Cols, d.Model.Rows
+	lc, lr := key%d.Cols(), key/d.Cols()
+	var pc, pr int
+	switch turns {
+	case 1:
+		pc, pr = lr, r-1-lc
+	case 2:
+		pc, pr = c-1-lc, r-1-lr
+	case 3:
+		pc, pr = c-1-lr, lc
+	}
+	return pr*c + pc
+}
+
+// logicalKey maps a hardware key index to its index in the rotated layout.
+func (d *Device) logicalKey(key int) int {
+	turns := d.quarterTurns()
+	if turns == 0 || d.Model.Cols == 0 || key < 0 || key >= d.Model.Cols*d.Model.Rows {
+		return key
+	}
+	c, r := d.Model.Cols, d.Model.Rows
+	pc, pr := key%c, key/c
+	var lc, lr int
+	switch turns {
+	case 1:
+		lc, lr = r-1-pr, pc
+	case 2:
+		lc, lr = c-1-pc, r-1-pr
+	case 3:
+		lc, lr = pr, c-1-pc
+	}
+	return lr*d.Cols() + lc
+}
+
+// rotateQuarterTurns returns a square image turned clockwise by turns
+// quarter turns. Zero turns returns img itself.
+func rotateQuarterTurns(img *image.RGBA, turns int) *image.RGBA {
+	turns = ((turns % 4) + 4) % 4
+	if turns == 0 {
+		return img
+	}
+	b := img.Bounds()
+	n := b.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			var dx, dy int
+			switch turns {
+			case 1:
+				dx, dy = n-1-y, x
+			case 2:
+				dx, dy = n-1-x, n-1-y
+			case 3:
+				dx, dy = y, n-1-x
+			}
+			si, di := img.PixOffset(b.Min.X+x, b.Min.Y+y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], img.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// toRGBA returns img as an *image.RGBA, converting it if needed.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	return dst
+}
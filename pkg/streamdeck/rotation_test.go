@@ -0,0 +1,130 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+// rotationModel is a small lossless model for checking key images:
+//
+//	0 1 2
+//	3 4 5
+var rotationModel = Model{Name: "Fake 3x2", Cols: 3, Rows: 2, Keys: 6, PixelSize: 4, ImageFormat: "BMP"}
+
+func TestSetRotationRejectsOddAngles(t *testing.T) {
+	d := &Device{Model: rotationModel}
+	if err := d.SetRotation(45); err == nil {
+		t.Fatal("expected an error for 45 degrees")
+	}
+	if d.Rotation() != 0 {
+		t.Errorf("Rotation() = %d after a rejected angle, want 0", d.Rotation())
+	}
+}
+
+func TestRotationKeyMapping(t *testing.T) {
+	// Mounted 90 degrees clockwise the deck is seen as 2 columns x 3 rows:
+	//
+	//	3 0
+	//	4 1
+	//	5 2
+	d := &Device{Model: rotationModel}
+	if err := d.SetRotation(90); err != nil {
+		t.Fatal(err)
+	}
+	if d.Cols() != 2 || d.Rows() != 3 {
+		t.Fatalf("layout = %dx%d, want 2x3", d.Cols(), d.Rows())
+	}
+	want := []int{3, 0, 4, 1, 5, 2}
+	for key, phys := range want {
+		if got := d.physicalKey(key); got != phys {
+			t.Errorf("physicalKey(%d) = %d, want %d", key, got, phys)
+		}
+	}
+	if got := d.RowKeys(2); !reflect.DeepEqual(got, []int{4, 5}) {
+		t.Errorf("RowKeys(2) = %v, want [4 5]", got)
+	}
+
+	// Every rotation is a permutation that logicalKey undoes.
+	for _, deg := range []int{0, 90, 180, 270} {
+		if err := d.SetRotation(deg); err != nil {
+			t.Fatal(err)
+		}
+		seen := map[int]bool{}
+		for key := 0; key < d.Model.Keys; key++ {
+			phys := d.physicalKey(key)
+			if phys < 0 || phys >= d.Model.Keys || seen[phys] {
+				t.Fatalf("%d degrees: key %d maps to %d, not a permutation", deg, key, phys)
+			}
+			seen[phys] = true
+			if back := d.logicalKey(phys); back != key {
+				t.Errorf("%d degrees: logicalKey(physicalKey(%d)) = %d", deg, key, back)
+			}
+		}
+	}
+}
+
+func TestRotationRemapsWritesAndPresses(t *testing.T) {
+	fake := &fakeHID{}
+	d := &Device{hid: fake, Model: rotationModel}
+	if err := d.SetRotation(90); err != nil {
+		t.Fatal(err)
+	}
+
+	// Top-left pixel of logical key 0 marked white.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
+	img.Set(0, 0, color.White)
+	if err := d.SetImage(0, img); err != nil {
+		t.Fatal(err)
+	}
+	if key := fake.writes[0][2]; key != 3 {
+		t.Errorf("logical key 0 written to hardware key %d, want 3", key)
+	}
+
+	// Stored images are the 180 degree hardware rotation plus a quarter
+	// turn back against the mounting, i.e. one clockwise quarter turn.
+	stored, _, err := image.Decode(bytes.NewReader(d.KeyData(0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.RGBAModel.Convert(stored.At(3, 0)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("stored marker pixel = %v, want white at top right", c)
+	}
+
+	// The snapshot shows the deck as mounted, marker back at top left.
+	snap := d.Snapshot()
+	wantBounds := image.Rect(0, 0, 2*4+3*snapshotGap, 3*4+4*snapshotGap)
+	if snap.Bounds() != wantBounds {
+		t.Fatalf("snapshot bounds = %v, want %v", snap.Bounds(), wantBounds)
+	}
+	if c := color.RGBAModel.Convert(snap.At(snapshotGap, snapshotGap)).(color.RGBA); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("snapshot marker = %v, want white", c)
+	}
+
+	// Hardware key 0 is logical key 1.
+	report := make([]byte, 4+d.Model.Keys)
+	report[4] = 1
+	if got := d.parseKeyReport(report); !reflect.DeepEqual(got, []bool{false, true, false, false, false, false}) {
+		t.Errorf("press of hardware key 0 = %v, want logical key 1", got)
+	}
+}
+
+func TestRotationRemapsNavigatorKeys(t *testing.T) {
+	d := &Device{hid: &fakeHID{}, Model: Models[0x0080]}
+	if err := d.SetRotation(90); err != nil {
+		t.Fatal(err)
+	}
+	n := NewNavigator(d, t.TempDir())
+
+	// 3 columns x 5 rows: column 0 is reserved, the other two hold content.
+	if got, want := n.GetContentKeys(), []int{1, 2, 4, 5, 7, 8, 10, 11, 13, 14}; !reflect.DeepEqual(got, want) {
+		t.Errorf("content keys = %v, want %v", got, want)
+	}
+	if t1, t2 := n.ToggleKeys(); t1 != 3 || t2 != 6 {
+		t.Errorf("toggle keys = %d, %d, want 3, 6", t1, t2)
+	}
+}
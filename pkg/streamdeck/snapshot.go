@@ -0,0 +1,62 @@
+package streamdeck
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+
+	_ "golang.org/x/image/bmp" // decode keyData on BMP models
+)
+
+// snapshotGap is the spacing in pixels between keys (and around the edge)
+// in a Snapshot.
+const snapshotGap = 8
+
+// snapshotBackground fills the gaps and any key that has not been drawn.
+var snapshotBackground = color.RGBA{20, 20, 20, 255}
+
+// Snapshot composites the last image written to every key into a single
+// picture laid out like the deck as mounted, with gaps between keys. Keys that
+// have not been drawn since the device was opened are left as background.
+// Returns nil for models without a display.
+func (d *Device) Snapshot() image.Image {
+	size := d.Model.PixelSize
+	cols, rows := d.Cols(), d.Rows()
+	if size == 0 || cols == 0 || rows == 0 {
+		return nil
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0,
+		cols*size+(cols+1)*snapshotGap,
+		rows*size+(rows+1)*snapshotGap))
+	draw.Draw(out, out.Bounds(), &image.Uniform{snapshotBackground}, image.Point{}, draw.Src)
+
+	d.mu.Lock()
+	keyData := make(map[int][]byte, len(d.keyData))
+	for k, v := range d.keyData {
+		keyData[k] = v
+	}
+	d.mu.Unlock()
+
+	for key, data := range keyData {
+		if key < 0 || key >= cols*rows {
+			continue
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		col, row := key%cols, key/cols
+		x0 := snapshotGap + col*(size+snapshotGap)
+		y0 := snapshotGap + row*(size+snapshotGap)
+
+		// Key images are stored rotated 180 degrees for the hardware and
+		// turned against the mounting rotation; undo both so the snapshot
+		// matches what is seen on the deck.
+		upright := rotateQuarterTurns(toRGBA(img), 2+d.quarterTurns())
+		draw.Draw(out, image.Rect(x0, y0, x0+size, y0+size), upright, upright.Bounds().Min, draw.Src)
+	}
+
+	return out
+}
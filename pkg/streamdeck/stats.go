@@ -0,0 +1,27 @@
+package streamdeck
+
+import "sync/atomic"
+
+// WriteStats counts the HID traffic of a device since it was opened.
+type WriteStats struct {
+	Reports   uint64 // Output reports written
+	Errors    uint64 // Reports that failed after all retries
+	KeyImages uint64 // Key images written (each is one or more reports)
+}
+
+// writeCounters backs WriteStats. The counters are atomic so WriteStats
+// does not wait for a write in progress.
+type writeCounters struct {
+	reports   atomic.Uint64
+	errors    atomic.Uint64
+	keyImages atomic.Uint64
+}
+
+// WriteStats returns the device's HID write counters.
+func (d *Device) WriteStats() WriteStats {
+	return WriteStats{
+		Reports:   d.stats.reports.Load(),
+		Errors:    d.stats.errors.Load(),
+		KeyImages: d.stats.keyImages.Load(),
+	}
+}
@@ -0,0 +1,54 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// labelMargin is the gap between a label's baseline area and the bottom
+// edge of the key.
+const labelMargin = 4
+
+// drawText draws text with its baseline at (x, y) in basicfont. When
+// outline is non-nil the text is first drawn offset by one pixel in every
+// direction so it stays readable on busy backgrounds.
+func drawText(dst draw.Image, text string, x, y int, textColor, outline color.Color) {
+	d := &font.Drawer{Dst: dst, Face: basicfont.Face7x13}
+	if outline != nil {
+		d.Src = image.NewUniform(outline)
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dx == 0 && dy == 0 {
+					continue
+				}
+				d.Dot = fixed.P(x+dx, y+dy)
+				d.DrawString(text)
+			}
+		}
+	}
+	d.Src = image.NewUniform(textColor)
+	d.Dot = fixed.P(x, y)
+	d.DrawString(text)
+}
+
+// LabelImage returns a copy of img with text drawn centred along its bottom
+// edge, the usual layout for an icon with a caption. outline may be nil.
+func LabelImage(img image.Image, text string, textColor, outline color.Color) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Bounds(), img, b.Min, draw.Src)
+
+	width := font.MeasureString(basicfont.Face7x13, text).Round()
+	x := (b.Dx() - width) / 2
+	if x < 2 {
+		x = 2
+	}
+	y := b.Dy() - labelMargin - basicfont.Face7x13.Descent
+	drawText(out, text, x, y, textColor, outline)
+	return out
+}
@@ -0,0 +1,64 @@
+package streamdeck
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestLabelImageOverlaysText(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	icon := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	draw.Draw(icon, icon.Bounds(), &image.Uniform{red}, image.Point{}, draw.Src)
+
+	out := LabelImage(icon, "PLAY", color.White, color.Black)
+
+	if out.Bounds() != icon.Bounds() {
+		t.Fatalf("bounds %v, want %v", out.Bounds(), icon.Bounds())
+	}
+	if got := out.RGBAAt(36, 10); got != red {
+		t.Fatalf("icon area is %v, want %v", got, red)
+	}
+	if got := icon.RGBAAt(36, 60); got != red {
+		t.Fatal("LabelImage modified its input")
+	}
+
+	// Count label pixels in the bottom band, where the caption belongs.
+	var white, black int
+	for y := 48; y < 72; y++ {
+		for x := 0; x < 72; x++ {
+			switch out.RGBAAt(x, y) {
+			case color.RGBA{255, 255, 255, 255}:
+				white++
+			case color.RGBA{0, 0, 0, 255}:
+				black++
+			}
+		}
+	}
+	if white == 0 {
+		t.Fatal("no text pixels in the label area")
+	}
+	if black == 0 {
+		t.Fatal("no outline pixels in the label area")
+	}
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 72; x++ {
+			if out.RGBAAt(x, y) != red {
+				t.Fatalf("pixel (%d,%d) above the label changed", x, y)
+			}
+		}
+	}
+}
+
+func TestLabelImageNoOutline(t *testing.T) {
+	icon := image.NewRGBA(image.Rect(0, 0, 72, 72))
+	out := LabelImage(icon, "A", color.White, nil)
+	for y := 0; y < 72; y++ {
+		for x := 0; x < 72; x++ {
+			if c := out.RGBAAt(x, y); c != (color.RGBA{}) && c != (color.RGBA{255, 255, 255, 255}) {
+				t.Fatalf("pixel (%d,%d) = %v, want only background or text", x, y, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package streamdeck
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// reportTrace writes raw HID reports for EnableReportTrace.
+type reportTrace struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// EnableReportTrace writes a hex dump of every input report read from the
+// device and every feature report sent to it to w, one line per report:
+//
+//	15:04:05.000 input   32 bytes: 01 00 0f 00 00 01 00 ...
+//
+// It helps work out the key offset and report layout of models this
+// package does not know yet. Image writes are not traced. A nil w turns
+// tracing off.
+func (d *Device) EnableReportTrace(w io.Writer) {
+	d.trace.mu.Lock()
+	defer d.trace.mu.Unlock()
+	d.trace.w = w
+}
+
+// traceReport writes one report to the trace, if enabled.
+func (d *Device) traceReport(kind string, p []byte) {
+	d.trace.mu.Lock()
+	defer d.trace.mu.Unlock()
+	if d.trace.w == nil {
+		return
+	}
+	fmt.Fprintf(d.trace.w, "%s %-7s %d bytes: % x\n", time.Now().Format("15:04:05.000"), kind, len(p), p)
+}
+
+// sendFeatureReport sends a feature report, tracing it.
+// Must be called with d.mu held.
+func (d *Device) sendFeatureReport(p []byte) (int, error) {
+	if d.closed {
+		return 0, errClosed
+	}
+	d.traceReport("feature", p)
+	return d.hid.SendFeatureReport(p)
+}
@@ -0,0 +1,48 @@
+package streamdeck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportTrace(t *testing.T) {
+	hid := newReportHID()
+	d := &Device{hid: hid, Model: Models[0x0080]}
+	d.SetKeyPolling(0, time.Millisecond)
+	var out bytes.Buffer
+	d.EnableReportTrace(&out)
+
+	hid.reports <- []byte{0x01, 0x00, 0x0f, 0x00, 0x00, 0x00, 0xab}
+	if _, err := d.ReadKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetBrightness(50); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteKeyData(0, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("traced %d lines, want 2 (image writes are not traced):\n%s", len(lines), out.String())
+	}
+	if want := "input   7 bytes: 01 00 0f 00 00 00 ab"; !strings.HasSuffix(lines[0], want) {
+		t.Errorf("input line = %q, want suffix %q", lines[0], want)
+	}
+	if want := "feature 32 bytes: 03 08 32 00"; !strings.Contains(lines[1], want) {
+		t.Errorf("feature line = %q, want %q", lines[1], want)
+	}
+
+	d.EnableReportTrace(nil)
+	out.Reset()
+	hid.reports <- keyReport(1, true)
+	if _, err := d.ReadKeys(); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("traced after disabling: %q", out.String())
+	}
+}
@@ -0,0 +1,35 @@
+package streamdeck
+
+import "time"
+
+// OpenVirtual returns a Device for model that is not backed by hardware.
+// Writes succeed and are discarded, though KeyData and Snapshot still
+// report what was drawn; no key events are ever read. It lets the app run
+// headless, driven through the control API.
+func OpenVirtual(model Model) *Device {
+	return &Device{
+		hid:   nullHID{},
+		Model: model,
+		Info: DeviceInfo{
+			Path:         "virtual",
+			Manufacturer: "NOMAD",
+			Product:      model.Name + " (virtual)",
+			Model:        model,
+		},
+	}
+}
+
+// nullHID is the hidDevice behind a virtual Device.
+type nullHID struct{}
+
+func (nullHID) Write(p []byte) (int, error)             { return len(p), nil }
+func (nullHID) SendFeatureReport(p []byte) (int, error) { return len(p), nil }
+func (nullHID) GetFeatureReport(p []byte) (int, error)  { return len(p), nil }
+func (nullHID) Close() error                            { return nil }
+
+// ReadWithTimeout waits out the timeout and reports no data, so key
+// listeners poll at their usual rate instead of spinning.
+func (nullHID) ReadWithTimeout(p []byte, timeout time.Duration) (int, error) {
+	time.Sleep(timeout)
+	return 0, nil
+}